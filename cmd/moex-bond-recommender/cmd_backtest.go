@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// suggestStrategy - название стратегии, при которой портфель формируется так же, как в команде suggest
+const suggestStrategy = "suggest"
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "backtest",
+		Short: "Replay collections and portfolio suggestions on historical data and compare realized returns with predicted ones",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	rootCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL, collectionsPath string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
+	strategies := cmd.Flags().StringArray("strategy", []string{}, "strategy to test: \"suggest\" or a collection ID (defaults to all)")
+	partsRaw := cmd.Flags().StringArray("part", []string{}, "define portfolio part for \"suggest\" strategy (format: COLLECTION_NAME=WEIGHT)")
+	amount := cmd.Flags().Float64("amount", 100000.0, "amount to invest (RUB)")
+	durationStr := cmd.Flags().StringP(
+		"duration",
+		"d",
+		string(recommender.Duration1Year),
+		"Bond duration range (1y/2y/3y/4y/5y)")
+	fromStr := cmd.Flags().String("from", "", "first portfolio date (YYYY-MM-DD), defaults to 3 years ago")
+	tillStr := cmd.Flags().String("till", "", "last portfolio date (YYYY-MM-DD), defaults to today")
+	step := cmd.Flags().Int("step", 1, "interval between portfolio dates, months")
+	noReinvest := cmd.Flags().Bool("no-reinvest", false, "keep received payments in cash instead of reinvesting them")
+	fetchHistory := cmd.Flags().Bool("fetch-history", false, "fetch missing historical daily prices before running the backtest")
+	format := cmd.Flags().String("format", "table", "output format (table/csv)")
+	outputPath := cmd.Flags().StringP("output", "o", "-", "write report to file (\"-\" for stdout)")
+	getCostModel := attachCostModelFlags(cmd)
+	getConstraints := attachSuggestConstraintsFlags(cmd)
+
+	parseDate := func(s string, defaultValue time.Time) (time.Time, error) {
+		if s == "" {
+			return defaultValue, nil
+		}
+
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("\"%s\" is not a valid date", s)
+		}
+
+		return t, nil
+	}
+
+	formatDate := func(t time.Time) string {
+		return t.Format("2006-01-02")
+	}
+
+	formatStatus := func(p *recommender.BacktestPeriod) string {
+		switch {
+		case len(p.Positions) == 0:
+			return "no bonds"
+		case p.Completed:
+			return "completed"
+		default:
+			return "open"
+		}
+	}
+
+	printTable := func(w io.Writer, names []string, results []*recommender.BacktestResult) {
+		table := uitable.New()
+		for i := 3; i <= 10; i++ {
+			table.RightAlign(i)
+		}
+		table.AddRow("STRATEGY", "DATE", "END DATE", "POSITIONS", "INVESTED", "PREDICTED RATE", "REALIZED RATE", "DEVIATION", "PREDICTED YTM", "REALIZED YTM", "STATUS")
+		for i, result := range results {
+			for _, p := range result.Periods {
+				table.AddRow(
+					names[i],
+					formatDate(p.Date),
+					formatDate(p.EndDate),
+					fmt.Sprintf("%d", len(p.Positions)),
+					fmt.Sprintf("%0.2f RUB", p.Amount),
+					fmt.Sprintf("%0.2f%%", p.PredictedInterestRate),
+					fmt.Sprintf("%0.2f%%", p.InterestRate),
+					fmt.Sprintf("%+0.2f%%", p.Deviation),
+					fmt.Sprintf("%0.2f%%", p.PredictedYieldToMaturity),
+					fmt.Sprintf("%0.2f%%", p.YieldToMaturity),
+					formatStatus(p))
+			}
+		}
+		fmt.Fprintf(w, "PERIODS\n\n%s\n\n", table)
+
+		table = uitable.New()
+		for i := 1; i <= 4; i++ {
+			table.RightAlign(i)
+		}
+		table.AddRow("STRATEGY", "COMPLETED PERIODS", "PREDICTED RATE", "REALIZED RATE", "DEVIATION")
+		for i, result := range results {
+			table.AddRow(
+				names[i],
+				fmt.Sprintf("%d of %d", result.CompletedPeriods, len(result.Periods)),
+				fmt.Sprintf("%0.2f%%", result.PredictedInterestRate),
+				fmt.Sprintf("%0.2f%%", result.RealizedInterestRate),
+				fmt.Sprintf("%+0.2f%%", result.Deviation))
+		}
+		fmt.Fprintf(w, "SUMMARY\n\n%s\n", table)
+	}
+
+	writeCSV := func(w io.Writer, names []string, results []*recommender.BacktestResult) error {
+		formatFloat := func(v float64) string {
+			return strconv.FormatFloat(v, 'f', 2, 64)
+		}
+
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{
+			"strategy", "date", "end_date", "positions", "amount", "predicted_profit_loss", "predicted_interest_rate",
+			"predicted_yield_to_maturity", "reinvested", "value", "profit_loss", "interest_rate", "yield_to_maturity",
+			"deviation", "completed",
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, result := range results {
+			for _, p := range result.Periods {
+				err = writer.Write([]string{
+					names[i],
+					formatDate(p.Date),
+					formatDate(p.EndDate),
+					strconv.Itoa(len(p.Positions)),
+					formatFloat(p.Amount),
+					formatFloat(p.PredictedProfitLoss),
+					formatFloat(p.PredictedInterestRate),
+					formatFloat(p.PredictedYieldToMaturity),
+					formatFloat(p.Reinvested),
+					formatFloat(p.Value),
+					formatFloat(p.ProfitLoss),
+					formatFloat(p.InterestRate),
+					formatFloat(p.YieldToMaturity),
+					formatFloat(p.Deviation),
+					strconv.FormatBool(p.Completed),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *format != "table" && *format != "csv" {
+			return fmt.Errorf("\"%s\" is not a valid format, valid values are: table, csv", *format)
+		}
+
+		duration, err := parseDuration(*durationStr)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		from, err := parseDate(*fromStr, today.AddDate(-3, 0, 0))
+		if err != nil {
+			return err
+		}
+		till, err := parseDate(*tillStr, today)
+		if err != nil {
+			return err
+		}
+
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+
+		constraints, err := getConstraints()
+		if err != nil {
+			return err
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		if *fetchHistory {
+			err = app.FetchHistory(ctx, from.AddDate(0, 0, -7))
+			if err != nil {
+				return err
+			}
+		}
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		names := *strategies
+		if len(names) == 0 {
+			names = []string{suggestStrategy}
+			for _, collection := range u.ListCollections() {
+				names = append(names, collection.ID())
+			}
+		}
+
+		results := make([]*recommender.BacktestResult, len(names))
+		for i, name := range names {
+			request := &recommender.BacktestRequest{
+				Portfolio: recommender.SuggestRequest{
+					Amount:      *amount,
+					MaxDuration: duration,
+					Costs:       costs,
+					Constraints: constraints,
+				},
+				From:       from,
+				Till:       till,
+				StepMonths: *step,
+				Reinvest:   !*noReinvest,
+			}
+
+			if name == suggestStrategy {
+				for _, partRaw := range *partsRaw {
+					part, err := parseSuggestPart(u, partRaw)
+					if err != nil {
+						return err
+					}
+
+					request.Portfolio.Parts = append(request.Portfolio.Parts, part)
+				}
+			} else {
+				collection, err := u.GetCollection(name)
+				if err != nil {
+					return fmt.Errorf("collection \"%s\" doesn't exist", name)
+				}
+
+				request.Portfolio.Parts = []*recommender.SuggestRequestPart{{Collection: collection, Weight: 1}}
+			}
+
+			results[i], err = u.Backtest(request)
+			if err != nil {
+				return err
+			}
+		}
+
+		var w io.Writer = os.Stdout
+		if *outputPath != "-" {
+			f, err := os.Create(*outputPath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if *format == "csv" {
+			return writeCSV(w, names, results)
+		}
+
+		printTable(w, names, results)
+		return nil
+	}
+}
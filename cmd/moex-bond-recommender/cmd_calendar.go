@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/ical"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "calendar [BOND[:QUANTITY]...]",
+		Short: "Show upcoming cash flow calendar for a portfolio or a set of bonds",
+	}
+
+	rootCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+	portfolioID := cmd.Flags().Int("portfolio", 0, "portfolio ID")
+	icsPath := cmd.Flags().String("ics", "", "export calendar to iCalendar file (\"-\" for stdout)")
+
+	formatEventType := func(t recommender.CalendarEventType) string {
+		switch t {
+		case recommender.CouponEvent:
+			return "CPN"
+		case recommender.AmortizationEvent:
+			return "AMR"
+		case recommender.MaturityEvent:
+			return "MAT"
+		case recommender.OfferEvent:
+			return "OFR"
+		}
+		return string(t)
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if (*portfolioID == 0) == (len(args) == 0) {
+			return fmt.Errorf("either --portfolio or a list of bonds must be specified")
+		}
+
+		positions, err := app.ParseCalendarPositions(args)
+		if err != nil {
+			return err
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		name := "Выплаты по облигациям"
+		var calendar *recommender.Calendar
+		if *portfolioID != 0 {
+			portfolio, c, err := u.GetPortfolioCalendar(*portfolioID)
+			if err != nil {
+				return err
+			}
+
+			name = fmt.Sprintf("Выплаты: %s", portfolio.Name)
+			calendar = c
+		} else {
+			calendar, err = u.GetCalendar(positions)
+			if err != nil {
+				return err
+			}
+		}
+
+		if *icsPath != "" {
+			var w io.Writer = os.Stdout
+			if *icsPath != "-" {
+				f, err := os.Create(*icsPath)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			return ical.Write(w, name, calendar)
+		}
+
+		table := uitable.New()
+		table.RightAlign(4)
+		table.AddRow("DATE", "TYPE", "ISIN", "Q", "VALUE")
+		for _, e := range calendar.Events {
+			table.AddRow(
+				e.Date.Format("2006-01-02"),
+				formatEventType(e.Type),
+				e.Bond.ISIN,
+				fmt.Sprintf("%d", e.Quantity),
+				fmt.Sprintf("%0.2f %s", e.ValueRub, "RUB"))
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", table)
+
+		return nil
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/app"
@@ -14,37 +17,80 @@ func init() {
 	rootCommand.AddCommand(cmd)
 
 	var (
-		postgresConnString, moexURL      string
-		fetchStaticData, fetchMarketData bool
+		postgresConnString, moexURL, historyFrom, collectionsPath, ratingsPath string
+		fetchStaticData, fetchMarketData, fetchHistory                         bool
 	)
 	attachPostgresUrlFlag(cmd, &postgresConnString)
 	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
+	attachIssuerRatingsFlag(cmd, &ratingsPath)
+	getHistoryOptions := attachHistoryRetentionFlags(cmd)
+	getProjectionOptions := attachProjectionFlags(cmd)
+	getCostModel := attachCostModelFlags(cmd)
 	cmd.Flags().BoolVarP(&fetchStaticData, "static", "s", false, "Fetch static data")
 	cmd.Flags().BoolVarP(&fetchMarketData, "market", "m", false, "Fetch market data")
+	cmd.Flags().BoolVar(&fetchHistory, "history", false, "Fetch historical daily prices")
+	cmd.Flags().StringVar(&historyFrom, "history-from", "", "Fetch historical daily prices since this date (YYYY-MM-DD), defaults to full history")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		ctx := createCancellableContext()
 
-		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		var from time.Time
+		if historyFrom != "" {
+			var err error
+			from, err = time.Parse("2006-01-02", historyFrom)
+			if err != nil {
+				return fmt.Errorf("\"%s\" is not a valid date", historyFrom)
+			}
+		}
+
+		options, err := getHistoryOptions()
+		if err != nil {
+			return err
+		}
+		projectionOptions, err := getProjectionOptions()
+		if err != nil {
+			return err
+		}
+		options = append(options, projectionOptions...)
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+		if costs != nil {
+			options = append(options, app.WithCostModel(costs))
+		}
+		options = append(options, app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath), app.WithIssuerRatingsPath(ratingsPath))
+
+		app, err := app.New(options...)
 		if err != nil {
 			return err
 		}
 		defer app.Close()
 
-		if fetchStaticData || !fetchStaticData && !fetchMarketData {
+		fetchDefault := !fetchStaticData && !fetchMarketData && !fetchHistory
+
+		if fetchStaticData || fetchDefault {
 			err = app.FetchStaticData(ctx)
 			if err != nil {
 				return err
 			}
 		}
 
-		if fetchMarketData || !fetchStaticData && !fetchMarketData {
+		if fetchMarketData || fetchDefault {
 			err = app.FetchMarketData(ctx)
 			if err != nil {
 				return err
 			}
 		}
 
+		if fetchHistory {
+			err = app.FetchHistory(ctx, from)
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "income",
+		Short: "Build a portfolio which cash flows cover target payouts",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	rootCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL, collectionsPath string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
+	monthly := cmd.Flags().Float64("monthly", 10000.0, "target monthly payout (RUB)")
+	horizon := cmd.Flags().Int("horizon", 12, "number of monthly payouts")
+	liabilitiesRaw := cmd.Flags().StringArray("liability", []string{}, "define payout schedule instead of monthly payouts (format: YYYY-MM-DD=AMOUNT)")
+	collectionID := cmd.Flags().StringP("collection", "c", "", "select bonds from collection (defaults to all bonds except high risk ones)")
+	getCostModel := attachCostModelFlags(cmd)
+
+	printResult := func(result *recommender.CashFlowMatchResult) {
+		indent := ""
+
+		// Overview
+		table := uitable.New()
+		table.RightAlign(2)
+		table.AddRow(indent, "Invested", fmt.Sprintf("%0.2f %s", result.Portfolio.Amount, "RUB"))
+		table.AddRow(indent, "Profit", fmt.Sprintf("%0.2f %s", result.Portfolio.ProfitLoss, "RUB"))
+		table.AddRow(indent, "Yield to maturity", fmt.Sprintf("%0.2f%%", result.Portfolio.YieldToMaturity))
+		if result.Covered {
+			table.AddRow(indent, "Payouts covered", "yes")
+		} else {
+			table.AddRow(indent, "Payouts covered", "no")
+		}
+		fmt.Fprintf(os.Stdout, "OVERVIEW\n\n%s\n\n", table)
+
+		// Positions
+		table = uitable.New()
+		for i := 3; i <= 6; i++ {
+			table.RightAlign(i)
+		}
+		table.AddRow("", "ISIN", "NAME", "Q", "INVESTED", "PRINCIPAL", "YTM")
+		for _, position := range result.Portfolio.Positions {
+			table.AddRow(
+				indent,
+				position.Bond.ISIN,
+				position.Bond.ShortName,
+				fmt.Sprintf("%d", position.Quantity),
+				fmt.Sprintf("%0.2f %s", position.OpenValue, position.Currency),
+				fmt.Sprintf("%0.2f %s", position.Principal(), position.Currency),
+				fmt.Sprintf("%0.2f%%", position.YieldToMaturity))
+		}
+		fmt.Fprintf(os.Stdout, "POSITIONS\n\n%s\n\n", table)
+
+		// Payout schedule
+		table = uitable.New()
+		for i := 2; i <= 4; i++ {
+			table.RightAlign(i)
+		}
+		table.AddRow("", "DATE", "PAYOUT", "INFLOW", "BALANCE")
+		for _, item := range result.Schedule {
+			table.AddRow(
+				indent,
+				item.Date.Format("2006-01-02"),
+				fmt.Sprintf("%0.2f %s", item.Liability, "RUB"),
+				fmt.Sprintf("%0.2f %s", item.Inflow, "RUB"),
+				fmt.Sprintf("%0.2f %s", item.Balance, "RUB"))
+		}
+		fmt.Fprintf(os.Stdout, "SCHEDULE\n\n%s\n", table)
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+
+		request := &recommender.CashFlowMatchRequest{
+			MonthlyPayout: *monthly,
+			Horizon:       *horizon,
+			Costs:         costs,
+		}
+		for _, s := range *liabilitiesRaw {
+			liability, err := parseLiability(s)
+			if err != nil {
+				return err
+			}
+
+			request.Liabilities = append(request.Liabilities, liability)
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		if *collectionID != "" {
+			request.Collection, err = u.GetCollection(*collectionID)
+			if err != nil {
+				if err == recommender.ErrNotFound {
+					return fmt.Errorf("collection \"%s\" doesn't exist", *collectionID)
+				}
+				return err
+			}
+		}
+
+		result, err := u.MatchCashFlows(request)
+		if err != nil {
+			if err == recommender.ErrNotFound {
+				return fmt.Errorf("no bonds found to cover the payouts")
+			}
+			return err
+		}
+
+		printResult(result)
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "ladder",
+		Short: "Build a bond ladder",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	rootCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL, collectionsPath string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
+	amount := cmd.Flags().Float64("amount", 100000.0, "amount to invest (RUB)")
+	horizon := cmd.Flags().Int("horizon", 36, "ladder horizon, months")
+	interval := cmd.Flags().Int("interval", 6, "interval between ladder rungs, months")
+	collectionID := cmd.Flags().StringP("collection", "c", "", "select bonds from collection (defaults to all bonds except high risk ones)")
+	getCostModel := attachCostModelFlags(cmd)
+	getConstraints := attachSuggestConstraintsFlags(cmd)
+
+	printLadder := func(result *recommender.LadderResult) {
+		indent := ""
+
+		// Overview
+		table := uitable.New()
+		table.RightAlign(2)
+		table.AddRow(indent, "Invested", fmt.Sprintf("%0.2f %s", result.Portfolio.Amount, "RUB"))
+		table.AddRow(indent, "Duration", fmt.Sprintf("%d days", result.Portfolio.DurationDays))
+		table.AddRow(indent, "Profit", fmt.Sprintf("%0.2f %s", result.Portfolio.ProfitLoss, "RUB"))
+		table.AddRow(indent, "Yield to maturity", fmt.Sprintf("%0.2f%%", result.Portfolio.YieldToMaturity))
+		table.AddRow(indent, "Macaulay duration", fmt.Sprintf("%0.2f", result.Portfolio.MacaulayDuration))
+		fmt.Fprintf(os.Stdout, "OVERVIEW\n\n%s\n\n", table)
+
+		// Rungs and positions
+		table = uitable.New()
+		for i := 5; i <= 8; i++ {
+			table.RightAlign(i)
+		}
+		table.AddRow("", "FROM", "TILL", "ISIN", "NAME", "Q", "INVESTED", "PRINCIPAL", "YTM")
+		for _, rung := range result.Rungs {
+			table.AddRow(
+				indent,
+				rung.From.Format("2006-01-02"),
+				rung.Till.Format("2006-01-02"),
+				"",
+				"",
+				"",
+				fmt.Sprintf("%0.2f %s", rung.Amount, "RUB"),
+				fmt.Sprintf("%0.2f %s", rung.Principal, "RUB"),
+				"")
+			for _, position := range rung.Positions {
+				table.AddRow(
+					indent,
+					"",
+					"",
+					position.Bond.ISIN,
+					position.Bond.ShortName,
+					fmt.Sprintf("%d", position.Quantity),
+					fmt.Sprintf("%0.2f %s", position.OpenValue, position.Currency),
+					fmt.Sprintf("%0.2f %s", position.Principal(), position.Currency),
+					fmt.Sprintf("%0.2f%%", position.YieldToMaturity))
+			}
+		}
+		fmt.Fprintf(os.Stdout, "RUNGS\n\n%s\n\n", table)
+
+		// Cash flow
+		days := recommender.NewSuggestionCalendar(result.Portfolio).Days()
+		if len(days) > 0 {
+			table = uitable.New()
+			table.RightAlign(2)
+			table.AddRow("", "DATE", "AMOUNT")
+			for _, r := range days {
+				table.AddRow(indent, r.Date.Format("2006-01-02"), fmt.Sprintf("%0.2f %s", r.Amount, "RUB"))
+			}
+			fmt.Fprintf(os.Stdout, "CASH FLOW\n\n%s\n", table)
+		}
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+
+		constraints, err := getConstraints()
+		if err != nil {
+			return err
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		request := &recommender.LadderRequest{
+			Amount:      *amount,
+			Horizon:     *horizon,
+			Interval:    *interval,
+			Costs:       costs,
+			Constraints: constraints,
+		}
+		if *collectionID != "" {
+			request.Collection, err = u.GetCollection(*collectionID)
+			if err != nil {
+				if err == recommender.ErrNotFound {
+					return fmt.Errorf("collection \"%s\" doesn't exist", *collectionID)
+				}
+				return err
+			}
+		}
+
+		result, err := u.BuildLadder(request)
+		if err != nil {
+			if err == recommender.ErrNotFound {
+				return fmt.Errorf("no bonds found for the ladder")
+			}
+			return err
+		}
+
+		printLadder(result)
+		return nil
+	}
+}
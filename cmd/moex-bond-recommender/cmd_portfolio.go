@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var portfolioCommand = &cobra.Command{
+	Use:              "portfolio",
+	Short:            "Portfolio commands",
+	TraverseChildren: true,
+}
+
+func init() {
+	rootCommand.AddCommand(portfolioCommand)
+}
+
+func parsePortfolioID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("\"%s\" is not a valid portfolio ID", s)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create an empty portfolio",
+		Args:  cobra.ExactArgs(1),
+	}
+	portfolioCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		portfolio, err := u.CreatePortfolio(args[0])
+		if err != nil {
+			return err
+		}
+
+		err = u.Commit()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "portfolio #%d \"%s\" has been created\n", portfolio.ID, portfolio.Name)
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List portfolios",
+		Args:  cobra.ExactArgs(0),
+	}
+	portfolioCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		portfolios, err := u.ListPortfolios()
+		if err != nil {
+			return err
+		}
+
+		table := uitable.New()
+		table.MaxColWidth = 80
+		table.Wrap = true
+		table.AddRow("ID", "NAME", "UPDATED")
+		for _, portfolio := range portfolios {
+			table.AddRow(fmt.Sprintf("%d", portfolio.ID), portfolio.Name, portfolio.UpdatedAt.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", table)
+
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "trade ID BOND QUANTITY",
+		Short: "Record a trade in a portfolio (negative quantity for sell)",
+		Args:  cobra.ExactArgs(3),
+	}
+	portfolioCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+	price := cmd.Flags().Float64("price", 0, "clean price, % of face value (defaults to current price)")
+	fee := cmd.Flags().Float64("fee", 0, "trade fee (defaults to default broker fee)")
+	dateStr := cmd.Flags().String("date", "", "trade date, YYYY-MM-DD (defaults to today)")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		id, err := parsePortfolioID(args[0])
+		if err != nil {
+			return err
+		}
+
+		quantity, err := strconv.Atoi(args[2])
+		if err != nil || quantity == 0 {
+			return fmt.Errorf("\"%s\" is not a valid quantity", args[2])
+		}
+
+		tradeArgs := app.AddTradeArgs{
+			Bond:     args[1],
+			Quantity: quantity,
+		}
+		if cmd.Flags().Changed("price") {
+			tradeArgs.Price = price
+		}
+		if cmd.Flags().Changed("fee") {
+			tradeArgs.Fee = fee
+		}
+		if *dateStr != "" {
+			tradeArgs.Date, err = time.Parse("2006-01-02", *dateStr)
+			if err != nil {
+				return fmt.Errorf("\"%s\" is not a valid date", *dateStr)
+			}
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		trade, err := u.AddTrade(id, tradeArgs)
+		if err != nil {
+			return err
+		}
+
+		err = u.Commit()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(
+			os.Stdout,
+			"trade #%d has been recorded: %d x %0.2f%% on %s, fee %0.2f\n",
+			trade.ID,
+			trade.Quantity,
+			trade.Price,
+			trade.Date.Format("2006-01-02"),
+			trade.Fee)
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "untrade ID TRADE_ID",
+		Short: "Delete a trade from a portfolio",
+		Args:  cobra.ExactArgs(2),
+	}
+	portfolioCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		id, err := parsePortfolioID(args[0])
+		if err != nil {
+			return err
+		}
+
+		tradeID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("\"%s\" is not a valid trade ID", args[1])
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		err = u.DeleteTrade(id, tradeID)
+		if err != nil {
+			return err
+		}
+
+		err = u.Commit()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "trade #%d has been deleted\n", tradeID)
+		return nil
+	}
+}
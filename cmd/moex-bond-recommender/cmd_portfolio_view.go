@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "view ID",
+		Short: "View portfolio valuation",
+		Args:  cobra.ExactArgs(1),
+	}
+	portfolioCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		id, err := parsePortfolioID(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		valuation, err := u.GetPortfolio(id)
+		if err != nil {
+			return err
+		}
+
+		indent := ""
+
+		// Overview
+		table := uitable.New()
+		table.RightAlign(2)
+		table.AddRow(indent, "Invested", fmt.Sprintf("%0.2f %s", valuation.Cost, "RUB"))
+		table.AddRow(indent, "Market value", fmt.Sprintf("%0.2f %s", valuation.MarketValue, "RUB"))
+		table.AddRow(indent, "Unrealized P/L", fmt.Sprintf("%0.2f %s", valuation.UnrealizedProfitLoss, "RUB"))
+		table.AddRow(indent, "", fmt.Sprintf("%0.2f%%", valuation.RelativeProfitLoss))
+		table.AddRow(indent, "Realized P/L", fmt.Sprintf("%0.2f %s", valuation.RealizedProfitLoss, "RUB"))
+		table.AddRow(indent, "Yield to maturity", fmt.Sprintf("%0.2f%%", valuation.YieldToMaturity))
+		table.AddRow(indent, "Modified duration", fmt.Sprintf("%0.2f", valuation.ModifiedDuration))
+		table.AddRow(indent, "DV01", fmt.Sprintf("%0.2f RUB", valuation.DV01))
+		fmt.Fprintf(os.Stdout, "#%d \"%s\"\n\n%s\n\n", valuation.Portfolio.ID, valuation.Portfolio.Name, table)
+
+		// Holdings
+		table = uitable.New()
+		for i := 3; i <= 7; i++ {
+			table.RightAlign(i)
+		}
+		table.AddRow("", "ISIN", "NAME", "Q", "INVESTED", "MARKET VALUE", "P/L", "YTM", "PART IN PORTFOLIO")
+		for _, h := range valuation.Holdings {
+			ytm := ""
+			if h.Report != nil {
+				ytm = fmt.Sprintf("%0.2f%%", h.Report.YieldToMaturity)
+			}
+			table.AddRow(
+				indent,
+				h.Bond.ISIN,
+				h.Bond.ShortName,
+				fmt.Sprintf("%d", h.Quantity),
+				fmt.Sprintf("%0.2f", h.Cost),
+				fmt.Sprintf("%0.2f", h.MarketValue),
+				fmt.Sprintf("%0.2f", h.UnrealizedProfitLoss),
+				ytm,
+				fmt.Sprintf("%0.2f%%", h.Weight*100.0))
+		}
+		fmt.Fprintf(os.Stdout, "HOLDINGS\n\n%s\n\n", table)
+
+		// Trades
+		table = uitable.New()
+		table.RightAlign(4)
+		table.RightAlign(5)
+		table.RightAlign(6)
+		table.AddRow("", "ID", "DATE", "ISIN", "Q", "PRICE", "FEE")
+		for _, t := range valuation.Portfolio.Trades {
+			table.AddRow(
+				indent,
+				fmt.Sprintf("%d", t.ID),
+				t.Date.Format("2006-01-02"),
+				t.Bond.ISIN,
+				fmt.Sprintf("%d", t.Quantity),
+				fmt.Sprintf("%0.2f%%", t.Price),
+				fmt.Sprintf("%0.2f", t.Fee))
+		}
+		fmt.Fprintf(os.Stdout, "TRADES\n\n%s\n", table)
+
+		return nil
+	}
+}
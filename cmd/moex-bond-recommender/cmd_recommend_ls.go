@@ -18,15 +18,16 @@ func init() {
 	recommendCommand.AddCommand(cmd)
 
 	var (
-		postgresConnString, moexURL string
+		postgresConnString, moexURL, collectionsPath string
 	)
 	attachPostgresUrlFlag(cmd, &postgresConnString)
 	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		ctx := createCancellableContext()
 
-		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath))
 		if err != nil {
 			return err
 		}
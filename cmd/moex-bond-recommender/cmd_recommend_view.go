@@ -22,10 +22,11 @@ func init() {
 	recommendCommand.AddCommand(cmd)
 
 	var (
-		postgresConnString, moexURL string
+		postgresConnString, moexURL, collectionsPath string
 	)
 	attachPostgresUrlFlag(cmd, &postgresConnString)
 	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
 
 	var durationStr string
 	cmd.Flags().StringVarP(
@@ -34,6 +35,7 @@ func init() {
 		"d",
 		string(recommender.Duration1Year),
 		"Bond duration range (1y/2y/3y/4y/5y)")
+	rankStr := cmd.Flags().String("rank", "", "bond ranking (yield/spread), defaults to the first ranking supported by the collection")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		duration, err := parseDuration(durationStr)
@@ -43,7 +45,7 @@ func init() {
 
 		ctx := createCancellableContext()
 
-		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath))
 		if err != nil {
 			return err
 		}
@@ -60,7 +62,15 @@ func init() {
 			return err
 		}
 
-		reports, err := u.ListCollectionBonds(collection.ID(), duration)
+		ranking := collection.Rankings()[0]
+		if *rankStr != "" {
+			ranking = recommender.Ranking(*rankStr)
+			if !recommender.SupportsRanking(collection, ranking) {
+				return fmt.Errorf("collection \"%s\" can't be ranked by \"%s\"", collection.ID(), ranking)
+			}
+		}
+
+		reports, err := u.ListCollectionBonds(collection.ID(), duration, ranking)
 		if err != nil {
 			return err
 		}
@@ -73,13 +83,23 @@ func init() {
 			return v.Time.Format("2006-01-02")
 		}
 
+		var formatSpread = func(v *float64) string {
+			if v == nil {
+				return ""
+			}
+
+			return fmt.Sprintf("%0.0f bp", *v)
+		}
+
 		table := uitable.New()
 		table.RightAlign(2)
 		table.RightAlign(3)
 		table.RightAlign(4)
 		table.RightAlign(5)
 		table.RightAlign(6)
-		table.AddRow("ISIN", "NAME", "MATURITY DATE", "PRICE", "OPEN VALUE", "PROFIT/LOSS", "INTEREST RATE")
+		table.RightAlign(7)
+		table.RightAlign(8)
+		table.AddRow("ISIN", "NAME", "MATURITY DATE", "PRICE", "OPEN VALUE", "PROFIT/LOSS", "INTEREST RATE", "YTM", "SPREAD")
 		for _, report := range reports {
 			table.AddRow(
 				report.Bond.ISIN,
@@ -88,7 +108,9 @@ func init() {
 				fmt.Sprintf("%0.2f%%", report.OpenPrice),
 				fmt.Sprintf("%0.2f %s", report.OpenValue, report.Currency),
 				fmt.Sprintf("%0.2f %s", report.ProfitLoss, report.Currency),
-				fmt.Sprintf("%0.2f%%", report.InterestRate))
+				fmt.Sprintf("%0.2f%%", report.InterestRate),
+				fmt.Sprintf("%0.2f%%", report.YieldToMaturity),
+				formatSpread(report.Spread))
 		}
 		fmt.Fprintf(os.Stdout, "%s (%s)\n\n%s\n", collection.Name(), duration, table)
 
@@ -19,10 +19,15 @@ func init() {
 	rootCommand.AddCommand(cmd)
 
 	var (
-		postgresConnString, moexURL, address, googleAnalyticsID string
+		postgresConnString, moexURL, address, googleAnalyticsID, collectionsPath, ratingsPath string
 	)
 	attachPostgresUrlFlag(cmd, &postgresConnString)
 	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
+	attachIssuerRatingsFlag(cmd, &ratingsPath)
+	getHistoryOptions := attachHistoryRetentionFlags(cmd)
+	getProjectionOptions := attachProjectionFlags(cmd)
+	getCostModel := attachCostModelFlags(cmd)
 	attachListenAddressFlag(cmd, &address)
 	attachGoogleAnalyticsFlag(cmd, &googleAnalyticsID)
 	debugMode := cmd.Flags().Bool("debug", false, "enable debug mode")
@@ -30,7 +35,25 @@ func init() {
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		ctx := createCancellableContext()
 
-		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		options, err := getHistoryOptions()
+		if err != nil {
+			return err
+		}
+		projectionOptions, err := getProjectionOptions()
+		if err != nil {
+			return err
+		}
+		options = append(options, projectionOptions...)
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+		if costs != nil {
+			options = append(options, app.WithCostModel(costs))
+		}
+		options = append(options, app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath), app.WithIssuerRatingsPath(ratingsPath))
+
+		app, err := app.New(options...)
 		if err != nil {
 			return err
 		}
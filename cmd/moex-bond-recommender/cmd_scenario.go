@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "scenario PORTFOLIO_ID",
+		Short: "Run interest rate scenarios against portfolio holdings",
+		Args:  cobra.ExactArgs(1),
+	}
+
+	rootCommand.AddCommand(cmd)
+
+	var (
+		postgresConnString, moexURL string
+	)
+	attachPostgresUrlFlag(cmd, &postgresConnString)
+	attachMoexUrlFlag(cmd, &moexURL)
+	shift := cmd.Flags().Float64("shift", 0, "custom scenario: parallel yield shift, bp")
+	twist := cmd.Flags().Float64("twist", 0, "custom scenario: yield curve slope change, bp per year of duration (positive for steepener)")
+	creditSpread := cmd.Flags().Float64("credit-spread", 0, "custom scenario: credit spread change for non-government bonds, bp")
+	issuerSpreadsRaw := cmd.Flags().StringArray("issuer-spread", []string{}, "custom scenario: issuer spread change (format: ISSUER_ID=BP)")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		id, err := parsePortfolioID(args[0])
+		if err != nil {
+			return err
+		}
+
+		// Если параметры сценария не заданы, то используются стандартные сценарии
+		scenarios := recommender.StandardScenarios()
+		if cmd.Flags().Changed("shift") || cmd.Flags().Changed("twist") ||
+			cmd.Flags().Changed("credit-spread") || cmd.Flags().Changed("issuer-spread") {
+			scenario := &recommender.Scenario{
+				Name:          "custom",
+				Shift:         *shift,
+				Twist:         *twist,
+				CreditSpread:  *creditSpread,
+				IssuerSpreads: make(map[int]float64),
+			}
+			for _, s := range *issuerSpreadsRaw {
+				issuerID, spread, err := parseIssuerSpread(s)
+				if err != nil {
+					return err
+				}
+
+				scenario.IssuerSpreads[issuerID] += spread
+			}
+
+			scenarios = []*recommender.Scenario{scenario}
+		}
+
+		ctx := createCancellableContext()
+
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+
+		u, err := app.NewUnitOfWork(ctx)
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+
+		portfolio, results, err := u.RunPortfolioScenarios(id, scenarios)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "#%d \"%s\"\n\n", portfolio.ID, portfolio.Name)
+		printScenarios(results)
+		return nil
+	}
+}
+
+func parseIssuerSpread(s string) (int, float64, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("\"%s\" is not a valid issuer spread, expected format is ISSUER_ID=BP", s)
+	}
+
+	issuerID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("\"%s\" is not a valid issuer ID", parts[0])
+	}
+
+	spread, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return issuerID, spread, nil
+}
+
+func printScenarios(results []*recommender.ScenarioResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	indent := ""
+
+	// Scenarios
+	table := uitable.New()
+	for i := 2; i <= 4; i++ {
+		table.RightAlign(i)
+	}
+	table.AddRow("", "SCENARIO", "VALUE", "P/L", "")
+	for _, r := range results {
+		table.AddRow(
+			indent,
+			r.Scenario.Name,
+			fmt.Sprintf("%0.2f %s", r.Value, "RUB"),
+			fmt.Sprintf("%0.2f %s", r.ProfitLoss, "RUB"),
+			fmt.Sprintf("%0.2f%%", r.RelativeProfitLoss))
+	}
+	fmt.Fprintf(os.Stdout, "SCENARIOS\n\n%s\n\n", table)
+
+	// P/L by positions
+	table = uitable.New()
+	header := []interface{}{"", "ISIN", "NAME", "Q"}
+	for i, r := range results {
+		table.RightAlign(i + 4)
+		header = append(header, strings.ToUpper(r.Scenario.Name))
+	}
+	table.RightAlign(3)
+	table.AddRow(header...)
+	for i, p := range results[0].Positions {
+		row := []interface{}{indent, p.Bond.ISIN, p.Bond.ShortName, fmt.Sprintf("%d", p.Quantity)}
+		for _, r := range results {
+			row = append(row, fmt.Sprintf("%0.2f", r.Positions[i].ProfitLoss))
+		}
+		table.AddRow(row...)
+	}
+	fmt.Fprintf(os.Stdout, "POSITIONS P/L\n\n%s\n", table)
+}
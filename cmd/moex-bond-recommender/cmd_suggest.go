@@ -4,10 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
@@ -26,10 +22,11 @@ func init() {
 	rootCommand.AddCommand(cmd)
 
 	var (
-		postgresConnString, moexURL string
+		postgresConnString, moexURL, collectionsPath string
 	)
 	attachPostgresUrlFlag(cmd, &postgresConnString)
 	attachMoexUrlFlag(cmd, &moexURL)
+	attachCollectionsFlag(cmd, &collectionsPath)
 	amount := cmd.Flags().Float64("amount", 1000.0, "amount to invest (RUB)")
 	durationStr := cmd.Flags().StringP(
 		"duration",
@@ -37,29 +34,12 @@ func init() {
 		string(recommender.Duration1Year),
 		"Bond duration range (1y/2y/3y/4y/5y)")
 	partsRaw := cmd.Flags().StringArray("part", []string{}, "define portfolio part (format: COLLECTION_NAME=WEIGHT)")
-
-	parsePart := func(u app.UnitOfWork, partRaw string) (recommender.SuggestRequestPart, error) {
-		parts := strings.SplitN(partRaw, "=", 2)
-
-		weight := 1.0
-		if len(parts) == 2 {
-			w, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return recommender.SuggestRequestPart{}, err
-			}
-			weight = w
-		}
-
-		collection, err := u.GetCollection(parts[0])
-		if err != nil {
-			return recommender.SuggestRequestPart{}, err
-		}
-
-		return recommender.SuggestRequestPart{
-			Collection: collection,
-			Weight:     weight,
-		}, nil
-	}
+	getCostModel := attachCostModelFlags(cmd)
+	getConstraints := attachSuggestConstraintsFlags(cmd)
+	saveAs := cmd.Flags().String("save", "", "save suggested portfolio under specified name")
+	horizon := cmd.Flags().Int("horizon", 0, "simulate portfolio return over holding horizon, months")
+	runScenarios := cmd.Flags().Bool("scenarios", false, "run standard interest rate scenarios against suggested portfolio")
+	reinvestRate := cmd.Flags().Float64("reinvest-rate", 0, "reinvestment rate for horizon simulation, % per year (defaults to current yields of the same collections)")
 
 	formatDate := func(v sql.NullTime) string {
 		if !v.Valid {
@@ -81,6 +61,18 @@ func init() {
 		table.AddRow(indent, "Profit", fmt.Sprintf("%0.2f %s", result.ProfitLoss, "RUB"))
 		table.AddRow(indent, "", fmt.Sprintf("%0.2f%%", result.RelativeProfitLoss))
 		table.AddRow(indent, "Interest rate", fmt.Sprintf("%0.2f%%", result.InterestRate))
+		table.AddRow(indent, "Yield to maturity", fmt.Sprintf("%0.2f%%", result.YieldToMaturity))
+		table.AddRow(indent, "Modified duration", fmt.Sprintf("%0.2f", result.ModifiedDuration))
+		table.AddRow(indent, "DV01", fmt.Sprintf("%0.2f RUB", result.DV01))
+		if result.TaxDeduction > 0 {
+			table.AddRow(indent, "Tax deduction", fmt.Sprintf("%0.2f %s", result.TaxDeduction, "RUB"))
+		}
+		if result.UnusedAmount > 0 {
+			table.AddRow(indent, "Unused cash", fmt.Sprintf("%0.2f %s", result.UnusedAmount, "RUB"))
+		}
+		if result.LimitsRelaxed {
+			table.AddRow(indent, "Limits relaxed", "share limits were relaxed to keep unused cash below --max-cash-share")
+		}
 		fmt.Fprintf(os.Stdout, "OVERVIEW\n\n%s\n\n", table)
 
 		// Positions
@@ -91,7 +83,8 @@ func init() {
 		table.RightAlign(5)
 		table.RightAlign(6)
 		table.RightAlign(7)
-		table.AddRow("", "ISIN", "NAME", "MATURITY DATE", "Q", "INVESTED", "PROFIT/LOSS", "INTEREST RATE", "PART IN PORTFOLIO")
+		table.RightAlign(8)
+		table.AddRow("", "ISIN", "NAME", "MATURITY DATE", "Q", "INVESTED", "PROFIT/LOSS", "INTEREST RATE", "YTM", "PART IN PORTFOLIO")
 		for _, position := range result.Positions {
 			table.AddRow(
 				indent,
@@ -102,48 +95,35 @@ func init() {
 				fmt.Sprintf("%0.2f %s", position.OpenValue, position.Currency),
 				fmt.Sprintf("%0.2f %s", position.ProfitLoss, position.Currency),
 				fmt.Sprintf("%0.2f%%", position.InterestRate),
+				fmt.Sprintf("%0.2f%%", position.YieldToMaturity),
 				fmt.Sprintf("%0.2f%%", position.Weight*100.0))
 		}
 		fmt.Fprintf(os.Stdout, "POSITIONS\n\n%s\n\n", table)
 
-		// Cash flow
-		type CashFlowRow struct {
-			Date                                    time.Time
-			Amount                                  float64
-			HasCoupon, HasAmortization, HasMaturity bool
-		}
-		cashflows := make(map[time.Time]*CashFlowRow)
-		for _, p := range result.Positions {
-			for _, c := range p.CashFlow {
-				row, exists := cashflows[c.Date]
-				if !exists {
-					row = &CashFlowRow{Date: c.Date, Amount: 0}
-					cashflows[c.Date] = row
-				}
-
-				row.Amount += c.ValueRub
-				switch c.Type {
-				case recommender.Coupon:
-					row.HasCoupon = true
-				case recommender.Amortization:
-					row.HasAmortization = true
-				case recommender.Maturity:
-					row.HasMaturity = true
-				}
+		// Concentrations
+		table = uitable.New()
+		table.RightAlign(3)
+		table.RightAlign(4)
+		table.AddRow("", "GROUP", "NAME", "INVESTED", "PART IN PORTFOLIO")
+		addConcentrations := func(group string, concentrations []*recommender.SuggestConcentration) {
+			for _, c := range concentrations {
+				table.AddRow(
+					indent,
+					group,
+					c.Name,
+					fmt.Sprintf("%0.2f %s", c.Amount, "RUB"),
+					fmt.Sprintf("%0.2f%%", c.Weight*100.0))
 			}
 		}
+		addConcentrations("issuer", result.IssuerConcentrations)
+		addConcentrations("bond type", result.BondTypeConcentrations)
+		addConcentrations("maturity year", result.MaturityYearConcentrations)
+		addConcentrations("currency", result.CurrencyConcentrations)
+		fmt.Fprintf(os.Stdout, "CONCENTRATIONS\n\n%s\n\n", table)
 
-		if len(cashflows) > 0 {
-			array := make([]*CashFlowRow, len(cashflows))
-			i := 0
-			for _, r := range cashflows {
-				array[i] = r
-				i++
-			}
-			sort.Slice(array, func(i, j int) bool {
-				return array[i].Date.Before(array[j].Date)
-			})
-
+		// Cash flow
+		days := recommender.NewSuggestionCalendar(result).Days()
+		if len(days) > 0 {
 			table = uitable.New()
 			table.RightAlign(6)
 			table.AddRow("", "DATE", "COUPON", "AMORTIZATION", "MATURITY", "AMOUNT")
@@ -156,17 +136,7 @@ func init() {
 				return "+"
 			}
 
-			for _, r := range array {
-				types := ""
-				if r.HasCoupon {
-					types += "C "
-				}
-				if r.HasAmortization {
-					types += "A "
-				}
-				if r.HasMaturity {
-					types += "M "
-				}
+			for _, r := range days {
 				table.AddRow(
 					indent,
 					r.Date.Format("2006-01-02"),
@@ -179,15 +149,56 @@ func init() {
 		}
 	}
 
+	printHorizon := func(result *recommender.HorizonResult) {
+		indent := ""
+
+		table := uitable.New()
+		table.RightAlign(2)
+		table.AddRow(indent, "Horizon", result.Date.Format("2006-01-02"))
+		table.AddRow(indent, "Reinvestment rate", fmt.Sprintf("%0.2f%%", result.ReinvestRate))
+		table.AddRow(indent, "Invested", fmt.Sprintf("%0.2f %s", result.Invested, "RUB"))
+		table.AddRow(indent, "Received", fmt.Sprintf("%0.2f %s", result.Received, "RUB"))
+		table.AddRow(indent, "Reinvestment income", fmt.Sprintf("%0.2f %s", result.ReinvestmentIncome, "RUB"))
+		table.AddRow(indent, "Market value", fmt.Sprintf("%0.2f %s", result.MarketValue, "RUB"))
+		table.AddRow(indent, "Value", fmt.Sprintf("%0.2f %s", result.Value, "RUB"))
+		table.AddRow(indent, "Profit", fmt.Sprintf("%0.2f %s", result.ProfitLoss, "RUB"))
+		table.AddRow(indent, "Total return", fmt.Sprintf("%0.2f%%", result.TotalReturn))
+		table.AddRow(indent, "Annualized return", fmt.Sprintf("%0.2f%%", result.AnnualizedReturn))
+		fmt.Fprintf(os.Stdout, "\nHORIZON\n\n%s\n", table)
+	}
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		duration, err := parseDuration(*durationStr)
 		if err != nil {
 			return err
 		}
 
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+
+		constraints, err := getConstraints()
+		if err != nil {
+			return err
+		}
+
+		var horizonRequest *recommender.HorizonRequest
+		if *horizon > 0 {
+			horizonRequest = &recommender.HorizonRequest{Horizon: *horizon, Costs: costs}
+			if cmd.Flags().Changed("reinvest-rate") {
+				horizonRequest.ReinvestRate = reinvestRate
+			}
+
+			err = horizonRequest.Validate()
+			if err != nil {
+				return err
+			}
+		}
+
 		ctx := createCancellableContext()
 
-		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
+		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString), app.WithCollectionsPath(collectionsPath))
 		if err != nil {
 			return err
 		}
@@ -202,17 +213,19 @@ func init() {
 		request := &recommender.SuggestRequest{
 			Amount:      *amount,
 			MaxDuration: duration,
+			Costs:       costs,
+			Constraints: constraints,
 		}
 
 		if partsRaw != nil && len(*partsRaw) > 0 {
 			request.Parts = make([]*recommender.SuggestRequestPart, len(*partsRaw))
 			for i, partRaw := range *partsRaw {
-				part, err := parsePart(u, partRaw)
+				part, err := parseSuggestPart(u, partRaw)
 				if err != nil {
 					return err
 				}
 
-				request.Parts[i] = &part
+				request.Parts[i] = part
 			}
 		}
 
@@ -223,6 +236,39 @@ func init() {
 
 		printPortfolio(result)
 
+		if horizonRequest != nil {
+			horizonResult, err := u.SimulateHorizon(result, horizonRequest)
+			if err != nil {
+				return err
+			}
+
+			printHorizon(horizonResult)
+		}
+
+		if *runScenarios {
+			scenarios, err := u.RunScenarios(result, recommender.StandardScenarios())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(os.Stdout)
+			printScenarios(scenarios)
+		}
+
+		if *saveAs != "" {
+			portfolio, err := u.SaveSuggestion(*saveAs, result)
+			if err != nil {
+				return err
+			}
+
+			err = u.Commit()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stdout, "\nportfolio #%d \"%s\" has been saved\n", portfolio.ID, portfolio.Name)
+		}
+
 		return nil
 	}
 }
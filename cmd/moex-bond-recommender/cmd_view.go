@@ -23,11 +23,19 @@ func init() {
 
 	var (
 		postgresConnString, moexURL string
+		originalCurrency            bool
 	)
 	attachPostgresUrlFlag(cmd, &postgresConnString)
 	attachMoexUrlFlag(cmd, &moexURL)
+	getCostModel := attachCostModelFlags(cmd)
+	cmd.Flags().BoolVar(&originalCurrency, "original-currency", false, "Report amounts in bond face currency instead of RUB")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		costs, err := getCostModel()
+		if err != nil {
+			return err
+		}
+
 		ctx := createCancellableContext()
 
 		app, err := app.New(app.WithMoexURL(moexURL), app.WithDataSource(postgresConnString))
@@ -47,6 +55,13 @@ func init() {
 			return err
 		}
 
+		if costs != nil {
+			report = recommender.ApplyCostModel(report, costs)
+		}
+		if originalCurrency {
+			report = recommender.InOriginalCurrency(report)
+		}
+
 		var formatDate = func(v sql.NullTime) string {
 			if !v.Valid {
 				return ""
@@ -80,15 +95,38 @@ func init() {
 		overview.AddRow("Days till maturity", "", "", fmt.Sprintf("%d", report.DaysTillMaturity))
 		overview.AddRow("Profit/loss", "", "", fmt.Sprintf("%0.2f %s", report.ProfitLoss, report.Currency))
 		overview.AddRow("Interest rate", "", "", fmt.Sprintf("%0.2f%%", report.InterestRate))
+		overview.AddRow("Yield to maturity", "", "", fmt.Sprintf("%0.2f%%", report.YieldToMaturity))
+		overview.AddRow("Macaulay duration", "", "", fmt.Sprintf("%0.2f", report.MacaulayDuration))
+		overview.AddRow("Modified duration", "", "", fmt.Sprintf("%0.2f", report.ModifiedDuration))
+		overview.AddRow("DV01", "", "", fmt.Sprintf("%0.4f %s", report.DV01, report.Currency))
+		overview.AddRow("Convexity", "", "", fmt.Sprintf("%0.2f", report.Convexity))
+		if report.ToOffer != nil {
+			overview.AddRow("Offer date", "", "", report.OfferDate.Format("2006-01-02"))
+			overview.AddRow("Days till offer", "", "", fmt.Sprintf("%d", report.DaysTillOffer))
+			overview.AddRow("Offer price", "", "", fmt.Sprintf("%0.2f%%", report.OfferPrice))
+			overview.AddRow("Profit/loss to offer", "", "", fmt.Sprintf("%0.2f %s", report.ToOffer.ProfitLoss, report.Currency))
+			overview.AddRow("Yield to offer", "", "", fmt.Sprintf("%0.2f%%", report.YieldToOffer))
+		}
 
 		table := uitable.New()
-		table.AddRow("DATE", "TYPE", "VALUE")
+		table.AddRow("DATE", "TYPE", "VALUE", "")
 		table.RightAlign(2)
 		for _, item := range report.CashFlow {
+			value := item.ValueRub
+			if report.Currency != "RUB" {
+				value = item.Value
+			}
+
+			estimated := ""
+			if item.Estimated {
+				estimated = "estimate"
+			}
+
 			table.AddRow(
 				item.Date.Format("2006-01-05"),
 				formatCashFlowType(item.Type),
-				fmt.Sprintf("%0.2f %s", item.ValueRub, "RUB"))
+				fmt.Sprintf("%0.2f %s", value, report.Currency),
+				estimated)
 		}
 		fmt.Fprintf(
 			os.Stdout,
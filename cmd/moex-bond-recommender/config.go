@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
 	"github.com/kapitanov/moex-bond-recommender/pkg/data"
 	"github.com/kapitanov/moex-bond-recommender/pkg/moex"
 	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
@@ -62,6 +66,22 @@ func attachListenAddressFlag(cmd *cobra.Command, value *string) {
 	cmd.Flags().StringVarP(value, "address", "a", defaultValue, usage)
 }
 
+func attachCollectionsFlag(cmd *cobra.Command, value *string) {
+	envVarName := "COLLECTIONS_PATH"
+	defaultValue := os.Getenv(envVarName)
+
+	usage := fmt.Sprintf("Path to a YAML/JSON file or a directory with custom collection definitions (defaults to $%s)", envVarName)
+	cmd.Flags().StringVar(value, "collections", defaultValue, usage)
+}
+
+func attachIssuerRatingsFlag(cmd *cobra.Command, value *string) {
+	envVarName := "ISSUER_RATINGS_PATH"
+	defaultValue := os.Getenv(envVarName)
+
+	usage := fmt.Sprintf("Path to a YAML/JSON file with issuer credit ratings (defaults to $%s)", envVarName)
+	cmd.Flags().StringVar(value, "ratings", defaultValue, usage)
+}
+
 func attachGoogleAnalyticsFlag(cmd *cobra.Command, value *string) {
 	envVarName := "GOOGLE_ANALYTICS_ID"
 	defaultValue := os.Getenv(envVarName)
@@ -73,6 +93,185 @@ func attachGoogleAnalyticsFlag(cmd *cobra.Command, value *string) {
 	cmd.Flags().StringVar(value, "ga-id", defaultValue, usage)
 }
 
+// attachHistoryRetentionFlags добавляет флаги сроков хранения истории рыночных данных
+// Возвращаемая функция возвращает соответствующие опции для app.New
+func attachHistoryRetentionFlags(cmd *cobra.Command) func() ([]app.Option, error) {
+	readEnv := func(envVarName string, defaultValue int) int {
+		if value, err := strconv.Atoi(os.Getenv(envVarName)); err == nil {
+			return value
+		}
+		return defaultValue
+	}
+
+	defaultIntradayDays := int(app.DefaultIntradayHistoryRetention.Hours() / 24)
+	historyDays := cmd.Flags().Int("history-retention", readEnv("HISTORY_RETENTION_DAYS", 0),
+		"end-of-day market data history retention, days, 0 to keep forever (defaults to $HISTORY_RETENTION_DAYS)")
+	intradayDays := cmd.Flags().Int("intraday-retention", readEnv("INTRADAY_HISTORY_RETENTION_DAYS", defaultIntradayDays),
+		"intraday market data history retention, days, 0 to disable (defaults to $INTRADAY_HISTORY_RETENTION_DAYS)")
+
+	return func() ([]app.Option, error) {
+		if *historyDays < 0 {
+			return nil, fmt.Errorf("\"%d\" is not a valid history retention", *historyDays)
+		}
+		if *intradayDays < 0 {
+			return nil, fmt.Errorf("\"%d\" is not a valid intraday history retention", *intradayDays)
+		}
+
+		day := 24 * time.Hour
+		return []app.Option{
+			app.WithHistoryRetention(time.Duration(*historyDays) * day),
+			app.WithIntradayHistoryRetention(time.Duration(*intradayDays) * day),
+		}, nil
+	}
+}
+
+// attachProjectionFlags добавляет флаги допущений, по которым оцениваются еще не объявленные выплаты по облигациям
+// Возвращаемая функция возвращает соответствующие опции для app.New
+func attachProjectionFlags(cmd *cobra.Command) func() ([]app.Option, error) {
+	readEnv := func(envVarName string, defaultValue float64) float64 {
+		if value, err := strconv.ParseFloat(os.Getenv(envVarName), 64); err == nil {
+			return value
+		}
+		return defaultValue
+	}
+
+	floatingRate := cmd.Flags().Float64("floating-rate", readEnv("FLOATING_RATE", 0),
+		"projected coupon rate of floating-rate bonds, %, 0 to use the last known coupon rate (defaults to $FLOATING_RATE)")
+	inflation := cmd.Flags().Float64("inflation", readEnv("INFLATION", recommender.DefaultInflation),
+		"projected inflation for inflation-linked bonds, % (defaults to $INFLATION)")
+
+	return func() ([]app.Option, error) {
+		assumptions := &recommender.ProjectionAssumptions{Inflation: *inflation}
+		if *floatingRate > 0 {
+			assumptions.FloatingRate = floatingRate
+		}
+
+		err := assumptions.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		return []app.Option{app.WithProjectionAssumptions(assumptions)}, nil
+	}
+}
+
+// attachCostModelFlags добавляет флаги модели комиссий и налогов
+// Возвращаемая функция возвращает ограничения из флагов (незаданные флаги - по умолчанию)
+func attachCostModelFlags(cmd *cobra.Command) func() (*recommender.CostModel, error) {
+	brokerFee := cmd.Flags().String("broker-fee", "0.05", "broker fee schedule, % (format: RATE or AMOUNT:RATE,AMOUNT:RATE,...)")
+	minBrokerFee := cmd.Flags().Float64("min-broker-fee", 0, "minimal broker fee per trade (RUB)")
+	exchangeFee := cmd.Flags().Float64("exchange-fee", 0, "exchange fee, %")
+	account := cmd.Flags().String("account", string(recommender.BrokerageAccount), "account type (brokerage/iis_a/iis_b)")
+	longTermExemption := cmd.Flags().Bool("long-term-exemption", false, "apply long-term ownership tax exemption (3+ years)")
+	govCouponsTaxExempt := cmd.Flags().Bool("gov-coupons-tax-exempt", false, "treat OFZ, subfederal and municipal coupons as tax exempt")
+
+	return func() (*recommender.CostModel, error) {
+		changed := false
+		for _, name := range []string{"broker-fee", "min-broker-fee", "exchange-fee", "account", "long-term-exemption", "gov-coupons-tax-exempt"} {
+			changed = changed || cmd.Flags().Changed(name)
+		}
+		if !changed {
+			return nil, nil
+		}
+
+		schedule, err := recommender.ParseBrokerFeeSchedule(*brokerFee)
+		if err != nil {
+			return nil, err
+		}
+
+		accountType, err := recommender.ParseAccountType(*account)
+		if err != nil {
+			return nil, err
+		}
+
+		return &recommender.CostModel{
+			BrokerFee:           schedule,
+			MinBrokerFee:        *minBrokerFee,
+			ExchangeFeeRate:     *exchangeFee / 100.0,
+			Account:             accountType,
+			LongTermExemption:   *longTermExemption,
+			GovCouponsTaxExempt: *govCouponsTaxExempt,
+		}, nil
+	}
+}
+
+// attachSuggestConstraintsFlags добавляет флаги ограничений оптимизатора портфеля
+// Возвращаемая функция возвращает nil, если ни один из флагов не был задан
+func attachSuggestConstraintsFlags(cmd *cobra.Command) func() (*recommender.SuggestConstraints, error) {
+	defaults := recommender.DefaultSuggestConstraints()
+	maxBondShare := cmd.Flags().Float64("max-bond-share", defaults.MaxBondWeight*100.0, "max share of a single bond, % (0 - no limit)")
+	maxIssuerShare := cmd.Flags().Float64("max-issuer-share", defaults.MaxIssuerWeight*100.0, "max share of a single issuer, % (0 - no limit)")
+	maxBondTypeShare := cmd.Flags().Float64("max-bond-type-share", defaults.MaxBondTypeWeight*100.0, "max share of a single bond type (ofz_bond, corporate_bond, ...), % (0 - no limit)")
+	maxMaturityYearShare := cmd.Flags().Float64("max-maturity-year-share", defaults.MaxMaturityYearWeight*100.0, "max share of bonds maturing in the same year, % (0 - no limit)")
+	minPositions := cmd.Flags().Int("min-positions", defaults.MinPositions, "min number of positions (0 - no limit)")
+	targetDuration := cmd.Flags().Float64("target-duration", defaults.TargetDuration, "target portfolio Macaulay duration, years (0 - no target)")
+	durationTolerance := cmd.Flags().Float64("duration-tolerance", defaults.DurationTolerance, "allowed deviation from target duration, years")
+	maxCashShare := cmd.Flags().Float64("max-cash-share", defaults.MaxCashWeight*100.0, "max share of uninvested cash, %, share limits are relaxed to keep cash below it (0 - limits are never relaxed)")
+
+	return func() (*recommender.SuggestConstraints, error) {
+		constraints := &recommender.SuggestConstraints{
+			MaxBondWeight:         *maxBondShare / 100.0,
+			MaxIssuerWeight:       *maxIssuerShare / 100.0,
+			MaxBondTypeWeight:     *maxBondTypeShare / 100.0,
+			MaxMaturityYearWeight: *maxMaturityYearShare / 100.0,
+			MinPositions:          *minPositions,
+			TargetDuration:        *targetDuration,
+			DurationTolerance:     *durationTolerance,
+			MaxCashWeight:         *maxCashShare / 100.0,
+		}
+
+		err := constraints.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		return constraints, nil
+	}
+}
+
+// parseSuggestPart разбирает ограничение по составу портфеля (формат: COLLECTION_NAME=WEIGHT)
+func parseSuggestPart(u app.UnitOfWork, partRaw string) (*recommender.SuggestRequestPart, error) {
+	parts := strings.SplitN(partRaw, "=", 2)
+
+	weight := 1.0
+	if len(parts) == 2 {
+		w, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		weight = w
+	}
+
+	collection, err := u.GetCollection(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &recommender.SuggestRequestPart{
+		Collection: collection,
+		Weight:     weight,
+	}, nil
+}
+
+func parseLiability(s string) (*recommender.Liability, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("\"%s\" is not a valid liability, expected format is DATE=AMOUNT", s)
+	}
+
+	date, err := time.Parse("2006-01-02", parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recommender.Liability{Date: date, Amount: amount}, nil
+}
+
 func parseDuration(s string) (recommender.Duration, error) {
 	switch s {
 	case "1y":
@@ -2,7 +2,9 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/reugn/go-quartz/quartz"
 	"github.com/subchen/go-trylock"
@@ -22,6 +24,10 @@ type App interface {
 	// FetchMarketData выполняет выгрузку рыночных данных
 	FetchMarketData(ctx context.Context) error
 
+	// FetchHistory выполняет выгрузку истории торгов
+	// Выгрузка продолжается с даты последней загруженной записи, но не ранее даты from
+	FetchHistory(ctx context.Context, from time.Time) error
+
 	// NewUnitOfWork создает новый unit of work
 	NewUnitOfWork(ctx context.Context) (UnitOfWork, error)
 
@@ -32,9 +38,18 @@ type App interface {
 	Close()
 }
 
+// DefaultIntradayHistoryRetention - срок хранения внутридневной истории рыночных данных по умолчанию
+const DefaultIntradayHistoryRetention = 7 * 24 * time.Hour
+
 type config struct {
-	MoexURL     string
-	PostgresURL string
+	MoexURL                  string
+	PostgresURL              string
+	HistoryRetention         time.Duration
+	IntradayHistoryRetention time.Duration
+	CollectionsPath          string
+	IssuerRatingsPath        string
+	Projections              *recommender.ProjectionAssumptions
+	Costs                    *recommender.CostModel
 }
 
 // Option конфигурирует объект App
@@ -57,11 +72,79 @@ func WithDataSource(value string) Option {
 	}
 }
 
+// WithHistoryRetention задает срок хранения истории рыночных данных на конец дня
+// По умолчанию (0) история хранится бессрочно
+func WithHistoryRetention(value time.Duration) Option {
+	return func(c *config) error {
+		if value < 0 {
+			return fmt.Errorf("history retention must not be negative")
+		}
+
+		c.HistoryRetention = value
+		return nil
+	}
+}
+
+// WithIntradayHistoryRetention задает срок хранения внутридневной истории рыночных данных
+// По умолчанию используется DefaultIntradayHistoryRetention, значение 0 отключает сохранение внутридневной истории
+func WithIntradayHistoryRetention(value time.Duration) Option {
+	return func(c *config) error {
+		if value < 0 {
+			return fmt.Errorf("intraday history retention must not be negative")
+		}
+
+		c.IntradayHistoryRetention = value
+		return nil
+	}
+}
+
+// WithCollectionsPath задает путь к файлу или каталогу с описаниями пользовательских коллекций рекомендаций
+// По умолчанию (пустая строка) используются только встроенные коллекции
+func WithCollectionsPath(value string) Option {
+	return func(c *config) error {
+		c.CollectionsPath = value
+		return nil
+	}
+}
+
+// WithIssuerRatingsPath задает путь к файлу с кредитными рейтингами эмитентов
+// По умолчанию (пустая строка) рейтинги в БД не изменяются
+func WithIssuerRatingsPath(value string) Option {
+	return func(c *config) error {
+		c.IssuerRatingsPath = value
+		return nil
+	}
+}
+
+// WithProjectionAssumptions задает допущения, по которым оцениваются еще не объявленные выплаты по облигациям
+// По умолчанию используется recommender.DefaultProjectionAssumptions
+func WithProjectionAssumptions(value *recommender.ProjectionAssumptions) Option {
+	return func(c *config) error {
+		err := value.Validate()
+		if err != nil {
+			return err
+		}
+
+		c.Projections = value
+		return nil
+	}
+}
+
+// WithCostModel задает модель комиссий и налогов, по которой рассчитываются отчеты, коллекции и скринер
+// По умолчанию используется recommender.DefaultCostModel
+func WithCostModel(value *recommender.CostModel) Option {
+	return func(c *config) error {
+		c.Costs = value
+		return nil
+	}
+}
+
 // New создает новый объект App
 func New(options ...Option) (App, error) {
 	c := &config{
-		MoexURL:     moex.DefaultURL,
-		PostgresURL: data.DefaultDataSource,
+		MoexURL:                  moex.DefaultURL,
+		PostgresURL:              data.DefaultDataSource,
+		IntradayHistoryRetention: DefaultIntradayHistoryRetention,
 	}
 
 	for _, fn := range options {
@@ -95,11 +178,37 @@ func New(options ...Option) (App, error) {
 		return nil, err
 	}
 
-	recommenderService, err := recommender.New()
+	recommenderOptions := make([]recommender.Option, 0)
+	if c.CollectionsPath != "" {
+		definitions, err := recommender.LoadCollectionDefinitions(c.CollectionsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		recommenderOptions = append(recommenderOptions, recommender.WithCollections(definitions...))
+	}
+	if c.IssuerRatingsPath != "" {
+		ratings, err := recommender.LoadIssuerRatings(c.IssuerRatingsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		recommenderOptions = append(recommenderOptions, recommender.WithIssuerRatings(ratings...))
+	}
+	if c.Projections != nil {
+		recommenderOptions = append(recommenderOptions, recommender.WithProjectionAssumptions(c.Projections))
+	}
+	if c.Costs != nil {
+		recommenderOptions = append(recommenderOptions, recommender.WithCostModel(c.Costs))
+	}
+
+	recommenderService, err := recommender.New(recommenderOptions...)
 	if err != nil {
 		return nil, err
 	}
 
+	appLogger := log.New(log.Writer(), "app:  ", log.Flags())
+
 	app := &appImpl{
 		moexProvider:       provider,
 		db:                 db,
@@ -108,6 +217,9 @@ func New(options ...Option) (App, error) {
 		recommenderService: recommenderService,
 		fetchInProgress:    trylock.New(),
 		scheduler:          quartz.NewStdScheduler(),
+		historyRetention:   c.HistoryRetention,
+		intradayRetention:  c.IntradayHistoryRetention,
+		logger:             appLogger,
 	}
 
 	isUpToDate, err := app.IsStaticDataUpToDate(context.Background())
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/reugn/go-quartz/quartz"
@@ -23,6 +24,9 @@ type appImpl struct {
 	fetchInProgress    trylock.TryLocker
 	scheduler          quartz.Scheduler
 	isSchedulerRunning bool
+	historyRetention   time.Duration
+	intradayRetention  time.Duration
+	logger             *log.Logger
 }
 
 // IsStaticDataUpToDate возвращает false, если статические данные нуждаются в обновлении
@@ -90,6 +94,8 @@ func (app *appImpl) FetchStaticData(ctx context.Context) error {
 }
 
 // FetchMarketData выполняет выгрузку рыночных данных
+// Курсы валют выгружаются в отдельной транзакции до рыночных данных: если выгрузить их не удалось,
+// то ошибка логируется, а рыночные данные выгружаются и пересчитываются по последним известным курсам
 func (app *appImpl) FetchMarketData(ctx context.Context) error {
 	if !app.fetchInProgress.TryLock(time.Second) {
 		return nil
@@ -97,6 +103,11 @@ func (app *appImpl) FetchMarketData(ctx context.Context) error {
 
 	defer app.fetchInProgress.Unlock()
 
+	err := app.fetchFxRates(ctx)
+	if err != nil {
+		app.logger.Printf("unable to fetch fx rates, last known rates are kept: %s", err)
+	}
+
 	tx, err := app.db.BeginTX()
 	if err != nil {
 		return err
@@ -113,6 +124,11 @@ func (app *appImpl) FetchMarketData(ctx context.Context) error {
 		return err
 	}
 
+	err = app.SaveHistory(tx)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -120,6 +136,82 @@ func (app *appImpl) FetchMarketData(ctx context.Context) error {
 	return nil
 }
 
+// fetchFxRates выполняет выгрузку курсов валют в отдельной транзакции
+// Если выгрузить курсы не удалось, то транзакция откатывается, и в БД остаются последние известные курсы
+func (app *appImpl) fetchFxRates(ctx context.Context) error {
+	tx, err := app.db.BeginTX()
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	_, err = app.fetchService.FetchFxRates(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FetchHistory выполняет выгрузку истории торгов
+// Выгрузка продолжается с даты последней загруженной записи, но не ранее даты from
+func (app *appImpl) FetchHistory(ctx context.Context, from time.Time) error {
+	app.fetchInProgress.Lock()
+	defer app.fetchInProgress.Unlock()
+
+	tx, err := app.db.BeginTX()
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	_, err = app.fetchService.FetchHistory(ctx, tx, from)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveHistory сохраняет текущие рыночные данные в историю и удаляет устаревшие записи
+func (app *appImpl) SaveHistory(tx *data.TX) error {
+	now := time.Now().UTC()
+
+	count, err := tx.History.Snapshot(data.EndOfDayHistory)
+	if err != nil {
+		return err
+	}
+	app.logger.Printf("%d end-of-day history record(s) were saved", count)
+
+	if app.historyRetention > 0 {
+		count, err = tx.History.DeleteBefore(data.EndOfDayHistory, now.Add(-app.historyRetention))
+		if err != nil {
+			return err
+		}
+		app.logger.Printf("%d end-of-day history record(s) were deleted", count)
+	}
+
+	if app.intradayRetention > 0 {
+		count, err = tx.History.Snapshot(data.IntradayHistory)
+		if err != nil {
+			return err
+		}
+		app.logger.Printf("%d intraday history record(s) were saved", count)
+	}
+
+	count, err = tx.History.DeleteBefore(data.IntradayHistory, now.Add(-app.intradayRetention))
+	if err != nil {
+		return err
+	}
+	app.logger.Printf("%d intraday history record(s) were deleted", count)
+
+	return nil
+}
+
 // NewUnitOfWork создает новый unit of work
 func (app *appImpl) NewUnitOfWork(ctx context.Context) (UnitOfWork, error) {
 	tx, err := app.db.BeginTX()
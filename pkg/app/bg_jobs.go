@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"time"
 
 	"github.com/reugn/go-quartz/quartz"
 )
@@ -27,6 +28,15 @@ func (app *appImpl) StartBackgroundTasks() error {
 		return err
 	}
 
+	// Выгрузка истории торгов выполняется каждый день в 1:15 MSK (22:15 UTC)
+	// Для облигаций без истории загружается только последний год, полная история загружается командой "fetch --history"
+	err = app.ScheduleBackgroundJob("FetchHistory", "0 15 22 * * *", func() error {
+		return app.FetchHistory(context.Background(), time.Now().AddDate(-1, 0, 0))
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2,7 +2,10 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/data"
 	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
@@ -20,8 +23,9 @@ type UnitOfWork interface {
 	// GetCollection возвращает коллекцию рекомендаций по ее ID
 	GetCollection(id string) (recommender.Collection, error)
 
-	// ListCollectionBonds возвращает облигации из коллекции рекомендаций по ее ID
-	ListCollectionBonds(id string, duration recommender.Duration) ([]*recommender.Report, error)
+	// ListCollectionBonds возвращает облигации из коллекции рекомендаций по ее ID в заданном порядке
+	// Если коллекция не поддерживает порядок ranking, то возвращается ошибка recommender.ErrUnsupportedRanking
+	ListCollectionBonds(id string, duration recommender.Duration, ranking recommender.Ranking) ([]*recommender.Report, error)
 
 	// GetReport возвращает отчет по отдельной облигации
 	GetReport(idOrISIN string) (*recommender.Report, error)
@@ -29,10 +33,96 @@ type UnitOfWork interface {
 	// Suggest выполняет расчет предложений по инвестированию
 	Suggest(request *recommender.SuggestRequest) (*recommender.SuggestResult, error)
 
+	// Backtest выполняет бэктест стратегии инвестирования на исторических данных
+	Backtest(request *recommender.BacktestRequest) (*recommender.BacktestResult, error)
+
+	// GetYieldCurve возвращает последнюю построенную кривую доходности ОФЗ и положение текущих ОФЗ относительно нее
+	// Если кривая еще не построена, то возвращается ошибка recommender.ErrNotFound
+	GetYieldCurve() (*recommender.YieldCurve, error)
+
+	// Screen выполняет отбор облигаций по условиям скринера
+	Screen(query *recommender.ScreenerQuery) (*recommender.ScreenerResult, error)
+
+	// BuildLadder выполняет построение лесенки облигаций
+	// Если подходящих облигаций не нашлось, то возвращается ошибка recommender.ErrNotFound
+	BuildLadder(request *recommender.LadderRequest) (*recommender.LadderResult, error)
+
+	// MatchCashFlows подбирает портфель минимальной стоимости, выплаты по которому покрывают заданные обязательства (см. matchCashFlows)
+	// Если подходящих облигаций не нашлось, то возвращается ошибка recommender.ErrNotFound
+	MatchCashFlows(request *recommender.CashFlowMatchRequest) (*recommender.CashFlowMatchResult, error)
+
+	// SimulateHorizon моделирует доходность предложенного портфеля при владении им в течение горизонта инвестирования
+	SimulateHorizon(portfolio *recommender.SuggestResult, request *recommender.HorizonRequest) (*recommender.HorizonResult, error)
+
+	// RunScenarios выполняет переоценку предложенного портфеля по сценариям
+	RunScenarios(portfolio *recommender.SuggestResult, scenarios []*recommender.Scenario) ([]*recommender.ScenarioResult, error)
+
+	// RunPortfolioScenarios выполняет переоценку открытых позиций портфеля пользователя по сценариям
+	RunPortfolioScenarios(id int, scenarios []*recommender.Scenario) (*data.Portfolio, []*recommender.ScenarioResult, error)
+
+	// ListPortfolios возвращает список портфелей пользователя
+	ListPortfolios() ([]*data.Portfolio, error)
+
+	// CreatePortfolio создает новый портфель пользователя
+	CreatePortfolio(name string) (*data.Portfolio, error)
+
+	// GetPortfolio возвращает оценку портфеля пользователя по текущим рыночным данным
+	GetPortfolio(id int) (*recommender.PortfolioValuation, error)
+
+	// DeletePortfolio удаляет портфель пользователя
+	DeletePortfolio(id int) error
+
+	// AddTrade добавляет сделку в портфель пользователя
+	// Если сделка несовместима с портфелем, то возвращается ошибка, оборачивающая recommender.ErrInvalidTrade
+	AddTrade(portfolioID int, args AddTradeArgs) (*data.Trade, error)
+
+	// DeleteTrade удаляет сделку из портфеля пользователя
+	// Если без сделки портфель становится несогласованным, то возвращается ошибка, оборачивающая recommender.ErrInvalidTrade
+	DeleteTrade(portfolioID, tradeID int) error
+
+	// SaveSuggestion сохраняет предложенный портфель как портфель пользователя
+	// Сделки создаются по текущим ценам
+	SaveSuggestion(name string, result *recommender.SuggestResult) (*data.Portfolio, error)
+
+	// GetCalendar формирует календарь предстоящих выплат по набору облигаций
+	// Ключ - ID, ISIN или код облигации, значение - размер позиции
+	GetCalendar(positions map[string]int) (*recommender.Calendar, error)
+
+	// GetPortfolioCalendar формирует календарь предстоящих выплат по открытым позициям портфеля пользователя
+	GetPortfolioCalendar(id int) (*data.Portfolio, *recommender.Calendar, error)
+
+	// GetHistory возвращает историю рыночных данных облигации за период [from, to]
+	GetHistory(idOrISIN string, kind data.HistoryKind, from, to time.Time) ([]*data.HistoryRecord, error)
+
+	// GetPriceHistory возвращает историю цен, доходностей и спредов облигации за период
+	GetPriceHistory(idOrISIN string, r recommender.HistoryRange) (*recommender.PriceHistory, error)
+
+	// Commit фиксирует изменения
+	Commit() error
+
 	// Close закрывает unit of work
 	Close()
 }
 
+// AddTradeArgs содержит параметры сделки для добавления в портфель пользователя
+type AddTradeArgs struct {
+	// ID, ISIN или код облигации
+	Bond string
+
+	// Дата сделки (если не задана, то используется текущая дата)
+	Date time.Time
+
+	// Количество облигаций (отрицательное - для продажи)
+	Quantity int
+
+	// Чистая цена, в % от номинала (если не задана, то используется текущая цена,
+	// а для сделки прошлой датой - цена закрытия по истории торгов за эту дату)
+	Price *float64
+
+	// Комиссия за сделку, в валюте (если не задана, то рассчитывается по тарифу по умолчанию)
+	Fee *float64
+}
+
 type unitOfWork struct {
 	tx                 *data.TX
 	ctx                context.Context
@@ -55,15 +145,16 @@ func (u *unitOfWork) GetCollection(id string) (recommender.Collection, error) {
 	return u.recommenderService.GetCollection(id)
 }
 
-// ListCollectionBonds возвращает облигации из коллекции рекомендаций по ее ID
-func (u *unitOfWork) ListCollectionBonds(id string, duration recommender.Duration) ([]*recommender.Report, error) {
+// ListCollectionBonds возвращает облигации из коллекции рекомендаций по ее ID в заданном порядке
+// Если коллекция не поддерживает порядок ranking, то возвращается ошибка recommender.ErrUnsupportedRanking
+func (u *unitOfWork) ListCollectionBonds(id string, duration recommender.Duration, ranking recommender.Ranking) ([]*recommender.Report, error) {
 	collection, err := u.recommenderService.GetCollection(id)
 	if err != nil {
 		return nil, err
 	}
 
 	limit := 25
-	items, err := collection.ListBonds(u.ctx, u.tx, limit, duration)
+	items, err := collection.ListBonds(u.ctx, u.tx, limit, duration, ranking)
 	if err != nil {
 		return nil, err
 	}
@@ -73,21 +164,9 @@ func (u *unitOfWork) ListCollectionBonds(id string, duration recommender.Duratio
 
 // GetReport возвращает отчет по отдельной облигации
 func (u *unitOfWork) GetReport(idOrISIN string) (*recommender.Report, error) {
-	id, err := strconv.Atoi(idOrISIN)
+	id, err := u.resolveBondID(idOrISIN)
 	if err != nil {
-		bond, err := u.tx.Bonds.GetByISIN(idOrISIN)
-		if err != nil {
-			if err == data.ErrNotFound {
-				bond, err = u.tx.Bonds.GetBySecurityID(idOrISIN)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				return nil, err
-			}
-		}
-
-		id = bond.ID
+		return nil, err
 	}
 
 	report, err := u.recommenderService.GetReport(u.ctx, u.tx, id)
@@ -108,7 +187,405 @@ func (u *unitOfWork) Suggest(request *recommender.SuggestRequest) (*recommender.
 	return result, nil
 }
 
+// Backtest выполняет бэктест стратегии инвестирования на исторических данных
+func (u *unitOfWork) Backtest(request *recommender.BacktestRequest) (*recommender.BacktestResult, error) {
+	result, err := u.recommenderService.Backtest(u.ctx, u.tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetYieldCurve возвращает последнюю построенную кривую доходности ОФЗ и положение текущих ОФЗ относительно нее
+// Если кривая еще не построена, то возвращается ошибка recommender.ErrNotFound
+func (u *unitOfWork) GetYieldCurve() (*recommender.YieldCurve, error) {
+	curve, err := u.recommenderService.GetYieldCurve(u.ctx, u.tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return curve, nil
+}
+
+// Screen выполняет отбор облигаций по условиям скринера
+func (u *unitOfWork) Screen(query *recommender.ScreenerQuery) (*recommender.ScreenerResult, error) {
+	result, err := u.recommenderService.Screen(u.ctx, u.tx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// BuildLadder выполняет построение лесенки облигаций
+// Если подходящих облигаций не нашлось, то возвращается ошибка recommender.ErrNotFound
+func (u *unitOfWork) BuildLadder(request *recommender.LadderRequest) (*recommender.LadderResult, error) {
+	result, err := u.recommenderService.BuildLadder(u.ctx, u.tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// MatchCashFlows подбирает портфель минимальной стоимости, выплаты по которому покрывают заданные обязательства (см. matchCashFlows)
+// Если подходящих облигаций не нашлось, то возвращается ошибка recommender.ErrNotFound
+func (u *unitOfWork) MatchCashFlows(request *recommender.CashFlowMatchRequest) (*recommender.CashFlowMatchResult, error) {
+	result, err := u.recommenderService.MatchCashFlows(u.ctx, u.tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SimulateHorizon моделирует доходность предложенного портфеля при владении им в течение горизонта инвестирования
+func (u *unitOfWork) SimulateHorizon(portfolio *recommender.SuggestResult, request *recommender.HorizonRequest) (*recommender.HorizonResult, error) {
+	return u.recommenderService.SimulateHorizon(u.ctx, u.tx, portfolio, request)
+}
+
+// RunScenarios выполняет переоценку предложенного портфеля по сценариям
+func (u *unitOfWork) RunScenarios(portfolio *recommender.SuggestResult, scenarios []*recommender.Scenario) ([]*recommender.ScenarioResult, error) {
+	return u.recommenderService.RunScenarios(u.ctx, u.tx, portfolio, scenarios)
+}
+
+// RunPortfolioScenarios выполняет переоценку открытых позиций портфеля пользователя по сценариям
+func (u *unitOfWork) RunPortfolioScenarios(id int, scenarios []*recommender.Scenario) (*data.Portfolio, []*recommender.ScenarioResult, error) {
+	portfolio, err := u.tx.Portfolios.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := u.recommenderService.RunPortfolioScenarios(u.ctx, u.tx, portfolio, scenarios)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return portfolio, results, nil
+}
+
+// resolveBondID возвращает ID облигации по ее ID, ISIN или коду
+func (u *unitOfWork) resolveBondID(idOrISIN string) (int, error) {
+	id, err := strconv.Atoi(idOrISIN)
+	if err == nil {
+		return id, nil
+	}
+
+	bond, err := u.tx.Bonds.GetByISIN(idOrISIN)
+	if err != nil {
+		if err != data.ErrNotFound {
+			return 0, err
+		}
+
+		bond, err = u.tx.Bonds.GetBySecurityID(idOrISIN)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return bond.ID, nil
+}
+
+// ListPortfolios возвращает список портфелей пользователя
+func (u *unitOfWork) ListPortfolios() ([]*data.Portfolio, error) {
+	return u.tx.Portfolios.List()
+}
+
+// CreatePortfolio создает новый портфель пользователя
+func (u *unitOfWork) CreatePortfolio(name string) (*data.Portfolio, error) {
+	return u.tx.Portfolios.Create(data.CreatePortfolioArgs{Name: name})
+}
+
+// GetPortfolio возвращает оценку портфеля пользователя по текущим рыночным данным
+func (u *unitOfWork) GetPortfolio(id int) (*recommender.PortfolioValuation, error) {
+	portfolio, err := u.tx.Portfolios.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.recommenderService.ValuatePortfolio(u.ctx, u.tx, portfolio)
+}
+
+// DeletePortfolio удаляет портфель пользователя
+func (u *unitOfWork) DeletePortfolio(id int) error {
+	return u.tx.Portfolios.Delete(id)
+}
+
+// AddTrade добавляет сделку в портфель пользователя
+func (u *unitOfWork) AddTrade(portfolioID int, args AddTradeArgs) (*data.Trade, error) {
+	if args.Quantity == 0 {
+		return nil, fmt.Errorf("%w: quantity must not be zero", recommender.ErrInvalidTrade)
+	}
+
+	portfolio, err := u.tx.Portfolios.Get(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	bondID, err := u.resolveBondID(args.Bond)
+	if err != nil {
+		return nil, err
+	}
+
+	bond, err := u.tx.Bonds.GetByID(bondID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	tradeArgs := data.CreateTradeArgs{
+		BondID:    bondID,
+		Date:      args.Date,
+		Quantity:  args.Quantity,
+		FaceValue: bond.InitialFaceValue,
+	}
+	if tradeArgs.Date.IsZero() {
+		tradeArgs.Date = today
+	}
+	if tradeArgs.Date.After(today) {
+		return nil, fmt.Errorf("%w: trade date %s is in the future", recommender.ErrInvalidTrade, tradeArgs.Date.Format("2006-01-02"))
+	}
+
+	if tradeArgs.Date.Before(today) {
+		// Недостающие параметры сделки прошлой датой заполняются по истории торгов за эту дату
+		history, err := u.tx.History.List(bondID, data.TradesHistory, tradeArgs.Date, tradeArgs.Date.AddDate(0, 0, 1).Add(-time.Nanosecond))
+		if err != nil {
+			return nil, err
+		}
+
+		var record *data.HistoryRecord
+		if len(history) > 0 {
+			record = history[len(history)-1]
+		}
+		if record != nil && record.Price != nil {
+			tradeArgs.Price = *record.Price
+			if record.AccruedInterest != nil {
+				tradeArgs.AccruedInterest = *record.AccruedInterest
+			}
+			if record.FaceValue != nil {
+				tradeArgs.FaceValue = *record.FaceValue
+			}
+		} else if args.Price == nil {
+			return nil, fmt.Errorf("%w: no market price for %s on %s, the price is required",
+				recommender.ErrInvalidTrade, bond.ISIN, tradeArgs.Date.Format("2006-01-02"))
+		}
+	} else {
+		// Недостающие параметры сделки текущей датой заполняются по текущим рыночным данным
+		report, err := u.recommenderService.GetReport(u.ctx, u.tx, bondID)
+		if err != nil && err != recommender.ErrNotFound {
+			return nil, err
+		}
+		if report != nil {
+			tradeArgs.Price = report.OpenPrice
+			tradeArgs.AccruedInterest = report.OpenAccruedInterest
+			tradeArgs.FaceValue = report.OpenFaceValue
+		} else if args.Price == nil {
+			return nil, recommender.ErrNotFound
+		}
+	}
+
+	if args.Price != nil {
+		tradeArgs.Price = *args.Price
+	}
+
+	if args.Fee != nil {
+		tradeArgs.Fee = *args.Fee
+	} else {
+		quantity := float64(args.Quantity)
+		if quantity < 0 {
+			quantity = -quantity
+		}
+		amount := quantity * (tradeArgs.Price*tradeArgs.FaceValue/100.0 + tradeArgs.AccruedInterest)
+		tradeArgs.Fee = recommender.DefaultCostModel().Fee(amount)
+	}
+
+	// Сделка не должна приводить к продаже большего количества облигаций, чем есть в портфеле
+	trades := append(portfolio.Trades, &data.Trade{
+		BondID:          bondID,
+		Date:            tradeArgs.Date,
+		Quantity:        tradeArgs.Quantity,
+		Price:           tradeArgs.Price,
+		FaceValue:       tradeArgs.FaceValue,
+		AccruedInterest: tradeArgs.AccruedInterest,
+		Fee:             tradeArgs.Fee,
+		Bond:            *bond,
+	})
+	err = recommender.ValidateTrades(trades)
+	if err != nil {
+		return nil, err
+	}
+
+	trade, err := u.tx.Portfolios.AddTrade(portfolioID, tradeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	trade.Bond = *bond
+	return trade, nil
+}
+
+// DeleteTrade удаляет сделку из портфеля пользователя
+func (u *unitOfWork) DeleteTrade(portfolioID, tradeID int) error {
+	portfolio, err := u.tx.Portfolios.Get(portfolioID)
+	if err != nil {
+		return err
+	}
+
+	// Удаление покупки не должно приводить к продаже большего количества облигаций, чем есть в портфеле
+	trades := make([]*data.Trade, 0, len(portfolio.Trades))
+	for _, trade := range portfolio.Trades {
+		if trade.ID != tradeID {
+			trades = append(trades, trade)
+		}
+	}
+	err = recommender.ValidateTrades(trades)
+	if err != nil {
+		return err
+	}
+
+	return u.tx.Portfolios.DeleteTrade(portfolioID, tradeID)
+}
+
+// SaveSuggestion сохраняет предложенный портфель как портфель пользователя
+// Сделки создаются по текущим ценам
+func (u *unitOfWork) SaveSuggestion(name string, result *recommender.SuggestResult) (*data.Portfolio, error) {
+	portfolio, err := u.tx.Portfolios.Create(data.CreatePortfolioArgs{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, p := range result.Positions {
+		_, err = u.tx.Portfolios.AddTrade(portfolio.ID, data.CreateTradeArgs{
+			BondID:          p.Bond.ID,
+			Date:            date,
+			Quantity:        p.Quantity,
+			Price:           p.OpenPrice,
+			FaceValue:       p.OpenFaceValue,
+			AccruedInterest: p.OpenAccruedInterest,
+			Fee:             p.OpenFee,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return portfolio, nil
+}
+
+// GetCalendar формирует календарь предстоящих выплат по набору облигаций
+// Ключ - ID, ISIN или код облигации, значение - размер позиции
+func (u *unitOfWork) GetCalendar(positions map[string]int) (*recommender.Calendar, error) {
+	array := make([]recommender.CalendarPosition, 0, len(positions))
+	for idOrISIN, quantity := range positions {
+		id, err := u.resolveBondID(idOrISIN)
+		if err != nil {
+			return nil, err
+		}
+
+		array = append(array, recommender.CalendarPosition{BondID: id, Quantity: quantity})
+	}
+
+	return u.recommenderService.GetCalendar(u.ctx, u.tx, array)
+}
+
+// GetPortfolioCalendar формирует календарь предстоящих выплат по открытым позициям портфеля пользователя
+func (u *unitOfWork) GetPortfolioCalendar(id int) (*data.Portfolio, *recommender.Calendar, error) {
+	valuation, err := u.GetPortfolio(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Облигации, по которым нет отчетов (например, уже погашенные), в календарь не попадают
+	positions := make([]recommender.CalendarPosition, 0, len(valuation.Holdings))
+	for _, h := range valuation.Holdings {
+		if h.Report != nil {
+			positions = append(positions, recommender.CalendarPosition{BondID: h.Bond.ID, Quantity: h.Quantity})
+		}
+	}
+
+	calendar, err := u.recommenderService.GetCalendar(u.ctx, u.tx, positions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return valuation.Portfolio, calendar, nil
+}
+
+// GetHistory возвращает историю рыночных данных облигации за период [from, to]
+func (u *unitOfWork) GetHistory(idOrISIN string, kind data.HistoryKind, from, to time.Time) ([]*data.HistoryRecord, error) {
+	id, err := u.resolveBondID(idOrISIN)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.tx.History.List(id, kind, from, to)
+}
+
+// GetPriceHistory возвращает историю цен, доходностей и спредов облигации за период
+func (u *unitOfWork) GetPriceHistory(idOrISIN string, r recommender.HistoryRange) (*recommender.PriceHistory, error) {
+	id, err := u.resolveBondID(idOrISIN)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.tx.Bonds.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now().UTC()
+	from := r.From(to)
+
+	records, err := u.tx.History.List(id, data.EndOfDayHistory, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	benchmark, err := u.tx.History.ListByBondType(data.OFZBond, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return recommender.NewPriceHistory(r, records, benchmark), nil
+}
+
+// Commit фиксирует изменения
+func (u *unitOfWork) Commit() error {
+	return u.tx.Commit()
+}
+
 // Close закрывает unit of work
 func (u *unitOfWork) Close() {
 	u.tx.Close()
 }
+
+// ParseCalendarPositions разбирает набор позиций для календаря выплат
+// Каждый элемент имеет формат "BOND" либо "BOND:QUANTITY", где BOND - ID, ISIN или код облигации
+func ParseCalendarPositions(items []string) (map[string]int, error) {
+	positions := make(map[string]int)
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		quantity := 1
+		if len(parts) == 2 {
+			q, err := strconv.Atoi(parts[1])
+			if err != nil || q <= 0 {
+				return nil, fmt.Errorf("\"%s\" is not a valid position", item)
+			}
+			quantity = q
+		}
+
+		positions[parts[0]] += quantity
+	}
+
+	return positions, nil
+}
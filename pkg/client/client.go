@@ -0,0 +1,282 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client - клиент для JSON API сервиса
+type Client interface {
+	// Search выполняет поиск облигаций по тексту
+	Search(ctx context.Context, text string, skip, limit int) (*SearchResult, error)
+
+	// GetBond возвращает отчет по облигации по ее ID, ISIN или коду
+	// Если costs не задан, то используется модель комиссий и налогов по умолчанию
+	GetBond(ctx context.Context, id string, costs *CostModel) (*Report, error)
+
+	// GetBondHistory возвращает историю цен, доходностей и спредов облигации
+	// Допустимые значения historyRange: "1m", "6m", "1y", "all" (если не задан, то "1y")
+	GetBondHistory(ctx context.Context, id string, historyRange string) (*PriceHistory, error)
+
+	// ListCollections возвращает список коллекций
+	ListCollections(ctx context.Context) ([]*Collection, error)
+
+	// GetCollection возвращает облигации из коллекции для заданного срока
+	// Допустимые значения ranking: "yield", "spread" (если не задан, то порядок по умолчанию для коллекции)
+	GetCollection(ctx context.Context, id string, duration string, ranking string) (*CollectionBonds, error)
+
+	// Screen выполняет отбор облигаций по условиям скринера
+	Screen(ctx context.Context, params *ScreenerParams) (*ScreenerResult, error)
+
+	// Suggest выполняет расчет предложений по инвестированию
+	Suggest(ctx context.Context, request *SuggestRequest) (*SuggestResult, error)
+}
+
+// SuggestRequest - запрос на расчет предложений по инвестированию
+type SuggestRequest struct {
+	// Сумма для инвестирования
+	Amount float64 `json:"amount"`
+
+	// Максимальный срок инвестирования, лет (1..5)
+	MaxDuration int `json:"max_duration"`
+
+	// Ограничения по составу портфеля
+	Parts []*SuggestRequestPart `json:"parts,omitempty"`
+
+	// Модель комиссий и налогов
+	Costs *CostModel `json:"costs,omitempty"`
+
+	// Ограничения оптимизатора портфеля
+	Constraints *SuggestConstraints `json:"constraints,omitempty"`
+}
+
+// SuggestRequestPart - ограничения по составу портфеля для запроса SuggestRequest
+type SuggestRequestPart struct {
+	// ID коллекции
+	Collection string `json:"collection"`
+
+	// Вес в портфеле
+	Weight float64 `json:"weight"`
+}
+
+// Error - ошибка, возвращенная API
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error возвращает сообщение об ошибке
+func (e *Error) Error() string {
+	return fmt.Sprintf("[HTTP %d] %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound возвращает true, если ошибка означает отсутствие запрошенного объекта
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// Option настраивает клиент
+type Option func(c *client) error
+
+// WithHTTPClient задает HTTP клиент
+// По умолчанию используется http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) error {
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// New создает новый объект Client
+// Параметр baseURL - корневой URL сервиса, например "http://localhost:5000"
+func New(baseURL string, options ...Option) (Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/api/v1")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+	}
+
+	for _, fn := range options {
+		err = fn(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+type client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// Search выполняет поиск облигаций по тексту
+func (c *client) Search(ctx context.Context, text string, skip, limit int) (*SearchResult, error) {
+	query := url.Values{}
+	query.Set("q", text)
+	if skip > 0 {
+		query.Set("skip", strconv.Itoa(skip))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var response SearchResult
+	err := c.do(ctx, http.MethodGet, "/search", query, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// GetBond возвращает отчет по облигации по ее ID, ISIN или коду
+func (c *client) GetBond(ctx context.Context, id string, costs *CostModel) (*Report, error) {
+	query := url.Values{}
+	if costs != nil {
+		query = costs.Values()
+	}
+
+	var response Report
+	err := c.do(ctx, http.MethodGet, "/bonds/"+url.PathEscape(id), query, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// GetBondHistory возвращает историю цен, доходностей и спредов облигации
+func (c *client) GetBondHistory(ctx context.Context, id string, historyRange string) (*PriceHistory, error) {
+	query := url.Values{}
+	if historyRange != "" {
+		query.Set("range", historyRange)
+	}
+
+	var response PriceHistory
+	err := c.do(ctx, http.MethodGet, "/bonds/"+url.PathEscape(id)+"/history", query, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// ListCollections возвращает список коллекций
+func (c *client) ListCollections(ctx context.Context) ([]*Collection, error) {
+	var response []*Collection
+	err := c.do(ctx, http.MethodGet, "/collections", nil, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetCollection возвращает облигации из коллекции для заданного срока
+func (c *client) GetCollection(ctx context.Context, id string, duration string, ranking string) (*CollectionBonds, error) {
+	query := url.Values{}
+	if duration != "" {
+		query.Set("duration", duration)
+	}
+	if ranking != "" {
+		query.Set("rank", ranking)
+	}
+
+	var response CollectionBonds
+	err := c.do(ctx, http.MethodGet, "/collections/"+url.PathEscape(id), query, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Screen выполняет отбор облигаций по условиям скринера
+func (c *client) Screen(ctx context.Context, params *ScreenerParams) (*ScreenerResult, error) {
+	query := url.Values{}
+	if params != nil {
+		query = params.Values()
+	}
+
+	var response ScreenerResult
+	err := c.do(ctx, http.MethodGet, "/screener", query, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Suggest выполняет расчет предложений по инвестированию
+func (c *client) Suggest(ctx context.Context, request *SuggestRequest) (*SuggestResult, error) {
+	var response SuggestResult
+	err := c.do(ctx, http.MethodPost, "/suggest", nil, request, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// do выполняет запрос к API и разбирает ответ в объект response
+func (c *client) do(ctx context.Context, method, path string, query url.Values, request, response interface{}) error {
+	// Путь передается уже экранированным (см. url.PathEscape), поэтому задается и в RawPath,
+	// иначе символы '%' в ID будут экранированы повторно
+	u := *c.baseURL
+	u.RawPath = u.EscapedPath() + path
+	unescapedPath, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return err
+	}
+	u.Path = unescapedPath
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+	if request != nil {
+		buf, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		err = json.NewDecoder(resp.Body).Decode(&errResp)
+		if err != nil || errResp.Error.Message == "" {
+			return &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: errResp.Error.Message}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
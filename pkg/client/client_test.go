@@ -0,0 +1,209 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"go/build"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/client"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/api"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+func TestClient_GetBond(t *testing.T) {
+	assert := assertion.New(t)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/api/v1/bonds/RU000A0JX0J2":
+			assert.Equal("iis_a", req.URL.Query().Get("account"))
+			_, _ = w.Write([]byte(`{
+				"bond": {"id": 42, "isin": "RU000A0JX0J2", "short_name": "ОФЗ 29012"},
+				"yield_to_maturity": 8.25,
+				"cash_flow": [{"type": "coupon", "date": "2030-01-15T00:00:00Z", "value_rub": 36.9}]
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"status": 404, "message": "bond \"XXX\" doesn't exist"}}`))
+		}
+	}))
+	defer testServer.Close()
+
+	c, err := client.New(testServer.URL)
+	if !assert.NoError(err) {
+		return
+	}
+
+	report, err := c.GetBond(context.Background(), "RU000A0JX0J2", &client.CostModel{Account: "iis_a"})
+	if assert.NoError(err) {
+		assert.Equal(42, report.Bond.ID)
+		assert.Equal(8.25, report.YieldToMaturity)
+		assert.Len(report.CashFlow, 1)
+		assert.Equal("coupon", report.CashFlow[0].Type)
+	}
+
+	_, err = c.GetBond(context.Background(), "XXX", nil)
+	assert.True(client.IsNotFound(err))
+	assert.EqualError(err, "[HTTP 404] bond \"XXX\" doesn't exist")
+}
+
+func TestClient_Suggest(t *testing.T) {
+	assert := assertion.New(t)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(http.MethodPost, req.Method)
+		assert.Equal("/api/v1/suggest", req.URL.Path)
+
+		body, _ := io.ReadAll(req.Body)
+		var request map[string]interface{}
+		assert.NoError(json.Unmarshal(body, &request))
+		assert.Equal(100000.0, request["amount"])
+		assert.Equal(3.0, request["max_duration"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"amount": 99500, "positions": [{"bond": {"id": 1}, "quantity": 10, "weight": 1}]}`))
+	}))
+	defer testServer.Close()
+
+	c, err := client.New(testServer.URL + "/")
+	if !assert.NoError(err) {
+		return
+	}
+
+	result, err := c.Suggest(context.Background(), &client.SuggestRequest{Amount: 100000, MaxDuration: 3})
+	if assert.NoError(err) {
+		assert.Equal(99500.0, result.Amount)
+		assert.Len(result.Positions, 1)
+		assert.Equal(10, result.Positions[0].Quantity)
+		assert.Equal(1, result.Positions[0].Bond.ID)
+	}
+}
+
+func TestClient_GetBond_EscapesID(t *testing.T) {
+	assert := assertion.New(t)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal("/api/v1/bonds/RU%2525%2F1", req.URL.EscapedPath())
+		assert.Equal("/api/v1/bonds/RU%25/1", req.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"bond": {"id": 1}}`))
+	}))
+	defer testServer.Close()
+
+	c, err := client.New(testServer.URL + "/")
+	if !assert.NoError(err) {
+		return
+	}
+
+	_, err = c.GetBond(context.Background(), "RU%25/1", nil)
+	assert.NoError(err)
+}
+
+func TestClient_HasNoServerDependencies(t *testing.T) {
+	assert := assertion.New(t)
+
+	pkg, err := build.ImportDir(".", 0)
+	if !assert.NoError(err) {
+		return
+	}
+
+	for _, path := range pkg.Imports {
+		assert.NotContains(strings.Split(path, "/")[0], ".", "client must depend on the standard library only")
+	}
+}
+
+func TestClient_ModelsMatchAPI(t *testing.T) {
+	assert := assertion.New(t)
+
+	models := []struct {
+		client interface{}
+		api    interface{}
+	}{
+		{client.Bond{}, api.BondModel{}},
+		{client.Issuer{}, api.IssuerModel{}},
+		{client.MarketData{}, api.MarketDataModel{}},
+		{client.Report{}, api.ReportModel{}},
+		{client.CashFlowItem{}, api.CashFlowItemModel{}},
+		{client.SearchResult{}, api.SearchResponse{}},
+		{client.Collection{}, api.CollectionModel{}},
+		{client.CollectionBonds{}, api.CollectionBondsResponse{}},
+		{client.ScreenerResult{}, api.ScreenerResponse{}},
+		{client.SuggestResult{}, api.SuggestResultModel{}},
+		{client.SuggestConcentration{}, api.SuggestConcentrationModel{}},
+		{client.SuggestedPosition{}, api.SuggestedPositionModel{}},
+		{client.PriceHistory{}, api.PriceHistoryModel{}},
+		{client.PriceHistoryPoint{}, api.PriceHistoryPointModel{}},
+		{client.CostModel{}, pages.CostModelParams{}},
+		{client.SuggestConstraints{}, pages.SuggestConstraintsParams{}},
+	}
+
+	for _, m := range models {
+		assert.Equal(jsonFields(reflect.TypeOf(m.api)), jsonFields(reflect.TypeOf(m.client)), reflect.TypeOf(m.client).String())
+	}
+
+	screenerParams := pages.ScreenerParams{
+		Types:             []string{"ofz"},
+		IssuerID:          "1",
+		Issuer:            "2",
+		ListingLevels:     []string{"1"},
+		QualifiedOnly:     "3",
+		HighRisk:          "4",
+		Currencies:        []string{"RUB"},
+		CouponFrequencies: []string{"2"},
+		MaturityFrom:      "5",
+		MaturityTo:        "6",
+		OfferFrom:         "7",
+		OfferTo:           "8",
+		MinYield:          "9",
+		MaxYield:          "10",
+		MinSpread:         "11",
+		MaxSpread:         "12",
+		MinPrice:          "13",
+		MaxPrice:          "14",
+		Amortization:      "15",
+		Sort:              "16",
+		Order:             "17",
+		Skip:              "18",
+		Limit:             "19",
+	}
+	clientScreenerParams := client.ScreenerParams(screenerParams)
+	assert.Equal(screenerParams.Values(), clientScreenerParams.Values())
+}
+
+func jsonFields(t reflect.Type) []string {
+	fields := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			fields = append(fields, jsonFields(ft)...)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+
+	sort.Strings(fields)
+	return fields
+}
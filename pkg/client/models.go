@@ -0,0 +1,300 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bond - облигация
+type Bond struct {
+	ID               int        `json:"id"`
+	SecurityID       string     `json:"security_id"`
+	ISIN             string     `json:"isin"`
+	ShortName        string     `json:"short_name"`
+	FullName         string     `json:"full_name"`
+	Type             string     `json:"type"`
+	IsTraded         bool       `json:"is_traded"`
+	QualifiedOnly    bool       `json:"qualified_only"`
+	IsHighRisk       bool       `json:"high_risk"`
+	InitialFaceValue float64    `json:"initial_face_value"`
+	FaceUnit         string     `json:"face_unit"`
+	IssueDate        *time.Time `json:"issue_date"`
+	MaturityDate     *time.Time `json:"maturity_date"`
+	ListingLevel     int        `json:"listing_level"`
+	CouponFrequency  int        `json:"coupon_frequency"`
+	CouponType       string     `json:"coupon_type"`
+	Issuer           *Issuer    `json:"issuer,omitempty"`
+}
+
+// Issuer - эмитент
+type Issuer struct {
+	ID   int     `json:"id"`
+	Name string  `json:"name"`
+	INN  *string `json:"inn"`
+	OKPO *string `json:"okpo"`
+}
+
+// MarketData - рыночные данные
+type MarketData struct {
+	Time            time.Time `json:"time"`
+	FaceValue       *float64  `json:"face_value"`
+	Currency        *string   `json:"currency"`
+	Last            *float64  `json:"last"`
+	LastChange      *float64  `json:"last_change"`
+	ClosePrice      *float64  `json:"close_price"`
+	LegalClosePrice *float64  `json:"legal_close_price"`
+	AccruedInterest *float64  `json:"accrued_interest"`
+	LotSize         *int      `json:"lot_size"`
+	TradingStatus   *string   `json:"trading_status"`
+}
+
+// Report - отчет по облигации
+type Report struct {
+	Bond                 *Bond           `json:"bond"`
+	MarketData           *MarketData     `json:"market_data,omitempty"`
+	DaysTillMaturity     int             `json:"days_till_maturity"`
+	Currency             string          `json:"currency"`
+	FxRate               float64         `json:"fx_rate"`
+	OpenPrice            float64         `json:"open_price"`
+	OpenAccruedInterest  float64         `json:"open_accrued_interest"`
+	OpenFaceValue        float64         `json:"open_face_value"`
+	OpenFee              float64         `json:"open_fee"`
+	OpenValue            float64         `json:"open_value"`
+	CouponPayments       float64         `json:"coupon_payments"`
+	AmortizationPayments float64         `json:"amortization_payments"`
+	MaturityPayment      float64         `json:"maturity_payment"`
+	Taxes                float64         `json:"taxes"`
+	Revenue              float64         `json:"revenue"`
+	ProfitLoss           float64         `json:"profit_loss"`
+	RelativeProfitLoss   float64         `json:"relative_profit_loss"`
+	InterestRate         float64         `json:"interest_rate"`
+	YieldToMaturity      float64         `json:"yield_to_maturity"`
+	MacaulayDuration     float64         `json:"macaulay_duration"`
+	ModifiedDuration     float64         `json:"modified_duration"`
+	DV01                 float64         `json:"dv01"`
+	Convexity            float64         `json:"convexity"`
+	OfferDate            *time.Time      `json:"offer_date"`
+	OfferPrice           float64         `json:"offer_price"`
+	DaysTillOffer        int             `json:"days_till_offer"`
+	YieldToOffer         float64         `json:"yield_to_offer"`
+	Spread               *float64        `json:"spread"`
+	ToOffer              *Report         `json:"to_offer,omitempty"`
+	CashFlow             []*CashFlowItem `json:"cash_flow"`
+}
+
+// CashFlowItem - выплата по облигации
+type CashFlowItem struct {
+	Type      string    `json:"type"`
+	Date      time.Time `json:"date"`
+	Value     float64   `json:"value"`
+	ValueRub  float64   `json:"value_rub"`
+	Estimated bool      `json:"estimated"`
+}
+
+// SearchResult - результат поиска облигаций
+type SearchResult struct {
+	Bonds      []*Bond `json:"bonds"`
+	TotalCount int     `json:"total_count"`
+}
+
+// Collection - коллекция облигаций
+type Collection struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Rankings []string `json:"rankings"`
+}
+
+// CollectionBonds - облигации из коллекции для заданного срока
+type CollectionBonds struct {
+	*Collection
+	Duration string    `json:"duration"`
+	Ranking  string    `json:"rank"`
+	Bonds    []*Report `json:"bonds"`
+}
+
+// ScreenerResult - результат отбора облигаций в скринере
+type ScreenerResult struct {
+	Bonds      []*Report `json:"bonds"`
+	TotalCount int       `json:"total_count"`
+}
+
+// SuggestResult - результат расчета предложений по инвестированию
+type SuggestResult struct {
+	Positions          []*SuggestedPosition `json:"positions"`
+	Amount             float64              `json:"amount"`
+	DurationDays       int                  `json:"duration_days"`
+	ProfitLoss         float64              `json:"profit_loss"`
+	RelativeProfitLoss float64              `json:"relative_profit_loss"`
+	InterestRate       float64              `json:"interest_rate"`
+	YieldToMaturity    float64              `json:"yield_to_maturity"`
+	MacaulayDuration   float64              `json:"macaulay_duration"`
+	ModifiedDuration   float64              `json:"modified_duration"`
+	DV01               float64              `json:"dv01"`
+	Convexity          float64              `json:"convexity"`
+	TaxDeduction       float64              `json:"tax_deduction"`
+	UnusedAmount       float64              `json:"unused_amount"`
+	LimitsRelaxed      bool                 `json:"limits_relaxed"`
+
+	IssuerConcentrations       []*SuggestConcentration `json:"issuer_concentrations"`
+	BondTypeConcentrations     []*SuggestConcentration `json:"bond_type_concentrations"`
+	MaturityYearConcentrations []*SuggestConcentration `json:"maturity_year_concentrations"`
+	CurrencyConcentrations     []*SuggestConcentration `json:"currency_concentrations"`
+}
+
+// SuggestConcentration - доля эмитента, типа облигаций, года погашения или валюты в предложенном портфеле
+type SuggestConcentration struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+	Weight float64 `json:"weight"`
+}
+
+// SuggestedPosition - позиция в предложенном портфеле
+// Суммы выплат и затрат указаны на всю позицию, цены - на одну облигацию
+type SuggestedPosition struct {
+	*Report
+	Quantity int     `json:"quantity"`
+	Weight   float64 `json:"weight"`
+}
+
+// PriceHistory - история цен и доходностей облигации
+type PriceHistory struct {
+	Range  string               `json:"range"`
+	Points []*PriceHistoryPoint `json:"points"`
+}
+
+// PriceHistoryPoint - данные облигации на конец дня
+type PriceHistoryPoint struct {
+	Date   time.Time `json:"date"`
+	Price  *float64  `json:"price"`
+	Yield  *float64  `json:"yield"`
+	Spread *float64  `json:"spread"`
+}
+
+// CostModel - параметры модели комиссий и налогов
+type CostModel struct {
+	BrokerFee           string  `json:"broker_fee,omitempty"`
+	MinBrokerFee        float64 `json:"min_broker_fee,omitempty"`
+	ExchangeFee         float64 `json:"exchange_fee,omitempty"`
+	Account             string  `json:"account,omitempty"`
+	LongTermExemption   bool    `json:"long_term_exemption,omitempty"`
+	GovCouponsTaxExempt bool    `json:"gov_coupons_tax_exempt,omitempty"`
+}
+
+// Values возвращает параметры модели в виде query-параметров
+func (m *CostModel) Values() url.Values {
+	values := url.Values{}
+	if m.BrokerFee != "" {
+		values.Set("broker_fee", m.BrokerFee)
+	}
+	if m.MinBrokerFee != 0 {
+		values.Set("min_broker_fee", strconv.FormatFloat(m.MinBrokerFee, 'f', -1, 64))
+	}
+	if m.ExchangeFee != 0 {
+		values.Set("exchange_fee", strconv.FormatFloat(m.ExchangeFee, 'f', -1, 64))
+	}
+	if m.Account != "" {
+		values.Set("account", m.Account)
+	}
+	if m.LongTermExemption {
+		values.Set("long_term_exemption", "true")
+	}
+	if m.GovCouponsTaxExempt {
+		values.Set("gov_coupons_tax_exempt", "true")
+	}
+
+	return values
+}
+
+// SuggestConstraints - ограничения оптимизатора портфеля
+// Доли задаются в процентах; незаданные параметры принимают значения по умолчанию, нулевое значение отключает ограничение
+type SuggestConstraints struct {
+	MaxBondShare         *float64 `json:"max_bond_share,omitempty"`
+	MaxIssuerShare       *float64 `json:"max_issuer_share,omitempty"`
+	MaxBondTypeShare     *float64 `json:"max_bond_type_share,omitempty"`
+	MaxMaturityYearShare *float64 `json:"max_maturity_year_share,omitempty"`
+	MinPositions         *int     `json:"min_positions,omitempty"`
+	TargetDuration       *float64 `json:"target_duration,omitempty"`
+	DurationTolerance    *float64 `json:"duration_tolerance,omitempty"`
+	MaxCashShare         *float64 `json:"max_cash_share,omitempty"`
+}
+
+// ScreenerParams - условия отбора облигаций в скринере
+// Списочные условия объединяются по "или", пустые значения игнорируются
+type ScreenerParams struct {
+	Types             []string
+	IssuerID          string
+	Issuer            string
+	ListingLevels     []string
+	QualifiedOnly     string
+	HighRisk          string
+	Currencies        []string
+	CouponFrequencies []string
+	MaturityFrom      string
+	MaturityTo        string
+	OfferFrom         string
+	OfferTo           string
+	MinYield          string
+	MaxYield          string
+	MinSpread         string
+	MaxSpread         string
+	MinPrice          string
+	MaxPrice          string
+	Amortization      string
+	Sort              string
+	Order             string
+	Skip              string
+	Limit             string
+}
+
+// Values возвращает условия отбора в виде query-параметров
+func (p *ScreenerParams) Values() url.Values {
+	values := url.Values{}
+	set := func(name, s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			values.Set(name, s)
+		}
+	}
+	add := func(name string, list []string) {
+		for _, s := range list {
+			if s = strings.TrimSpace(s); s != "" {
+				values.Add(name, s)
+			}
+		}
+	}
+
+	add("type", p.Types)
+	set("issuer_id", p.IssuerID)
+	set("issuer", p.Issuer)
+	add("listing_level", p.ListingLevels)
+	set("qualified_only", p.QualifiedOnly)
+	set("high_risk", p.HighRisk)
+	add("currency", p.Currencies)
+	add("coupon_frequency", p.CouponFrequencies)
+	set("maturity_from", p.MaturityFrom)
+	set("maturity_to", p.MaturityTo)
+	set("offer_from", p.OfferFrom)
+	set("offer_to", p.OfferTo)
+	set("min_yield", p.MinYield)
+	set("max_yield", p.MaxYield)
+	set("min_spread", p.MinSpread)
+	set("max_spread", p.MaxSpread)
+	set("min_price", p.MinPrice)
+	set("max_price", p.MaxPrice)
+	set("amortization", p.Amortization)
+	set("sort", p.Sort)
+	set("order", p.Order)
+	set("skip", p.Skip)
+	set("limit", p.Limit)
+
+	return values
+}
+
+// errorResponse - ответ API в случае ошибки
+type errorResponse struct {
+	Error struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
@@ -39,6 +39,20 @@ const (
 	EuroBond BondType = BondType(moex.EuroBond)
 )
 
+// CouponType содержит тип купона облигации
+type CouponType string
+
+const (
+	// FixedCoupon - купон с известной заранее ставкой
+	FixedCoupon CouponType = CouponType(moex.FixedCoupon)
+
+	// FloatingCoupon - переменный купон (флоатер)
+	FloatingCoupon CouponType = CouponType(moex.FloatingCoupon)
+
+	// IndexedCoupon - купон по облигации с индексируемым номиналом (линкер)
+	IndexedCoupon CouponType = CouponType(moex.IndexedCoupon)
+)
+
 // Bond содержит данные облигаций
 type Bond struct {
 	ID                 int          `gorm:"column:id; primaryKey"`
@@ -60,6 +74,7 @@ type Bond struct {
 	MaturityDate       sql.NullTime `gorm:"column:maturity_date"`
 	ListingLevel       int          `gorm:"column:listing_level"`
 	CouponFrequency    int          `gorm:"column:coupon_freq"`
+	CouponType         CouponType   `gorm:"column:coupon_type"`
 	CreatedAt          time.Time    `gorm:"column:created"`
 	UpdatedAt          time.Time    `gorm:"column:updated"`
 	Issuer             Issuer
@@ -91,6 +106,7 @@ type CreateBondArgs struct {
 	MaturityDate       sql.NullTime
 	ListingLevel       int
 	CouponFrequency    int
+	CouponType         CouponType
 }
 
 // UpdateBondArgs содержит данные для создания облигации
@@ -131,6 +147,14 @@ type BondRepository interface {
 
 	// GetLastUpdateTime возвращает дату и время последней выгрузки данных
 	GetLastUpdateTime() (*time.Time, error)
+
+	// ListActiveSince возвращает список торгуемых облигаций, а также облигаций, погашенных не ранее указанной даты
+	// Направление сортировки - по возрастанию ID
+	ListActiveSince(t time.Time) ([]*Bond, error)
+
+	// ListTradedByCouponType возвращает список торгуемых облигаций с указанными типами купона
+	// Направление сортировки - по возрастанию ID
+	ListTradedByCouponType(types ...CouponType) ([]*Bond, error)
 }
 
 type bondRepository struct {
@@ -256,6 +280,7 @@ func (repo *bondRepository) Create(args CreateBondArgs) (*Bond, error) {
 		MaturityDate:       args.MaturityDate,
 		ListingLevel:       args.ListingLevel,
 		CouponFrequency:    args.CouponFrequency,
+		CouponType:         args.CouponType,
 		CreatedAt:          now,
 		UpdatedAt:          now,
 	}
@@ -323,3 +348,27 @@ func (repo *bondRepository) GetLastUpdateTime() (*time.Time, error) {
 	}
 	return time, nil
 }
+
+// ListActiveSince возвращает список торгуемых облигаций, а также облигаций, погашенных не ранее указанной даты
+// Направление сортировки - по возрастанию ID
+func (repo *bondRepository) ListActiveSince(t time.Time) ([]*Bond, error) {
+	var bonds []*Bond
+	err := repo.db.Where("is_traded = ? OR maturity_date >= ?", true, t).Order("id ASC").Find(&bonds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return bonds, nil
+}
+
+// ListTradedByCouponType возвращает список торгуемых облигаций с указанными типами купона
+// Направление сортировки - по возрастанию ID
+func (repo *bondRepository) ListTradedByCouponType(types ...CouponType) ([]*Bond, error) {
+	var bonds []*Bond
+	err := repo.db.Where("is_traded = ? AND coupon_type IN ?", true, types).Order("id ASC").Find(&bonds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return bonds, nil
+}
@@ -8,10 +8,13 @@ import (
 
 // CashFlowItem представляет запись в таблице текущих выплат
 type CashFlowItem struct {
-	BondID   int         `gorm:"column:bond_id"`
-	Type     PaymentType `gorm:"column:type"`
-	Date     time.Time   `gorm:"column:date"`
-	ValueRub float64     `gorm:"column:value_rub"`
+	BondID    int         `gorm:"column:bond_id"`
+	Type      PaymentType `gorm:"column:type"`
+	Date      time.Time   `gorm:"column:date"`
+	Currency  string      `gorm:"column:currency"`
+	Value     float64     `gorm:"column:value"`
+	ValueRub  float64     `gorm:"column:value_rub"`
+	Estimated bool        `gorm:"column:estimated"`
 }
 
 // TableName задает название таблицы
@@ -25,6 +28,10 @@ type CashFlowRepository interface {
 	// Направление сортировки - по возрастанию даты
 	List(id int) ([]*CashFlowItem, error)
 
+	// ListAll возвращает полный список текущих выплат для всех облигаций
+	// Направление сортировки - по возрастанию ID облигации и даты
+	ListAll() ([]*CashFlowItem, error)
+
 	// Rebuild выполняет перерасчет текущих выплат для всех облигаций
 	Rebuild() error
 }
@@ -51,6 +58,23 @@ ORDER BY date ASC, type ASC
 	return items, nil
 }
 
+// ListAll возвращает полный список текущих выплат для всех облигаций
+// Направление сортировки - по возрастанию ID облигации и даты
+func (repo *cashFlowRepository) ListAll() ([]*CashFlowItem, error) {
+	sqlQuery := `
+SELECT *
+FROM cashflows
+ORDER BY bond_id ASC, date ASC, type ASC
+`
+	var items []*CashFlowItem
+	err := repo.db.Raw(sqlQuery).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
 // Rebuild выполняет перерасчет текущих выплат для всех облигаций
 func (repo *cashFlowRepository) Rebuild() error {
 	return repo.db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY cashflows").Error
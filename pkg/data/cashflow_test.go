@@ -27,13 +27,16 @@ func TestCashFlowItem_Scan(t *testing.T) {
 
 	mock.ExpectQuery("SELECT \\* FROM \"cashflows\"").
 		WillReturnRows(
-			sqlmock.NewRows([]string{"bond_id", "type", "value_rub"}).
-				AddRow(123, "C", 45.67))
+			sqlmock.NewRows([]string{"bond_id", "type", "currency", "value", "value_rub", "estimated"}).
+				AddRow(123, "C", "USD", 0.5, 45.67, true))
 
 	var item data.CashFlowItem
 	err = db.First(&item).Error
 	assert.Nil(err)
 	assert.Equal(123, item.BondID)
 	assert.Equal(data.CouponPayment, item.Type)
+	assert.Equal("USD", item.Currency)
+	assert.Equal(float64(0.5), item.Value)
 	assert.Equal(float64(45.67), item.ValueRub)
+	assert.True(item.Estimated)
 }
@@ -22,7 +22,8 @@ func (CollectionBondRef) TableName() string {
 // CollectionBondRefRepository отвечает за управление записями в таблице связей "коллекция-облигация"
 type CollectionBondRefRepository interface {
 	// Rebuild выполняет перерасчет списка облигаций для отдельной коллекции
-	Rebuild(collectionID string, duration int, filter string) error
+	// Значения values подставляются в параметры подзапроса filter
+	Rebuild(collectionID string, duration int, filter string, values ...interface{}) error
 }
 
 type collectionBondRefRepository struct {
@@ -30,7 +31,8 @@ type collectionBondRefRepository struct {
 }
 
 // Rebuild выполняет перерасчет списка облигаций для отдельной коллекции
-func (repo *collectionBondRefRepository) Rebuild(collectionID string, duration int, filter string) error {
+// Значения values подставляются в параметры подзапроса filter
+func (repo *collectionBondRefRepository) Rebuild(collectionID string, duration int, filter string, values ...interface{}) error {
 	err := repo.db.
 		Exec("DELETE FROM collection_bonds WHERE collection_id = ? AND duration = ?", collectionID, duration).
 		Error
@@ -38,25 +40,29 @@ func (repo *collectionBondRefRepository) Rebuild(collectionID string, duration i
 		return err
 	}
 
+	// Облигации отбираются по сроку до оферты (если она предвидится) или погашения,
+	// поэтому и ранжируются по доходности к той же дате
 	sqlQuery := `
 INSERT INTO collection_bonds(collection_id, duration, bond_id, index)
 SELECT ? AS collection_id,
        ? AS duration,
-       bond_id,
-       ROW_NUMBER() OVER (ORDER BY interest_rate DESC) AS index
+       reports.bond_id,
+       ROW_NUMBER() OVER (ORDER BY COALESCE(report_metrics.yield_to_offer, report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate) DESC) AS index
 FROM reports
 INNER JOIN bonds on reports.bond_id = bonds.id
-WHERE bond_id IN (
+LEFT JOIN report_metrics ON report_metrics.bond_id = reports.bond_id
+WHERE reports.bond_id IN (
 %s
 )
-AND interest_rate > 0
-AND (AGE(bonds.maturity_date::date, NOW()::date) >= '3 day'::interval)
-AND (AGE(bonds.maturity_date::date, NOW()::date) <= '%d year'::interval)
-ORDER BY interest_rate DESC;
+AND COALESCE(report_metrics.yield_to_offer, report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate) > 0
+AND (AGE(COALESCE(report_metrics.offer_date, bonds.maturity_date)::date, NOW()::date) >= '3 day'::interval)
+AND (AGE(COALESCE(report_metrics.offer_date, bonds.maturity_date)::date, NOW()::date) <= '%d year'::interval)
+ORDER BY COALESCE(report_metrics.yield_to_offer, report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate) DESC;
 `
 	sqlQuery = fmt.Sprintf(sqlQuery, filter, duration)
 
-	err = repo.db.Exec(sqlQuery, collectionID, duration).Error
+	args := append([]interface{}{collectionID, duration}, values...)
+	err = repo.db.Exec(sqlQuery, args...).Error
 	if err != nil {
 		return err
 	}
@@ -38,10 +38,16 @@ type TX struct {
 	Payments                 PaymentRepository
 	Offers                   OfferRepository
 	MarketData               MarketDataRepository
+	FxRates                  FxRateRepository
 	Search                   SearchRepository
 	CashFlow                 CashFlowRepository
 	Reports                  ReportRepository
+	ReportMetrics            ReportMetricsRepository
+	PaymentProjections       PaymentProjectionRepository
 	CollectionBondReferences CollectionBondRefRepository
+	Portfolios               PortfolioRepository
+	History                  HistoryRepository
+	YieldCurves              YieldCurveRepository
 	db                       *gorm.DB
 	committed                bool
 }
@@ -213,10 +219,16 @@ func (ctx *dbContext) BeginTX() (*TX, error) {
 		Payments:                 &paymentRepository{db},
 		Offers:                   &offerRepository{db},
 		MarketData:               &marketDataRepository{db},
+		FxRates:                  &fxRateRepository{db},
 		Search:                   &searchRepository{db},
 		CashFlow:                 &cashFlowRepository{db},
 		Reports:                  &reportRepository{db},
+		ReportMetrics:            &reportMetricsRepository{db},
+		PaymentProjections:       &paymentProjectionRepository{db},
 		CollectionBondReferences: &collectionBondRefRepository{db},
+		Portfolios:               &portfolioRepository{db},
+		History:                  &historyRepository{db},
+		YieldCurves:              &yieldCurveRepository{db},
 		db:                       db,
 		committed:                false,
 	}
@@ -0,0 +1,98 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BaseCurrency - валюта, в которую пересчитываются выплаты и цены облигаций
+const BaseCurrency = "RUB"
+
+// FxRate содержит текущий курс иностранной валюты к рублю
+type FxRate struct {
+	Currency string    `gorm:"column:currency; primaryKey"`
+	Rate     float64   `gorm:"column:rate"`
+	Time     time.Time `gorm:"column:time"`
+}
+
+// TableName задает название таблицы
+func (FxRate) TableName() string {
+	return "fx_rates"
+}
+
+// FxRateRepository отвечает за управление записями в таблице курсов валют
+type FxRateRepository interface {
+	// Get возвращает курс указанной валюты
+	// Если курс валюты неизвестен, то возвращается ошибка ErrNotFound
+	Get(currency string) (*FxRate, error)
+
+	// List возвращает курсы всех валют
+	// Направление сортировки - по возрастанию кода валюты
+	List() ([]*FxRate, error)
+
+	// Put записывает курс валюты
+	// Если курс валюты уже существует, он обновляется
+	Put(currency string, rate float64, t time.Time) (*FxRate, error)
+}
+
+type fxRateRepository struct {
+	db *gorm.DB
+}
+
+// Get возвращает курс указанной валюты
+// Если курс валюты неизвестен, то возвращается ошибка ErrNotFound
+func (repo *fxRateRepository) Get(currency string) (*FxRate, error) {
+	var rate FxRate
+	err := repo.db.First(&rate, "currency = ?", currency).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return &rate, nil
+}
+
+// List возвращает курсы всех валют
+// Направление сортировки - по возрастанию кода валюты
+func (repo *fxRateRepository) List() ([]*FxRate, error) {
+	var rates []*FxRate
+	err := repo.db.Order("currency ASC").Find(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// Put записывает курс валюты
+// Если курс валюты уже существует, он обновляется
+func (repo *fxRateRepository) Put(currency string, rate float64, t time.Time) (*FxRate, error) {
+	fxRate, err := repo.Get(currency)
+	if err != nil {
+		if err != ErrNotFound {
+			return nil, err
+		}
+
+		fxRate = &FxRate{Currency: currency, Rate: rate, Time: t}
+		err = repo.db.Create(fxRate).Error
+		if err != nil {
+			return nil, err
+		}
+
+		return fxRate, nil
+	}
+
+	fxRate.Rate = rate
+	fxRate.Time = t
+	err = repo.db.Updates(fxRate).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return fxRate, nil
+}
@@ -0,0 +1,276 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HistoryKind кодирует тип записи в истории рыночных данных
+type HistoryKind string
+
+const (
+	// EndOfDayHistory - данные на конец дня (одна запись на облигацию в день)
+	// Доходность к погашению - расчетная, с учетом налогов и комиссий (см. report_metrics)
+	EndOfDayHistory HistoryKind = "eod"
+
+	// IntradayHistory - внутридневные данные (одна запись на облигацию на каждую выгрузку)
+	// Доходность к погашению - расчетная, как и для EndOfDayHistory
+	IntradayHistory HistoryKind = "intraday"
+
+	// TradesHistory - итоги торгов за день, загруженные из истории торгов биржи (одна запись на облигацию в день)
+	// Доходность к погашению - доходность по цене закрытия по данным биржи, без учета налогов и комиссий
+	TradesHistory HistoryKind = "trades"
+)
+
+// HistoryRecord содержит исторические рыночные данные облигации
+type HistoryRecord struct {
+	ID              int         `gorm:"column:id; primaryKey"`
+	BondID          int         `gorm:"column:bond_id"`
+	Kind            HistoryKind `gorm:"column:kind"`
+	Time            time.Time   `gorm:"column:time"`
+	FaceValue       *float64    `gorm:"column:face_value"`
+	Currency        *string     `gorm:"column:currency"`
+	Price           *float64    `gorm:"column:price"`
+	AccruedInterest *float64    `gorm:"column:accrued_interest"`
+	YieldToMaturity *float64    `gorm:"column:yield_to_maturity"`
+	BoardID         *string     `gorm:"column:board_id"`
+	Open            *float64    `gorm:"column:open"`
+	High            *float64    `gorm:"column:high"`
+	Low             *float64    `gorm:"column:low"`
+	Close           *float64    `gorm:"column:close"`
+	Volume          *float64    `gorm:"column:volume"`
+	Value           *float64    `gorm:"column:value"`
+	DurationDays    *float64    `gorm:"column:duration_days"`
+	Bond            Bond
+}
+
+// TableName задает название таблицы
+func (HistoryRecord) TableName() string {
+	return "marketdata_history"
+}
+
+// PutHistoryArgs содержит параметры для записи исторических рыночных данных
+type PutHistoryArgs struct {
+	Kind            HistoryKind
+	Time            time.Time
+	FaceValue       *float64
+	Currency        *string
+	Price           *float64
+	AccruedInterest *float64
+	YieldToMaturity *float64
+	BoardID         *string
+	Open            *float64
+	High            *float64
+	Low             *float64
+	Close           *float64
+	Volume          *float64
+	Value           *float64
+	DurationDays    *float64
+}
+
+// HistoryRepository отвечает за управление записями в таблице истории рыночных данных
+type HistoryRepository interface {
+	// Snapshot сохраняет текущие рыночные данные и доходности к погашению всех облигаций в историю
+	// Для EndOfDayHistory время записи округляется до начала дня, и запись за текущий день перезаписывается
+	// Возвращает количество записанных строк
+	Snapshot(kind HistoryKind) (int, error)
+
+	// Put записывает исторические рыночные данные для указанной облигации
+	// Если запись с таким же типом и временем уже существует, она обновляется
+	Put(bondID int, args PutHistoryArgs) (*HistoryRecord, error)
+
+	// List возвращает историю рыночных данных облигации за период [from, to]
+	// Направление сортировки - по возрастанию времени
+	List(bondID int, kind HistoryKind, from, to time.Time) ([]*HistoryRecord, error)
+
+	// ListByBondType возвращает историю рыночных данных на конец дня за период [from, to] по всем облигациям заданного типа
+	// Возвращаются только записи с известными доходностью и дюрацией, облигации загружаются вместе с записями
+	// Направление сортировки - по возрастанию времени
+	ListByBondType(bondType BondType, from, to time.Time) ([]*HistoryRecord, error)
+
+	// ListLatest возвращает по каждой облигации последнюю запись указанного типа за период [from, to]
+	// Возвращаются только записи с известными ценой, НКД и номиналом, облигации и эмитенты загружаются вместе с записями
+	// Направление сортировки - по возрастанию ID облигации
+	ListLatest(kind HistoryKind, from, to time.Time) ([]*HistoryRecord, error)
+
+	// DeleteBefore удаляет записи указанного типа, созданные ранее заданного момента времени
+	// Возвращает количество удаленных строк
+	DeleteBefore(kind HistoryKind, t time.Time) (int, error)
+
+	// GetLastTradeDate возвращает дату последней записи, загруженной из истории торгов биржи
+	// Если таких записей нет, то возвращается nil
+	GetLastTradeDate(bondID int) (*time.Time, error)
+}
+
+type historyRepository struct {
+	db *gorm.DB
+}
+
+// Snapshot сохраняет текущие рыночные данные и доходности к погашению всех облигаций в историю
+// Для EndOfDayHistory время записи округляется до начала дня, и запись за текущий день перезаписывается
+// Возвращает количество записанных строк
+func (repo *historyRepository) Snapshot(kind HistoryKind) (int, error) {
+	sql := `
+INSERT INTO marketdata_history (bond_id, kind, time, face_value, currency, price, accrued_interest, yield_to_maturity, duration_days)
+SELECT m.bond_id,
+       @kind,
+       CASE WHEN @kind = 'eod' THEN DATE_TRUNC('day', m.time) ELSE m.time END,
+       m.face_value,
+       m.currency,
+       COALESCE(m.last, m.close_price, m.legal_close_price),
+       m.accrued_interest,
+       rm.yield_to_maturity,
+       rm.macaulay_duration * 365
+FROM marketdata m
+         LEFT JOIN report_metrics rm ON rm.bond_id = m.bond_id
+WHERE COALESCE(m.last, m.close_price, m.legal_close_price) IS NOT NULL
+ON CONFLICT (bond_id, kind, time) DO UPDATE
+    SET face_value        = excluded.face_value,
+        currency          = excluded.currency,
+        price             = excluded.price,
+        accrued_interest  = excluded.accrued_interest,
+        yield_to_maturity = excluded.yield_to_maturity,
+        duration_days     = COALESCE(excluded.duration_days, marketdata_history.duration_days)
+`
+	result := repo.db.Exec(sql, map[string]interface{}{"kind": string(kind)})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+// Put записывает исторические рыночные данные для указанной облигации
+// Если запись с таким же типом и временем уже существует, она обновляется
+func (repo *historyRepository) Put(bondID int, args PutHistoryArgs) (*HistoryRecord, error) {
+	record := &HistoryRecord{
+		BondID:          bondID,
+		Kind:            args.Kind,
+		Time:            args.Time,
+		FaceValue:       args.FaceValue,
+		Currency:        args.Currency,
+		Price:           args.Price,
+		AccruedInterest: args.AccruedInterest,
+		YieldToMaturity: args.YieldToMaturity,
+		BoardID:         args.BoardID,
+		Open:            args.Open,
+		High:            args.High,
+		Low:             args.Low,
+		Close:           args.Close,
+		Volume:          args.Volume,
+		Value:           args.Value,
+		DurationDays:    args.DurationDays,
+	}
+
+	err := repo.db.
+		Omit("Bond").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "bond_id"}, {Name: "kind"}, {Name: "time"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"face_value", "currency", "price", "accrued_interest", "yield_to_maturity",
+				"board_id", "open", "high", "low", "close", "volume", "value", "duration_days",
+			}),
+		}).
+		Create(record).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// List возвращает историю рыночных данных облигации за период [from, to]
+// Направление сортировки - по возрастанию времени
+func (repo *historyRepository) List(bondID int, kind HistoryKind, from, to time.Time) ([]*HistoryRecord, error) {
+	var records []*HistoryRecord
+	err := repo.db.
+		Where("bond_id = ? AND kind = ? AND time >= ? AND time <= ?", bondID, kind, from, to).
+		Order("time ASC").
+		Find(&records).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// ListByBondType возвращает историю рыночных данных на конец дня за период [from, to] по всем облигациям заданного типа
+// Возвращаются только записи с известными доходностью и дюрацией, облигации загружаются вместе с записями
+// Направление сортировки - по возрастанию времени
+func (repo *historyRepository) ListByBondType(bondType BondType, from, to time.Time) ([]*HistoryRecord, error) {
+	var records []*HistoryRecord
+	err := repo.db.
+		Preload("Bond").
+		Joins("INNER JOIN bonds ON bonds.id = marketdata_history.bond_id").
+		Where("bonds.type = ? AND marketdata_history.kind = ?", bondType, EndOfDayHistory).
+		Where("marketdata_history.time >= ? AND marketdata_history.time <= ?", from, to).
+		Where("marketdata_history.yield_to_maturity IS NOT NULL AND marketdata_history.duration_days IS NOT NULL").
+		Order("marketdata_history.time ASC").
+		Find(&records).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// ListLatest возвращает по каждой облигации последнюю запись указанного типа за период [from, to]
+// Возвращаются только записи с известными ценой, НКД и номиналом, облигации и эмитенты загружаются вместе с записями
+// Направление сортировки - по возрастанию ID облигации
+func (repo *historyRepository) ListLatest(kind HistoryKind, from, to time.Time) ([]*HistoryRecord, error) {
+	sql := `
+SELECT DISTINCT ON (bond_id) id
+FROM marketdata_history
+WHERE kind = ?
+  AND time >= ?
+  AND time <= ?
+  AND price IS NOT NULL
+  AND accrued_interest IS NOT NULL
+  AND face_value IS NOT NULL
+ORDER BY bond_id, time DESC
+`
+	var records []*HistoryRecord
+	err := repo.db.
+		Preload("Bond").
+		Preload("Bond.Issuer").
+		Where("id IN (?)", repo.db.Raw(sql, kind, from, to)).
+		Order("bond_id ASC").
+		Find(&records).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// DeleteBefore удаляет записи указанного типа, созданные ранее заданного момента времени
+// Возвращает количество удаленных строк
+func (repo *historyRepository) DeleteBefore(kind HistoryKind, t time.Time) (int, error) {
+	result := repo.db.Exec("DELETE FROM marketdata_history WHERE kind = ? AND time < ?", kind, t)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+// GetLastTradeDate возвращает дату последней записи, загруженной из истории торгов биржи
+// Если таких записей нет, то возвращается nil
+func (repo *historyRepository) GetLastTradeDate(bondID int) (*time.Time, error) {
+	var result *time.Time
+	err := repo.db.
+		Raw("SELECT MAX(time) FROM marketdata_history WHERE bond_id = ? AND kind = ?", bondID, TradesHistory).
+		Scan(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
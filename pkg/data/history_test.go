@@ -0,0 +1,47 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+	"gopkg.in/data-dog/go-sqlmock.v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestHistoryRecord_Scan(t *testing.T) {
+	assert := assertion.New(t)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		panic(err)
+	}
+
+	date := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM \"marketdata_history\"").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "bond_id", "kind", "time", "price", "yield_to_maturity"}).
+				AddRow(123, 456, "eod", date, 98.76, 7.89))
+
+	var record data.HistoryRecord
+	err = db.First(&record).Error
+	assert.Nil(err)
+	assert.Equal(123, record.ID)
+	assert.Equal(456, record.BondID)
+	assert.Equal(data.EndOfDayHistory, record.Kind)
+	assert.Equal(date, record.Time)
+	assert.NotNil(record.Price)
+	assert.Equal(98.76, *record.Price)
+	assert.NotNil(record.YieldToMaturity)
+	assert.Equal(7.89, *record.YieldToMaturity)
+	assert.Nil(record.AccruedInterest)
+}
@@ -14,6 +14,7 @@ type Issuer struct {
 	Name      string    `gorm:"column:name"`
 	INN       *string   `gorm:"column:inn; unique"`
 	OKPO      *string   `gorm:"column:okpo"`
+	Rating    *string   `gorm:"column:rating"`
 	CreatedAt time.Time `gorm:"column:created"`
 	UpdatedAt time.Time `gorm:"column:updated"`
 	Bonds     []Bond    `gorm:"foreignKey:IssuerID"`
@@ -46,6 +47,10 @@ type IssuerRepository interface {
 	// Если эмитент не найден, возвращается ошибка ErrNotFound
 	// Если эмитент не найден, возвращается ошибка ErrNotFound
 	Update(id int, args UpdateIssuerArgs) (*Issuer, error)
+
+	// SetRatings задает кредитные рейтинги эмитентов по ИНН (ИНН -> рейтинг)
+	// Рейтинги эмитентов, которых нет в списке, сбрасываются
+	SetRatings(ratings map[string]string) error
 }
 
 // CreateIssuerArgs содержит данные для создания эмитента
@@ -183,3 +188,21 @@ func (repo *issuerRepository) Update(id int, args UpdateIssuerArgs) (*Issuer, er
 
 	return issuer, nil
 }
+
+// SetRatings задает кредитные рейтинги эмитентов по ИНН (ИНН -> рейтинг)
+// Рейтинги эмитентов, которых нет в списке, сбрасываются
+func (repo *issuerRepository) SetRatings(ratings map[string]string) error {
+	err := repo.db.Exec("UPDATE issuers SET rating = NULL WHERE rating IS NOT NULL").Error
+	if err != nil {
+		return err
+	}
+
+	for inn, rating := range ratings {
+		err = repo.db.Exec("UPDATE issuers SET rating = ? WHERE inn = ?", rating, inn).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -19,9 +19,31 @@ type MarketData struct {
 	ClosePrice      *float64  `gorm:"column:close_price"`
 	LegalClosePrice *float64  `gorm:"column:legal_close_price"`
 	AccruedInterest *float64  `gorm:"column:accrued_interest"`
+	LotSize         *int      `gorm:"column:lot_size"`
+	TradingStatus   *string   `gorm:"column:trading_status"`
 	Bond            Bond
 }
 
+// TradingStatusActive - статус торгов "торги разрешены"
+// Прочие статусы означают, что торги облигацией на основном режиме приостановлены или не ведутся
+const TradingStatusActive = "A"
+
+// Lot возвращает размер лота, шт.
+// Если размер лота неизвестен, то считается, что лот состоит из одной облигации
+func (m *MarketData) Lot() int {
+	if m.LotSize == nil || *m.LotSize <= 0 {
+		return 1
+	}
+
+	return *m.LotSize
+}
+
+// IsTradingActive возвращает true, если торги облигацией не приостановлены
+// Если статус торгов неизвестен, то считается, что торги ведутся
+func (m *MarketData) IsTradingActive() bool {
+	return m.TradingStatus == nil || *m.TradingStatus == "" || *m.TradingStatus == TradingStatusActive
+}
+
 // TableName задает название таблицы
 func (MarketData) TableName() string {
 	return "marketdata"
@@ -37,6 +59,8 @@ type PutMarketDataArgs struct {
 	ClosePrice      *float64
 	LegalClosePrice *float64
 	AccruedInterest *float64
+	LotSize         *int
+	TradingStatus   *string
 }
 
 // MarketDataRepository отвечает за управление записями в таблице рыночных данных
@@ -110,4 +134,6 @@ func (repo *marketDataRepository) PutValues(marketData *MarketData, args *PutMar
 	marketData.ClosePrice = args.ClosePrice
 	marketData.LegalClosePrice = args.LegalClosePrice
 	marketData.AccruedInterest = args.AccruedInterest
+	marketData.LotSize = args.LotSize
+	marketData.TradingStatus = args.TradingStatus
 }
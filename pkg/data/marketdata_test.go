@@ -27,8 +27,8 @@ func TestMarketData_Scan(t *testing.T) {
 
 	mock.ExpectQuery("SELECT \\* FROM \"marketdata\"").
 		WillReturnRows(
-			sqlmock.NewRows([]string{"id", "bond_id", "last"}).
-				AddRow(123, 456, 123.45))
+			sqlmock.NewRows([]string{"id", "bond_id", "last", "lot_size", "trading_status"}).
+				AddRow(123, 456, 123.45, 10, "S"))
 
 	var marketData data.MarketData
 	err = db.First(&marketData).Error
@@ -37,4 +37,6 @@ func TestMarketData_Scan(t *testing.T) {
 	assert.Equal(456, marketData.BondID)
 	assert.NotNil(marketData.Last)
 	assert.Equal(123.45, *marketData.Last)
+	assert.Equal(10, marketData.Lot())
+	assert.False(marketData.IsTradingActive())
 }
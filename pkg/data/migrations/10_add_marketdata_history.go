@@ -0,0 +1,27 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+CREATE TABLE marketdata_history
+(
+    id                int       NOT NULL GENERATED BY DEFAULT AS IDENTITY CONSTRAINT pk_marketdata_history PRIMARY KEY,
+    bond_id           int       NOT NULL CONSTRAINT "FK_bond_marketdata_history" REFERENCES bonds ON DELETE CASCADE,
+    kind              text      NOT NULL,
+    time              timestamp NOT NULL,
+    face_value        numeric   NULL,
+    currency          text      NULL,
+    price             numeric   NULL,
+    accrued_interest  numeric   NULL,
+    yield_to_maturity numeric   NULL,
+    CONSTRAINT ux_marketdata_history UNIQUE (bond_id, kind, time)
+);
+
+CREATE INDEX ix_marketdata_history_kind_time ON marketdata_history (kind, time);
+`
+
+	rollback := `
+DROP TABLE IF EXISTS marketdata_history;
+`
+
+	registerSQL("10_add_marketdata_history", migrateSQL, rollback)
+}
@@ -0,0 +1,29 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+ALTER TABLE marketdata_history
+    ADD COLUMN board_id      text    NULL,
+    ADD COLUMN open          numeric NULL,
+    ADD COLUMN high          numeric NULL,
+    ADD COLUMN low           numeric NULL,
+    ADD COLUMN close         numeric NULL,
+    ADD COLUMN volume        numeric NULL,
+    ADD COLUMN value         numeric NULL,
+    ADD COLUMN duration_days numeric NULL;
+`
+
+	rollback := `
+ALTER TABLE marketdata_history
+    DROP COLUMN IF EXISTS board_id,
+    DROP COLUMN IF EXISTS open,
+    DROP COLUMN IF EXISTS high,
+    DROP COLUMN IF EXISTS low,
+    DROP COLUMN IF EXISTS close,
+    DROP COLUMN IF EXISTS volume,
+    DROP COLUMN IF EXISTS value,
+    DROP COLUMN IF EXISTS duration_days;
+`
+
+	registerSQL("11_add_history_ohlc", migrateSQL, rollback)
+}
@@ -0,0 +1,26 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+CREATE TABLE yield_curves
+(
+    id     int     NOT NULL GENERATED BY DEFAULT AS IDENTITY CONSTRAINT pk_yield_curves PRIMARY KEY,
+    date   date    NOT NULL,
+    beta0  numeric NOT NULL,
+    beta1  numeric NOT NULL,
+    beta2  numeric NOT NULL,
+    beta3  numeric NOT NULL,
+    tau1   numeric NOT NULL,
+    tau2   numeric NOT NULL,
+    rmse   numeric NOT NULL,
+    points int     NOT NULL,
+    CONSTRAINT ux_yield_curves_date UNIQUE (date)
+);
+`
+
+	rollback := `
+DROP TABLE IF EXISTS yield_curves;
+`
+
+	registerSQL("12_add_yield_curves", migrateSQL, rollback)
+}
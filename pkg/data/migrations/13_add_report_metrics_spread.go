@@ -0,0 +1,15 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+ALTER TABLE report_metrics
+    ADD COLUMN spread numeric NULL;
+`
+
+	rollback := `
+ALTER TABLE report_metrics
+    DROP COLUMN IF EXISTS spread;
+`
+
+	registerSQL("13_add_report_metrics_spread", migrateSQL, rollback)
+}
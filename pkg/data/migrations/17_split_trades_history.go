@@ -0,0 +1,29 @@
+package migrations
+
+func init() {
+	// Итоги торгов, загруженные из истории биржи, ранее записывались как данные на конец дня
+	// и перезаписывались снимками рыночных данных (и наоборот), поэтому переносятся в отдельный тип
+	migrateSQL := `
+UPDATE marketdata_history
+SET kind = 'trades'
+WHERE kind = 'eod'
+  AND board_id IS NOT NULL;
+`
+
+	// Итоги торгов возвращаются в данные на конец дня
+	// При совпадении даты итоги торгов, как и при переносе, имеют приоритет над снимками рыночных данных
+	rollback := `
+DELETE FROM marketdata_history AS e
+USING marketdata_history AS t
+WHERE e.kind = 'eod'
+  AND t.kind = 'trades'
+  AND t.bond_id = e.bond_id
+  AND t.time = e.time;
+
+UPDATE marketdata_history
+SET kind = 'eod'
+WHERE kind = 'trades';
+`
+
+	registerSQL("17_split_trades_history", migrateSQL, rollback)
+}
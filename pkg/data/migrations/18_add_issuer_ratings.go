@@ -0,0 +1,19 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+ALTER TABLE issuers
+    ADD COLUMN rating text NULL;
+
+CREATE INDEX ix_issuers_rating ON issuers (rating);
+`
+
+	rollback := `
+DROP INDEX IF EXISTS ix_issuers_rating;
+
+ALTER TABLE issuers
+    DROP COLUMN IF EXISTS rating;
+`
+
+	registerSQL("18_add_issuer_ratings", migrateSQL, rollback)
+}
@@ -0,0 +1,15 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+ALTER TABLE report_metrics
+    ADD COLUMN interest_rate numeric NULL;
+`
+
+	rollback := `
+ALTER TABLE report_metrics
+    DROP COLUMN IF EXISTS interest_rate;
+`
+
+	registerSQL("19_add_report_metrics_interest_rate", migrateSQL, rollback)
+}
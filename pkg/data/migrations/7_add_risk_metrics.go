@@ -0,0 +1,19 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+ALTER TABLE report_metrics ADD COLUMN macaulay_duration numeric NULL;
+ALTER TABLE report_metrics ADD COLUMN modified_duration numeric NULL;
+ALTER TABLE report_metrics ADD COLUMN dv01 numeric NULL;
+ALTER TABLE report_metrics ADD COLUMN convexity numeric NULL;
+`
+
+	rollback := `
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS convexity;
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS dv01;
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS modified_duration;
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS macaulay_duration;
+`
+
+	registerSQL("7_add_risk_metrics", migrateSQL, rollback)
+}
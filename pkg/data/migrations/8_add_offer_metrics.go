@@ -0,0 +1,21 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+ALTER TABLE report_metrics ADD COLUMN offer_date date NULL;
+ALTER TABLE report_metrics ADD COLUMN offer_price numeric NULL;
+ALTER TABLE report_metrics ADD COLUMN yield_to_offer numeric NULL;
+
+CREATE INDEX ix_report_metrics_offer_date ON report_metrics (offer_date);
+`
+
+	rollback := `
+DROP INDEX IF EXISTS ix_report_metrics_offer_date;
+
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS yield_to_offer;
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS offer_price;
+ALTER TABLE report_metrics DROP COLUMN IF EXISTS offer_date;
+`
+
+	registerSQL("8_add_offer_metrics", migrateSQL, rollback)
+}
@@ -0,0 +1,37 @@
+package migrations
+
+func init() {
+	migrateSQL := `
+CREATE TABLE portfolios
+(
+    id      int       NOT NULL GENERATED BY DEFAULT AS IDENTITY CONSTRAINT pk_portfolios PRIMARY KEY,
+    name    text      NOT NULL,
+    created timestamp NOT NULL,
+    updated timestamp NOT NULL
+);
+
+CREATE TABLE trades
+(
+    id               int       NOT NULL GENERATED BY DEFAULT AS IDENTITY CONSTRAINT pk_trades PRIMARY KEY,
+    portfolio_id     int       NOT NULL CONSTRAINT "FK_portfolio_trade" REFERENCES portfolios ON DELETE CASCADE,
+    bond_id          int       NOT NULL CONSTRAINT "FK_bond_trade" REFERENCES bonds ON DELETE CASCADE,
+    date             date      NOT NULL,
+    quantity         int       NOT NULL,
+    price            numeric   NOT NULL,
+    face_value       numeric   NOT NULL,
+    accrued_interest numeric   NOT NULL,
+    fee              numeric   NOT NULL,
+    created          timestamp NOT NULL
+);
+
+CREATE INDEX ix_trades_portfolio_id ON trades (portfolio_id);
+CREATE INDEX ix_trades_bond_id ON trades (bond_id);
+`
+
+	rollback := `
+DROP TABLE IF EXISTS trades;
+DROP TABLE IF EXISTS portfolios;
+`
+
+	registerSQL("9_add_portfolios", migrateSQL, rollback)
+}
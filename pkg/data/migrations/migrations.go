@@ -2,6 +2,7 @@ package migrations
 
 import (
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/go-gormigrate/gormigrate/v2"
@@ -13,7 +14,7 @@ var list = make([]*gormigrate.Migration, 0)
 // Up применяет миграции к БД
 func Up(db *gorm.DB) error {
 	sort.Slice(list, func(i, j int) bool {
-		return strings.Compare(list[i].ID, list[j].ID) < 0
+		return compareIDs(list[i].ID, list[j].ID) < 0
 	})
 
 	options := &gormigrate.Options{
@@ -32,6 +33,20 @@ func Up(db *gorm.DB) error {
 	return nil
 }
 
+// compareIDs сравнивает ID миграций по числовому префиксу (чтобы "10_..." шла после "9_...")
+func compareIDs(x, y string) int {
+	nx, errX := strconv.Atoi(strings.SplitN(x, "_", 2)[0])
+	ny, errY := strconv.Atoi(strings.SplitN(y, "_", 2)[0])
+	if errX != nil || errY != nil || nx == ny {
+		return strings.Compare(x, y)
+	}
+
+	if nx < ny {
+		return -1
+	}
+	return 1
+}
+
 func register(id string, migrate gormigrate.MigrateFunc, rollback gormigrate.RollbackFunc) {
 	m := &gormigrate.Migration{
 		ID:       id,
@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestCompareIDs(t *testing.T) {
+	assert := assertion.New(t)
+
+	assert.Equal(-1, compareIDs("9_add_portfolios", "10_add_marketdata_history"))
+	assert.Equal(1, compareIDs("10_add_marketdata_history", "1_add_search"))
+	assert.Equal(-1, compareIDs("0_initial", "1_add_search"))
+	assert.Equal(0, compareIDs("1_add_search", "1_add_search"))
+}
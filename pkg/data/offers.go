@@ -78,6 +78,14 @@ type OfferRepository interface {
 	// Create создает новую выплату по оферте
 	// Если указанная оферта уже существует, то возвращается ошибка ErrAlreadyExists
 	Create(args CreateOfferArgs) (*Offer, error)
+
+	// ListUpcoming возвращает список предстоящих (еще не состоявшихся) оферт
+	// Направление сортировки - по возрастанию ID облигации и даты оферты
+	ListUpcoming() ([]*Offer, error)
+
+	// ListAfter возвращает список оферт, которые состоялись или состоятся позднее указанной даты
+	// Направление сортировки - по возрастанию ID облигации и даты оферты
+	ListAfter(t time.Time) ([]*Offer, error)
 }
 
 type offerRepository struct {
@@ -121,3 +129,39 @@ func (repo *offerRepository) Create(args CreateOfferArgs) (*Offer, error) {
 
 	return &offer, nil
 }
+
+// ListUpcoming возвращает список предстоящих (еще не состоявшихся) оферт
+// Направление сортировки - по возрастанию ID облигации и даты оферты
+func (repo *offerRepository) ListUpcoming() ([]*Offer, error) {
+	var offers []*Offer
+	err := repo.db.
+		Where("type = ?", GenericOffer).
+		Where("date > NOW()::date").
+		Order("bond_id ASC").
+		Order("date ASC").
+		Find(&offers).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return offers, nil
+}
+
+// ListAfter возвращает список оферт, которые состоялись или состоятся позднее указанной даты
+// Направление сортировки - по возрастанию ID облигации и даты оферты
+func (repo *offerRepository) ListAfter(t time.Time) ([]*Offer, error) {
+	var offers []*Offer
+	err := repo.db.
+		Where("type = ?", GenericOffer).
+		Where("date > ?", t).
+		Order("bond_id ASC").
+		Order("date ASC").
+		Find(&offers).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return offers, nil
+}
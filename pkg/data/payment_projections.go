@@ -0,0 +1,41 @@
+package data
+
+import (
+	"gorm.io/gorm"
+)
+
+// PaymentProjection содержит расчетную оценку выплаты (в валюте номинала), размер которой еще не объявлен эмитентом
+// (купоны флоатеров и линкеров, погашение линкеров)
+type PaymentProjection struct {
+	PaymentID int     `gorm:"column:payment_id; primaryKey"`
+	Value     float64 `gorm:"column:value"`
+}
+
+// TableName задает название таблицы
+func (PaymentProjection) TableName() string {
+	return "payment_projections"
+}
+
+// PaymentProjectionRepository отвечает за управление записями в таблице расчетных оценок выплат
+type PaymentProjectionRepository interface {
+	// Rebuild полностью заменяет содержимое таблицы расчетных оценок выплат
+	Rebuild(items []*PaymentProjection) error
+}
+
+type paymentProjectionRepository struct {
+	db *gorm.DB
+}
+
+// Rebuild полностью заменяет содержимое таблицы расчетных оценок выплат
+func (repo *paymentProjectionRepository) Rebuild(items []*PaymentProjection) error {
+	err := repo.db.Exec("DELETE FROM payment_projections WHERE TRUE::bool").Error
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	return repo.db.CreateInBatches(items, 500).Error
+}
@@ -0,0 +1,38 @@
+package data_test
+
+import (
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+	"gopkg.in/data-dog/go-sqlmock.v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestPaymentProjection_Scan(t *testing.T) {
+	assert := assertion.New(t)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		panic(err)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM \"payment_projections\"").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"payment_id", "value"}).
+				AddRow(123, 38.64))
+
+	var item data.PaymentProjection
+	err = db.First(&item).Error
+	assert.Nil(err)
+	assert.Equal(123, item.PaymentID)
+	assert.Equal(float64(38.64), item.Value)
+}
@@ -39,6 +39,7 @@ func (Payment) TableName() string {
 }
 
 // PaymentListQuery содержит параметры запроса списка выплат
+// Если BondID не задан, то возвращаются выплаты по всем облигациям
 type PaymentListQuery struct {
 	BondID int
 	Types  []PaymentType
@@ -107,7 +108,9 @@ type paymentRepository struct {
 func (repo *paymentRepository) List(query PaymentListQuery) ([]*Payment, error) {
 	q := repo.db
 
-	q = q.Where("bond_id = ?", query.BondID)
+	if query.BondID != 0 {
+		q = q.Where("bond_id = ?", query.BondID)
+	}
 	if query.Types != nil && len(query.Types) > 0 {
 		q = q.Where("type IN ?", query.Types)
 	}
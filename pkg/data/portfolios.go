@@ -0,0 +1,221 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Portfolio содержит данные портфеля пользователя
+type Portfolio struct {
+	ID        int       `gorm:"column:id; primaryKey"`
+	Name      string    `gorm:"column:name"`
+	CreatedAt time.Time `gorm:"column:created"`
+	UpdatedAt time.Time `gorm:"column:updated"`
+	Trades    []*Trade  `gorm:"foreignKey:PortfolioID"`
+}
+
+// TableName задает название таблицы
+func (Portfolio) TableName() string {
+	return "portfolios"
+}
+
+// Trade содержит данные сделки в портфеле пользователя
+// Положительное количество означает покупку, отрицательное - продажу
+type Trade struct {
+	ID              int       `gorm:"column:id; primaryKey"`
+	PortfolioID     int       `gorm:"column:portfolio_id"`
+	BondID          int       `gorm:"column:bond_id"`
+	Date            time.Time `gorm:"column:date"`
+	Quantity        int       `gorm:"column:quantity"`
+	Price           float64   `gorm:"column:price"`
+	FaceValue       float64   `gorm:"column:face_value"`
+	AccruedInterest float64   `gorm:"column:accrued_interest"`
+	Fee             float64   `gorm:"column:fee"`
+	CreatedAt       time.Time `gorm:"column:created"`
+	Bond            Bond
+}
+
+// TableName задает название таблицы
+func (Trade) TableName() string {
+	return "trades"
+}
+
+// CreatePortfolioArgs содержит параметры для создания портфеля
+type CreatePortfolioArgs struct {
+	Name string
+}
+
+// CreateTradeArgs содержит параметры для создания сделки
+type CreateTradeArgs struct {
+	// ID облигации
+	BondID int
+
+	// Дата сделки
+	Date time.Time
+
+	// Количество облигаций (отрицательное - для продажи)
+	Quantity int
+
+	// Чистая цена, в % от номинала
+	Price float64
+
+	// Номинал одной облигации на дату сделки, в валюте
+	FaceValue float64
+
+	// НКД на одну облигацию, в валюте
+	AccruedInterest float64
+
+	// Комиссия за сделку, в валюте
+	Fee float64
+}
+
+// PortfolioRepository отвечает за управление записями в таблицах портфелей и сделок
+type PortfolioRepository interface {
+	// List возвращает список портфелей (без сделок)
+	// Направление сортировки - по возрастанию ID
+	List() ([]*Portfolio, error)
+
+	// Get возвращает портфель по его ID вместе со списком сделок
+	// Если портфель не найден, возвращается ошибка ErrNotFound
+	Get(id int) (*Portfolio, error)
+
+	// Create создает новый портфель
+	Create(args CreatePortfolioArgs) (*Portfolio, error)
+
+	// Delete удаляет портфель вместе со всеми сделками
+	// Если портфель не найден, возвращается ошибка ErrNotFound
+	Delete(id int) error
+
+	// AddTrade добавляет сделку в портфель
+	// Если портфель не найден, возвращается ошибка ErrNotFound
+	AddTrade(portfolioID int, args CreateTradeArgs) (*Trade, error)
+
+	// DeleteTrade удаляет сделку из портфеля
+	// Если сделка не найдена, возвращается ошибка ErrNotFound
+	DeleteTrade(portfolioID, tradeID int) error
+}
+
+type portfolioRepository struct {
+	db *gorm.DB
+}
+
+// List возвращает список портфелей (без сделок)
+// Направление сортировки - по возрастанию ID
+func (repo *portfolioRepository) List() ([]*Portfolio, error) {
+	var portfolios []*Portfolio
+	err := repo.db.Order("id ASC").Find(&portfolios).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return portfolios, nil
+}
+
+// Get возвращает портфель по его ID вместе со списком сделок
+// Если портфель не найден, возвращается ошибка ErrNotFound
+func (repo *portfolioRepository) Get(id int) (*Portfolio, error) {
+	var portfolio Portfolio
+	err := repo.db.
+		Preload("Trades", func(db *gorm.DB) *gorm.DB {
+			return db.Order("date ASC").Order("id ASC")
+		}).
+		Preload("Trades.Bond").
+		First(&portfolio, "id = ?", id).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return &portfolio, nil
+}
+
+// Create создает новый портфель
+func (repo *portfolioRepository) Create(args CreatePortfolioArgs) (*Portfolio, error) {
+	now := time.Now().UTC()
+	portfolio := Portfolio{
+		Name:      args.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := repo.db.Omit("Trades").Create(&portfolio).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &portfolio, nil
+}
+
+// Delete удаляет портфель вместе со всеми сделками
+// Если портфель не найден, возвращается ошибка ErrNotFound
+func (repo *portfolioRepository) Delete(id int) error {
+	result := repo.db.Exec("DELETE FROM portfolios WHERE id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// AddTrade добавляет сделку в портфель
+// Если портфель не найден, возвращается ошибка ErrNotFound
+func (repo *portfolioRepository) AddTrade(portfolioID int, args CreateTradeArgs) (*Trade, error) {
+	var portfolio Portfolio
+	err := repo.db.First(&portfolio, "id = ?", portfolioID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	trade := Trade{
+		PortfolioID:     portfolioID,
+		BondID:          args.BondID,
+		Date:            args.Date,
+		Quantity:        args.Quantity,
+		Price:           args.Price,
+		FaceValue:       args.FaceValue,
+		AccruedInterest: args.AccruedInterest,
+		Fee:             args.Fee,
+		CreatedAt:       now,
+	}
+	err = repo.db.Omit("Bond").Create(&trade).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.db.Model(&portfolio).Update("updated", now).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &trade, nil
+}
+
+// DeleteTrade удаляет сделку из портфеля
+// Если сделка не найдена, возвращается ошибка ErrNotFound
+func (repo *portfolioRepository) DeleteTrade(portfolioID, tradeID int) error {
+	result := repo.db.Exec("DELETE FROM trades WHERE portfolio_id = ? AND id = ?", portfolioID, tradeID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return repo.db.Exec("UPDATE portfolios SET updated = ? WHERE id = ?", time.Now().UTC(), portfolioID).Error
+}
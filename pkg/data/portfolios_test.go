@@ -0,0 +1,46 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+	"gopkg.in/data-dog/go-sqlmock.v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestTrade_Scan(t *testing.T) {
+	assert := assertion.New(t)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		panic(err)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM \"trades\"").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "portfolio_id", "bond_id", "date", "quantity", "price", "face_value", "accrued_interest", "fee"}).
+				AddRow(1, 2, 3, time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), -10, 99.5, 1000.0, 12.34, 4.98))
+
+	var trade data.Trade
+	err = db.First(&trade).Error
+	assert.Nil(err)
+	assert.Equal(1, trade.ID)
+	assert.Equal(2, trade.PortfolioID)
+	assert.Equal(3, trade.BondID)
+	assert.Equal(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), trade.Date)
+	assert.Equal(-10, trade.Quantity)
+	assert.Equal(99.5, trade.Price)
+	assert.Equal(float64(1000), trade.FaceValue)
+	assert.Equal(12.34, trade.AccruedInterest)
+	assert.Equal(4.98, trade.Fee)
+}
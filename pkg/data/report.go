@@ -1,6 +1,7 @@
 package data
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 
@@ -9,24 +10,35 @@ import (
 
 // Report содержит данные отчета по облигации
 type Report struct {
-	Bond                 Bond       `gorm:"embedded;embeddedPrefix:bond_"`
-	Issuer               Issuer     `gorm:"embedded;embeddedPrefix:issuer_"`
-	MarketData           MarketData `gorm:"embedded;embeddedPrefix:marketdata_"`
-	DaysTillMaturity     int        `gorm:"column:days_till_maturity"`
-	Currency             string     `gorm:"column:currency"`
-	OpenPrice            float64    `gorm:"column:open_price"`
-	OpenAccruedInterest  float64    `gorm:"column:open_accrued_interest"`
-	OpenFaceValue        float64    `gorm:"column:open_face_value"`
-	OpenFee              float64    `gorm:"column:open_fee"`
-	OpenValue            float64    `gorm:"column:open_value"`
-	CouponPayments       float64    `gorm:"column:coupon_payments"`
-	AmortizationPayments float64    `gorm:"column:amortization_payments"`
-	MaturityPayment      float64    `gorm:"column:maturity_payments"`
-	Taxes                float64    `gorm:"column:taxes"`
-	Revenue              float64    `gorm:"column:revenue"`
-	ProfitLoss           float64    `gorm:"column:profit_loss"`
-	RelativeProfitLoss   float64    `gorm:"column:relative_profit_loss"`
-	InterestRate         float64    `gorm:"column:interest_rate"`
+	Bond                 Bond         `gorm:"embedded;embeddedPrefix:bond_"`
+	Issuer               Issuer       `gorm:"embedded;embeddedPrefix:issuer_"`
+	MarketData           MarketData   `gorm:"embedded;embeddedPrefix:marketdata_"`
+	DaysTillMaturity     int          `gorm:"column:days_till_maturity"`
+	Currency             string       `gorm:"column:currency"`
+	FxRate               float64      `gorm:"column:fx_rate"`
+	OpenPrice            float64      `gorm:"column:open_price"`
+	OpenAccruedInterest  float64      `gorm:"column:open_accrued_interest"`
+	OpenFaceValue        float64      `gorm:"column:open_face_value"`
+	OpenFee              float64      `gorm:"column:open_fee"`
+	OpenValue            float64      `gorm:"column:open_value"`
+	CouponPayments       float64      `gorm:"column:coupon_payments"`
+	AmortizationPayments float64      `gorm:"column:amortization_payments"`
+	MaturityPayment      float64      `gorm:"column:maturity_payments"`
+	Taxes                float64      `gorm:"column:taxes"`
+	Revenue              float64      `gorm:"column:revenue"`
+	ProfitLoss           float64      `gorm:"column:profit_loss"`
+	RelativeProfitLoss   float64      `gorm:"column:relative_profit_loss"`
+	InterestRate         float64      `gorm:"column:interest_rate"`
+	YieldToMaturity      float64      `gorm:"column:yield_to_maturity"`
+	MacaulayDuration     float64      `gorm:"column:macaulay_duration"`
+	ModifiedDuration     float64      `gorm:"column:modified_duration"`
+	DV01                 float64      `gorm:"column:dv01"`
+	Convexity            float64      `gorm:"column:convexity"`
+	OfferDate            sql.NullTime `gorm:"column:offer_date"`
+	OfferPrice           float64      `gorm:"column:offer_price"`
+	DaysTillOffer        int          `gorm:"column:days_till_offer"`
+	YieldToOffer         float64      `gorm:"column:yield_to_offer"`
+	Spread               *float64     `gorm:"column:spread"`
 }
 
 // TableName задает название таблицы
@@ -34,6 +46,25 @@ func (Report) TableName() string {
 	return "reports"
 }
 
+// reportMetricsColumnsSQL содержит список колонок из таблицы report_metrics, которые дополняют отчет
+const reportMetricsColumnsSQL = `
+       COALESCE(report_metrics.yield_to_maturity,
+                report_metrics.interest_rate,
+                reports.interest_rate)                                   AS yield_to_maturity,
+       COALESCE(report_metrics.macaulay_duration, 0)                     AS macaulay_duration,
+       COALESCE(report_metrics.modified_duration, 0)                     AS modified_duration,
+       COALESCE(report_metrics.dv01, 0)                                  AS dv01,
+       COALESCE(report_metrics.convexity, 0)                             AS convexity,
+       report_metrics.offer_date                                         AS offer_date,
+       COALESCE(report_metrics.offer_price, 0)                           AS offer_price,
+       COALESCE(report_metrics.offer_date - NOW()::date,
+                reports.days_till_maturity)                              AS days_till_offer,
+       COALESCE(report_metrics.yield_to_offer,
+                report_metrics.yield_to_maturity,
+                report_metrics.interest_rate,
+                reports.interest_rate)                                   AS yield_to_offer,
+       report_metrics.spread                                             AS spread`
+
 // ReportRepository отвечает за управление записями в таблице отчетов по облигациям
 type ReportRepository interface {
 	// Get возвращает отчет по облигации
@@ -43,6 +74,9 @@ type ReportRepository interface {
 	// List возвращает отчеты по облигациям, которые удовлетворяют указанному подзапросу
 	List(limit int, filter string, values ...interface{}) ([]*Report, error)
 
+	// Count возвращает количество облигаций, которые удовлетворяют указанному подзапросу
+	Count(filter string, values ...interface{}) (int, error)
+
 	// Rebuild выполняет перерасчет отчетов по облигациям
 	Rebuild() error
 }
@@ -55,11 +89,14 @@ type reportRepository struct {
 // Если данные по указанной облигации не найдены, то возвращается ErrNotFound
 func (repo *reportRepository) Get(id int) (*Report, error) {
 	sqlQuery := `
-SELECT *
+SELECT reports.*,%s
 FROM reports
-WHERE bond_id = ?
+LEFT JOIN report_metrics ON report_metrics.bond_id = reports.bond_id
+WHERE reports.bond_id = ?
 LIMIT 1
 `
+	sqlQuery = fmt.Sprintf(sqlQuery, reportMetricsColumnsSQL)
+
 	var report Report
 	err := repo.db.Raw(sqlQuery, id).First(&report).Error
 	if err != nil {
@@ -79,16 +116,17 @@ func (repo *reportRepository) List(limit int, filter string, values ...interface
 WITH cte AS (
 %s
 )
-SELECT reports.*
+SELECT reports.*,%s
 FROM reports
 INNER JOIN cte ON cte.bond_id = reports.bond_id
+LEFT JOIN report_metrics ON report_metrics.bond_id = reports.bond_id
 ORDER BY cte.index ASC%s;
 `
 	limitClause := ""
 	if limit > 0 {
 		limitClause = fmt.Sprintf("\nLIMIT %d", limit)
 	}
-	sqlQuery = fmt.Sprintf(sqlQuery, filter, limitClause)
+	sqlQuery = fmt.Sprintf(sqlQuery, filter, reportMetricsColumnsSQL, limitClause)
 
 	var reports []*Report
 	err := repo.db.Raw(sqlQuery, values...).Scan(&reports).Error
@@ -99,6 +137,26 @@ ORDER BY cte.index ASC%s;
 	return reports, nil
 }
 
+// Count возвращает количество облигаций, которые удовлетворяют указанному подзапросу
+func (repo *reportRepository) Count(filter string, values ...interface{}) (int, error) {
+	sqlQuery := `
+WITH cte AS (
+%s
+)
+SELECT COUNT(*)
+FROM cte;
+`
+	sqlQuery = fmt.Sprintf(sqlQuery, filter)
+
+	var count int64
+	err := repo.db.Raw(sqlQuery, values...).Scan(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
 // Rebuild выполняет перерасчет текущих выплат для всех облигаций
 func (repo *reportRepository) Rebuild() error {
 	return repo.db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY reports").Error
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// ReportMetrics содержит расчетные показатели по облигации, которые вычисляются вне БД
+type ReportMetrics struct {
+	BondID           int          `gorm:"column:bond_id; primaryKey"`
+	InterestRate     *float64     `gorm:"column:interest_rate"`
+	YieldToMaturity  *float64     `gorm:"column:yield_to_maturity"`
+	MacaulayDuration *float64     `gorm:"column:macaulay_duration"`
+	ModifiedDuration *float64     `gorm:"column:modified_duration"`
+	DV01             *float64     `gorm:"column:dv01"`
+	Convexity        *float64     `gorm:"column:convexity"`
+	OfferDate        sql.NullTime `gorm:"column:offer_date"`
+	OfferPrice       *float64     `gorm:"column:offer_price"`
+	YieldToOffer     *float64     `gorm:"column:yield_to_offer"`
+	Spread           *float64     `gorm:"column:spread"`
+}
+
+// TableName задает название таблицы
+func (ReportMetrics) TableName() string {
+	return "report_metrics"
+}
+
+// ReportMetricsRepository отвечает за управление записями в таблице расчетных показателей по облигациям
+type ReportMetricsRepository interface {
+	// Rebuild полностью заменяет содержимое таблицы расчетных показателей
+	Rebuild(items []*ReportMetrics) error
+}
+
+type reportMetricsRepository struct {
+	db *gorm.DB
+}
+
+// Rebuild полностью заменяет содержимое таблицы расчетных показателей
+func (repo *reportMetricsRepository) Rebuild(items []*ReportMetrics) error {
+	err := repo.db.Exec("DELETE FROM report_metrics WHERE TRUE::bool").Error
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	return repo.db.CreateInBatches(items, 500).Error
+}
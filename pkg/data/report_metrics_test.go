@@ -0,0 +1,56 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+	"gopkg.in/data-dog/go-sqlmock.v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestReportMetrics_Scan(t *testing.T) {
+	assert := assertion.New(t)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		panic(err)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM \"report_metrics\"").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"bond_id", "yield_to_maturity", "macaulay_duration", "modified_duration", "dv01", "convexity", "offer_date", "offer_price", "yield_to_offer", "spread"}).
+				AddRow(123, 8.75, 1.95, 1.79, 0.18, 4.2, time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), 100.0, 9.1, 215.5))
+
+	var item data.ReportMetrics
+	err = db.First(&item).Error
+	assert.Nil(err)
+	assert.Equal(123, item.BondID)
+	assert.NotNil(item.YieldToMaturity)
+	assert.Equal(float64(8.75), *item.YieldToMaturity)
+	assert.NotNil(item.MacaulayDuration)
+	assert.Equal(float64(1.95), *item.MacaulayDuration)
+	assert.NotNil(item.ModifiedDuration)
+	assert.Equal(float64(1.79), *item.ModifiedDuration)
+	assert.NotNil(item.DV01)
+	assert.Equal(float64(0.18), *item.DV01)
+	assert.NotNil(item.Convexity)
+	assert.Equal(float64(4.2), *item.Convexity)
+	assert.True(item.OfferDate.Valid)
+	assert.Equal(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), item.OfferDate.Time)
+	assert.NotNil(item.OfferPrice)
+	assert.Equal(float64(100), *item.OfferPrice)
+	assert.NotNil(item.YieldToOffer)
+	assert.Equal(float64(9.1), *item.YieldToOffer)
+	assert.NotNil(item.Spread)
+	assert.Equal(float64(215.5), *item.Spread)
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// YieldCurve содержит параметры кривой доходности к погашению ОФЗ (Нельсона-Сигеля-Свенссона) на дату
+// Кривая описывает расчетную доходность к погашению (с учетом налогов и комиссий) в зависимости от дюрации Маколея
+// и не является кривой бескупонной доходности
+type YieldCurve struct {
+	ID     int       `gorm:"column:id; primaryKey"`
+	Date   time.Time `gorm:"column:date"`
+	Beta0  float64   `gorm:"column:beta0"`
+	Beta1  float64   `gorm:"column:beta1"`
+	Beta2  float64   `gorm:"column:beta2"`
+	Beta3  float64   `gorm:"column:beta3"`
+	Tau1   float64   `gorm:"column:tau1"`
+	Tau2   float64   `gorm:"column:tau2"`
+	RMSE   float64   `gorm:"column:rmse"`
+	Points int       `gorm:"column:points"`
+}
+
+// TableName задает название таблицы
+func (YieldCurve) TableName() string {
+	return "yield_curves"
+}
+
+// PutYieldCurveArgs содержит параметры для записи кривой доходности
+type PutYieldCurveArgs struct {
+	Date   time.Time
+	Beta0  float64
+	Beta1  float64
+	Beta2  float64
+	Beta3  float64
+	Tau1   float64
+	Tau2   float64
+	RMSE   float64
+	Points int
+}
+
+// YieldCurveRepository отвечает за управление записями в таблице кривых доходности
+type YieldCurveRepository interface {
+	// Put записывает параметры кривой доходности
+	// Если кривая на эту дату уже существует, она обновляется
+	Put(args PutYieldCurveArgs) (*YieldCurve, error)
+
+	// GetLast возвращает последнюю по дате кривую доходности
+	// Если кривых нет, то возвращается ErrNotFound
+	GetLast() (*YieldCurve, error)
+
+	// List возвращает кривые доходности за период [from, to]
+	// Направление сортировки - по возрастанию даты
+	List(from, to time.Time) ([]*YieldCurve, error)
+}
+
+type yieldCurveRepository struct {
+	db *gorm.DB
+}
+
+// Put записывает параметры кривой доходности
+// Если кривая на эту дату уже существует, она обновляется
+func (repo *yieldCurveRepository) Put(args PutYieldCurveArgs) (*YieldCurve, error) {
+	curve := &YieldCurve{
+		Date:   args.Date,
+		Beta0:  args.Beta0,
+		Beta1:  args.Beta1,
+		Beta2:  args.Beta2,
+		Beta3:  args.Beta3,
+		Tau1:   args.Tau1,
+		Tau2:   args.Tau2,
+		RMSE:   args.RMSE,
+		Points: args.Points,
+	}
+
+	err := repo.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"beta0", "beta1", "beta2", "beta3", "tau1", "tau2", "rmse", "points"}),
+		}).
+		Create(curve).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return curve, nil
+}
+
+// GetLast возвращает последнюю по дате кривую доходности
+// Если кривых нет, то возвращается ErrNotFound
+func (repo *yieldCurveRepository) GetLast() (*YieldCurve, error) {
+	var curve YieldCurve
+	err := repo.db.Order("date DESC").First(&curve).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return &curve, nil
+}
+
+// List возвращает кривые доходности за период [from, to]
+// Направление сортировки - по возрастанию даты
+func (repo *yieldCurveRepository) List(from, to time.Time) ([]*YieldCurve, error) {
+	var curves []*YieldCurve
+	err := repo.db.
+		Where("date >= ? AND date <= ?", from, to).
+		Order("date ASC").
+		Find(&curves).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return curves, nil
+}
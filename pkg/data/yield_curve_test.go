@@ -0,0 +1,48 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+	"gopkg.in/data-dog/go-sqlmock.v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestYieldCurve_Scan(t *testing.T) {
+	assert := assertion.New(t)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		panic(err)
+	}
+
+	date := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM \"yield_curves\"").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "date", "beta0", "beta1", "beta2", "beta3", "tau1", "tau2", "rmse", "points"}).
+				AddRow(1, date, 8.5, -1.2, 0.4, -0.3, 1.5, 6.0, 0.07, 24))
+
+	var curve data.YieldCurve
+	err = db.First(&curve).Error
+	assert.Nil(err)
+	assert.Equal(1, curve.ID)
+	assert.Equal(date, curve.Date)
+	assert.Equal(8.5, curve.Beta0)
+	assert.Equal(-1.2, curve.Beta1)
+	assert.Equal(0.4, curve.Beta2)
+	assert.Equal(-0.3, curve.Beta3)
+	assert.Equal(1.5, curve.Tau1)
+	assert.Equal(6.0, curve.Tau2)
+	assert.Equal(0.07, curve.RMSE)
+	assert.Equal(24, curve.Points)
+}
@@ -103,6 +103,7 @@ func (w *bondFetchWorker) CreateOrUpdateBond(ctx context.Context, security *moex
 		MaturityDate:       props.MaturityDate,
 		ListingLevel:       props.ListingLevel,
 		CouponFrequency:    props.CouponFrequency,
+		CouponType:         props.CouponType,
 	}
 	b, err := w.tx.Bonds.Create(args)
 	if err != nil {
@@ -187,6 +188,8 @@ func (w *bondFetchWorker) GetSecurityProps(ctx context.Context, security *moex.S
 		props.CouponFrequency = int(*couponFrequency)
 	}
 
+	props.CouponType = data.CouponType(desc.CouponType())
+
 	return &props, nil
 }
 
@@ -199,4 +202,5 @@ type securityProps struct {
 	MaturityDate     sql.NullTime
 	ListingLevel     int
 	CouponFrequency  int
+	CouponType       data.CouponType
 }
@@ -0,0 +1,42 @@
+package fetch
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/moex"
+)
+
+type fxRateFetchWorker struct {
+	provider moex.Provider
+	tx       *data.TX
+	log      *log.Logger
+	stats    *FxRateFetchStats
+}
+
+// FetchFxRates выполняет выгрузку курсов валют из биржи в БД
+// Курсы записываются только после того, как получены все курсы, поэтому при ошибке выгрузки в БД остаются прежние курсы
+func (w *fxRateFetchWorker) FetchFxRates(ctx context.Context) error {
+	rates, err := w.provider.GetFxRates(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range rates {
+		t := time.Now().UTC()
+		if rate.Time != nil {
+			t = rate.Time.Time()
+		}
+
+		_, err = w.tx.FxRates.Put(normalizeCurrency(rate.Currency), rate.Rate, t)
+		if err != nil {
+			return err
+		}
+
+		w.stats.NewFxRates++
+	}
+
+	return nil
+}
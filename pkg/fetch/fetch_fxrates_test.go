@@ -0,0 +1,84 @@
+package fetch_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/fetch"
+	"github.com/kapitanov/moex-bond-recommender/pkg/moex"
+)
+
+type fxRateProvider struct {
+	moex.Provider
+	rates []*moex.FxRate
+	err   error
+}
+
+func (p *fxRateProvider) GetFxRates(ctx context.Context) ([]*moex.FxRate, error) {
+	return p.rates, p.err
+}
+
+type fxRateRepository struct {
+	rates map[string]*data.FxRate
+}
+
+func (repo *fxRateRepository) Get(currency string) (*data.FxRate, error) {
+	rate, exists := repo.rates[currency]
+	if !exists {
+		return nil, data.ErrNotFound
+	}
+
+	return rate, nil
+}
+
+func (repo *fxRateRepository) List() ([]*data.FxRate, error) {
+	rates := make([]*data.FxRate, 0, len(repo.rates))
+	for _, rate := range repo.rates {
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+func (repo *fxRateRepository) Put(currency string, rate float64, t time.Time) (*data.FxRate, error) {
+	item := &data.FxRate{Currency: currency, Rate: rate, Time: t}
+	repo.rates[currency] = item
+	return item, nil
+}
+
+func TestService_FetchFxRates(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	repo := &fxRateRepository{rates: map[string]*data.FxRate{"USD": {Currency: "USD", Rate: 90, Time: t0}}}
+	tx := &data.TX{FxRates: repo}
+	provider := &fxRateProvider{err: errors.New("service unavailable")}
+
+	service, err := fetch.New(fetch.WithProvider(provider))
+	if err != nil {
+		panic(err)
+	}
+
+	// Если курсы получить не удалось, то в БД остаются последние известные курсы
+	_, err = service.FetchFxRates(context.Background(), tx)
+	assert.Error(err)
+	rate, err := repo.Get("USD")
+	assert.NoError(err)
+	assert.Equal(90.0, rate.Rate)
+	assert.Equal(t0, rate.Time)
+
+	// Полученные курсы обновляют последние известные
+	provider.err = nil
+	provider.rates = []*moex.FxRate{{Currency: "USD", Rate: 95}}
+	stats, err := service.FetchFxRates(context.Background(), tx)
+	assert.NoError(err)
+	assert.Equal(1, stats.NewFxRates)
+	rate, err = repo.Get("USD")
+	assert.NoError(err)
+	assert.Equal(95.0, rate.Rate)
+}
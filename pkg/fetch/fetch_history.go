@@ -0,0 +1,147 @@
+package fetch
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/moex"
+)
+
+type historyFetchWorker struct {
+	provider moex.Provider
+	tx       *data.TX
+	log      *log.Logger
+	stats    *HistoryFetchStats
+	from     time.Time
+}
+
+// FetchHistory выполняет выгрузку истории торгов по всем торгуемым облигациям,
+// а также по облигациям, погашенным после даты w.from (они нужны для бэктеста)
+func (w *historyFetchWorker) FetchHistory(ctx context.Context) error {
+	bonds, err := w.tx.Bonds.ListActiveSince(w.from)
+	if err != nil {
+		return err
+	}
+
+	for i, bond := range bonds {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		if i%100 == 0 {
+			w.log.Printf("fetch history: %d of %d bond(s) processed", i, len(bonds))
+		}
+
+		err = w.FetchBondHistory(ctx, bond)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FetchBondHistory выполняет выгрузку истории торгов по облигации
+// Выгрузка продолжается с даты последней загруженной записи, но не ранее даты w.from
+func (w *historyFetchWorker) FetchBondHistory(ctx context.Context, bond *data.Bond) error {
+	from := w.from
+	lastDate, err := w.tx.History.GetLastTradeDate(bond.ID)
+	if err != nil {
+		return err
+	}
+	if lastDate != nil && lastDate.After(from) {
+		from = *lastDate
+	}
+
+	// История торгов по погашенным облигациям больше не пополняется, поэтому повторно не выгружается
+	if lastDate != nil && bond.MaturityDate.Valid && bond.MaturityDate.Time.Before(time.Now()) {
+		return nil
+	}
+
+	query := moex.HistoryListQuery{
+		SecurityID: bond.SecurityID,
+		BoardID:    w.GetBoardID(bond),
+	}
+	if !from.IsZero() {
+		query.From = &from
+	}
+
+	it := w.provider.ListHistory(ctx, query)
+	for {
+		items, err := it.Next()
+		if err != nil {
+			if err == moex.EOF {
+				break
+			}
+			return err
+		}
+
+		for _, item := range items {
+			err = ctx.Err()
+			if err != nil {
+				return err
+			}
+
+			err = w.PutHistoryItem(bond.ID, item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	w.stats.Bonds++
+	return nil
+}
+
+// PutHistoryItem записывает итоги торгов за день в БД
+func (w *historyFetchWorker) PutHistoryItem(bondID int, item *moex.HistoryItem) error {
+	price := item.Close
+	if price == nil {
+		price = item.LegalClosePrice
+	}
+
+	var currency *string
+	if item.Currency != nil {
+		c := normalizeCurrency(*item.Currency)
+		currency = &c
+	}
+
+	boardID := item.BoardID
+	args := data.PutHistoryArgs{
+		Kind:            data.TradesHistory,
+		Time:            item.TradeDate.Time(),
+		FaceValue:       item.FaceValue,
+		Currency:        currency,
+		Price:           price,
+		AccruedInterest: item.AccruedInterest,
+		YieldToMaturity: item.YieldClose,
+		BoardID:         &boardID,
+		Open:            item.Open,
+		High:            item.High,
+		Low:             item.Low,
+		Close:           item.Close,
+		Volume:          item.Volume,
+		Value:           item.Value,
+		DurationDays:    item.Duration,
+	}
+
+	_, err := w.tx.History.Put(bondID, args)
+	if err != nil {
+		return err
+	}
+
+	w.stats.NewRecords++
+	return nil
+}
+
+// GetBoardID возвращает режим торгов, по которому выгружается история облигации
+func (w *historyFetchWorker) GetBoardID(bond *data.Bond) string {
+	if bond.MarketPriceBoardID != "" {
+		return bond.MarketPriceBoardID
+	}
+
+	return bond.PrimaryBoardID
+}
@@ -14,6 +14,7 @@ type marketDataFetchWorker struct {
 	log      *log.Logger
 	stats    *MarketDataFetchStats
 	bondIDs  map[string]int
+	boardIDs map[string]string
 }
 
 // FetchMarketData выполняет выгрузку рыночных данных из биржи в БД
@@ -42,6 +43,11 @@ func (w *marketDataFetchWorker) FetchMarketData(ctx context.Context) error {
 			continue
 		}
 
+		// Размер лота и статус торгов зависят от режима торгов, поэтому используются данные основного режима
+		if boardID := w.boardIDs[item.SecurityID]; boardID != "" && item.BoardID != boardID {
+			continue
+		}
+
 		var currency *string = nil
 		if item.Currency != nil {
 			c := normalizeCurrency(*item.Currency)
@@ -57,6 +63,8 @@ func (w *marketDataFetchWorker) FetchMarketData(ctx context.Context) error {
 			ClosePrice:      item.ClosePrice,
 			LegalClosePrice: item.LegalClosePrice,
 			AccruedInterest: item.AccruedInterest,
+			LotSize:         item.LotSize,
+			TradingStatus:   item.TradingStatus,
 		}
 
 		_, err = w.tx.MarketData.Put(bondID, args)
@@ -89,5 +97,6 @@ func (w *marketDataFetchWorker) GetBondID(securityID string) (int, error) {
 	}
 
 	w.bondIDs[securityID] = bond.ID
+	w.boardIDs[securityID] = bond.PrimaryBoardID
 	return bond.ID, nil
 }
@@ -34,6 +34,17 @@ type MarketDataFetchStats struct {
 	NewMarketData int
 }
 
+// FxRateFetchStats содержит статистику выгрузки курсов валют
+type FxRateFetchStats struct {
+	NewFxRates int
+}
+
+// HistoryFetchStats содержит статистику выгрузки истории торгов
+type HistoryFetchStats struct {
+	Bonds      int
+	NewRecords int
+}
+
 // Service содержит функции для выгрузки данных из биржи в БД
 type Service interface {
 	// FetchBonds выполняет выгрузку облигаций из биржи в БД
@@ -47,6 +58,15 @@ type Service interface {
 
 	// FetchMarketData выполняет выгрузку рыночных данных из биржи в БД
 	FetchMarketData(ctx context.Context, tx *data.TX) (*MarketDataFetchStats, error)
+
+	// FetchFxRates выполняет выгрузку курсов валют из биржи в БД
+	FetchFxRates(ctx context.Context, tx *data.TX) (*FxRateFetchStats, error)
+
+	// FetchHistory выполняет выгрузку истории торгов из биржи в БД
+	// Выгружаются торгуемые облигации, а также облигации, погашенные после даты from
+	// Для каждой облигации выгрузка продолжается с даты последней загруженной записи, но не ранее даты from
+	// Если from не задан, то при первой выгрузке загружается вся история торгов
+	FetchHistory(ctx context.Context, tx *data.TX, from time.Time) (*HistoryFetchStats, error)
 }
 
 // Option настраивает сервис
@@ -187,6 +207,7 @@ func (s *service) FetchMarketData(ctx context.Context, tx *data.TX) (*MarketData
 		log:      s.log,
 		stats:    &MarketDataFetchStats{},
 		bondIDs:  make(map[string]int),
+		boardIDs: make(map[string]string),
 	}
 
 	err := w.FetchMarketData(ctx)
@@ -201,3 +222,55 @@ func (s *service) FetchMarketData(ctx context.Context, tx *data.TX) (*MarketData
 
 	return w.stats, nil
 }
+
+// FetchFxRates выполняет выгрузку курсов валют из биржи в БД
+func (s *service) FetchFxRates(ctx context.Context, tx *data.TX) (*FxRateFetchStats, error) {
+	start := time.Now()
+
+	w := &fxRateFetchWorker{
+		provider: s.provider,
+		tx:       tx,
+		log:      s.log,
+		stats:    &FxRateFetchStats{},
+	}
+
+	err := w.FetchFxRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+
+	duration := end.Sub(start)
+	s.log.Printf("fetch completed, %d fx rate(s) were fetched in %s", w.stats.NewFxRates, duration.Round(time.Second))
+
+	return w.stats, nil
+}
+
+// FetchHistory выполняет выгрузку истории торгов из биржи в БД
+// Для каждой облигации выгрузка продолжается с даты последней загруженной записи, но не ранее даты from
+// Если from не задан, то при первой выгрузке загружается вся история торгов
+func (s *service) FetchHistory(ctx context.Context, tx *data.TX, from time.Time) (*HistoryFetchStats, error) {
+	start := time.Now()
+
+	w := &historyFetchWorker{
+		provider: s.provider,
+		tx:       tx,
+		log:      s.log,
+		stats:    &HistoryFetchStats{},
+		from:     from,
+	}
+
+	err := w.FetchHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+
+	duration := end.Sub(start)
+	s.log.Printf("fetch completed, %d history record(s) for %d bond(s) were fetched in %s",
+		w.stats.NewRecords, w.stats.Bonds, duration.Round(time.Second))
+
+	return w.stats, nil
+}
@@ -0,0 +1,117 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// maxLineLength - максимальная длина строки в формате iCalendar, в байтах
+const maxLineLength = 75
+
+// Write записывает календарь выплат в формате iCalendar (RFC 5545)
+// Каждое событие записывается как событие на весь день
+func Write(w io.Writer, name string, calendar *recommender.Calendar) error {
+	writer := &lineWriter{w: bufio.NewWriter(w)}
+
+	writer.Line("BEGIN:VCALENDAR")
+	writer.Line("VERSION:2.0")
+	writer.Line("PRODID:-//kapitanov//moex-bond-recommender//RU")
+	writer.Line("CALSCALE:GREGORIAN")
+	writer.Line("METHOD:PUBLISH")
+	writer.Line("X-WR-CALNAME:" + escape(name))
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, e := range calendar.Events {
+		writer.Line("BEGIN:VEVENT")
+		writer.Line(fmt.Sprintf("UID:%s-%s-%s@moex-bond-recommender", e.Date.Format("20060102"), e.Type, e.Bond.ISIN))
+		writer.Line("DTSTAMP:" + stamp)
+		writer.Line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		writer.Line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+		writer.Line("SUMMARY:" + escape(summary(e)))
+		writer.Line("DESCRIPTION:" + escape(description(e)))
+		writer.Line("TRANSP:TRANSPARENT")
+		writer.Line("END:VEVENT")
+	}
+
+	writer.Line("END:VCALENDAR")
+	return writer.Flush()
+}
+
+// summary формирует заголовок события
+func summary(e *recommender.CalendarEvent) string {
+	switch e.Type {
+	case recommender.CouponEvent:
+		return fmt.Sprintf("Купон %s: %0.2f RUB", e.Bond.ShortName, e.ValueRub)
+	case recommender.AmortizationEvent:
+		return fmt.Sprintf("Амортизация %s: %0.2f RUB", e.Bond.ShortName, e.ValueRub)
+	case recommender.MaturityEvent:
+		return fmt.Sprintf("Погашение %s: %0.2f RUB", e.Bond.ShortName, e.ValueRub)
+	case recommender.OfferEvent:
+		return fmt.Sprintf("Оферта %s", e.Bond.ShortName)
+	}
+
+	return e.Bond.ShortName
+}
+
+// description формирует описание события
+func description(e *recommender.CalendarEvent) string {
+	return fmt.Sprintf("%s\n%s\nКоличество: %d шт.", e.Bond.FullName, e.Bond.ISIN, e.Quantity)
+}
+
+// escape экранирует текстовое значение согласно RFC 5545
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ";", "\\;")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "\r\n", "\\n")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return s
+}
+
+// lineWriter записывает строки в формате iCalendar, выполняя перенос длинных строк
+// Первая ошибка записи запоминается и возвращается из Flush
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// Line записывает строку, разбивая ее на части не длиннее maxLineLength байт
+func (w *lineWriter) Line(s string) {
+	limit := maxLineLength
+	for len(s) > limit {
+		// Разрыв строки не должен приходиться на середину символа UTF-8
+		i := limit
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+
+		w.write(s[:i] + "\r\n ")
+		s = s[i:]
+		limit = maxLineLength - 1
+	}
+
+	w.write(s + "\r\n")
+}
+
+// Flush завершает запись
+func (w *lineWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	return w.w.Flush()
+}
+
+func (w *lineWriter) write(s string) {
+	if w.err != nil {
+		return
+	}
+
+	_, w.err = w.w.WriteString(s)
+}
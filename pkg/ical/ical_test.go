@@ -0,0 +1,56 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+func TestWrite(t *testing.T) {
+	assert := assertion.New(t)
+
+	bond := &data.Bond{
+		ISIN:      "RU000A0JXQ93",
+		ShortName: "ОФЗ 26207",
+		FullName:  "ОФЗ-ПД выпуск 26207, с очень длинным названием; которое не помещается в одну строку",
+	}
+	calendar := &recommender.Calendar{
+		Events: []*recommender.CalendarEvent{
+			{Date: time.Date(2021, 8, 4, 0, 0, 0, 0, time.UTC), Type: recommender.CouponEvent, Bond: bond, Quantity: 10, ValueRub: 406.4},
+			{Date: time.Date(2022, 2, 3, 0, 0, 0, 0, time.UTC), Type: recommender.MaturityEvent, Bond: bond, Quantity: 10, ValueRub: 10000},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, "Портфель", calendar)
+	assert.Nil(err)
+
+	str := buf.String()
+	assert.True(strings.HasPrefix(str, "BEGIN:VCALENDAR\r\n"))
+	assert.True(strings.HasSuffix(str, "END:VCALENDAR\r\n"))
+	assert.Equal(2, strings.Count(str, "BEGIN:VEVENT"))
+	assert.Contains(str, "UID:20210804-coupon-RU000A0JXQ93@moex-bond-recommender\r\n")
+	assert.Contains(str, "DTSTART;VALUE=DATE:20210804\r\nDTEND;VALUE=DATE:20210805\r\n")
+	assert.Contains(str, "SUMMARY:Купон ОФЗ 26207: 406.40 RUB\r\n")
+
+	// Длинные строки переносятся, не разрывая символы UTF-8
+	for _, line := range strings.Split(str, "\r\n") {
+		assert.LessOrEqual(len(line), maxLineLength)
+		assert.True(strings.ToValidUTF8(line, "?") == line)
+	}
+
+	unfolded := strings.ReplaceAll(str, "\r\n ", "")
+	assert.Contains(unfolded, "с очень длинным названием\\; которое не помещается в одну строку\\n")
+}
+
+func TestEscape(t *testing.T) {
+	assert := assertion.New(t)
+
+	assert.Equal("a\\,b\\;c\\\\d\\ne", escape("a,b;c\\d\ne"))
+}
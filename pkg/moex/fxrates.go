@@ -0,0 +1,123 @@
+package moex
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FxSecurities содержит инструменты валютного рынка (расчеты "завтра"), по которым определяются курсы валют к рублю
+var FxSecurities = []string{
+	"USD000UTSTOM",
+	"EUR_RUB__TOM",
+	"CNYRUB_TOM",
+	"HKDRUB_TOM",
+	"CHFRUB_TOM",
+	"GBPRUB_TOM",
+}
+
+// FxRate описывает курс иностранной валюты к рублю
+type FxRate struct {
+	SecurityID string
+	Currency   string
+	Rate       float64
+	Time       *DateTime
+}
+
+// rawFxSecurity описывает параметры инструмента валютного рынка
+type rawFxSecurity struct {
+	SecurityID  string   `json:"SECID"`
+	BoardID     string   `json:"BOARDID"`
+	FaceUnit    string   `json:"FACEUNIT"`
+	FaceValue   *float64 `json:"FACEVALUE"`
+	PrevWAPrice *float64 `json:"PREVWAPRICE"`
+	PrevPrice   *float64 `json:"PREVPRICE"`
+}
+
+// rawFxMarketData описывает итоги торгов по инструменту валютного рынка
+type rawFxMarketData struct {
+	SecurityID string    `json:"SECID"`
+	BoardID    string    `json:"BOARDID"`
+	Last       *float64  `json:"LAST"`
+	WAPrice    *float64  `json:"WAPRICE"`
+	Time       *DateTime `json:"SYSTIME"`
+}
+
+// GetFxRates возвращает текущие курсы валют к рублю по инструментам FxSecurities
+// Курс определяется по цене последней сделки, средневзвешенной цене или (если торгов еще не было) по ценам
+// предыдущего дня. Валюты, курс которых определить не удалось, не возвращаются
+func (p *provider) GetFxRates(ctx context.Context) ([]*FxRate, error) {
+	values := make(url.Values)
+
+	values.Set("iss.only", "securities,marketdata")
+	values.Set("iss.json", "extended")
+	values.Set("iss.meta", "off")
+	values.Set("securities", strings.Join(FxSecurities, ","))
+
+	u := fmt.Sprintf("/iss/engines/currency/markets/selt/boards/CETS/securities.json?%s", values.Encode())
+
+	resp := make([]fxRatesResponse, 0)
+	err := p.getJSON(ctx, u, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	securities := make(map[string]*rawFxSecurity)
+	marketData := make(map[string]*rawFxMarketData)
+	for _, respItem := range resp {
+		for _, s := range respItem.Securities {
+			securities[s.SecurityID] = s
+		}
+		for _, m := range respItem.MarketData {
+			marketData[m.SecurityID] = m
+		}
+	}
+
+	rates := make([]*FxRate, 0, len(securities))
+	for _, id := range FxSecurities {
+		s, exists := securities[id]
+		if !exists || s.FaceUnit == "" {
+			continue
+		}
+
+		rate := &FxRate{SecurityID: id, Currency: s.FaceUnit}
+
+		var price *float64
+		if m, exists := marketData[id]; exists {
+			price = firstPositive(m.Last, m.WAPrice)
+			rate.Time = m.Time
+		}
+		if price == nil {
+			price = firstPositive(s.PrevWAPrice, s.PrevPrice)
+		}
+		if price == nil {
+			continue
+		}
+
+		// Курсы некоторых валют котируются за несколько единиц валюты
+		rate.Rate = *price
+		if s.FaceValue != nil && *s.FaceValue > 0 {
+			rate.Rate /= *s.FaceValue
+		}
+
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+type fxRatesResponse struct {
+	Securities []*rawFxSecurity   `json:"securities"`
+	MarketData []*rawFxMarketData `json:"marketdata"`
+}
+
+func firstPositive(values ...*float64) *float64 {
+	for _, v := range values {
+		if v != nil && *v > 0 {
+			return v
+		}
+	}
+
+	return nil
+}
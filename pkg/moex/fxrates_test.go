@@ -0,0 +1,125 @@
+package moex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/moex"
+)
+
+func TestProvider_GetFxRates(t *testing.T) {
+	assert := assertion.New(t)
+
+	json := `
+[
+    {
+        "charsetinfo": {
+            "name": "utf-8"
+        }
+    },
+    {
+        "securities": [
+            {
+                "SECID": "USD000UTSTOM",
+                "BOARDID": "CETS",
+                "SHORTNAME": "USDRUB_TOM",
+                "LOTSIZE": 1000,
+                "FACEVALUE": 1,
+                "FACEUNIT": "USD",
+                "CURRENCYID": "RUB",
+                "PREVWAPRICE": 92.15,
+                "PREVPRICE": 92.2
+            },
+            {
+                "SECID": "CNYRUB_TOM",
+                "BOARDID": "CETS",
+                "SHORTNAME": "CNYRUB_TOM",
+                "LOTSIZE": 1000,
+                "FACEVALUE": 1,
+                "FACEUNIT": "CNY",
+                "CURRENCYID": "RUB",
+                "PREVWAPRICE": 12.71,
+                "PREVPRICE": 12.7
+            },
+            {
+                "SECID": "HKDRUB_TOM",
+                "BOARDID": "CETS",
+                "SHORTNAME": "HKDRUB_TOM",
+                "LOTSIZE": 1000,
+                "FACEVALUE": 1,
+                "FACEUNIT": "HKD",
+                "CURRENCYID": "RUB",
+                "PREVWAPRICE": null,
+                "PREVPRICE": null
+            }
+        ],
+        "marketdata": [
+            {
+                "SECID": "USD000UTSTOM",
+                "BOARDID": "CETS",
+                "LAST": 92.5,
+                "WAPRICE": 92.4,
+                "SYSTIME": "2021-09-14 19:15:51"
+            },
+            {
+                "SECID": "CNYRUB_TOM",
+                "BOARDID": "CETS",
+                "LAST": null,
+                "WAPRICE": null,
+                "SYSTIME": "2021-09-14 19:15:51"
+            },
+            {
+                "SECID": "HKDRUB_TOM",
+                "BOARDID": "CETS",
+                "LAST": null,
+                "WAPRICE": null,
+                "SYSTIME": null
+            }
+        ]
+    }
+]
+`
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		u, err := url.Parse(req.RequestURI)
+		if err != nil {
+			panic(err)
+		}
+
+		switch u.Path {
+		case "/iss/engines/currency/markets/selt/boards/CETS/securities.json":
+			w.WriteHeader(200)
+			w.Header().Set("content-type", "application/json")
+			_, _ = w.Write([]byte(json))
+
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer func() { testServer.Close() }()
+
+	provider, err := moex.NewProvider(moex.WithURL(testServer.URL))
+	if !assert.Nil(err) {
+		return
+	}
+
+	list, err := provider.GetFxRates(context.Background())
+	assert.Nil(err)
+	assert.Equal(2, len(list))
+
+	// Курс по последней сделке
+	assert.Equal("USD000UTSTOM", list[0].SecurityID)
+	assert.Equal("USD", list[0].Currency)
+	assert.Equal(float64(92.5), list[0].Rate)
+	assert.NotNil(list[0].Time)
+	assert.Equal("2021-09-14 19:15:51", list[0].Time.String())
+
+	// Торгов еще не было - курс по средневзвешенной цене предыдущего дня
+	assert.Equal("CNY", list[1].Currency)
+	assert.Equal(float64(12.71), list[1].Rate)
+}
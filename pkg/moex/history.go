@@ -0,0 +1,143 @@
+package moex
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// HistoryItem описывает итоги торгов по облигации за один день в одном режиме торгов
+type HistoryItem struct {
+	BoardID         string   `json:"BOARDID"`
+	TradeDate       Date     `json:"TRADEDATE"`
+	SecurityID      string   `json:"SECID"`
+	NumTrades       *float64 `json:"NUMTRADES"`
+	Value           *float64 `json:"VALUE"`
+	Volume          *float64 `json:"VOLUME"`
+	Open            *float64 `json:"OPEN"`
+	Low             *float64 `json:"LOW"`
+	High            *float64 `json:"HIGH"`
+	Close           *float64 `json:"CLOSE"`
+	LegalClosePrice *float64 `json:"LEGALCLOSEPRICE"`
+	WAPrice         *float64 `json:"WAPRICE"`
+	AccruedInterest *float64 `json:"ACCINT"`
+	YieldClose      *float64 `json:"YIELDCLOSE"`
+	Duration        *float64 `json:"DURATION"`
+	FaceValue       *float64 `json:"FACEVALUE"`
+	Currency        *string  `json:"CURRENCYID"`
+	FaceUnit        *string  `json:"FACEUNIT"`
+}
+
+// HistoryListQuery определяет параметры запроса истории торгов по облигации
+type HistoryListQuery struct {
+	// Код ценной бумаги (обязательный параметр)
+	SecurityID string
+
+	// Режим торгов (если не задан, то возвращаются данные по всем режимам)
+	BoardID string
+
+	// Дата, больше либо равно
+	From *time.Time
+
+	// Дата, меньше либо равно
+	Till *time.Time
+
+	// Сколько записей выводить
+	Limit int
+
+	// Сколько записей пропускать
+	Start int
+}
+
+func (q HistoryListQuery) getValues(values url.Values) {
+	if q.From != nil {
+		values.Set("from", q.From.Format("2006-01-02"))
+	}
+
+	if q.Till != nil {
+		values.Set("till", q.Till.Format("2006-01-02"))
+	}
+
+	if q.Limit > 0 {
+		values.Set("limit", fmt.Sprintf("%d", q.Limit))
+	}
+
+	if q.Start > 0 {
+		values.Set("start", fmt.Sprintf("%d", q.Start))
+	}
+}
+
+// HistoryListIterator определяет итератор для истории торгов
+type HistoryListIterator interface {
+	// Next загружает следующую страницу данных
+	// Если данных больше нет, то возвращается ошибка EOF
+	Next() ([]*HistoryItem, error)
+}
+
+// ListHistory возвращает итератор на историю торгов по облигации
+func (p *provider) ListHistory(ctx context.Context, query HistoryListQuery) HistoryListIterator {
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+
+	return &historyListIterator{p, query, ctx}
+}
+
+type historyListIterator struct {
+	provider *provider
+	query    HistoryListQuery
+	ctx      context.Context
+}
+
+// Next загружает следующую страницу данных
+// Если данных больше нет, то возвращается ошибка EOF
+func (it *historyListIterator) Next() ([]*HistoryItem, error) {
+	if it.query.SecurityID == "" {
+		return nil, fmt.Errorf("missing security id in history query")
+	}
+
+	u := it.getURL()
+
+	resp := make([]historyResponse, 0)
+	err := it.provider.getJSON(it.ctx, u, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*HistoryItem, 0)
+	for _, respItem := range resp {
+		if respItem.History != nil {
+			items = append(items, respItem.History...)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, EOF
+	}
+
+	it.query.Start += len(items)
+	return items, nil
+}
+
+func (it *historyListIterator) getURL() string {
+	values := make(url.Values)
+
+	it.query.getValues(values)
+
+	values.Set("iss.only", "history")
+	values.Set("iss.json", "extended")
+	values.Set("iss.meta", "off")
+
+	if it.query.BoardID != "" {
+		return fmt.Sprintf("/iss/history/engines/stock/markets/bonds/boards/%s/securities/%s.json?%s",
+			url.PathEscape(it.query.BoardID), url.PathEscape(it.query.SecurityID), values.Encode())
+	}
+
+	return fmt.Sprintf("/iss/history/engines/stock/markets/bonds/securities/%s.json?%s",
+		url.PathEscape(it.query.SecurityID), values.Encode())
+}
+
+type historyResponse struct {
+	History []*HistoryItem `json:"history"`
+}
@@ -0,0 +1,171 @@
+package moex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/moex"
+)
+
+func TestProvider_ListHistory(t *testing.T) {
+	assert := assertion.New(t)
+
+	json1 := `
+[
+    {
+        "charsetinfo": {
+            "name": "utf-8"
+        }
+    },
+    {
+        "history": [
+            {
+                "BOARDID": "TQOB",
+                "TRADEDATE": "2021-10-01",
+                "SHORTNAME": "ОФЗ 26207",
+                "SECID": "SU26207RMFS9",
+                "NUMTRADES": 1520,
+                "VALUE": 412345678.9,
+                "LOW": 103.1,
+                "HIGH": 103.6,
+                "CLOSE": 103.45,
+                "LEGALCLOSEPRICE": 103.45,
+                "ACCINT": 20.1,
+                "WAPRICE": 103.38,
+                "YIELDCLOSE": 7.61,
+                "OPEN": 103.2,
+                "VOLUME": 398765,
+                "DURATION": 1890,
+                "FACEVALUE": 1000,
+                "CURRENCYID": "SUR",
+                "FACEUNIT": "SUR"
+            }
+        ]
+    }
+]`
+	json2 := `
+[
+    {
+        "charsetinfo": {
+            "name": "utf-8"
+        }
+    },
+    {
+        "history": [
+            {
+                "BOARDID": "TQOB",
+                "TRADEDATE": "2021-10-02",
+                "SECID": "SU26207RMFS9",
+                "NUMTRADES": 0,
+                "VALUE": 0,
+                "LOW": null,
+                "HIGH": null,
+                "CLOSE": null,
+                "LEGALCLOSEPRICE": 103.45,
+                "ACCINT": 20.3,
+                "WAPRICE": null,
+                "YIELDCLOSE": null,
+                "OPEN": null,
+                "VOLUME": 0,
+                "DURATION": null,
+                "FACEVALUE": 1000,
+                "CURRENCYID": "SUR",
+                "FACEUNIT": "SUR"
+            }
+        ]
+    }
+]`
+	json3 := `
+[
+    {
+        "charsetinfo": {
+            "name": "utf-8"
+        }
+    },
+    {
+        "history": []
+    }
+]`
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		u, err := url.Parse(req.RequestURI)
+		if err != nil {
+			panic(err)
+		}
+
+		switch u.Path {
+		case "/iss/history/engines/stock/markets/bonds/boards/TQOB/securities/SU26207RMFS9.json":
+			assert.Equal("2021-10-01", u.Query().Get("from"))
+
+			start, _ := strconv.Atoi(u.Query().Get("start"))
+
+			body := []byte(json1)
+			if start > 0 {
+				body = []byte(json2)
+			}
+			if start > 1 {
+				body = []byte(json3)
+			}
+
+			w.WriteHeader(200)
+			w.Header().Set("content-type", "application/json")
+			_, _ = w.Write(body)
+
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer func() { testServer.Close() }()
+
+	provider, err := moex.NewProvider(moex.WithURL(testServer.URL))
+	if !assert.Nil(err) {
+		return
+	}
+
+	from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	it := provider.ListHistory(context.Background(), moex.HistoryListQuery{
+		SecurityID: "SU26207RMFS9",
+		BoardID:    "TQOB",
+		From:       &from,
+	})
+
+	{
+		items, err := it.Next()
+		assert.Nil(err)
+		assert.Equal(1, len(items))
+		assert.Equal("TQOB", items[0].BoardID)
+		assert.Equal("2021-10-01", items[0].TradeDate.String())
+		assert.Equal("SU26207RMFS9", items[0].SecurityID)
+		assert.Equal(103.2, *items[0].Open)
+		assert.Equal(103.1, *items[0].Low)
+		assert.Equal(103.6, *items[0].High)
+		assert.Equal(103.45, *items[0].Close)
+		assert.Equal(float64(398765), *items[0].Volume)
+		assert.Equal(7.61, *items[0].YieldClose)
+		assert.Equal(float64(1890), *items[0].Duration)
+		assert.Equal(20.1, *items[0].AccruedInterest)
+		assert.Equal("SUR", *items[0].Currency)
+	}
+
+	{
+		items, err := it.Next()
+		assert.Nil(err)
+		assert.Equal(1, len(items))
+		assert.Equal("2021-10-02", items[0].TradeDate.String())
+		assert.Nil(items[0].Close)
+		assert.Nil(items[0].YieldClose)
+		assert.Equal(103.45, *items[0].LegalClosePrice)
+	}
+
+	{
+		_, err = it.Next()
+		assert.Equal(moex.EOF, err)
+	}
+}
@@ -17,6 +17,8 @@ type MarketData struct {
 	LastChange      *float64
 	ClosePrice      *float64
 	LegalClosePrice *float64
+	LotSize         *int
+	TradingStatus   *string
 	Time            *DateTime
 }
 
@@ -27,6 +29,8 @@ type rawSecurityData struct {
 	AccruedInterest *float64 `json:"ACCRUEDINT"`
 	FaceValue       float64  `json:"FACEVALUE"`
 	Currency        string   `json:"CURRENCYID"`
+	LotSize         *int     `json:"LOTSIZE"`
+	Status          *string  `json:"STATUS"`
 }
 
 // rawMarketData описывает итоги торгов по облигации (сырые данные)
@@ -64,6 +68,8 @@ func (p *provider) GetMarketData(ctx context.Context) ([]*MarketData, error) {
 				item.AccruedInterest = s.AccruedInterest
 				item.FaceValue = &s.FaceValue
 				item.Currency = &s.Currency
+				item.LotSize = s.LotSize
+				item.TradingStatus = s.Status
 			}
 
 			for _, m := range respItem.MarketData {
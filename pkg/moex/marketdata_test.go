@@ -172,6 +172,10 @@ func TestProvider_GetMarketData(t *testing.T) {
 	assert.Equal(float64(1000), *list[0].FaceValue)
 	assert.NotNil(list[0].Currency)
 	assert.Equal("SUR", *list[0].Currency)
+	assert.NotNil(list[0].LotSize)
+	assert.Equal(1, *list[0].LotSize)
+	assert.NotNil(list[0].TradingStatus)
+	assert.Equal("A", *list[0].TradingStatus)
 
 	// "marketdata"
 	assert.NotNil(list[0].Last)
@@ -35,9 +35,15 @@ type Provider interface {
 	// ListOffers возвращает итератор на список оферт
 	ListOffers(ctx context.Context, query OfferListQuery) OfferListIterator
 
+	// ListHistory возвращает итератор на историю торгов по облигации
+	ListHistory(ctx context.Context, query HistoryListQuery) HistoryListIterator
+
 	// GetMarketData возвращает текущие рыночные данные
 	GetMarketData(ctx context.Context) ([]*MarketData, error)
 
+	// GetFxRates возвращает текущие курсы валют к рублю
+	GetFxRates(ctx context.Context) ([]*FxRate, error)
+
 	// GetSecurityDescription возвращает описание ценной бумаги
 	GetSecurityDescription(ctx context.Context, isin string) (*SecurityDescription, error)
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 // SecurityDescription содержит набор параметров ценной бумаги
@@ -101,6 +102,74 @@ func (desc SecurityDescription) IsHighRisk() (bool, error) {
 	return value, err
 }
 
+// CouponType содержит тип купона облигации
+type CouponType string
+
+const (
+	// FixedCoupon - купон с известной заранее ставкой
+	FixedCoupon CouponType = "fixed"
+
+	// FloatingCoupon - переменный купон, привязанный к ключевой ставке, RUONIA и т.п. (флоатер)
+	FloatingCoupon CouponType = "floating"
+
+	// IndexedCoupon - купон по облигации с индексируемым по инфляции номиналом (линкер)
+	IndexedCoupon CouponType = "indexed"
+)
+
+var (
+	// indexedCouponMarkers - фрагменты названий и типов облигаций с индексируемым номиналом
+	indexedCouponMarkers = []string{"офз-ин", "индексируем", "инфляц", "линкер"}
+
+	// floatingCouponMarkers - фрагменты названий и типов облигаций с переменным купоном
+	floatingCouponMarkers = []string{"офз-пк", "переменн", "плавающ", "ruonia", "руониа", "ключев", "флоатер"}
+)
+
+// CouponType определяет тип купона облигации
+// В первую очередь используются поля описания BOND_TYPE и BOND_SUBTYPE, в которых ISS указывает флоатеры и линкеры,
+// в том числе корпоративные с наименованиями вида "...БО-001Р-06", а при их отсутствии - полное и краткое наименование
+// Если ни одно из полей не указывает на переменный купон или индексируемый номинал, то возвращается FixedCoupon
+func (desc SecurityDescription) CouponType() CouponType {
+	for _, ids := range [][]PropertyID{{BondTypeProperty, BondSubTypeProperty}, {NameProperty, ShortNameProperty}} {
+		values := desc.lowerStrings(ids...)
+
+		switch {
+		case containsAny(values, indexedCouponMarkers):
+			return IndexedCoupon
+		case containsAny(values, floatingCouponMarkers):
+			return FloatingCoupon
+		}
+	}
+
+	return FixedCoupon
+}
+
+// lowerStrings возвращает строковые значения указанных параметров в нижнем регистре
+func (desc SecurityDescription) lowerStrings(ids ...PropertyID) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if prop, exists := desc.Properties[id]; exists {
+			if value, err := prop.AsString(); err == nil {
+				values = append(values, strings.ToLower(value))
+			}
+		}
+	}
+
+	return values
+}
+
+// containsAny проверяет, содержит ли хотя бы одно из значений хотя бы один из фрагментов
+func containsAny(values []string, markers []string) bool {
+	for _, value := range values {
+		for _, marker := range markers {
+			if strings.Contains(value, marker) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // PropertyID содержит тип параметра ценной бумаги
 type PropertyID string
 
@@ -113,6 +182,10 @@ const (
 	IsForQualifiedInvestorsOnlyProperty PropertyID = "ISQUALIFIEDINVESTORS"
 	CouponFrequencyProperty             PropertyID = "COUPONFREQUENCY"
 	IsHighRiskProperty                  PropertyID = "HIGHRISK"
+	NameProperty                        PropertyID = "NAME"
+	ShortNameProperty                   PropertyID = "SHORTNAME"
+	BondTypeProperty                    PropertyID = "BOND_TYPE"
+	BondSubTypeProperty                 PropertyID = "BOND_SUBTYPE"
 )
 
 // PropertyType содержит тип значения параметра ценной бумаги
@@ -187,3 +187,34 @@ func TestProvider_GetSecurityDescription(t *testing.T) {
 	_, exists = desc.Properties[moex.IsForQualifiedInvestorsOnlyProperty]
 	assert.True(exists)
 }
+
+func TestSecurityDescription_CouponType(t *testing.T) {
+	assert := assertion.New(t)
+
+	newDesc := func(name, shortName string) moex.SecurityDescription {
+		return moex.SecurityDescription{
+			Properties: map[moex.PropertyID]*moex.Property{
+				moex.NameProperty:      {Name: moex.NameProperty, Value: name, Type: moex.StringPropertyType},
+				moex.ShortNameProperty: {Name: moex.ShortNameProperty, Value: shortName, Type: moex.StringPropertyType},
+			},
+		}
+	}
+
+	assert.Equal(moex.FixedCoupon, newDesc("ОФЗ-ПД 26238 15/05/2041", "ОФЗ 26238").CouponType())
+	assert.Equal(moex.FloatingCoupon, newDesc("ОФЗ-ПК 29006 29/01/2025", "ОФЗ 29006").CouponType())
+	assert.Equal(moex.FloatingCoupon, newDesc("ГТЛК БО-02 (RUONIA+1,5%)", "ГТЛК 2Р-02").CouponType())
+	assert.Equal(moex.IndexedCoupon, newDesc("ОФЗ-ИН 52002 02/02/2028", "ОФЗ 52002").CouponType())
+	assert.Equal(moex.FixedCoupon, moex.SecurityDescription{}.CouponType())
+
+	withBondType := func(desc moex.SecurityDescription, bondType, bondSubType string) moex.SecurityDescription {
+		desc.Properties[moex.BondTypeProperty] = &moex.Property{Name: moex.BondTypeProperty, Value: bondType, Type: moex.StringPropertyType}
+		desc.Properties[moex.BondSubTypeProperty] = &moex.Property{Name: moex.BondSubTypeProperty, Value: bondSubType, Type: moex.StringPropertyType}
+		return desc
+	}
+
+	// корпоративный флоатер, наименование которого не указывает на переменный купон
+	assert.Equal(moex.FixedCoupon, newDesc("Газпром Капитал ООО БО-001Р-06", "ГазКЗ-Р06").CouponType())
+	assert.Equal(moex.FloatingCoupon, withBondType(newDesc("Газпром Капитал ООО БО-001Р-06", "ГазКЗ-Р06"), "Флоатер", "").CouponType())
+	assert.Equal(moex.IndexedCoupon, withBondType(newDesc("Облигации БО-01", "Обл БО-01"), "Линкер", "").CouponType())
+	assert.Equal(moex.FixedCoupon, withBondType(newDesc("Облигации БО-02", "Обл БО-02"), "Фикс с известным купоном", "").CouponType())
+}
@@ -0,0 +1,617 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// backtestPriceAge - максимальный возраст исторической цены облигации, при котором она считается актуальной, дней
+const backtestPriceAge = 7
+
+// backtestMaxPositions - максимальное количество облигаций, отбираемых для одной части портфеля
+const backtestMaxPositions = 10
+
+// BacktestRequest - запрос на бэктест стратегии инвестирования
+type BacktestRequest struct {
+	// Параметры формирования портфеля (сумма, срок, ограничения по составу и модель комиссий и налогов)
+	// Если ограничения по составу не заданы, то портфель формируется по тем же правилам, что и в Suggest
+	Portfolio SuggestRequest
+
+	// Дата первого формирования портфеля
+	From time.Time
+
+	// Дата последнего формирования портфеля
+	Till time.Time
+
+	// Интервал между датами формирования портфеля, месяцев
+	StepMonths int
+
+	// Реинвестировать ли полученные выплаты
+	Reinvest bool
+}
+
+// BacktestResult - результат бэктеста стратегии инвестирования
+type BacktestResult struct {
+	// Результаты по каждой дате формирования портфеля
+	Periods []*BacktestPeriod
+
+	// Количество периодов, в которых все позиции были погашены
+	CompletedPeriods int
+
+	// Средняя ожидаемая приведенная доходность по завершенным периодам, % годовых
+	PredictedInterestRate float64
+
+	// Средняя фактическая приведенная доходность по завершенным периодам, % годовых
+	RealizedInterestRate float64
+
+	// Среднее отклонение фактической доходности от ожидаемой по завершенным периодам, п.п.
+	Deviation float64
+}
+
+// BacktestPeriod - результат инвестирования в портфель, сформированный в определенную дату
+type BacktestPeriod struct {
+	// Дата формирования портфеля
+	Date time.Time
+
+	// Дата окончания инвестирования (погашение последней позиции либо дата последних доступных данных)
+	EndDate time.Time
+
+	// Все ли позиции были погашены к дате окончания инвестирования
+	// Если нет, то непогашенные позиции оцениваются по последним известным ценам
+	Completed bool
+
+	// Позиции портфеля на дату формирования
+	Positions []*SuggestedPortfolioPosition
+
+	// Сумма инвестирования (без учета комиссий), в валюте
+	Amount float64
+
+	// Ожидаемая прибыль, в валюте
+	PredictedProfitLoss float64
+
+	// Ожидаемая приведенная доходность, % годовых
+	PredictedInterestRate float64
+
+	// Ожидаемая эффективная доходность к погашению (XIRR), % годовых
+	PredictedYieldToMaturity float64
+
+	// Сумма реинвестированных выплат (с учетом комиссий), в валюте
+	Reinvested float64
+
+	// Стоимость портфеля на дату окончания инвестирования (денежные средства и непогашенные позиции), в валюте
+	Value float64
+
+	// Фактическая прибыль, в валюте
+	ProfitLoss float64
+
+	// Фактическая приведенная доходность, % годовых
+	InterestRate float64
+
+	// Фактическая эффективная доходность, % годовых
+	YieldToMaturity float64
+
+	// Отклонение фактической приведенной доходности от ожидаемой, п.п.
+	Deviation float64
+}
+
+// backtestSource предоставляет исторические отчеты по облигациям
+// Отчеты рассчитываются по модели комиссий и налогов сервиса, как и отчеты в БД
+type backtestSource interface {
+	// ListReports возвращает отчеты по всем облигациям, которые торговались на дату now
+	ListReports(now time.Time) ([]*Report, error)
+
+	// GetReport возвращает отчет по облигации на дату now
+	// Если облигация в этот день не торговалась, то возвращается nil
+	GetReport(bond *data.Bond, now time.Time) (*Report, error)
+}
+
+// Backtest выполняет бэктест стратегии инвестирования на исторических данных
+func (s *service) Backtest(ctx context.Context, tx *data.TX, request *BacktestRequest) (*BacktestResult, error) {
+	source, err := newHistoryBacktestSource(tx, request.From, s.costs)
+	if err != nil {
+		return nil, err
+	}
+
+	r := *request
+	r.Portfolio.Costs = s.costModel(request.Portfolio.Costs)
+	return runBacktest(ctx, today(), &r, source)
+}
+
+// runBacktest выполняет бэктест стратегии инвестирования
+// Данные после момента now считаются недоступными
+func runBacktest(ctx context.Context, now time.Time, request *BacktestRequest, source backtestSource) (*BacktestResult, error) {
+	step := request.StepMonths
+	if step <= 0 {
+		step = 1
+	}
+
+	result := &BacktestResult{
+		Periods: make([]*BacktestPeriod, 0),
+	}
+	for i := 0; ; i++ {
+		date := request.From.AddDate(0, i*step, 0)
+		if date.After(request.Till) || date.After(now) {
+			break
+		}
+
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		period, err := runBacktestPeriod(now, date, request, source)
+		if err != nil {
+			return nil, err
+		}
+		result.Periods = append(result.Periods, period)
+
+		if period.Completed && len(period.Positions) > 0 {
+			result.CompletedPeriods++
+			result.PredictedInterestRate += period.PredictedInterestRate
+			result.RealizedInterestRate += period.InterestRate
+			result.Deviation += period.Deviation
+		}
+	}
+
+	if result.CompletedPeriods > 0 {
+		n := float64(result.CompletedPeriods)
+		result.PredictedInterestRate = round2(result.PredictedInterestRate / n)
+		result.RealizedInterestRate = round2(result.RealizedInterestRate / n)
+		result.Deviation = round2(result.Deviation / n)
+	}
+
+	return result, nil
+}
+
+// backtestLot - пакет облигаций, купленный в ходе бэктеста
+type backtestLot struct {
+	// Позиция на момент покупки
+	position *SuggestedPortfolioPosition
+
+	// Предстоящие выплаты по пакету (за вычетом налогов)
+	flows []xirrFlow
+}
+
+// runBacktestPeriod формирует портфель на дату date и моделирует владение им до погашения
+func runBacktestPeriod(now, date time.Time, request *BacktestRequest, source backtestSource) (*BacktestPeriod, error) {
+	costs := request.Portfolio.Costs
+	if costs == nil {
+		costs = DefaultCostModel()
+	}
+
+	reports, err := source.ListReports(date)
+	if err != nil {
+		return nil, err
+	}
+
+	// Портфель формируется по тем же правилам, что и в Suggest, но по отчетам на дату date
+	portfolio := request.Portfolio
+	portfolio.Costs = costs
+	portfolio.Parts = make([]*SuggestRequestPart, len(request.Portfolio.Parts))
+	for i, part := range request.Portfolio.Parts {
+		p := *part
+		portfolio.Parts[i] = &p
+	}
+
+	positions, _, err := generatePositionsForSuggestion(date, &portfolio, func(collection Collection, duration Duration) ([]*Report, error) {
+		return selectBondsAt(date, reports, collection, duration)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	period := &BacktestPeriod{
+		Date:      date,
+		EndDate:   date,
+		Positions: positions,
+	}
+	if len(positions) == 0 {
+		return period, nil
+	}
+
+	suggestion := newSuggestResult(date, positions, costs)
+	period.Amount = round2(suggestion.Amount)
+	period.PredictedProfitLoss = round2(suggestion.ProfitLoss)
+	period.PredictedInterestRate = round2(suggestion.InterestRate)
+	period.PredictedYieldToMaturity = suggestion.YieldToMaturity
+
+	// Моделирование владения портфелем: выплаты поступают на счет и, если требуется,
+	// реинвестируются в непогашенные позиции (см. reinvestAt)
+	horizon := date.AddDate(0, 0, suggestion.DurationDays)
+	end := horizon
+	if end.After(now) {
+		end = now
+	}
+
+	outlay := 0.0
+	lots := make([]*backtestLot, len(positions))
+	for i, p := range positions {
+		outlay += p.OpenValue + p.OpenFee
+		flows := yieldCashFlows(date, p.OpenValue, p.OpenFee, costs.CouponTaxRate(p.Bond), p.Taxes, p.CashFlow)
+		lots[i] = &backtestLot{position: p, flows: flows[1:]}
+	}
+
+	cash := 0.0
+	for {
+		d, ok := nextBacktestFlowDate(lots, end)
+		if !ok {
+			break
+		}
+
+		for _, lot := range lots {
+			for len(lot.flows) > 0 && !lot.flows[0].Date.After(d) {
+				cash += lot.flows[0].Value
+				lot.flows = lot.flows[1:]
+			}
+		}
+
+		if !request.Reinvest || !d.Before(horizon) || cash <= 0 {
+			continue
+		}
+
+		bought, spent, err := reinvestAt(d, lots, cash, costs, source)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, bought...)
+		cash -= spent
+		period.Reinvested += spent
+	}
+
+	// Непогашенные к дате окончания позиции оцениваются по последней известной цене
+	period.Completed = true
+	value := cash
+	for _, lot := range lots {
+		if len(lot.flows) == 0 {
+			continue
+		}
+
+		period.Completed = false
+		report, err := source.GetReport(lot.position.Bond, end)
+		if err != nil {
+			return nil, err
+		}
+		if report != nil {
+			value += report.OpenValue * float64(lot.position.Quantity)
+		} else {
+			value += lot.position.OpenValue
+		}
+	}
+
+	period.EndDate = end
+	period.Reinvested = round2(period.Reinvested)
+	period.Value = round2(value)
+	period.ProfitLoss = round2(value - outlay)
+
+	days := end.Sub(date).Hours() / 24.0
+	if days > 0 && period.Amount > 0 {
+		period.InterestRate = round2(100.0 * period.ProfitLoss / period.Amount / (days / daysInYear))
+		period.YieldToMaturity = round2(100.0 * (math.Pow(value/outlay, daysInYear/days) - 1))
+	}
+	period.Deviation = round2(period.InterestRate - period.PredictedInterestRate)
+
+	return period, nil
+}
+
+// nextBacktestFlowDate возвращает дату ближайшей выплаты по пакетам облигаций, не позднее даты end
+func nextBacktestFlowDate(lots []*backtestLot, end time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, lot := range lots {
+		if len(lot.flows) == 0 || lot.flows[0].Date.After(end) {
+			continue
+		}
+
+		if !found || lot.flows[0].Date.Before(next) {
+			next = lot.flows[0].Date
+			found = true
+		}
+	}
+
+	return next, found
+}
+
+// reinvestAt распределяет сумму не более cash между облигациями непогашенных позиций с помощью оптимизатора портфеля
+// Лимиты на доли не применяются: они уже соблюдены при формировании портфеля, а выплаты лишь возвращаются в него
+// Возвращает новые пакеты облигаций и потраченную сумму (с учетом комиссий)
+// Если купить облигации не удалось, то возвращается пустой список
+func reinvestAt(date time.Time, lots []*backtestLot, cash float64, costs *CostModel, source backtestSource) ([]*backtestLot, float64, error) {
+	reports := make([]*Report, 0, len(lots))
+	seen := make(map[int]bool)
+	for _, lot := range lots {
+		if len(lot.flows) == 0 || seen[lot.position.Bond.ID] {
+			continue
+		}
+		seen[lot.position.Bond.ID] = true
+
+		report, err := source.GetReport(lot.position.Bond, date)
+		if err != nil {
+			return nil, 0, err
+		}
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+	if len(reports) == 0 {
+		return nil, 0, nil
+	}
+
+	// Комиссия не входит в сумму позиций, поэтому сумма уменьшается до тех пор, пока позиции вместе с комиссией не уложатся в cash
+	maxAmount := cash
+	for attempt := 0; attempt < 3; attempt++ {
+		positions, _ := newPortfolioOptimizer(date, maxAmount, costs, nil).allocate(reports, maxAmount)
+		if len(positions) == 0 {
+			return nil, 0, nil
+		}
+
+		spent := 0.0
+		for _, p := range positions {
+			spent += p.OpenValue + p.OpenFee
+		}
+		if spent > cash {
+			maxAmount -= spent - cash
+			continue
+		}
+
+		result := make([]*backtestLot, len(positions))
+		for i, p := range positions {
+			flows := yieldCashFlows(date, p.OpenValue, p.OpenFee, costs.CouponTaxRate(p.Bond), p.Taxes, p.CashFlow)
+			result[i] = &backtestLot{position: p, flows: flows[1:]}
+		}
+		return result, spent, nil
+	}
+
+	return nil, 0, nil
+}
+
+// selectBondsAt выполняет выборку облигаций для формирования предложений по отчетам на дату now
+// Условия выборки повторяют getBondForSuggestion и перестроение коллекций в БД
+func selectBondsAt(now time.Time, reports []*Report, collection Collection, duration Duration) ([]*Report, error) {
+	age := getAge(duration)
+	minDate := now.AddDate(0, 6*age, 0)
+	maxDate := now.AddDate(age, 0, 0)
+	inDuration := func(r *Report) bool {
+		d := horizonDate(r)
+		return !d.Before(minDate) && !d.After(maxDate) && suggestionYield(r) > 0
+	}
+
+	var selected []*Report
+	if collection == nil {
+		selected = filterReports(reports, func(r *Report) bool {
+			return !r.Bond.IsHighRisk && inDuration(r)
+		})
+		selected = withinThreeSigma(selected, suggestionYield)
+	} else {
+		coll, ok := collection.(*internalCollection)
+		if !ok || coll.filter == nil {
+			return nil, fmt.Errorf("collection \"%s\" can't be used with historical data", collection.ID())
+		}
+
+		// В коллекцию попадают облигации с погашением (или офертой) не ранее чем через 3 дня и не позднее максимального срока
+		maxAge := getAge(Durations[len(Durations)-1])
+		members := filterReports(coll.filter(reports), func(r *Report) bool {
+			d := horizonDate(r)
+			return suggestionYield(r) > 0 && !d.Before(now.AddDate(0, 0, 3)) && !d.After(now.AddDate(maxAge, 0, 0))
+		})
+
+		selected = filterReports(members, inDuration)
+		maxYield := math.Inf(-1)
+		for _, r := range selected {
+			maxYield = math.Max(maxYield, suggestionYield(r))
+		}
+		selected = filterReports(selected, func(r *Report) bool {
+			return maxYield-suggestionYield(r) <= 1
+		})
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return suggestionYield(selected[i]) > suggestionYield(selected[j])
+	})
+	if len(selected) > backtestMaxPositions {
+		selected = selected[:backtestMaxPositions]
+	}
+
+	return selected, nil
+}
+
+// horizonDate возвращает дату ближайшей оферты, а если оферт не предвидится - дату погашения
+func horizonDate(r *Report) time.Time {
+	if r.OfferDate != nil {
+		return *r.OfferDate
+	}
+
+	return r.Bond.MaturityDate.Time
+}
+
+// suggestionYield возвращает доходность, по которой облигации сравниваются при формировании предложений:
+// доходность к оферте, а если оферт не предвидится - доходность к погашению
+func suggestionYield(r *Report) float64 {
+	if r.ToOffer != nil {
+		return r.YieldToOffer
+	}
+
+	return r.YieldToMaturity
+}
+
+// pointInTimeReport формирует отчет по облигации на дату now по историческим рыночным данным, выплатам и офертам
+// Условия повторяют расчет отчетов в БД, комиссии и налоги рассчитываются по модели costs
+// Если данных недостаточно, то возвращается nil
+// Облигации с неизвестными будущими выплатами (флоатеры, линкеры, еще не объявленные купоны) пропускаются:
+// их прогноз строится по текущим данным, т.е. опирается на будущее относительно даты now
+func pointInTimeReport(now time.Time, bond *data.Bond, record *data.HistoryRecord, payments []*data.Payment, offers []*data.Offer, costs *CostModel) *Report {
+	if bond.FaceUnit != "RUB" || !bond.MaturityDate.Valid || !bond.MaturityDate.Time.After(now) {
+		return nil
+	}
+	if record.Price == nil || record.AccruedInterest == nil || record.FaceValue == nil {
+		return nil
+	}
+	if record.Currency != nil && *record.Currency != "RUB" {
+		return nil
+	}
+
+	// Итоги торгов на дату now означают, что облигация на эту дату торговалась
+	traded := *bond
+	traded.IsTraded = true
+
+	r := &Report{
+		Bond:                &traded,
+		DaysTillMaturity:    int(math.Round(bond.MaturityDate.Time.Sub(now).Hours() / 24.0)),
+		Currency:            "RUB",
+		FxRate:              1,
+		OpenPrice:           *record.Price,
+		OpenAccruedInterest: *record.AccruedInterest,
+		OpenFaceValue:       *record.FaceValue,
+		CashFlow:            make([]*CashFlowItem, 0),
+	}
+	if bond.Issuer.ID != 0 {
+		r.Issuer = &bond.Issuer
+	}
+	r.OpenValue = r.OpenPrice*r.OpenFaceValue/100.0 + r.OpenAccruedInterest
+	r.DaysTillOffer = r.DaysTillMaturity
+
+	for _, payment := range payments {
+		if !payment.Date.After(now) {
+			continue
+		}
+		if payment.Value <= 0 {
+			return nil
+		}
+
+		item := &CashFlowItem{
+			Type:     CashFlowItemType(payment.Type),
+			Date:     payment.Date,
+			Value:    payment.Value,
+			ValueRub: payment.ValueRub,
+		}
+		switch item.Type {
+		case Coupon:
+			r.CouponPayments += item.ValueRub
+		case Amortization:
+			r.AmortizationPayments += item.ValueRub
+		case Maturity:
+			r.MaturityPayment += item.ValueRub
+		}
+		r.CashFlow = append(r.CashFlow, item)
+	}
+	r.Revenue = r.CouponPayments + r.AmortizationPayments + r.MaturityPayment
+
+	// Оферта учитывается, только если она состоится раньше погашения
+	for _, offer := range offers {
+		if !offer.Date.Valid || !offer.Date.Time.After(now) {
+			continue
+		}
+
+		if offer.Date.Time.Before(bond.MaturityDate.Time) {
+			date := offer.Date.Time
+			r.OfferDate = &date
+			r.OfferPrice = getOfferPrice(offer)
+			r.ToOffer = offerReport(now, r, date, r.OfferPrice, costs)
+			r.DaysTillOffer = r.ToOffer.DaysTillMaturity
+		}
+		break
+	}
+
+	r = applyCostModel(now, r, 1, costs)
+	if metrics, ok := computeRiskMetrics(now, r); ok {
+		r.MacaulayDuration = metrics.MacaulayDuration
+		r.ModifiedDuration = metrics.ModifiedDuration
+		r.DV01 = metrics.DV01
+		r.Convexity = metrics.Convexity
+	}
+
+	return r
+}
+
+// historyBacktestSource формирует исторические отчеты по облигациям из истории торгов биржи в БД
+type historyBacktestSource struct {
+	tx       *data.TX
+	from     time.Time
+	payments map[int][]*data.Payment
+	offers   map[int][]*data.Offer
+	prices   map[int][]*data.HistoryRecord
+	costs    *CostModel
+}
+
+// newHistoryBacktestSource загружает выплаты и оферты, которые нужны для бэктеста начиная с даты from
+// Отчеты рассчитываются по модели комиссий и налогов costs
+func newHistoryBacktestSource(tx *data.TX, from time.Time, costs *CostModel) (*historyBacktestSource, error) {
+	source := &historyBacktestSource{
+		tx:       tx,
+		from:     from.AddDate(0, 0, -backtestPriceAge),
+		payments: make(map[int][]*data.Payment),
+		offers:   make(map[int][]*data.Offer),
+		prices:   make(map[int][]*data.HistoryRecord),
+		costs:    costs,
+	}
+
+	payments, err := tx.Payments.List(data.PaymentListQuery{Since: &from})
+	if err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		source.payments[payment.BondID] = append(source.payments[payment.BondID], payment)
+	}
+
+	offers, err := tx.Offers.ListAfter(from)
+	if err != nil {
+		return nil, err
+	}
+	for _, offer := range offers {
+		source.offers[offer.BondID] = append(source.offers[offer.BondID], offer)
+	}
+
+	return source, nil
+}
+
+// ListReports возвращает отчеты по всем облигациям, которые торговались на дату now
+func (s *historyBacktestSource) ListReports(now time.Time) ([]*Report, error) {
+	records, err := s.tx.History.ListLatest(data.TradesHistory, now.AddDate(0, 0, -backtestPriceAge), now)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, 0, len(records))
+	for _, record := range records {
+		bond := record.Bond
+		report := pointInTimeReport(now, &bond, record, s.payments[record.BondID], s.offers[record.BondID], s.costs)
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+// GetReport возвращает отчет по облигации на дату now
+// Если облигация в этот день не торговалась, то возвращается nil
+func (s *historyBacktestSource) GetReport(bond *data.Bond, now time.Time) (*Report, error) {
+	records, exists := s.prices[bond.ID]
+	if !exists {
+		var err error
+		records, err = s.tx.History.List(bond.ID, data.TradesHistory, s.from, today())
+		if err != nil {
+			return nil, err
+		}
+		s.prices[bond.ID] = records
+	}
+
+	var record *data.HistoryRecord
+	for _, r := range records {
+		if r.Time.After(now) {
+			break
+		}
+		if r.Price != nil && r.AccruedInterest != nil && r.FaceValue != nil {
+			record = r
+		}
+	}
+	if record == nil || record.Time.Before(now.AddDate(0, 0, -backtestPriceAge)) {
+		return nil, nil
+	}
+
+	return pointInTimeReport(now, bond, record, s.payments[bond.ID], s.offers[bond.ID], s.costs), nil
+}
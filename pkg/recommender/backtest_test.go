@@ -0,0 +1,169 @@
+package recommender
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+type fakeBacktestSource struct {
+	bonds    []*data.Bond
+	payments map[int][]*data.Payment
+	price    float64
+}
+
+func (s *fakeBacktestSource) ListReports(now time.Time) ([]*Report, error) {
+	reports := make([]*Report, 0)
+	for _, bond := range s.bonds {
+		report, _ := s.GetReport(bond, now)
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+func (s *fakeBacktestSource) GetReport(bond *data.Bond, now time.Time) (*Report, error) {
+	price, accruedInterest, faceValue := s.price, 0.0, 1000.0
+	record := &data.HistoryRecord{Time: now, Price: &price, AccruedInterest: &accruedInterest, FaceValue: &faceValue}
+	return pointInTimeReport(now, bond, record, s.payments[bond.ID], nil, DefaultCostModel()), nil
+}
+
+func newFakeBacktestSource(t0 time.Time, coupon float64) *fakeBacktestSource {
+	source := &fakeBacktestSource{
+		payments: make(map[int][]*data.Payment),
+		price:    98,
+	}
+
+	for id, months := range []int{9, 12} {
+		maturity := t0.AddDate(0, months, 0)
+		bond := &data.Bond{
+			ID:           id + 1,
+			FaceUnit:     "RUB",
+			Type:         data.CorporateBond,
+			MaturityDate: sql.NullTime{Time: maturity, Valid: true},
+		}
+		source.bonds = append(source.bonds, bond)
+		source.payments[bond.ID] = []*data.Payment{
+			{BondID: bond.ID, Type: data.CouponPayment, Date: maturity.AddDate(0, -6, 0), Value: coupon, ValueRub: coupon},
+			{BondID: bond.ID, Type: data.CouponPayment, Date: maturity, Value: coupon, ValueRub: coupon},
+			{BondID: bond.ID, Type: data.MaturityPayment, Date: maturity, Value: 1000, ValueRub: 1000},
+		}
+	}
+
+	return source
+}
+
+func TestRunBacktest(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newFakeBacktestSource(t0, 40)
+	request := &BacktestRequest{
+		Portfolio: SuggestRequest{Amount: 10000, MaxDuration: Duration1Year},
+		From:      t0,
+		Till:      t0,
+	}
+
+	result, err := runBacktest(context.Background(), t0.AddDate(2, 0, 0), request, source)
+	assert.NoError(err)
+	assert.Len(result.Periods, 1)
+	assert.Equal(1, result.CompletedPeriods)
+
+	// Выбирается облигация с наибольшей доходностью, без реинвестирования фактические выплаты совпадают с ожидаемыми
+	p := result.Periods[0]
+	assert.True(p.Completed)
+	assert.Len(p.Positions, 1)
+	assert.Equal(10, p.Positions[0].Quantity)
+	assert.Equal(t0.AddDate(0, 9, 0), p.EndDate)
+	assert.Equal(float64(0), p.Reinvested)
+	assert.InDelta(p.PredictedProfitLoss, p.ProfitLoss, 0.05)
+	assert.InDelta(p.PredictedInterestRate, p.InterestRate, 0.05)
+}
+
+func TestRunBacktest_Reinvest(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newFakeBacktestSource(t0, 60)
+	request := &BacktestRequest{
+		Portfolio: SuggestRequest{Amount: 20000, MaxDuration: Duration1Year},
+		From:      t0,
+		Till:      t0,
+		Reinvest:  true,
+	}
+
+	result, err := runBacktest(context.Background(), t0.AddDate(2, 0, 0), request, source)
+	assert.NoError(err)
+
+	// Купон реинвестируется в ту же облигацию, которая погашается с дисконтом
+	p := result.Periods[0]
+	assert.True(p.Completed)
+	assert.Greater(p.Reinvested, float64(0))
+	assert.Greater(p.ProfitLoss, p.PredictedProfitLoss)
+}
+
+func TestRunBacktest_Open(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newFakeBacktestSource(t0, 40)
+	request := &BacktestRequest{
+		Portfolio: SuggestRequest{Amount: 10000, MaxDuration: Duration1Year},
+		From:      t0,
+		Till:      t0.AddDate(1, 0, 0),
+	}
+
+	// Данные доступны только на 3 месяца вперед, поэтому позиции оцениваются по рыночной цене
+	now := t0.AddDate(0, 3, 0)
+	result, err := runBacktest(context.Background(), now, request, source)
+	assert.NoError(err)
+	assert.Len(result.Periods, 4)
+	assert.Equal(0, result.CompletedPeriods)
+
+	p := result.Periods[0]
+	assert.False(p.Completed)
+	assert.Equal(now, p.EndDate)
+	assert.Equal(10*980.0+10*40*0.87, p.Value)
+}
+
+func TestPointInTimeReport_UnknownPayments(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newFakeBacktestSource(t0, 40)
+	bond := source.bonds[0]
+
+	// Купон, размер которого неизвестен на дату отчета (например, у флоатера), делает облигацию непригодной для бэктеста
+	source.payments[bond.ID][0].Value, source.payments[bond.ID][0].ValueRub = 0, 0
+	report, err := source.GetReport(bond, t0)
+	assert.NoError(err)
+	assert.Nil(report)
+
+	// После даты выплаты неизвестный купон уже не влияет на отчет
+	report, err = source.GetReport(bond, t0.AddDate(0, 4, 0))
+	assert.NoError(err)
+	assert.NotNil(report)
+	assert.Equal(40.0, report.CouponPayments)
+}
+
+func TestWithinThreeSigma(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := make([]*Report, 0)
+	for i := 0; i < 20; i++ {
+		reports = append(reports, &Report{InterestRate: 10})
+	}
+	reports = append(reports, &Report{InterestRate: 100})
+
+	filtered := withinThreeSigma(reports, func(r *Report) float64 { return r.InterestRate })
+	assert.Len(filtered, 20)
+
+	assert.Len(withinThreeSigma(reports[:1], func(r *Report) float64 { return r.InterestRate }), 0)
+}
@@ -0,0 +1,191 @@
+package recommender
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// CalendarEventType кодирует тип события в календаре выплат
+type CalendarEventType string
+
+const (
+	// CouponEvent обозначает выплату по купону
+	CouponEvent CalendarEventType = "coupon"
+
+	// AmortizationEvent обозначает выплату по амортизации
+	AmortizationEvent CalendarEventType = "amortization"
+
+	// MaturityEvent обозначает погашение
+	MaturityEvent CalendarEventType = "maturity"
+
+	// OfferEvent обозначает дату оферты
+	OfferEvent CalendarEventType = "offer"
+)
+
+// CalendarPosition - позиция, для которой формируется календарь выплат
+type CalendarPosition struct {
+	// ID облигации
+	BondID int
+
+	// Размер позиции
+	Quantity int
+}
+
+// Calendar - календарь предстоящих выплат по набору позиций
+type Calendar struct {
+	// События, отсортированные по дате
+	Events []*CalendarEvent
+}
+
+// CalendarEvent - событие в календаре выплат
+type CalendarEvent struct {
+	// Дата события
+	Date time.Time
+
+	// Тип события
+	Type CalendarEventType
+
+	// Облигация
+	Bond *data.Bond
+
+	// Размер позиции
+	Quantity int
+
+	// Сумма выплаты по всей позиции, в рублях (для оферты - 0)
+	ValueRub float64
+}
+
+// CalendarDay - выплаты за один день календаря
+type CalendarDay struct {
+	// Дата
+	Date time.Time
+
+	// Сумма выплат, в рублях
+	Amount float64
+
+	// Признак наличия выплаты по купону
+	HasCoupon bool
+
+	// Признак наличия выплаты по амортизации
+	HasAmortization bool
+
+	// Признак наличия погашения
+	HasMaturity bool
+
+	// Признак наличия оферты
+	HasOffer bool
+}
+
+// GetCalendar формирует календарь предстоящих выплат по набору позиций
+// Если по облигации нет отчета, то возвращается ошибка ErrNotFound
+func (s *service) GetCalendar(ctx context.Context, tx *data.TX, positions []CalendarPosition) (*Calendar, error) {
+	now := today()
+	calendar := &Calendar{Events: make([]*CalendarEvent, 0)}
+
+	for _, position := range positions {
+		if position.Quantity <= 0 {
+			continue
+		}
+
+		report, err := s.GetReport(ctx, tx, position.BondID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range report.CashFlow {
+			if item.Date.Before(now) {
+				continue
+			}
+
+			calendar.Events = append(calendar.Events, &CalendarEvent{
+				Date:     item.Date,
+				Type:     calendarEventType(item.Type),
+				Bond:     report.Bond,
+				Quantity: position.Quantity,
+				ValueRub: round2(item.ValueRub * float64(position.Quantity)),
+			})
+		}
+
+		if report.OfferDate != nil {
+			calendar.Events = append(calendar.Events, &CalendarEvent{
+				Date:     *report.OfferDate,
+				Type:     OfferEvent,
+				Bond:     report.Bond,
+				Quantity: position.Quantity,
+			})
+		}
+	}
+
+	calendar.sort()
+	return calendar, nil
+}
+
+// NewSuggestionCalendar формирует календарь выплат по предложенному портфелю
+// Выплаты в позициях уже пересчитаны на размер позиции
+func NewSuggestionCalendar(result *SuggestResult) *Calendar {
+	calendar := &Calendar{Events: make([]*CalendarEvent, 0)}
+	for _, p := range result.Positions {
+		for _, item := range p.CashFlow {
+			calendar.Events = append(calendar.Events, &CalendarEvent{
+				Date:     item.Date,
+				Type:     calendarEventType(item.Type),
+				Bond:     p.Bond,
+				Quantity: p.Quantity,
+				ValueRub: item.ValueRub,
+			})
+		}
+	}
+
+	calendar.sort()
+	return calendar
+}
+
+// Days возвращает выплаты, сгруппированные по датам
+func (c *Calendar) Days() []*CalendarDay {
+	days := make([]*CalendarDay, 0)
+	index := make(map[time.Time]*CalendarDay)
+	for _, e := range c.Events {
+		day, exists := index[e.Date]
+		if !exists {
+			day = &CalendarDay{Date: e.Date}
+			index[e.Date] = day
+			days = append(days, day)
+		}
+
+		day.Amount += e.ValueRub
+		switch e.Type {
+		case CouponEvent:
+			day.HasCoupon = true
+		case AmortizationEvent:
+			day.HasAmortization = true
+		case MaturityEvent:
+			day.HasMaturity = true
+		case OfferEvent:
+			day.HasOffer = true
+		}
+	}
+
+	return days
+}
+
+// sort упорядочивает события по дате
+func (c *Calendar) sort() {
+	sort.SliceStable(c.Events, func(i, j int) bool {
+		return c.Events[i].Date.Before(c.Events[j].Date)
+	})
+}
+
+// calendarEventType преобразует тип выплаты в тип события календаря
+func calendarEventType(t CashFlowItemType) CalendarEventType {
+	switch t {
+	case Amortization:
+		return AmortizationEvent
+	case Maturity:
+		return MaturityEvent
+	default:
+		return CouponEvent
+	}
+}
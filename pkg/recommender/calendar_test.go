@@ -0,0 +1,51 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestSuggestionCalendar(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bond1 := &data.Bond{ID: 1}
+	bond2 := &data.Bond{ID: 2}
+
+	result := &SuggestResult{
+		Positions: []*SuggestedPortfolioPosition{
+			{
+				Report: Report{Bond: bond1, CashFlow: []*CashFlowItem{
+					{Type: Coupon, Date: t0.AddDate(0, 6, 0), ValueRub: 100},
+					{Type: Maturity, Date: t0.AddDate(1, 0, 0), ValueRub: 2000},
+				}},
+				Quantity: 2,
+			},
+			{
+				Report: Report{Bond: bond2, CashFlow: []*CashFlowItem{
+					{Type: Amortization, Date: t0.AddDate(0, 3, 0), ValueRub: 500},
+					{Type: Coupon, Date: t0.AddDate(0, 6, 0), ValueRub: 30},
+				}},
+				Quantity: 1,
+			},
+		},
+	}
+
+	calendar := NewSuggestionCalendar(result)
+	assert.Len(calendar.Events, 4)
+	assert.Equal(AmortizationEvent, calendar.Events[0].Type)
+	assert.Equal(MaturityEvent, calendar.Events[3].Type)
+
+	days := calendar.Days()
+	assert.Len(days, 3)
+	assert.Equal(t0.AddDate(0, 6, 0), days[1].Date)
+	assert.Equal(float64(130), days[1].Amount)
+	assert.True(days[1].HasCoupon)
+	assert.False(days[1].HasMaturity)
+	assert.True(days[0].HasAmortization)
+	assert.True(days[2].HasMaturity)
+}
@@ -0,0 +1,358 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// CashFlowMatchRequest - запрос на формирование портфеля, выплаты по которому покрывают заданные обязательства
+// Обязательства задаются либо графиком Liabilities, либо ежемесячной суммой MonthlyPayout на Horizon месяцев
+type CashFlowMatchRequest struct {
+	// Желаемая сумма выплат в месяц, в валюте
+	// Используется, если график обязательств не задан
+	MonthlyPayout float64
+
+	// Срок ежемесячных выплат, месяцев
+	Horizon int
+
+	// График обязательств
+	Liabilities []*Liability
+
+	// Коллекция, из которой выбираются облигации
+	// Если не задана, то выбираются облигации без признака высокого риска
+	Collection Collection
+
+	// Модель комиссий и налогов
+	// Если не задана, то используются показатели, рассчитанные в БД, а портфель подбирается по модели по умолчанию
+	Costs *CostModel
+}
+
+// Liability - обязательство, которое должно быть покрыто выплатами по портфелю
+type Liability struct {
+	// Дата обязательства
+	Date time.Time
+
+	// Сумма обязательства, в валюте
+	Amount float64
+}
+
+// Validate проверяет корректность запроса
+func (r *CashFlowMatchRequest) Validate() error {
+	if len(r.Liabilities) == 0 {
+		if r.MonthlyPayout <= 0 {
+			return fmt.Errorf("monthly payout must be positive")
+		}
+		if r.Horizon <= 0 || r.Horizon > MaxLadderHorizon {
+			return fmt.Errorf("horizon must be in range 1..%d months", MaxLadderHorizon)
+		}
+	}
+
+	for _, liability := range r.Liabilities {
+		if liability.Amount <= 0 {
+			return fmt.Errorf("liability amount must be positive")
+		}
+	}
+
+	return nil
+}
+
+// schedule возвращает график обязательств, упорядоченный по дате
+// Если обязательство приходится на срок ранее чем через ladderMinDaysTillMaturity дней или позднее MaxLadderHorizon месяцев,
+// то возвращается ошибка
+func (r *CashFlowMatchRequest) schedule(now time.Time) ([]*Liability, error) {
+	liabilities := make([]*Liability, 0)
+	if len(r.Liabilities) > 0 {
+		for _, liability := range r.Liabilities {
+			l := *liability
+			liabilities = append(liabilities, &l)
+		}
+	} else {
+		for i := 1; i <= r.Horizon; i++ {
+			liabilities = append(liabilities, &Liability{Date: now.AddDate(0, i, 0), Amount: r.MonthlyPayout})
+		}
+	}
+
+	sort.SliceStable(liabilities, func(i, j int) bool {
+		return liabilities[i].Date.Before(liabilities[j].Date)
+	})
+
+	first, last := liabilities[0].Date, liabilities[len(liabilities)-1].Date
+	if first.Before(now.AddDate(0, 0, ladderMinDaysTillMaturity)) || last.After(now.AddDate(0, MaxLadderHorizon, 0)) {
+		return nil, fmt.Errorf("liabilities must be due in %d days to %d months", ladderMinDaysTillMaturity, MaxLadderHorizon)
+	}
+
+	return liabilities, nil
+}
+
+// CashFlowMatchResult - результат формирования портфеля под обязательства
+type CashFlowMatchResult struct {
+	// Портфель, выплаты по которому покрывают обязательства
+	// Если не удалось подобрать ни одной позиции, то nil
+	Portfolio *SuggestResult
+
+	// График обязательств и выплат по портфелю
+	Schedule []*CashFlowMatchItem
+
+	// Признак того, что все обязательства покрыты
+	Covered bool
+}
+
+// CashFlowMatchItem - обязательство и выплаты по портфелю, которые приходятся на него
+type CashFlowMatchItem struct {
+	// Дата обязательства
+	Date time.Time
+
+	// Сумма обязательства, в валюте
+	Liability float64
+
+	// Выплаты по портфелю после предыдущего обязательства и до даты этого обязательства включительно
+	// (за вычетом налогов), в валюте
+	Inflow float64
+
+	// Остаток денежных средств после исполнения обязательства, в валюте
+	// Отрицательный остаток означает, что обязательство не покрыто
+	Balance float64
+}
+
+// MatchCashFlows подбирает портфель минимальной стоимости, выплаты по которому покрывают заданные обязательства (см. matchCashFlows)
+// Если подходящих облигаций не нашлось, то возвращается ошибка ErrNotFound
+func (s *service) MatchCashFlows(ctx context.Context, tx *data.TX, request *CashFlowMatchRequest) (*CashFlowMatchResult, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	now := today()
+	liabilities, err := request.schedule(now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Облигации должны погашаться не позднее последнего обязательства
+	horizon := 1
+	for now.AddDate(0, horizon, 0).Before(liabilities[len(liabilities)-1].Date) {
+		horizon++
+	}
+
+	entities, err := s.listBondsForLadder(tx, request.Collection, horizon)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, len(entities))
+	for i, entity := range entities {
+		reports[i] = mapReport(entity)
+	}
+
+	// Для каждого месяца отбираются наиболее доходные облигации, данные по выплатам загружаются только для них
+	rungs := newLadderRungs(now, &LadderRequest{Horizon: horizon, Interval: 1})
+	candidates := make([]*Report, 0)
+	for _, list := range selectLadderCandidates(now, rungs, reports) {
+		for _, report := range list {
+			err = s.enrichWithCashFlow(tx, report)
+			if err != nil {
+				return nil, err
+			}
+
+			candidates = append(candidates, report)
+		}
+	}
+
+	result := matchCashFlows(now, liabilities, candidates, request.Costs)
+	if result.Portfolio == nil {
+		return nil, ErrNotFound
+	}
+
+	return result, nil
+}
+
+// cashFlowMatchCandidate - облигация, которая может войти в портфель под обязательства
+type cashFlowMatchCandidate struct {
+	report *Report
+
+	// Размер лота, шт.
+	lot int
+
+	// Стоимость покупки одного лота с учетом комиссий, в валюте
+	cost float64
+
+	// Выплаты по одной облигации за вычетом налогов, по возрастанию даты
+	flows []*CashFlowItem
+}
+
+// cashBy возвращает сумму выплат по одной облигации до даты date включительно
+func (c *cashFlowMatchCandidate) cashBy(date time.Time) float64 {
+	sum := 0.0
+	for _, item := range c.flows {
+		if item.Date.After(date) {
+			break
+		}
+		sum += item.ValueRub
+	}
+
+	return sum
+}
+
+// newCashFlowMatchCandidate рассчитывает стоимость лота облигации и выплаты по ней после даты now с учетом модели комиссий и налогов:
+// купоны уменьшаются на налог на купоны, последняя выплата номинала - на налог на доход от погашения (см. principalIncome).
+// Комиссия рассчитывается по ставке feeRate, поскольку размер сделки заранее неизвестен
+func newCashFlowMatchCandidate(now time.Time, r *Report, costs *CostModel, feeRate float64) *cashFlowMatchCandidate {
+	lot := lotSize(r)
+	c := &cashFlowMatchCandidate{
+		report: r,
+		lot:    lot,
+		cost:   r.OpenValue * (1 + feeRate) * float64(lot),
+	}
+
+	taxRate := costs.CouponTaxRate(r.Bond)
+	var redemption *CashFlowItem
+	for _, item := range r.CashFlow {
+		if !item.Date.After(now) {
+			continue
+		}
+
+		flow := *item
+		if flow.Type == Coupon {
+			flow.ValueRub *= 1 - taxRate
+		} else if redemption == nil || !flow.Date.Before(redemption.Date) {
+			redemption = &flow
+		}
+		c.flows = append(c.flows, &flow)
+	}
+
+	withFee := *r
+	withFee.OpenFee = r.OpenValue * feeRate
+	if income, _ := costs.principalIncome(now, &withFee, 1); income > 0 && redemption != nil {
+		redemption.ValueRub -= costs.IncomeTax(income)
+	}
+
+	sort.SliceStable(c.flows, func(i, j int) bool {
+		return c.flows[i].Date.Before(c.flows[j].Date)
+	})
+
+	return c
+}
+
+// matchCashFlows подбирает портфель минимальной стоимости, выплаты по которому покрывают обязательства liabilities (упорядоченные по дате)
+//
+// Задача решается как задача целочисленного линейного программирования (см. solveCovering):
+// переменные - количество лотов каждой облигации, целевая функция - стоимость покупки с учетом комиссий,
+// а выплаты учитываются за вычетом налогов (см. newCashFlowMatchCandidate).
+// Остаток денежных средств после каждого обязательства переносится на следующее:
+// balance[k] = balance[k-1] + inflow[k] - liability[k] ≥ 0, что равносильно тому,
+// что на дату каждого обязательства сумма полученных выплат не меньше накопленной суммы обязательств.
+// Обязательства, до даты которых ни одна облигация не приносит выплат, покрыть невозможно, они исключаются из ограничений
+func matchCashFlows(now time.Time, liabilities []*Liability, reports []*Report, costs *CostModel) *CashFlowMatchResult {
+	model := costs
+	if model == nil {
+		model = DefaultCostModel()
+	}
+
+	// Накопленная сумма обязательств на дату каждого обязательства
+	cumulative := make([]float64, len(liabilities))
+	sum := 0.0
+	for k, liability := range liabilities {
+		sum += liability.Amount
+		cumulative[k] = sum
+	}
+
+	// Ставка комиссии оценивается по сделке на сумму всех обязательств
+	feeRate := model.Fee(sum) / sum
+
+	candidates := make([]*cashFlowMatchCandidate, 0, len(reports))
+	for _, report := range reports {
+		// Если по облигации предвидится оферта, то считаем, что облигация будет предъявлена к выкупу
+		r := report
+		if r.ToOffer != nil {
+			r = r.ToOffer
+		}
+		if r.OpenValue <= 0 || !isTradingActive(r) {
+			continue
+		}
+
+		candidates = append(candidates, newCashFlowMatchCandidate(now, r, model, feeRate))
+	}
+
+	a := make([][]float64, 0, len(liabilities))
+	b := make([]float64, 0, len(liabilities))
+	for k, liability := range liabilities {
+		row := make([]float64, len(candidates))
+		coverable := false
+		for i, c := range candidates {
+			row[i] = c.cashBy(liability.Date) * float64(c.lot)
+			coverable = coverable || row[i] > 0
+		}
+		if coverable {
+			a = append(a, row)
+			b = append(b, cumulative[k])
+		}
+	}
+
+	cost := make([]float64, len(candidates))
+	for i, c := range candidates {
+		cost[i] = c.cost
+	}
+
+	quantities := make([]int, len(candidates))
+	if lots, ok := solveCovering(a, b, cost); ok {
+		for i, c := range candidates {
+			quantities[i] = lots[i] * c.lot
+		}
+	}
+
+	inflowBy := func(date time.Time) float64 {
+		inflow := 0.0
+		for i, c := range candidates {
+			if quantities[i] > 0 {
+				inflow += float64(quantities[i]) * c.cashBy(date)
+			}
+		}
+		return inflow
+	}
+
+	result := &CashFlowMatchResult{
+		Schedule: make([]*CashFlowMatchItem, len(liabilities)),
+		Covered:  true,
+	}
+
+	positions := make([]*SuggestedPortfolioPosition, 0)
+	totalAmount := 0.0
+	for i, c := range candidates {
+		if quantities[i] == 0 {
+			continue
+		}
+
+		p := newSuggestedPosition(now, c.report, quantities[i], costs)
+		positions = append(positions, p)
+		totalAmount += p.OpenValue
+	}
+	for _, p := range positions {
+		p.Weight = p.OpenValue / totalAmount
+	}
+	if len(positions) > 0 {
+		result.Portfolio = newSuggestResult(now, positions, costs)
+	}
+
+	prevInflow := 0.0
+	for k, liability := range liabilities {
+		inflow := inflowBy(liability.Date)
+		item := &CashFlowMatchItem{
+			Date:      liability.Date,
+			Liability: liability.Amount,
+			Inflow:    inflow - prevInflow,
+			Balance:   inflow - cumulative[k],
+		}
+		if item.Balance < -1e-6 {
+			result.Covered = false
+		}
+
+		result.Schedule[k] = item
+		prevInflow = inflow
+	}
+
+	return result
+}
@@ -0,0 +1,90 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestMatchCashFlows(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	d1, d2 := now.AddDate(0, 1, 0), now.AddDate(0, 2, 0)
+
+	// Облигация без купонов, погашаемая к первому обязательству
+	r1 := newOptimizerReport(1, 1, 990, 10, 0.1)
+	r1.CashFlow = []*CashFlowItem{{Type: Maturity, Date: d1, ValueRub: 1000}}
+
+	// Облигация с купоном, погашаемая ко второму обязательству - дешевле, чем вторая облигация первого выпуска,
+	// остаток выплат по которой хранился бы до второго обязательства
+	r2 := newOptimizerReport(2, 2, 980, 10, 0.2)
+	r2.CashFlow = []*CashFlowItem{
+		{Type: Coupon, Date: now.AddDate(0, 0, -1), ValueRub: 100},
+		{Type: Coupon, Date: d2, ValueRub: 100},
+		{Type: Maturity, Date: d2, ValueRub: 1000},
+	}
+
+	liabilities := []*Liability{{Date: d1, Amount: 1000}, {Date: d2, Amount: 1000}}
+	result := matchCashFlows(now, liabilities, []*Report{r1, r2}, nil)
+
+	assert.True(result.Covered)
+	assert.Equal(map[int]float64{1: 990, 2: 980}, positionAmounts(result.Portfolio.Positions))
+	assert.Len(result.Schedule, 2)
+	assert.Equal(1000.0, result.Schedule[0].Inflow)
+	assert.Equal(0.0, result.Schedule[0].Balance)
+	// Прошедшие выплаты не учитываются, купон учитывается за вычетом налога
+	assert.InDelta(1087.0, result.Schedule[1].Inflow, 1e-6)
+	assert.InDelta(87.0, result.Schedule[1].Balance, 1e-6)
+
+	// Обязательство, до которого нет выплат, не покрывается
+	liabilities = []*Liability{{Date: now.AddDate(0, 0, 5), Amount: 1000}, {Date: d2, Amount: 1000}}
+	result = matchCashFlows(now, liabilities, []*Report{r1, r2}, nil)
+	assert.False(result.Covered)
+	assert.Equal(-1000.0, result.Schedule[0].Balance)
+}
+
+func TestMatchCashFlows_Optimal(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	d1, d2 := now.AddDate(0, 1, 0), now.AddDate(0, 2, 0)
+	costs := &CostModel{Account: IISTypeB}
+
+	// Облигация, погашаемая к первому обязательству - самая дешевая в расчете на рубль выплат до него
+	r1 := newOptimizerReport(1, 1, 990, 10, 0.1)
+	r1.CashFlow = []*CashFlowItem{{Type: Maturity, Date: d1, ValueRub: 1000}}
+
+	// Амортизируемая облигация, которая одна покрывает оба обязательства
+	r2 := newOptimizerReport(2, 2, 1900, 10, 0.2)
+	r2.CashFlow = []*CashFlowItem{
+		{Type: Amortization, Date: d1, ValueRub: 1000},
+		{Type: Maturity, Date: d2, ValueRub: 1000},
+	}
+
+	// Жадный выбор по обязательствам купил бы первую облигацию под первое обязательство и вторую под второе (2890)
+	liabilities := []*Liability{{Date: d1, Amount: 1000}, {Date: d2, Amount: 1000}}
+	result := matchCashFlows(now, liabilities, []*Report{r1, r2}, costs)
+	assert.True(result.Covered)
+	assert.Equal(map[int]float64{2: 1900}, positionAmounts(result.Portfolio.Positions))
+
+	// Налог на доход от погашения уменьшает выплаты, поэтому ни одной амортизируемой облигации, ни двух облигаций первого выпуска уже недостаточно
+	r1.MaturityPayment = 1000
+	r2.MaturityPayment = 1000
+	r2.AmortizationPayments = 1000
+	result = matchCashFlows(now, liabilities, []*Report{r1, r2}, &CostModel{Account: BrokerageAccount})
+	assert.True(result.Covered)
+	assert.Equal(map[int]float64{1: 990, 2: 1900}, positionAmounts(result.Portfolio.Positions))
+	assert.InDelta(987.0, result.Schedule[1].Inflow, 1e-6)
+}
+
+func TestCashFlowMatchRequest_Validate(t *testing.T) {
+	assert := assertion.New(t)
+
+	assert.NoError((&CashFlowMatchRequest{MonthlyPayout: 1000, Horizon: 12}).Validate())
+	assert.NoError((&CashFlowMatchRequest{Liabilities: []*Liability{{Date: time.Now(), Amount: 1000}}}).Validate())
+	assert.Error((&CashFlowMatchRequest{MonthlyPayout: 0, Horizon: 12}).Validate())
+	assert.Error((&CashFlowMatchRequest{MonthlyPayout: 1000, Horizon: MaxLadderHorizon + 1}).Validate())
+	assert.Error((&CashFlowMatchRequest{Liabilities: []*Liability{{Date: time.Now(), Amount: -1}}}).Validate())
+}
@@ -0,0 +1,324 @@
+package recommender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// CollectionDefinition содержит описание пользовательской коллекции рекомендаций
+// Все условия необязательны и объединяются по "И", незаданное условие не ограничивает выборку
+// Как и во встроенных коллекциях, в коллекцию попадают только торгующиеся облигации с известной датой погашения
+type CollectionDefinition struct {
+	// ID коллекции (латинские буквы, цифры, "-" и "_")
+	ID string `json:"id" yaml:"id"`
+
+	// Название коллекции
+	Name string `json:"name" yaml:"name"`
+
+	// Типы облигаций (например, "municipal_bond")
+	Types []data.BondType `json:"types,omitempty" yaml:"types,omitempty"`
+
+	// Уровни листинга
+	ListingLevels []int `json:"listing_levels,omitempty" yaml:"listing_levels,omitempty"`
+
+	// Признак "только для квалифицированных инвесторов"
+	QualifiedOnly *bool `json:"qualified_only,omitempty" yaml:"qualified_only,omitempty"`
+
+	// Признак "высокий риск"
+	HighRisk *bool `json:"high_risk,omitempty" yaml:"high_risk,omitempty"`
+
+	// Валюты номинала (например, "RUB")
+	Currencies []string `json:"currencies,omitempty" yaml:"currencies,omitempty"`
+
+	// ИНН эмитентов, облигации которых включаются в коллекцию
+	Issuers []string `json:"issuers,omitempty" yaml:"issuers,omitempty"`
+
+	// ИНН эмитентов, облигации которых исключаются из коллекции
+	ExcludeIssuers []string `json:"exclude_issuers,omitempty" yaml:"exclude_issuers,omitempty"`
+
+	// Минимальный кредитный рейтинг эмитента по национальной шкале (например, "A-")
+	// Облигации эмитентов без рейтинга в коллекцию не попадают, рейтинги загружаются из файла (см. LoadIssuerRatings)
+	MinRating string `json:"min_rating,omitempty" yaml:"min_rating,omitempty"`
+
+	// Минимальная доходность к погашению, % годовых
+	MinYield *float64 `json:"min_yield,omitempty" yaml:"min_yield,omitempty"`
+
+	// Максимальная доходность к погашению, % годовых
+	MaxYield *float64 `json:"max_yield,omitempty" yaml:"max_yield,omitempty"`
+
+	// Минимальный срок до погашения, дней
+	MinDaysTillMaturity *int `json:"min_days_till_maturity,omitempty" yaml:"min_days_till_maturity,omitempty"`
+
+	// Максимальный срок до погашения, дней
+	MaxDaysTillMaturity *int `json:"max_days_till_maturity,omitempty" yaml:"max_days_till_maturity,omitempty"`
+
+	// Порядок облигаций по умолчанию ("yield" или "spread", по умолчанию "yield")
+	Sort Ranking `json:"sort,omitempty" yaml:"sort,omitempty"`
+}
+
+// collectionDefinitionFile - структура файла с описаниями пользовательских коллекций
+type collectionDefinitionFile struct {
+	Collections []*CollectionDefinition `json:"collections" yaml:"collections"`
+}
+
+var (
+	collectionIDRegexp = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	innRegexp          = regexp.MustCompile(`^[0-9]{10}([0-9]{2})?$`)
+	currencyRegexp     = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// validBondTypes содержит список типов облигаций, которые можно указать в описании коллекции
+var validBondTypes = []data.BondType{
+	data.SubfederalBond,
+	data.OFZBond,
+	data.ExchangeBond,
+	data.CBBond,
+	data.MunicipalBond,
+	data.CorporateBond,
+	data.IFIBond,
+	data.EuroBond,
+}
+
+// LoadCollectionDefinitions загружает описания пользовательских коллекций из файла или каталога
+// Поддерживаются файлы в форматах YAML (.yaml, .yml) и JSON (.json) вида {"collections": [...]},
+// из каталога загружаются все такие файлы в алфавитном порядке
+// Неизвестные поля считаются ошибкой
+func LoadCollectionDefinitions(path string) ([]*CollectionDefinition, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = make([]string, 0, len(entries))
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+		sort.Strings(files)
+	}
+
+	definitions := make([]*CollectionDefinition, 0)
+	for _, file := range files {
+		items, err := loadCollectionDefinitionFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		definitions = append(definitions, items...)
+	}
+
+	return definitions, nil
+}
+
+func loadCollectionDefinitionFile(path string) ([]*CollectionDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file collectionDefinitionFile
+	err = decodeConfigFile(path, content, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.Collections, nil
+}
+
+// decodeConfigFile разбирает содержимое файла в формате JSON (.json) или YAML (остальные расширения)
+// Неизвестные поля считаются ошибкой
+func decodeConfigFile(path string, content []byte, v interface{}) error {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(v)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	return decoder.Decode(v)
+}
+
+// Validate проверяет корректность описания коллекции
+func (d *CollectionDefinition) Validate() error {
+	if !collectionIDRegexp.MatchString(d.ID) {
+		return fmt.Errorf("collection id \"%s\" is not valid, it may contain only lowercase latin letters, digits, \"-\" and \"_\"", d.ID)
+	}
+	if strings.TrimSpace(d.Name) == "" {
+		return fmt.Errorf("collection \"%s\": name is required", d.ID)
+	}
+
+	for _, t := range d.Types {
+		valid := false
+		for _, v := range validBondTypes {
+			valid = valid || t == v
+		}
+		if !valid {
+			return fmt.Errorf("collection \"%s\": \"%s\" is not a valid bond type", d.ID, t)
+		}
+	}
+	for _, level := range d.ListingLevels {
+		if level < 1 || level > 3 {
+			return fmt.Errorf("collection \"%s\": listing level must be in range 1..3", d.ID)
+		}
+	}
+	for _, currency := range d.Currencies {
+		if !currencyRegexp.MatchString(currency) {
+			return fmt.Errorf("collection \"%s\": \"%s\" is not a valid currency code", d.ID, currency)
+		}
+	}
+	for _, inn := range append(append([]string{}, d.Issuers...), d.ExcludeIssuers...) {
+		if !innRegexp.MatchString(inn) {
+			return fmt.Errorf("collection \"%s\": \"%s\" is not a valid issuer INN", d.ID, inn)
+		}
+	}
+	if d.MinRating != "" {
+		if _, ok := normalizeCreditRating(d.MinRating); !ok {
+			return fmt.Errorf("collection \"%s\": \"%s\" is not a valid credit rating", d.ID, d.MinRating)
+		}
+	}
+	if d.MinYield != nil && d.MaxYield != nil && *d.MinYield > *d.MaxYield {
+		return fmt.Errorf("collection \"%s\": min_yield must not exceed max_yield", d.ID)
+	}
+	if d.MinDaysTillMaturity != nil && *d.MinDaysTillMaturity < 0 ||
+		d.MaxDaysTillMaturity != nil && *d.MaxDaysTillMaturity < 0 {
+		return fmt.Errorf("collection \"%s\": days till maturity must not be negative", d.ID)
+	}
+	if d.MinDaysTillMaturity != nil && d.MaxDaysTillMaturity != nil && *d.MinDaysTillMaturity > *d.MaxDaysTillMaturity {
+		return fmt.Errorf("collection \"%s\": min_days_till_maturity must not exceed max_days_till_maturity", d.ID)
+	}
+	if d.Sort != "" && d.Sort != RankByYield && d.Sort != RankBySpread {
+		return fmt.Errorf("collection \"%s\": \"%s\" is not a valid sort key, valid values are: yield, spread", d.ID, d.Sort)
+	}
+
+	return nil
+}
+
+// compile формирует коллекцию по ее описанию
+// Каждое условие переводится в SQL, в который значения передаются только через параметры запроса,
+// и в эквивалентный ему предикат для фильтра отчетов (см. collectionCondition)
+func (d *CollectionDefinition) compile() (*internalCollection, error) {
+	err := d.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []collectionCondition{tradedCondition}
+	if len(d.Types) > 0 {
+		conditions = append(conditions, bondTypeCondition(d.Types...))
+	}
+	if len(d.ListingLevels) > 0 {
+		conditions = append(conditions, listingLevelCondition(d.ListingLevels...))
+	}
+	if d.QualifiedOnly != nil {
+		conditions = append(conditions, qualifiedOnlyCondition(*d.QualifiedOnly))
+	}
+	if d.HighRisk != nil {
+		conditions = append(conditions, highRiskCondition(*d.HighRisk))
+	}
+	if len(d.Currencies) > 0 {
+		conditions = append(conditions, currencyCondition(false, d.Currencies...))
+	}
+	if len(d.Issuers) > 0 {
+		issuers := d.Issuers
+		conditions = append(conditions, collectionCondition{
+			sql:  "issuers.inn::text IN ?",
+			args: []interface{}{issuers},
+			predicate: func(r *Report) bool {
+				return r.Issuer != nil && r.Issuer.INN != nil && containsString(issuers, *r.Issuer.INN)
+			},
+		})
+	}
+	if len(d.ExcludeIssuers) > 0 {
+		excludeIssuers := d.ExcludeIssuers
+		conditions = append(conditions, collectionCondition{
+			sql:  "(issuers.inn IS NULL OR issuers.inn::text NOT IN ?)",
+			args: []interface{}{excludeIssuers},
+			predicate: func(r *Report) bool {
+				return r.Issuer == nil || r.Issuer.INN == nil || !containsString(excludeIssuers, *r.Issuer.INN)
+			},
+		})
+	}
+	if d.MinRating != "" {
+		minRating, _ := normalizeCreditRating(d.MinRating)
+		ratings := creditRatingsAtLeast(minRating)
+		conditions = append(conditions, collectionCondition{
+			sql:  "issuers.rating IN ?",
+			args: []interface{}{ratings},
+			predicate: func(r *Report) bool {
+				return r.Issuer != nil && r.Issuer.Rating != nil && containsString(ratings, *r.Issuer.Rating)
+			},
+		})
+	}
+	if d.MinYield != nil {
+		minYield := *d.MinYield
+		conditions = append(conditions, collectionCondition{
+			sql:       "COALESCE(report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate) >= ?",
+			args:      []interface{}{minYield},
+			predicate: func(r *Report) bool { return r.YieldToMaturity >= minYield },
+		})
+	}
+	if d.MaxYield != nil {
+		maxYield := *d.MaxYield
+		conditions = append(conditions, collectionCondition{
+			sql:       "COALESCE(report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate) <= ?",
+			args:      []interface{}{maxYield},
+			predicate: func(r *Report) bool { return r.YieldToMaturity <= maxYield },
+		})
+	}
+	if d.MinDaysTillMaturity != nil {
+		minDays := *d.MinDaysTillMaturity
+		conditions = append(conditions, collectionCondition{
+			sql:       "reports.days_till_maturity >= ?",
+			args:      []interface{}{minDays},
+			predicate: func(r *Report) bool { return r.DaysTillMaturity >= minDays },
+		})
+	}
+	if d.MaxDaysTillMaturity != nil {
+		maxDays := *d.MaxDaysTillMaturity
+		conditions = append(conditions, collectionCondition{
+			sql:       "reports.days_till_maturity <= ?",
+			args:      []interface{}{maxDays},
+			predicate: func(r *Report) bool { return r.DaysTillMaturity <= maxDays },
+		})
+	}
+
+	rankings := []Ranking{RankByYield, RankBySpread}
+	if d.Sort == RankBySpread {
+		rankings = []Ranking{RankBySpread, RankByYield}
+	}
+
+	return newInternalCollection(d.ID, d.Name, conditions, false, rankings...), nil
+}
+
+// containsString проверяет, содержится ли строка в списке
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
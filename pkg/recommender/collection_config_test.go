@@ -0,0 +1,217 @@
+package recommender
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+const testCollectionsYAML = `
+collections:
+  - id: municipal
+    name: Муниципальные облигации
+    types: [municipal_bond, subfederal_bond]
+    listing_levels: [1, 2]
+    qualified_only: false
+    currencies: [RUB]
+    exclude_issuers: ["7700000000"]
+    min_yield: 5
+    max_days_till_maturity: 1000
+    sort: spread
+`
+
+const testCollectionsJSON = `{"collections": [{"id": "sber", "name": "Сбербанк", "issuers": ["7707083893"]}]}`
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadCollectionDefinitions(t *testing.T) {
+	assert := assertion.New(t)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "a.yaml", testCollectionsYAML)
+	writeTestFile(t, dir, "b.json", testCollectionsJSON)
+	writeTestFile(t, dir, "readme.txt", "not a collection")
+
+	definitions, err := LoadCollectionDefinitions(dir)
+	assert.NoError(err)
+	if !assert.Len(definitions, 2) {
+		return
+	}
+
+	d := definitions[0]
+	assert.Equal("municipal", d.ID)
+	assert.Equal([]data.BondType{data.MunicipalBond, data.SubfederalBond}, d.Types)
+	assert.Equal([]int{1, 2}, d.ListingLevels)
+	assert.NotNil(d.QualifiedOnly)
+	assert.False(*d.QualifiedOnly)
+	assert.Nil(d.HighRisk)
+	assert.Equal(5.0, *d.MinYield)
+	assert.Equal(1000, *d.MaxDaysTillMaturity)
+	assert.Equal(RankBySpread, d.Sort)
+	assert.Equal([]string{"7707083893"}, definitions[1].Issuers)
+}
+
+func TestLoadCollectionDefinitions_UnknownField(t *testing.T) {
+	assert := assertion.New(t)
+
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "c.yaml", "collections:\n  - id: rated\n    name: Rated\n    rating_agency: ACRA\n")
+
+	_, err := LoadCollectionDefinitions(path)
+	assert.Error(err)
+}
+
+func TestCollectionDefinition_Validate(t *testing.T) {
+	assert := assertion.New(t)
+
+	minYield, maxYield := 10.0, 5.0
+	invalid := []*CollectionDefinition{
+		{ID: "Bad ID", Name: "x"},
+		{ID: "x"},
+		{ID: "x", Name: "x", Types: []data.BondType{"stock"}},
+		{ID: "x", Name: "x", ListingLevels: []int{4}},
+		{ID: "x", Name: "x", Currencies: []string{"rub'"}},
+		{ID: "x", Name: "x", Issuers: []string{"77' OR 1=1"}},
+		{ID: "x", Name: "x", MinYield: &minYield, MaxYield: &maxYield},
+		{ID: "x", Name: "x", Sort: "price"},
+		{ID: "x", Name: "x", MinRating: "AAAA"},
+	}
+	for _, d := range invalid {
+		assert.Error(d.Validate(), "%+v", d)
+	}
+
+	assert.NoError((&CollectionDefinition{ID: "x", Name: "x"}).Validate())
+}
+
+func TestCollectionDefinition_Compile(t *testing.T) {
+	assert := assertion.New(t)
+
+	minYield, maxDays, qualifiedOnly := 5.0, 1000, false
+	d := &CollectionDefinition{
+		ID:                  "municipal",
+		Name:                "Муниципальные облигации",
+		Types:               []data.BondType{data.MunicipalBond},
+		QualifiedOnly:       &qualifiedOnly,
+		ExcludeIssuers:      []string{"7700000000"},
+		MinYield:            &minYield,
+		MaxDaysTillMaturity: &maxDays,
+		Sort:                RankBySpread,
+	}
+
+	coll, err := d.compile()
+	if !assert.NoError(err) {
+		return
+	}
+
+	// Значения передаются только через параметры запроса
+	text := coll.filterSQL(Duration1Year)
+	assert.Equal(len(coll.filterArgs), strings.Count(text, "?"))
+	assert.NotContains(text, "7700000000")
+	assert.Equal([]Ranking{RankBySpread, RankByYield}, coll.Rankings())
+
+	inn, otherINN := "7700000000", "7800000000"
+	maturity := sql.NullTime{Time: time.Now().AddDate(1, 0, 0), Valid: true}
+	report := func(bondType data.BondType, inn *string, ytm float64, days int) *Report {
+		return &Report{
+			Bond:             &data.Bond{Type: bondType, IsTraded: true, MaturityDate: maturity},
+			Issuer:           &data.Issuer{INN: inn},
+			YieldToMaturity:  ytm,
+			DaysTillMaturity: days,
+		}
+	}
+	reports := []*Report{
+		report(data.MunicipalBond, &otherINN, 7, 365),
+		report(data.CorporateBond, &otherINN, 7, 365),
+		report(data.MunicipalBond, &inn, 7, 365),
+		report(data.MunicipalBond, &otherINN, 4, 365),
+		report(data.MunicipalBond, nil, 7, 2000),
+	}
+
+	filtered := coll.filter(reports)
+	assert.Len(filtered, 1)
+	assert.Equal(reports[0], filtered[0])
+}
+
+func TestCollectionDefinition_Compile_MinRating(t *testing.T) {
+	assert := assertion.New(t)
+
+	d := &CollectionDefinition{ID: "rated", Name: "Rated", MinRating: "ruA-"}
+	coll, err := d.compile()
+	if !assert.NoError(err) {
+		return
+	}
+
+	text := coll.filterSQL(Duration1Year)
+	assert.Contains(text, "issuers.rating IN ?")
+	assert.Equal([]interface{}{[]string{"AAA", "AA+", "AA", "AA-", "A+", "A", "A-"}}, coll.filterArgs)
+
+	maturity := sql.NullTime{Time: time.Now().AddDate(1, 0, 0), Valid: true}
+	report := func(rating *string) *Report {
+		return &Report{
+			Bond:   &data.Bond{Type: data.CorporateBond, IsTraded: true, MaturityDate: maturity},
+			Issuer: &data.Issuer{Rating: rating},
+		}
+	}
+	aa, a, bbb := "AA", "A-", "BBB+"
+	reports := []*Report{report(&aa), report(&a), report(&bbb), report(nil)}
+
+	filtered := coll.filter(reports)
+	assert.Equal([]*Report{reports[0], reports[1]}, filtered)
+}
+
+func TestWithCollections_Duplicate(t *testing.T) {
+	assert := assertion.New(t)
+
+	_, err := New(WithCollections(&CollectionDefinition{ID: "ofz", Name: "ОФЗ"}))
+	assert.Error(err)
+
+	s, err := New(WithCollections(&CollectionDefinition{ID: "municipal", Name: "Муниципальные облигации"}))
+	assert.NoError(err)
+	_, err = s.GetCollection("municipal")
+	assert.NoError(err)
+	assert.Len(s.ListCollections(), len(collections)+1)
+}
+
+func TestBuiltinCollections_FilterMatchesSQL(t *testing.T) {
+	assert := assertion.New(t)
+
+	for id, coll := range collections {
+		text := coll.filterSQL(Duration1Year)
+		assert.Equal(len(coll.filterArgs), strings.Count(text, "?"), id)
+		assert.Contains(text, tradedCondition.sql, id)
+	}
+
+	inn := "7701000000"
+	maturity := sql.NullTime{Time: time.Now().AddDate(1, 0, 0), Valid: true}
+	report := func(traded bool) *Report {
+		return &Report{
+			Bond: &data.Bond{
+				Type:         data.OFZBond,
+				IsTraded:     traded,
+				MaturityDate: maturity,
+				ListingLevel: 1,
+				FaceUnit:     "RUB",
+			},
+			Issuer: &data.Issuer{INN: &inn},
+		}
+	}
+	reports := []*Report{report(true), report(false)}
+
+	// Как и в SQL, неторгующиеся облигации в коллекцию не попадают
+	assert.Equal([]*Report{reports[0]}, collections["ofz"].filter(reports))
+}
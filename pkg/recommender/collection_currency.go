@@ -0,0 +1,18 @@
+package recommender
+
+func init() {
+	// Выбираются все облигации по набору условий:
+	// - торгующиеся
+	// - погашение не позднее N лет с текущего момента
+	// - нет признака "только для квалифицированных инвесторов"
+	// - нет признака "высокий риск"
+	// - валюта номинала - не рубль (суммы в отчетах пересчитаны в рубли по текущему курсу)
+	// - приведенная доходность больше нуля и согласуется с критерием "три сигмы"
+	register("currency", "Валютные облигации", []collectionCondition{
+		tradedCondition,
+		qualifiedOnlyCondition(false),
+		highRiskCondition(false),
+		currencyCondition(true, "RUB"),
+		positiveInterestRateCondition,
+	}, true, RankByYield)
+}
@@ -1,30 +1,33 @@
 package recommender
 
+import (
+	"strings"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
 func init() {
-	register("ofz", "ОФЗ", func(duration Duration) string {
-		// Выбираются все облигации по набору условий:
-		// - торгующиеся
-		// - погашение не позднее N лет с текущего момента
-		// - тип - "ofz_bond"
-		// - нет признака "только для квалифицированных инвесторов"
-		// - нет признакак "высокий риск"
-		// - уровень листинга 1
-		// - валюта номинала - рубль
-		// - ИНН эмитента начинается с 77 (чтобы отфильтровать облигации других стран)
-		text := `
-SELECT bonds.id
-FROM bonds
-INNER JOIN issuers ON issuers.id = bonds.issuer_id
-WHERE is_traded
-  AND maturity_date IS NOT NULL
-  AND type = 'ofz_bond'
-  AND qualified_only = FALSE
-  AND high_risk = FALSE
-  AND listing_level = 1
-  AND face_unit = 'RUB'
-  AND issuers.inn IS NOT NULL
-  AND starts_with(issuers.inn::text, '77')
-`
-		return text
-	})
+	// Выбираются все облигации по набору условий:
+	// - торгующиеся
+	// - погашение не позднее N лет с текущего момента
+	// - тип - "ofz_bond"
+	// - нет признака "только для квалифицированных инвесторов"
+	// - нет признака "высокий риск"
+	// - уровень листинга 1
+	// - валюта номинала - рубль
+	// - ИНН эмитента начинается с 77 (чтобы отфильтровать облигации других стран)
+	register("ofz", "ОФЗ", []collectionCondition{
+		tradedCondition,
+		bondTypeCondition(data.OFZBond),
+		qualifiedOnlyCondition(false),
+		highRiskCondition(false),
+		listingLevelCondition(1),
+		currencyCondition(false, "RUB"),
+		{
+			sql: "issuers.inn IS NOT NULL AND starts_with(issuers.inn::text, '77')",
+			predicate: func(r *Report) bool {
+				return r.Issuer != nil && r.Issuer.INN != nil && strings.HasPrefix(*r.Issuer.INN, "77")
+			},
+		},
+	}, false)
 }
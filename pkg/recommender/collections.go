@@ -3,29 +3,190 @@ package recommender
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/data"
 )
 
 type internalCollection struct {
-	id        string
-	name      string
-	filterSQL func(duration Duration) string
+	id         string
+	name       string
+	filterSQL  func(duration Duration) string
+	filterArgs []interface{}
+	filter     collectionFilter
+	rankings   []Ranking
+	costs      *CostModel
 }
 
+// collectionFilter отбирает облигации в коллекцию по отчетам на произвольную дату
+// Фильтр эквивалентен filterSQL и используется там, где данные берутся не из текущих отчетов в БД (например, в бэктесте)
+type collectionFilter func(reports []*Report) []*Report
+
+// collectionCondition - условие отбора облигаций в коллекцию
+// Условие задается одновременно в виде SQL (с параметрами args) и эквивалентного ему предиката по отчету,
+// чтобы коллекции в БД и фильтры отчетов строились из одного описания
+// В SQL доступны таблицы bonds, issuers, reports и report_metrics
+type collectionCondition struct {
+	sql       string
+	args      []interface{}
+	predicate func(r *Report) bool
+}
+
+// collections содержит встроенные коллекции
 var collections = make(map[string]*internalCollection)
 
-func register(id, name string, filterSQL func(duration Duration) string) {
+func register(id, name string, conditions []collectionCondition, threeSigma bool, rankings ...Ranking) {
 	if _, exists := collections[id]; exists {
 		panic(fmt.Sprintf("collection \"%s\" already exists", id))
 	}
 
+	coll := newInternalCollection(id, name, conditions, threeSigma, rankings...)
+	collections[id] = coll
+}
+
+// newInternalCollection формирует коллекцию из условий отбора облигаций
+// Если задан threeSigma, то из коллекции дополнительно исключаются облигации, приведенная доходность которых
+// превышает среднюю по отобранным облигациям более чем на три стандартных отклонения
+func newInternalCollection(id, name string, conditions []collectionCondition, threeSigma bool, rankings ...Ranking) *internalCollection {
+	where := make([]string, len(conditions))
+	args := make([]interface{}, 0)
+	for i, c := range conditions {
+		where[i] = c.sql
+		args = append(args, c.args...)
+	}
+
+	text := `
+SELECT bonds.id
+FROM bonds
+INNER JOIN issuers ON issuers.id = bonds.issuer_id
+INNER JOIN reports ON reports.bond_id = bonds.id
+LEFT JOIN report_metrics ON report_metrics.bond_id = bonds.id
+WHERE ` + strings.Join(where, "\n  AND ") + `
+`
+	if threeSigma {
+		text = `
+SELECT id
+FROM (
+         SELECT bonds.id,
+                ` + collectionInterestRateSQL + ` AS interest_rate,
+                AVG(` + collectionInterestRateSQL + `) OVER () AS mean,
+                STDDEV(` + collectionInterestRateSQL + `) OVER () AS stddev
+         FROM bonds
+                  INNER JOIN issuers ON issuers.id = bonds.issuer_id
+                  INNER JOIN reports ON reports.bond_id = bonds.id
+                  LEFT JOIN report_metrics ON report_metrics.bond_id = bonds.id
+         WHERE ` + strings.Join(where, "\n           AND ") + `
+     ) xs
+WHERE interest_rate <= (mean + 3 * stddev)
+`
+	}
+
 	coll := &internalCollection{
-		id:        id,
-		name:      name,
-		filterSQL: filterSQL,
+		id:   id,
+		name: name,
+		filterSQL: func(duration Duration) string {
+			return text
+		},
+		filterArgs: args,
+		filter: func(reports []*Report) []*Report {
+			reports = filterReports(reports, func(r *Report) bool {
+				for _, c := range conditions {
+					if !c.predicate(r) {
+						return false
+					}
+				}
+				return true
+			})
+			if threeSigma {
+				reports = withinThreeSigma(reports, func(r *Report) float64 { return r.InterestRate })
+			}
+			return reports
+		},
+		rankings: rankings,
+	}
+	if len(coll.rankings) == 0 {
+		coll.rankings = []Ranking{RankByYield}
+	}
+	return coll
+}
+
+// collectionInterestRateSQL - приведенная доходность облигации (см. Report.InterestRate)
+const collectionInterestRateSQL = "COALESCE(report_metrics.interest_rate, reports.interest_rate)"
+
+// tradedCondition отбирает торгующиеся облигации с известной датой погашения
+// В отчетах на прошлую дату (см. pointInTimeReport) облигация считается торгующейся, если по ней есть итоги торгов
+var tradedCondition = collectionCondition{
+	sql:       "bonds.is_traded AND bonds.maturity_date IS NOT NULL",
+	predicate: func(r *Report) bool { return r.Bond.IsTraded && r.Bond.MaturityDate.Valid },
+}
+
+// positiveInterestRateCondition отбирает облигации с положительной приведенной доходностью
+var positiveInterestRateCondition = collectionCondition{
+	sql:       collectionInterestRateSQL + " > 0",
+	predicate: func(r *Report) bool { return r.InterestRate > 0 },
+}
+
+// bondTypeCondition отбирает облигации заданных типов
+func bondTypeCondition(types ...data.BondType) collectionCondition {
+	values := make([]string, len(types))
+	for i, t := range types {
+		values[i] = string(t)
+	}
+
+	return collectionCondition{
+		sql:       "bonds.type IN ?",
+		args:      []interface{}{values},
+		predicate: func(r *Report) bool { return containsString(values, string(r.Bond.Type)) },
+	}
+}
+
+// listingLevelCondition отбирает облигации с заданными уровнями листинга
+func listingLevelCondition(levels ...int) collectionCondition {
+	return collectionCondition{
+		sql:  "bonds.listing_level IN ?",
+		args: []interface{}{levels},
+		predicate: func(r *Report) bool {
+			for _, level := range levels {
+				if r.Bond.ListingLevel == level {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// qualifiedOnlyCondition отбирает облигации с заданным значением признака "только для квалифицированных инвесторов"
+func qualifiedOnlyCondition(qualifiedOnly bool) collectionCondition {
+	return collectionCondition{
+		sql:       "bonds.qualified_only = ?",
+		args:      []interface{}{qualifiedOnly},
+		predicate: func(r *Report) bool { return r.Bond.QualifiedOnly == qualifiedOnly },
+	}
+}
+
+// highRiskCondition отбирает облигации с заданным значением признака "высокий риск"
+func highRiskCondition(highRisk bool) collectionCondition {
+	return collectionCondition{
+		sql:       "bonds.high_risk = ?",
+		args:      []interface{}{highRisk},
+		predicate: func(r *Report) bool { return r.Bond.IsHighRisk == highRisk },
+	}
+}
+
+// currencyCondition отбирает облигации с номиналом в заданных валютах (или, если exclude, в любых других валютах)
+func currencyCondition(exclude bool, currencies ...string) collectionCondition {
+	sql := "bonds.face_unit IN ?"
+	if exclude {
+		sql = "bonds.face_unit NOT IN ?"
+	}
+
+	return collectionCondition{
+		sql:       sql,
+		args:      []interface{}{currencies},
+		predicate: func(r *Report) bool { return containsString(currencies, r.Bond.FaceUnit) != exclude },
 	}
-	collections[id] = coll
 }
 
 // ID возвращает ID коллекции
@@ -38,14 +199,34 @@ func (c *internalCollection) Name() string {
 	return c.name
 }
 
-// ListBonds возвращает список облигаций из коллекции
-func (c *internalCollection) ListBonds(ctx context.Context, tx *data.TX, limit int, duration Duration) ([]*Report, error) {
+// Rankings возвращает список поддерживаемых порядков облигаций, первый из них используется по умолчанию
+func (c *internalCollection) Rankings() []Ranking {
+	return c.rankings
+}
+
+// ListBonds возвращает список облигаций из коллекции в заданном порядке
+// Если коллекция не поддерживает порядок ranking, то возвращается ошибка ErrUnsupportedRanking
+func (c *internalCollection) ListBonds(ctx context.Context, tx *data.TX, limit int, duration Duration, ranking Ranking) ([]*Report, error) {
+	if !SupportsRanking(c, ranking) {
+		return nil, ErrUnsupportedRanking
+	}
+
 	filterSQL := `
 SELECT bond_id, index
 FROM collection_bonds
 WHERE collection_id = ? and duration = ?
 ORDER BY index ASC
 `
+	if ranking == RankBySpread {
+		// Облигации без спреда (например, если кривая ОФЗ еще не построена) идут в конце в порядке доходности
+		filterSQL = `
+SELECT collection_bonds.bond_id,
+       ROW_NUMBER() OVER (ORDER BY report_metrics.spread DESC NULLS LAST, collection_bonds.index ASC) AS index
+FROM collection_bonds
+LEFT JOIN report_metrics ON report_metrics.bond_id = collection_bonds.bond_id
+WHERE collection_id = ? and duration = ?
+`
+	}
 
 	entities, err := tx.Reports.List(limit, filterSQL, c.id, getAge(duration))
 	if err != nil {
@@ -55,6 +236,9 @@ ORDER BY index ASC
 	reports := make([]*Report, len(entities))
 	for i, entity := range entities {
 		reports[i] = mapReport(entity)
+		if c.costs != nil {
+			reports[i] = applyCostModel(today(), reports[i], 1, c.costs)
+		}
 	}
 	return reports, err
 }
@@ -62,7 +246,7 @@ ORDER BY index ASC
 // Rebuild выполняет обновление данных коллекции
 func (c *internalCollection) Rebuild(ctx context.Context, tx *data.TX) error {
 	for _, duration := range Durations {
-		err := tx.CollectionBondReferences.Rebuild(c.id, getAge(duration), c.filterSQL(duration))
+		err := tx.CollectionBondReferences.Rebuild(c.id, getAge(duration), c.filterSQL(duration), c.filterArgs...)
 		if err != nil {
 			return err
 		}
@@ -71,6 +255,42 @@ func (c *internalCollection) Rebuild(ctx context.Context, tx *data.TX) error {
 	return nil
 }
 
+// filterReports возвращает отчеты, удовлетворяющие условию
+func filterReports(reports []*Report, predicate func(r *Report) bool) []*Report {
+	result := make([]*Report, 0, len(reports))
+	for _, r := range reports {
+		if predicate(r) {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+// withinThreeSigma отбрасывает отчеты, у которых значение value превышает среднее более чем на три стандартных отклонения
+// Как и в SQL, стандартное отклонение рассчитывается по выборке, поэтому для менее чем двух отчетов результат пуст
+func withinThreeSigma(reports []*Report, value func(r *Report) float64) []*Report {
+	if len(reports) < 2 {
+		return make([]*Report, 0)
+	}
+
+	mean := 0.0
+	for _, r := range reports {
+		mean += value(r)
+	}
+	mean /= float64(len(reports))
+
+	variance := 0.0
+	for _, r := range reports {
+		variance += (value(r) - mean) * (value(r) - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(reports)-1))
+
+	return filterReports(reports, func(r *Report) bool {
+		return value(r) <= mean+3*stddev
+	})
+}
+
 func getAge(duration Duration) int {
 	switch duration {
 	case Duration1Year:
@@ -0,0 +1,352 @@
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// AccountType кодирует тип счета, на котором учитываются облигации
+type AccountType string
+
+const (
+	// BrokerageAccount - обычный брокерский счет
+	BrokerageAccount AccountType = "brokerage"
+
+	// IISTypeA - индивидуальный инвестиционный счет с вычетом на взносы (тип А)
+	IISTypeA AccountType = "iis_a"
+
+	// IISTypeB - индивидуальный инвестиционный счет с вычетом на доход (тип Б)
+	IISTypeB AccountType = "iis_b"
+)
+
+// AccountTypes содержит список всех возможных значений AccountType
+var AccountTypes = []AccountType{BrokerageAccount, IISTypeA, IISTypeB}
+
+// ParseAccountType разбирает тип счета из строки
+func ParseAccountType(s string) (AccountType, error) {
+	for _, t := range AccountTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+
+	return BrokerageAccount, fmt.Errorf("\"%s\" is not a valid account type, valid values are: brokerage, iis_a, iis_b", s)
+}
+
+const (
+	// highTaxRate - ставка НДФЛ для доходов свыше highTaxThreshold
+	highTaxRate = 0.15
+
+	// highTaxThreshold - порог дохода, свыше которого применяется повышенная ставка НДФЛ, в рублях
+	highTaxThreshold = 5000000.0
+
+	// iisDeductionLimit - максимальная сумма взноса на ИИС, с которой предоставляется вычет, в рублях
+	iisDeductionLimit = 400000.0
+
+	// longTermOwnershipDays - срок владения, после которого доход от погашения освобождается от НДФЛ, дней
+	longTermOwnershipDays = 3 * 365
+
+	// defaultBrokerFeeRate - комиссия брокера по умолчанию, в долях от суммы сделки
+	defaultBrokerFeeRate = 0.0005
+)
+
+// BrokerFeeTier - ступень тарифа брокера
+type BrokerFeeTier struct {
+	// Минимальная сумма сделки, с которой применяется ставка, в рублях
+	MinAmount float64
+
+	// Ставка комиссии, в долях от суммы сделки
+	Rate float64
+}
+
+// BrokerFeeSchedule - тариф брокера, состоящий из ступеней
+type BrokerFeeSchedule []BrokerFeeTier
+
+// ParseBrokerFeeSchedule разбирает тариф брокера из строки
+// Строка имеет формат "RATE" либо "AMOUNT:RATE,AMOUNT:RATE,...", ставки указываются в процентах
+func ParseBrokerFeeSchedule(s string) (BrokerFeeSchedule, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	schedule := make(BrokerFeeSchedule, 0)
+	for _, part := range strings.Split(s, ",") {
+		tier := BrokerFeeTier{}
+
+		fields := strings.SplitN(strings.TrimSpace(part), ":", 2)
+		rateStr := fields[0]
+		if len(fields) == 2 {
+			amount, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil || amount < 0 {
+				return nil, fmt.Errorf("\"%s\" is not a valid broker fee tier", part)
+			}
+			tier.MinAmount = amount
+			rateStr = fields[1]
+		}
+
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("\"%s\" is not a valid broker fee tier", part)
+		}
+		tier.Rate = rate / 100.0
+
+		schedule = append(schedule, tier)
+	}
+
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i].MinAmount < schedule[j].MinAmount
+	})
+
+	return schedule, nil
+}
+
+// Rate возвращает ставку комиссии брокера для сделки на указанную сумму
+func (s BrokerFeeSchedule) Rate(amount float64) float64 {
+	rate := 0.0
+	for _, tier := range s {
+		if amount >= tier.MinAmount {
+			rate = tier.Rate
+		}
+	}
+
+	return rate
+}
+
+// String преобразует тариф в строку в формате ParseBrokerFeeSchedule
+func (s BrokerFeeSchedule) String() string {
+	parts := make([]string, len(s))
+	for i, tier := range s {
+		rate := strconv.FormatFloat(tier.Rate*100.0, 'f', -1, 64)
+		if tier.MinAmount > 0 {
+			parts[i] = fmt.Sprintf("%s:%s", strconv.FormatFloat(tier.MinAmount, 'f', -1, 64), rate)
+		} else {
+			parts[i] = rate
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// CostModel описывает модель комиссий и налогов, применяемую при расчете доходности
+type CostModel struct {
+	// Тариф брокера
+	BrokerFee BrokerFeeSchedule
+
+	// Минимальная комиссия брокера за сделку, в рублях
+	MinBrokerFee float64
+
+	// Комиссия биржи, в долях от суммы сделки
+	ExchangeFeeRate float64
+
+	// Тип счета
+	Account AccountType
+
+	// Применять ли освобождение от НДФЛ при владении облигацией более 3 лет
+	LongTermExemption bool
+
+	// Освобождены ли от НДФЛ купоны по ОФЗ, субфедеральным и муниципальным облигациям
+	GovCouponsTaxExempt bool
+}
+
+// DefaultCostModel возвращает модель комиссий и налогов, которая используется при расчете отчетов в БД
+func DefaultCostModel() *CostModel {
+	return &CostModel{
+		BrokerFee: BrokerFeeSchedule{{MinAmount: 0, Rate: defaultBrokerFeeRate}},
+		Account:   BrokerageAccount,
+	}
+}
+
+// Fee возвращает сумму комиссий (брокера и биржи) за сделку на указанную сумму, в рублях
+func (m *CostModel) Fee(amount float64) float64 {
+	brokerFee := math.Max(amount*m.BrokerFee.Rate(amount), m.MinBrokerFee)
+	exchangeFee := amount * m.ExchangeFeeRate
+	return round2(brokerFee + exchangeFee)
+}
+
+// CouponTaxRate возвращает ставку налога, удерживаемого при выплате купонов по облигации
+func (m *CostModel) CouponTaxRate(bond *data.Bond) float64 {
+	if m.Account == IISTypeB {
+		return 0
+	}
+
+	if m.GovCouponsTaxExempt && bond != nil {
+		switch bond.Type {
+		case data.OFZBond, data.SubfederalBond, data.MunicipalBond:
+			return 0
+		}
+	}
+
+	return couponTaxRate
+}
+
+// IncomeTax рассчитывает НДФЛ с дохода за год по прогрессивной шкале
+func (m *CostModel) IncomeTax(income float64) float64 {
+	if income <= 0 || m.Account == IISTypeB {
+		return 0
+	}
+
+	tax := math.Min(income, highTaxThreshold) * couponTaxRate
+	if income > highTaxThreshold {
+		tax += (income - highTaxThreshold) * highTaxRate
+	}
+
+	return round2(tax)
+}
+
+// taxableIncome рассчитывает налогооблагаемый доход по позиции из quantity облигаций по годам его получения
+// Купонный доход относится к году выплаты купона и уменьшается на НКД, уплаченный при покупке,
+// доход от погашения (за вычетом комиссии за покупку) относится к году последней выплаты номинала
+// Если таблица выплат не загружена, то весь доход относится к году погашения
+func (m *CostModel) taxableIncome(now time.Time, report *Report, quantity int) map[int]float64 {
+	income := make(map[int]float64)
+	quantityF := float64(quantity)
+	maturityYear := now.AddDate(0, 0, report.DaysTillMaturity).Year()
+
+	if m.CouponTaxRate(report.Bond) > 0 {
+		paidInterest := report.OpenAccruedInterest * quantityF
+		if len(report.CashFlow) == 0 {
+			income[maturityYear] += math.Max(report.CouponPayments-paidInterest, 0)
+		}
+		for _, item := range report.CashFlow {
+			if item.Type == Coupon {
+				income[item.Date.Year()] += math.Max(item.ValueRub-paidInterest, 0)
+				paidInterest = math.Max(paidInterest-item.ValueRub, 0)
+			}
+		}
+	}
+
+	if principalGain, year := m.principalIncome(now, report, quantity); principalGain > 0 {
+		income[year] += principalGain
+	}
+
+	return income
+}
+
+// principalIncome возвращает налогооблагаемый доход от погашения номинала по позиции из quantity облигаций
+// (за вычетом комиссии за покупку) и год его получения - год последней выплаты номинала
+// Если доход освобожден от НДФЛ при длительном владении, то возвращается 0
+func (m *CostModel) principalIncome(now time.Time, report *Report, quantity int) (float64, int) {
+	year := now.AddDate(0, 0, report.DaysTillMaturity).Year()
+	for _, item := range report.CashFlow {
+		if item.Type != Coupon {
+			year = item.Date.Year()
+		}
+	}
+
+	if m.LongTermExemption && report.DaysTillMaturity >= longTermOwnershipDays {
+		return 0, year
+	}
+
+	gain := report.AmortizationPayments + report.MaturityPayment - (report.OpenValue - report.OpenAccruedInterest*float64(quantity)) - report.OpenFee
+	return math.Max(gain, 0), year
+}
+
+// incomeTaxes рассчитывает НДФЛ по позициям портфеля по их налогооблагаемому доходу (см. taxableIncome)
+// Прогрессивная шкала применяется к совокупному доходу всего портфеля за каждый год,
+// после чего налог за год распределяется между позициями пропорционально их доходу за этот год
+func (m *CostModel) incomeTaxes(incomes []map[int]float64) []float64 {
+	totals := make(map[int]float64)
+	for _, income := range incomes {
+		for year, value := range income {
+			totals[year] += value
+		}
+	}
+
+	taxes := make([]float64, len(incomes))
+	for year, total := range totals {
+		if total <= 0 {
+			continue
+		}
+
+		tax := m.IncomeTax(total)
+		for i, income := range incomes {
+			taxes[i] += tax * income[year] / total
+		}
+	}
+
+	for i := range taxes {
+		taxes[i] = round2(taxes[i])
+	}
+
+	return taxes
+}
+
+// TaxDeduction возвращает сумму налогового вычета на взнос указанной суммы
+// Вычет предоставляется только для ИИС типа А
+func (m *CostModel) TaxDeduction(amount float64) float64 {
+	if m.Account != IISTypeA {
+		return 0
+	}
+
+	return round2(math.Min(amount, iisDeductionLimit) * couponTaxRate)
+}
+
+// ApplyCostModel пересчитывает отчет по одной облигации с учетом модели комиссий и налогов
+// Исходный отчет не изменяется
+func ApplyCostModel(report *Report, model *CostModel) *Report {
+	return applyCostModel(today(), report, 1, model)
+}
+
+// applyCostModel пересчитывает отчет по позиции из quantity облигаций с учетом модели комиссий и налогов
+// Отчет должен содержать суммы, уже пересчитанные на размер позиции
+// Если таблица выплат не загружена, то доходность к погашению не пересчитывается
+func applyCostModel(now time.Time, report *Report, quantity int, model *CostModel) *Report {
+	r := *report
+	r.OpenFee = model.Fee(r.OpenValue)
+
+	taxes := model.incomeTaxes([]map[int]float64{model.taxableIncome(now, &r, quantity)})
+	applyTaxes(now, &r, model.CouponTaxRate(r.Bond), taxes[0])
+
+	if r.ToOffer != nil {
+		r.ToOffer = applyCostModel(now, r.ToOffer, quantity, model)
+		r.YieldToOffer = r.ToOffer.YieldToMaturity
+	} else if r.OfferDate == nil || r.DaysTillOffer == r.DaysTillMaturity {
+		r.YieldToOffer = r.YieldToMaturity
+	}
+
+	return &r
+}
+
+// applyPortfolioCostModel пересчитывает налоги по позициям портфеля, применяя прогрессивную шкалу НДФЛ
+// к совокупному доходу портфеля за каждый год (см. incomeTaxes)
+// Позиции должны быть уже пересчитаны по той же модели комиссий и налогов (см. applyCostModel)
+func applyPortfolioCostModel(now time.Time, positions []*SuggestedPortfolioPosition, model *CostModel) {
+	incomes := make([]map[int]float64, len(positions))
+	for i, p := range positions {
+		incomes[i] = model.taxableIncome(now, &p.Report, p.Quantity)
+	}
+
+	taxes := model.incomeTaxes(incomes)
+	for i, p := range positions {
+		applyTaxes(now, &p.Report, model.CouponTaxRate(p.Bond), taxes[i])
+	}
+}
+
+// applyTaxes пересчитывает прибыль и доходность отчета с учетом суммы налогов taxes
+// Налог с купонов удерживается по ставке couponRate в дату их выплаты (см. yieldCashFlows)
+func applyTaxes(now time.Time, r *Report, couponRate, taxes float64) {
+	r.Taxes = taxes
+	r.ProfitLoss = round2(r.Revenue - r.OpenValue - r.OpenFee - r.Taxes)
+	r.RelativeProfitLoss = 0
+	r.InterestRate = 0
+	if r.OpenValue > 0 {
+		r.RelativeProfitLoss = round2(100.0 * r.ProfitLoss / r.OpenValue)
+	}
+	if r.DaysTillMaturity > 0 {
+		r.InterestRate = round2(r.RelativeProfitLoss / (float64(r.DaysTillMaturity) / daysInYear))
+	}
+
+	if len(r.CashFlow) > 0 {
+		r.YieldToMaturity = r.InterestRate
+		if ytm, ok := computeYieldToMaturity(now, r, couponRate); ok {
+			r.YieldToMaturity = ytm
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestParseBrokerFeeSchedule(t *testing.T) {
+	assert := assertion.New(t)
+
+	schedule, err := ParseBrokerFeeSchedule("100000:0.03, 0.05")
+	assert.Nil(err)
+	assert.Len(schedule, 2)
+	assert.InDelta(0.0005, schedule.Rate(1000), 1e-9)
+	assert.InDelta(0.0003, schedule.Rate(100000), 1e-9)
+	assert.Equal("0.05,100000:0.03", schedule.String())
+
+	schedule, err = ParseBrokerFeeSchedule("")
+	assert.Nil(err)
+	assert.Nil(schedule)
+
+	_, err = ParseBrokerFeeSchedule("abc")
+	assert.NotNil(err)
+
+	_, err = ParseBrokerFeeSchedule("1000:-1")
+	assert.NotNil(err)
+}
+
+func TestCostModel(t *testing.T) {
+	assert := assertion.New(t)
+
+	model := &CostModel{
+		BrokerFee:       BrokerFeeSchedule{{MinAmount: 0, Rate: 0.001}},
+		MinBrokerFee:    5,
+		ExchangeFeeRate: 0.0001,
+		Account:         BrokerageAccount,
+	}
+
+	// Минимальная комиссия брокера
+	assert.Equal(5.1, model.Fee(1000))
+	assert.Equal(float64(110), model.Fee(100000))
+
+	// Прогрессивная шкала НДФЛ
+	assert.Equal(float64(130), model.IncomeTax(1000))
+	assert.Equal(650000.0+150000.0, model.IncomeTax(6000000))
+	assert.Equal(float64(0), model.IncomeTax(-100))
+
+	// Освобождение от НДФЛ купонов по ОФЗ
+	ofz := &data.Bond{Type: data.OFZBond}
+	corporate := &data.Bond{Type: data.CorporateBond}
+	assert.Equal(couponTaxRate, model.CouponTaxRate(ofz))
+	model.GovCouponsTaxExempt = true
+	assert.Equal(float64(0), model.CouponTaxRate(ofz))
+	assert.Equal(couponTaxRate, model.CouponTaxRate(corporate))
+
+	// ИИС
+	assert.Equal(float64(0), model.TaxDeduction(100000))
+	model.Account = IISTypeA
+	assert.Equal(float64(13000), model.TaxDeduction(100000))
+	assert.Equal(float64(52000), model.TaxDeduction(1000000))
+	model.Account = IISTypeB
+	assert.Equal(float64(0), model.IncomeTax(1000))
+	assert.Equal(float64(0), model.CouponTaxRate(corporate))
+}
+
+func TestApplyCostModel(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report := &Report{
+		Bond:             &data.Bond{Type: data.CorporateBond},
+		DaysTillMaturity: 365,
+		OpenPrice:        100,
+		OpenFaceValue:    1000,
+		OpenValue:        1000,
+		OpenFee:          0.5,
+		CouponPayments:   100,
+		MaturityPayment:  1000,
+		Revenue:          1100,
+		CashFlow: []*CashFlowItem{
+			{Type: Coupon, Date: t0.AddDate(0, 6, 0), ValueRub: 50},
+			{Type: Coupon, Date: t0.AddDate(1, 0, 0), ValueRub: 50},
+			{Type: Maturity, Date: t0.AddDate(1, 0, 0), ValueRub: 1000},
+		},
+	}
+
+	r := applyCostModel(t0, report, 1, DefaultCostModel())
+	assert.Equal(0.5, r.OpenFee)
+	assert.Equal(float64(13), r.Taxes)
+	assert.Equal(86.5, r.ProfitLoss)
+
+	r = applyCostModel(t0, report, 1, &CostModel{Account: IISTypeB})
+	assert.Equal(float64(0), r.OpenFee)
+	assert.Equal(float64(0), r.Taxes)
+	assert.Equal(float64(100), r.ProfitLoss)
+	assert.Greater(r.YieldToMaturity, 10.0)
+
+	// Исходный отчет не изменился
+	assert.Equal(0.5, report.OpenFee)
+	assert.Equal(float64(0), report.Taxes)
+}
+
+func TestCostModel_IncomeTaxes(t *testing.T) {
+	assert := assertion.New(t)
+
+	model := DefaultCostModel()
+
+	// Повышенная ставка применяется к совокупному доходу портфеля за год, а не к доходу каждой позиции
+	taxes := model.incomeTaxes([]map[int]float64{{2021: 3000000}, {2021: 3000000}})
+	assert.Equal([]float64{400000, 400000}, taxes)
+
+	// Доходы разных лет облагаются отдельно
+	taxes = model.incomeTaxes([]map[int]float64{{2021: 3000000}, {2022: 3000000}})
+	assert.Equal([]float64{390000, 390000}, taxes)
+
+	taxes = model.incomeTaxes([]map[int]float64{{2021: 6000000}, {}})
+	assert.Equal([]float64{800000, 0}, taxes)
+}
+
+func TestApplyPortfolioCostModel(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newPosition := func(quantity int) *SuggestedPortfolioPosition {
+		report := &Report{
+			Bond:             &data.Bond{Type: data.CorporateBond},
+			DaysTillMaturity: 180,
+			OpenPrice:        100,
+			OpenFaceValue:    1000,
+			OpenValue:        1000,
+			CouponPayments:   100,
+			MaturityPayment:  1000,
+			Revenue:          1100,
+			CashFlow: []*CashFlowItem{
+				{Type: Coupon, Date: t0.AddDate(0, 6, 0), ValueRub: 100},
+				{Type: Maturity, Date: t0.AddDate(0, 6, 0), ValueRub: 1000},
+			},
+		}
+		return &SuggestedPortfolioPosition{
+			Report:   *applyCostModel(t0, scaleReport(report, float64(quantity)), quantity, DefaultCostModel()),
+			Quantity: quantity,
+		}
+	}
+
+	// Купонный доход каждой позиции - 3 млн руб., по отдельности он облагается по ставке 13%
+	positions := []*SuggestedPortfolioPosition{newPosition(30000), newPosition(30000)}
+	assert.Equal(float64(390000), positions[0].Taxes)
+
+	// Совокупный доход портфеля за год превышает порог, поэтому часть дохода облагается по ставке 15%
+	applyPortfolioCostModel(t0, positions, DefaultCostModel())
+	assert.Equal(float64(400000), positions[0].Taxes)
+	assert.Equal(float64(400000), positions[1].Taxes)
+	assert.Equal(round2(positions[0].Revenue-positions[0].OpenValue-positions[0].OpenFee-400000), positions[0].ProfitLoss)
+}
@@ -0,0 +1,36 @@
+package recommender
+
+// InOriginalCurrency возвращает копию отчета, суммы в которой пересчитаны из рублей в валюту номинала облигации
+// по курсу, использованному при расчете отчета. Отчеты по облигациям с номиналом в рублях возвращаются без изменений
+func InOriginalCurrency(report *Report) *Report {
+	if report.Bond == nil || report.Bond.FaceUnit == "" || report.Bond.FaceUnit == report.Currency || report.FxRate <= 0 {
+		return report
+	}
+
+	r := *report
+	convert := func(value float64) float64 {
+		return round2(value / report.FxRate)
+	}
+
+	r.Currency = report.Bond.FaceUnit
+	r.FxRate = 1
+	r.OpenAccruedInterest = convert(r.OpenAccruedInterest)
+	r.OpenFaceValue = convert(r.OpenFaceValue)
+	r.OpenFee = convert(r.OpenFee)
+	r.OpenValue = convert(r.OpenValue)
+	r.CouponPayments = convert(r.CouponPayments)
+	r.AmortizationPayments = convert(r.AmortizationPayments)
+	r.MaturityPayment = convert(r.MaturityPayment)
+	r.Taxes = convert(r.Taxes)
+	r.Revenue = convert(r.Revenue)
+	r.ProfitLoss = convert(r.ProfitLoss)
+	r.DV01 = report.DV01 / report.FxRate
+
+	if report.ToOffer != nil {
+		offer := *report.ToOffer
+		offer.FxRate = report.FxRate
+		r.ToOffer = InOriginalCurrency(&offer)
+	}
+
+	return &r
+}
@@ -0,0 +1,48 @@
+package recommender
+
+import (
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestInOriginalCurrency(t *testing.T) {
+	assert := assertion.New(t)
+
+	report := &Report{
+		Bond:            &data.Bond{FaceUnit: "USD"},
+		Currency:        "RUB",
+		FxRate:          80,
+		OpenFaceValue:   80000,
+		OpenValue:       81600,
+		CouponPayments:  8000,
+		MaturityPayment: 80000,
+		Revenue:         88000,
+		ProfitLoss:      6400,
+		InterestRate:    7.84,
+		ToOffer:         &Report{Currency: "RUB", Bond: &data.Bond{FaceUnit: "USD"}, ProfitLoss: 1600},
+	}
+
+	r := InOriginalCurrency(report)
+	assert.Equal("USD", r.Currency)
+	assert.Equal(1000.0, r.OpenFaceValue)
+	assert.Equal(1020.0, r.OpenValue)
+	assert.Equal(100.0, r.CouponPayments)
+	assert.Equal(1000.0, r.MaturityPayment)
+	assert.Equal(1100.0, r.Revenue)
+	assert.Equal(80.0, r.ProfitLoss)
+	assert.Equal(7.84, r.InterestRate)
+	assert.Equal("USD", r.ToOffer.Currency)
+	assert.Equal(20.0, r.ToOffer.ProfitLoss)
+
+	// Исходный отчет не изменяется
+	assert.Equal("RUB", report.Currency)
+	assert.Equal(80000.0, report.OpenFaceValue)
+	assert.Equal(1600.0, report.ToOffer.ProfitLoss)
+
+	// Отчеты по рублевым облигациям не пересчитываются
+	rub := &Report{Bond: &data.Bond{FaceUnit: "RUB"}, Currency: "RUB", FxRate: 1, OpenValue: 1000}
+	assert.Same(rub, InOriginalCurrency(rub))
+}
@@ -0,0 +1,650 @@
+package recommender
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// NSSParams содержит параметры кривой бескупонной доходности Нельсона-Сигеля-Свенссона
+// Бескупонная доходность на срок t лет (в % годовых, с ежегодной капитализацией) рассчитывается как
+// Beta0 + Beta1*f(t/Tau1) + Beta2*(f(t/Tau1) - exp(-t/Tau1)) + Beta3*(f(t/Tau2) - exp(-t/Tau2)),
+// где f(x) = (1 - exp(-x)) / x, а коэффициент дисконтирования на срок t лет - как (1 + Yield(t)/100)^(-t)
+type NSSParams struct {
+	// Долгосрочный уровень доходности, % годовых
+	Beta0 float64
+
+	// Наклон кривой, % годовых
+	Beta1 float64
+
+	// Первый "горб" кривой, % годовых
+	Beta2 float64
+
+	// Второй "горб" кривой, % годовых
+	Beta3 float64
+
+	// Положение первого "горба", лет
+	Tau1 float64
+
+	// Положение второго "горба", лет
+	Tau2 float64
+}
+
+// Yield рассчитывает бескупонную доходность по кривой на срок t лет, % годовых
+func (p NSSParams) Yield(t float64) float64 {
+	f := nssFactors(t, p.Tau1, p.Tau2)
+	return p.Beta0*f[0] + p.Beta1*f[1] + p.Beta2*f[2] + p.Beta3*f[3]
+}
+
+// DiscountFactor рассчитывает коэффициент дисконтирования по кривой на срок t лет
+func (p NSSParams) DiscountFactor(t float64) float64 {
+	return math.Pow(1+p.Yield(t)/100.0, -t)
+}
+
+// nssFactors рассчитывает множители при Beta0..Beta3 для срока t лет
+func nssFactors(t, tau1, tau2 float64) [4]float64 {
+	slope := func(x float64) float64 {
+		if x < 1e-9 {
+			return 1
+		}
+		return (1 - math.Exp(-x)) / x
+	}
+
+	x1, x2 := t/tau1, t/tau2
+	s1, s2 := slope(x1), slope(x2)
+	return [4]float64{1, s1, s1 - math.Exp(-x1), s2 - math.Exp(-x2)}
+}
+
+const (
+	// nssMinPoints - минимальное количество облигаций для построения кривой
+	nssMinPoints = 8
+
+	// nssOutlierSigma - отклонение от кривой (в единицах среднеквадратичной ошибки),
+	// начиная с которого облигация исключается при повторной подгонке кривой
+	nssOutlierSigma = 3
+
+	// nssMaxIterations - максимальное количество итераций уточнения Beta0..Beta3 по ценам облигаций
+	nssMaxIterations = 30
+
+	// curveMinDaysTillMaturity - минимальный срок до погашения облигации, используемой для построения кривой
+	curveMinDaysTillMaturity = 30
+
+	// curveLineStep - шаг по сроку для отображения кривой, лет
+	curveLineStep = 0.1
+)
+
+// nssTauGrid задает начальные значения Tau1 и Tau2 для подгонки кривой
+var nssTauGrid = []float64{0.25, 0.5, 0.75, 1, 1.5, 2, 2.5, 3, 4, 5, 6, 8, 10, 12, 15}
+
+// curvePoint - облигация, по цене которой подбирается кривая
+type curvePoint struct {
+	// Грязная цена (с НКД), в валюте
+	Price float64
+
+	// Доходность к погашению без учета налогов и комиссий, % годовых
+	Yield float64
+
+	// Дюрация Маколея, лет
+	Duration float64
+
+	// Предстоящие выплаты без учета налогов
+	Flows []curveFlow
+}
+
+// curveFlow - выплата по облигации
+type curveFlow struct {
+	// Срок до выплаты, лет
+	Time float64
+
+	// Сумма выплаты, в валюте
+	Value float64
+}
+
+// newCurvePoint формирует точку кривой по отчету на дату now
+// Используются рыночная цена и выплаты без учета налогов и комиссий: кривая описывает рыночные цены ОФЗ
+// и не зависит от модели налогов и комиссий
+// Если выплаты неизвестны или доходность рассчитать не удалось, то возвращается false
+func newCurvePoint(now time.Time, report *Report) (curvePoint, bool) {
+	point := curvePoint{Price: report.OpenValue, Duration: report.MacaulayDuration}
+	for _, item := range report.CashFlow {
+		if !item.Date.After(now) || item.ValueRub <= 0 {
+			continue
+		}
+
+		t := item.Date.Sub(now).Hours() / 24.0 / daysInYear
+		point.Flows = append(point.Flows, curveFlow{Time: t, Value: item.ValueRub})
+	}
+	if len(point.Flows) == 0 || point.Duration <= 0 {
+		return curvePoint{}, false
+	}
+
+	yield, ok := point.yieldAt(point.Price)
+	if !ok {
+		return curvePoint{}, false
+	}
+	point.Yield = yield
+
+	return point, true
+}
+
+// price рассчитывает цену облигации по кривой с параметрами params
+func (p curvePoint) price(params NSSParams) float64 {
+	price := 0.0
+	for _, f := range p.Flows {
+		price += f.Value * params.DiscountFactor(f.Time)
+	}
+
+	return price
+}
+
+// residual рассчитывает отклонение цены облигации по кривой от рыночной, пересчитанное в доходность (% годовых),
+// и его производные по Beta0..Beta3
+// Отклонение цены делится на цену и дюрацию, поэтому короткие и длинные облигации вносят в ошибку сопоставимый вклад
+func (p curvePoint) residual(params NSSParams) (float64, [4]float64) {
+	scale := 100.0 / (p.Price * p.Duration)
+
+	var grad [4]float64
+	price := 0.0
+	for _, flow := range p.Flows {
+		f := nssFactors(flow.Time, params.Tau1, params.Tau2)
+		base := 1 + (params.Beta0*f[0]+params.Beta1*f[1]+params.Beta2*f[2]+params.Beta3*f[3])/100.0
+		pv := flow.Value * math.Pow(base, -flow.Time)
+		price += pv
+
+		d := -pv * flow.Time / base / 100.0 * scale
+		for k := 0; k < 4; k++ {
+			grad[k] += d * f[k]
+		}
+	}
+
+	return (price - p.Price) * scale, grad
+}
+
+// fitNSS подбирает параметры кривой методом наименьших квадратов по ценам облигаций:
+// минимизируются отклонения цен облигаций, рассчитанных дисконтированием их выплат по кривой, от рыночных цен
+// Tau1 и Tau2 перебираются по сетке с последующим уточнением вокруг лучшего значения, а Beta0..Beta3 при фиксированных
+// Tau1 и Tau2 находятся методом Левенберга-Марквардта (см. fitNSSBetas)
+// Возвращает параметры кривой и среднеквадратичную ошибку (% годовых)
+// Если точек недостаточно или систему решить не удалось, то возвращается false
+func fitNSS(points []curvePoint) (NSSParams, float64, bool) {
+	if len(points) < nssMinPoints {
+		return NSSParams{}, 0, false
+	}
+
+	var best NSSParams
+	bestSSE, found := math.Inf(1), false
+	try := func(tau1, tau2 float64) {
+		if tau1 <= 0 || tau2 <= tau1 {
+			return
+		}
+
+		params, sse, ok := fitNSSBetas(points, tau1, tau2)
+		if ok && sse < bestSSE {
+			best, bestSSE, found = params, sse, true
+		}
+	}
+
+	for i, tau1 := range nssTauGrid {
+		for _, tau2 := range nssTauGrid[i+1:] {
+			try(tau1, tau2)
+		}
+	}
+	if !found {
+		return NSSParams{}, 0, false
+	}
+
+	for step := 1.5; step > 1.001; step = math.Sqrt(step) {
+		for improved := true; improved; {
+			sse := bestSSE
+			tau1, tau2 := best.Tau1, best.Tau2
+			try(tau1*step, tau2)
+			try(tau1/step, tau2)
+			try(tau1, tau2*step)
+			try(tau1, tau2/step)
+			improved = bestSSE < sse
+		}
+	}
+
+	return best, math.Sqrt(bestSSE / float64(len(points))), true
+}
+
+// fitNSSBetas находит Beta0..Beta3 при фиксированных Tau1 и Tau2
+// Начальное приближение подбирается по доходностям и дюрациям облигаций (при фиксированных Tau1 и Tau2
+// кривая линейна по Beta0..Beta3), затем уточняется по ценам методом Левенберга-Марквардта
+// Возвращает параметры кривой и сумму квадратов отклонений (см. curvePoint.residual)
+func fitNSSBetas(points []curvePoint, tau1, tau2 float64) (NSSParams, float64, bool) {
+	params, ok := fitNSSYieldBetas(points, tau1, tau2)
+	if !ok {
+		return NSSParams{}, 0, false
+	}
+
+	sse := nssSSE(points, params)
+	if math.IsNaN(sse) || math.IsInf(sse, 0) {
+		return NSSParams{}, 0, false
+	}
+
+	mu := 1e-3
+	for iteration := 0; iteration < nssMaxIterations; iteration++ {
+		var a [4][5]float64
+		for _, p := range points {
+			r, grad := p.residual(params)
+			for i := 0; i < 4; i++ {
+				for j := 0; j < 4; j++ {
+					a[i][j] += grad[i] * grad[j]
+				}
+				a[i][4] -= grad[i] * r
+			}
+		}
+
+		improved := false
+		for ; mu < 1e6; mu *= 10 {
+			b := a
+			for i := 0; i < 4; i++ {
+				b[i][i] = a[i][i]*(1+mu) + 1e-12
+			}
+
+			delta, ok := solveLinear4(b)
+			if !ok {
+				continue
+			}
+
+			next := params
+			next.Beta0 += delta[0]
+			next.Beta1 += delta[1]
+			next.Beta2 += delta[2]
+			next.Beta3 += delta[3]
+			if nextSSE := nssSSE(points, next); nextSSE < sse {
+				improved = sse-nextSSE > 1e-12*sse
+				params, sse = next, nextSSE
+				mu = math.Max(mu/10, 1e-9)
+				break
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	return params, sse, true
+}
+
+// fitNSSYieldBetas находит Beta0..Beta3 при фиксированных Tau1 и Tau2 по доходностям и дюрациям облигаций
+// Используется как начальное приближение для подгонки кривой по ценам
+func fitNSSYieldBetas(points []curvePoint, tau1, tau2 float64) (NSSParams, bool) {
+	var a [4][5]float64
+	for _, p := range points {
+		f := nssFactors(p.Duration, tau1, tau2)
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				a[i][j] += f[i] * f[j]
+			}
+			a[i][4] += f[i] * p.Yield
+		}
+	}
+
+	betas, ok := solveLinear4(a)
+	if !ok {
+		return NSSParams{}, false
+	}
+
+	return NSSParams{Beta0: betas[0], Beta1: betas[1], Beta2: betas[2], Beta3: betas[3], Tau1: tau1, Tau2: tau2}, true
+}
+
+// nssSSE рассчитывает сумму квадратов отклонений цен облигаций по кривой от рыночных (см. curvePoint.residual)
+func nssSSE(points []curvePoint, params NSSParams) float64 {
+	sse := 0.0
+	for _, p := range points {
+		r, _ := p.residual(params)
+		sse += r * r
+	}
+
+	return sse
+}
+
+// solveLinear4 решает систему из 4 линейных уравнений, заданную расширенной матрицей, методом Гаусса
+// Если система вырождена, то возвращается false
+func solveLinear4(a [4][5]float64) ([4]float64, bool) {
+	var x [4]float64
+	for col := 0; col < 4; col++ {
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return x, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		for row := col + 1; row < 4; row++ {
+			k := a[row][col] / a[col][col]
+			for j := col; j < 5; j++ {
+				a[row][j] -= k * a[col][j]
+			}
+		}
+	}
+
+	for row := 3; row >= 0; row-- {
+		v := a[row][4]
+		for j := row + 1; j < 4; j++ {
+			v -= a[row][j] * x[j]
+		}
+		x[row] = v / a[row][row]
+	}
+
+	return x, true
+}
+
+// fitYieldCurve подбирает кривую по ценам облигаций
+// После первой подгонки облигации, отклоняющиеся от кривой более чем на nssOutlierSigma ошибок, исключаются,
+// и кривая подбирается повторно
+// Возвращает параметры кривой, среднеквадратичную ошибку и количество использованных облигаций
+func fitYieldCurve(points []curvePoint) (NSSParams, float64, int, bool) {
+	params, rmse, ok := fitNSS(points)
+	if !ok {
+		return NSSParams{}, 0, 0, false
+	}
+
+	inliers := make([]curvePoint, 0, len(points))
+	for _, p := range points {
+		if r, _ := p.residual(params); math.Abs(r) <= nssOutlierSigma*rmse {
+			inliers = append(inliers, p)
+		}
+	}
+	if len(inliers) == len(points) {
+		return params, rmse, len(points), true
+	}
+
+	refined, refinedRMSE, ok := fitNSS(inliers)
+	if !ok {
+		return params, rmse, len(points), true
+	}
+
+	return refined, refinedRMSE, len(inliers), true
+}
+
+// YieldCurve - кривая бескупонной доходности ОФЗ
+// Подбирается по рыночным ценам ОФЗ с постоянным купоном и их выплатам без учета налогов и комиссий,
+// поэтому доходности и спреды облигаций относительно нее тоже рассчитываются без учета налогов и комиссий
+type YieldCurve struct {
+	// Дата построения кривой
+	Date time.Time
+
+	// Параметры кривой
+	Params NSSParams
+
+	// Среднеквадратичное отклонение цен облигаций от кривой при подгонке, в пересчете на доходность, % годовых
+	RMSE float64
+
+	// Количество облигаций, использованных при подгонке
+	PointCount int
+
+	// ОФЗ с текущими доходностями, отсортированные по дюрации
+	Points []*YieldCurvePoint
+
+	// Точки кривой для отображения, отсортированные по сроку
+	Line []*YieldCurveLinePoint
+}
+
+// YieldCurvePoint - положение ОФЗ относительно кривой доходности
+type YieldCurvePoint struct {
+	// Отчет по облигации
+	Report *Report
+
+	// Дюрация Маколея, лет
+	Duration float64
+
+	// Доходность к погашению без учета налогов и комиссий, % годовых
+	Yield float64
+
+	// Доходность к погашению по цене, рассчитанной дисконтированием выплат облигации по кривой, % годовых
+	CurveYield float64
+
+	// Отклонение доходности от кривой, б.п.
+	// Положительное значение означает, что облигация дешевле кривой
+	Deviation float64
+}
+
+// YieldCurveLinePoint - точка кривой доходности
+type YieldCurveLinePoint struct {
+	// Срок, лет
+	Duration float64
+
+	// Бескупонная доходность, % годовых
+	Yield float64
+}
+
+// isCurveBond проверяет, используется ли облигация для построения кривой доходности
+// Кривая строится только по ОФЗ с постоянным купоном (ОФЗ-ПД, коды SU25xxx и SU26xxx):
+// доходности флоатеров и линкеров рассчитываются по известным купонам и искажают кривую
+func isCurveBond(report *Report) bool {
+	return isCurveSecurity(report.Bond.SecurityID) &&
+		report.DaysTillMaturity >= curveMinDaysTillMaturity &&
+		report.MacaulayDuration > 0 &&
+		report.YieldToMaturity != 0
+}
+
+// isCurveHistoryRecord проверяет, используется ли запись истории рыночных данных ОФЗ как точка кривой доходности
+// на дату записи (по тем же правилам, что и isCurveBond)
+// Облигация должна быть загружена вместе с записью
+func isCurveHistoryRecord(record *data.HistoryRecord) bool {
+	if !isCurveSecurity(record.Bond.SecurityID) || !record.Bond.MaturityDate.Valid ||
+		record.YieldToMaturity == nil || record.DurationDays == nil {
+		return false
+	}
+
+	daysTillMaturity := record.Bond.MaturityDate.Time.Sub(record.Time).Hours() / 24
+	return daysTillMaturity >= curveMinDaysTillMaturity &&
+		*record.DurationDays > 0 &&
+		*record.YieldToMaturity != 0
+}
+
+// isCurveSecurity проверяет, является ли облигация ОФЗ с постоянным купоном (ОФЗ-ПД) по ее коду
+func isCurveSecurity(code string) bool {
+	return strings.HasPrefix(code, "SU25") || strings.HasPrefix(code, "SU26")
+}
+
+// selectCurvePoints отбирает отчеты по ОФЗ, которые используются для построения кривой доходности на дату now,
+// и формирует по ним точки кривой
+// ОФЗ отбираются по коллекции "ofz" сервиса и условию isCurveBond, отчеты должны содержать таблицы выплат
+// Направление сортировки - по возрастанию дюрации
+func (s *service) selectCurvePoints(now time.Time, reports []*Report) ([]*Report, []curvePoint) {
+	coll, exists := s.collections["ofz"]
+	if !exists {
+		return nil, nil
+	}
+
+	reports = filterReports(coll.filter(reports), isCurveBond)
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].MacaulayDuration < reports[j].MacaulayDuration
+	})
+
+	selected := make([]*Report, 0, len(reports))
+	points := make([]curvePoint, 0, len(reports))
+	for _, r := range reports {
+		if point, ok := newCurvePoint(now, r); ok {
+			selected = append(selected, r)
+			points = append(points, point)
+		}
+	}
+
+	return selected, points
+}
+
+// listCurveReports возвращает текущие отчеты по ОФЗ вместе с таблицами выплат
+// Отчеты рассчитываются так же, как при перестроении показателей (см. rebuildMetrics)
+func (s *service) listCurveReports(tx *data.TX) ([]*Report, error) {
+	sql := `
+SELECT reports.bond_id, reports.bond_id AS index
+FROM reports
+INNER JOIN bonds ON bonds.id = reports.bond_id
+WHERE bonds.type = ?
+`
+	entities, err := tx.Reports.List(0, sql, data.OFZBond)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, len(entities))
+	for i, entity := range entities {
+		reports[i] = mapReport(entity)
+		err = s.enrichWithCashFlow(tx, reports[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return reports, nil
+}
+
+// rebuildYieldCurve подбирает кривую доходности ОФЗ по ценам и выплатам ОФЗ из отчетов и сохраняет ее параметры на дату now
+// Если ОФЗ недостаточно для построения кривой, то возвращается последняя сохраненная кривая,
+// а если сохраненных кривых нет - false
+func (s *service) rebuildYieldCurve(tx *data.TX, now time.Time, reports []*Report) (NSSParams, bool, error) {
+	_, points := s.selectCurvePoints(now, reports)
+	params, rmse, count, ok := fitYieldCurve(points)
+	if !ok {
+		entity, err := tx.YieldCurves.GetLast()
+		if err != nil {
+			if err == data.ErrNotFound {
+				return NSSParams{}, false, nil
+			}
+			return NSSParams{}, false, err
+		}
+
+		return mapNSSParams(entity), true, nil
+	}
+
+	_, err := tx.YieldCurves.Put(data.PutYieldCurveArgs{
+		Date:   now,
+		Beta0:  params.Beta0,
+		Beta1:  params.Beta1,
+		Beta2:  params.Beta2,
+		Beta3:  params.Beta3,
+		Tau1:   params.Tau1,
+		Tau2:   params.Tau2,
+		RMSE:   rmse,
+		Points: count,
+	})
+	if err != nil {
+		return NSSParams{}, false, err
+	}
+
+	return params, true, nil
+}
+
+// mapNSSParams возвращает параметры сохраненной кривой доходности
+func mapNSSParams(entity *data.YieldCurve) NSSParams {
+	return NSSParams{
+		Beta0: entity.Beta0,
+		Beta1: entity.Beta1,
+		Beta2: entity.Beta2,
+		Beta3: entity.Beta3,
+		Tau1:  entity.Tau1,
+		Tau2:  entity.Tau2,
+	}
+}
+
+// GetYieldCurve возвращает последнюю построенную кривую доходности ОФЗ и положение текущих ОФЗ относительно нее
+// ОФЗ отбираются так же, как при построении кривой
+// Если кривая еще не построена, то возвращается ошибка ErrNotFound
+func (s *service) GetYieldCurve(ctx context.Context, tx *data.TX) (*YieldCurve, error) {
+	entity, err := tx.YieldCurves.GetLast()
+	if err != nil {
+		if err == data.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	reports, err := s.listCurveReports(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := today()
+	reports, points := s.selectCurvePoints(now, reports)
+	return newYieldCurve(entity.Date, mapNSSParams(entity), entity.RMSE, entity.Points, reports, points), nil
+}
+
+// newYieldCurve рассчитывает отклонения ОФЗ от кривой и точки кривой для отображения
+// Отчеты reports и точки points должны соответствовать друг другу (см. selectCurvePoints)
+// Кривая отображается до максимальной дюрации облигаций (но не менее чем на 1 год)
+func newYieldCurve(date time.Time, params NSSParams, rmse float64, count int, reports []*Report, points []curvePoint) *YieldCurve {
+	curve := &YieldCurve{
+		Date:       date,
+		Params:     params,
+		RMSE:       rmse,
+		PointCount: count,
+		Points:     make([]*YieldCurvePoint, 0, len(reports)),
+		Line:       make([]*YieldCurveLinePoint, 0),
+	}
+
+	maxDuration := 1.0
+	for i, r := range reports {
+		curveYield, ok := points[i].curveYield(params)
+		if !ok {
+			continue
+		}
+
+		curve.Points = append(curve.Points, &YieldCurvePoint{
+			Report:     r,
+			Duration:   r.MacaulayDuration,
+			Yield:      round2(points[i].Yield),
+			CurveYield: round2(curveYield),
+			Deviation:  round2(100.0 * (points[i].Yield - curveYield)),
+		})
+		maxDuration = math.Max(maxDuration, r.MacaulayDuration)
+	}
+
+	for t := curveLineStep; t <= maxDuration+curveLineStep/2; t += curveLineStep {
+		curve.Line = append(curve.Line, &YieldCurveLinePoint{Duration: round2(t), Yield: round2(params.Yield(t))})
+	}
+
+	return curve
+}
+
+// curveYield рассчитывает доходность к погашению облигации по цене, рассчитанной дисконтированием ее выплат по кривой,
+// % годовых
+// Если доходность рассчитать не удалось, то возвращается false
+func (p curvePoint) curveYield(params NSSParams) (float64, bool) {
+	return p.yieldAt(p.price(params))
+}
+
+// yieldAt рассчитывает эффективную доходность к погашению облигации по грязной цене price, % годовых
+// Доходность находится методом бисекции: цена облигации с положительными выплатами убывает с ростом доходности
+// Если доходность рассчитать не удалось, то возвращается false
+func (p curvePoint) yieldAt(price float64) (float64, bool) {
+	if price <= 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, false
+	}
+
+	npv := func(rate float64) float64 {
+		sum := -price
+		for _, f := range p.Flows {
+			sum += f.Value * math.Pow(1+rate, -f.Time)
+		}
+		return sum
+	}
+
+	low, high := -0.99, 10.0
+	if npv(low) < 0 || npv(high) > 0 {
+		return 0, false
+	}
+	for i := 0; i < 100 && high-low > 1e-12; i++ {
+		mid := (low + high) / 2
+		if npv(mid) > 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return 100.0 * (low + high) / 2, true
+}
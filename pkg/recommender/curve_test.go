@@ -0,0 +1,188 @@
+package recommender
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+var testNSSParams = NSSParams{Beta0: 9, Beta1: -2, Beta2: 3, Beta3: -1.5, Tau1: 1.2, Tau2: 7}
+
+var testCurveDate = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestCurveReport формирует отчет по ОФЗ с ежегодным купоном 70 руб. и погашением через months месяцев
+// Грязная цена облигации рассчитывается дисконтированием выплат по кривой params, сдвинутой на spread (% годовых)
+func newTestCurveReport(id, months int, params NSSParams, spread float64) *Report {
+	inn := "7710168360"
+	maturity := testCurveDate.AddDate(0, months, 0)
+	report := &Report{
+		Bond: &data.Bond{
+			ID:           id,
+			SecurityID:   fmt.Sprintf("SU26%03dRMFS0", id),
+			Type:         data.OFZBond,
+			IsTraded:     true,
+			ListingLevel: 1,
+			FaceUnit:     "RUB",
+			MaturityDate: sql.NullTime{Time: maturity, Valid: true},
+		},
+		Issuer:           &data.Issuer{INN: &inn},
+		DaysTillMaturity: int(maturity.Sub(testCurveDate).Hours() / 24),
+		YieldToMaturity:  7,
+	}
+	for date := maturity; date.After(testCurveDate); date = date.AddDate(-1, 0, 0) {
+		report.CashFlow = append([]*CashFlowItem{{Type: Coupon, Date: date, Value: 70, ValueRub: 70}}, report.CashFlow...)
+	}
+	report.CashFlow = append(report.CashFlow, &CashFlowItem{Type: Maturity, Date: maturity, Value: 1000, ValueRub: 1000})
+
+	duration := 0.0
+	for _, item := range report.CashFlow {
+		t := item.Date.Sub(testCurveDate).Hours() / 24.0 / daysInYear
+		pv := item.ValueRub * math.Pow(1+(params.Yield(t)+spread)/100.0, -t)
+		report.OpenValue += pv
+		duration += t * pv
+	}
+	report.MacaulayDuration = duration / report.OpenValue
+
+	return report
+}
+
+func newTestCurvePoints() []curvePoint {
+	points := make([]curvePoint, 0)
+	for i, months := 0, 4; months <= 144; i, months = i+1, months+7 {
+		point, _ := newCurvePoint(testCurveDate, newTestCurveReport(i+1, months, testNSSParams, 0))
+		points = append(points, point)
+	}
+
+	return points
+}
+
+func TestNSSParams_Yield(t *testing.T) {
+	assert := assertion.New(t)
+
+	// В нуле доходность равна Beta0 + Beta1, на бесконечности стремится к Beta0
+	assert.InDelta(testNSSParams.Beta0+testNSSParams.Beta1, testNSSParams.Yield(0), 1e-9)
+	assert.InDelta(testNSSParams.Beta0, testNSSParams.Yield(1000), 0.01)
+	assert.InDelta(1/math.Pow(1+testNSSParams.Yield(2)/100, 2), testNSSParams.DiscountFactor(2), 1e-12)
+}
+
+func TestFitNSS(t *testing.T) {
+	assert := assertion.New(t)
+
+	// Кривая подбирается по ценам облигаций и восстанавливает исходные коэффициенты дисконтирования
+	points := newTestCurvePoints()
+	params, rmse, ok := fitNSS(points)
+	assert.True(ok)
+	assert.Less(rmse, 0.01)
+	for _, p := range points {
+		assert.InDelta(p.Price, p.price(params), 0.5)
+	}
+	for _, t := range []float64{0.5, 1, 3, 7, 10} {
+		assert.InDelta(testNSSParams.DiscountFactor(t), params.DiscountFactor(t), 1e-3)
+	}
+
+	_, _, ok = fitNSS(points[:nssMinPoints-1])
+	assert.False(ok)
+}
+
+func TestFitYieldCurve_Outlier(t *testing.T) {
+	assert := assertion.New(t)
+
+	points := newTestCurvePoints()
+	points[5].Price *= 0.95
+
+	params, _, count, ok := fitYieldCurve(points)
+	assert.True(ok)
+	assert.Equal(len(points)-1, count)
+	assert.InDelta(points[10].Price, points[10].price(params), 0.5)
+}
+
+func TestNewYieldCurve(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newTestCurveReport(1, 24, testNSSParams, 0.25),
+		newTestCurveReport(2, 66, testNSSParams, -0.1),
+	}
+	points := make([]curvePoint, len(reports))
+	for i, r := range reports {
+		points[i], _ = newCurvePoint(testCurveDate, r)
+	}
+
+	// Отклонение от кривой - это разница между доходностью по рыночной цене и доходностью по цене по кривой
+	curve := newYieldCurve(testCurveDate, testNSSParams, 0.05, 2, reports, points)
+	assert.Len(curve.Points, 2)
+	assert.InDelta(25, curve.Points[0].Deviation, 1)
+	assert.InDelta(-10, curve.Points[1].Deviation, 1)
+	assert.InDelta(reports[1].MacaulayDuration, curve.Line[len(curve.Line)-1].Duration, curveLineStep)
+}
+
+func TestSelectCurvePoints(t *testing.T) {
+	assert := assertion.New(t)
+
+	s := &service{collections: map[string]*internalCollection{"ofz": collections["ofz"]}}
+	reports := []*Report{
+		newTestCurveReport(2, 66, testNSSParams, 0),
+		newTestCurveReport(1, 24, testNSSParams, 0),
+		newTestCurveReport(3, 36, testNSSParams, 0),
+		newTestCurveReport(4, 48, testNSSParams, 0),
+	}
+	reports[2].Bond.SecurityID = "SU29006RMFS2"
+	reports[3].Bond.IsTraded = false
+
+	// Флоатеры и облигации вне коллекции "ofz" не используются, точки отсортированы по дюрации
+	selected, points := s.selectCurvePoints(testCurveDate, reports)
+	assert.Equal([]*Report{reports[1], reports[0]}, selected)
+	assert.Len(points, 2)
+	assert.Equal(reports[1].OpenValue, points[0].Price)
+
+	s = &service{collections: make(map[string]*internalCollection)}
+	selected, points = s.selectCurvePoints(testCurveDate, reports)
+	assert.Empty(selected)
+	assert.Empty(points)
+}
+
+func TestIsCurveBond(t *testing.T) {
+	assert := assertion.New(t)
+
+	report := func(code string) *Report {
+		return &Report{Bond: &data.Bond{SecurityID: code}, DaysTillMaturity: 365, MacaulayDuration: 1, YieldToMaturity: 7}
+	}
+
+	assert.True(isCurveBond(report("SU26207RMFS9")))
+	assert.True(isCurveBond(report("SU25083RMFS5")))
+	assert.False(isCurveBond(report("SU29006RMFS2")))
+	assert.False(isCurveBond(report("SU52002RMFS1")))
+}
+
+func TestComputeSpread(t *testing.T) {
+	assert := assertion.New(t)
+
+	report := newTestCurveReport(1, 36, testNSSParams, 1.5)
+	spread := computeSpread(testCurveDate, testNSSParams, report)
+	if assert.NotNil(spread) {
+		assert.InDelta(150, *spread, 1)
+	}
+
+	// Для облигаций с номиналом не в рублях и без дюрации спред не рассчитывается
+	report.Bond.FaceUnit = "USD"
+	assert.Nil(computeSpread(testCurveDate, testNSSParams, report))
+	report.Bond.FaceUnit = "RUB"
+	report.MacaulayDuration = 0
+	assert.Nil(computeSpread(testCurveDate, testNSSParams, report))
+}
+
+func TestSupportsRanking(t *testing.T) {
+	assert := assertion.New(t)
+
+	assert.True(SupportsRanking(collections["corporate"], RankBySpread))
+	assert.True(SupportsRanking(collections["highrisk"], RankBySpread))
+	assert.False(SupportsRanking(collections["ofz"], RankBySpread))
+	assert.False(SupportsRanking(collections["currency"], RankBySpread))
+	assert.Equal(RankByYield, collections["ofz"].Rankings()[0])
+}
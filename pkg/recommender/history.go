@@ -0,0 +1,147 @@
+package recommender
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// HistoryRange задает период истории цен
+type HistoryRange string
+
+const (
+	// HistoryRange1Month - последний месяц
+	HistoryRange1Month HistoryRange = "1m"
+
+	// HistoryRange6Months - последние полгода
+	HistoryRange6Months HistoryRange = "6m"
+
+	// HistoryRange1Year - последний год
+	HistoryRange1Year HistoryRange = "1y"
+
+	// HistoryRangeAll - вся доступная история
+	HistoryRangeAll HistoryRange = "all"
+)
+
+// HistoryRanges содержит список всех возможных значений HistoryRange
+var HistoryRanges = []HistoryRange{HistoryRange1Month, HistoryRange6Months, HistoryRange1Year, HistoryRangeAll}
+
+// ParseHistoryRange разбирает период истории цен из строки
+func ParseHistoryRange(s string) (HistoryRange, error) {
+	for _, r := range HistoryRanges {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+
+	return HistoryRange1Year, fmt.Errorf("\"%s\" is not a valid history range, valid values are: 1m, 6m, 1y, all", s)
+}
+
+// From возвращает начало периода относительно момента now
+func (r HistoryRange) From(now time.Time) time.Time {
+	switch r {
+	case HistoryRange1Month:
+		return now.AddDate(0, -1, 0)
+	case HistoryRange6Months:
+		return now.AddDate(0, -6, 0)
+	case HistoryRange1Year:
+		return now.AddDate(-1, 0, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// PriceHistory - история цен и доходностей облигации
+type PriceHistory struct {
+	// Период истории
+	Range HistoryRange
+
+	// Точки истории, отсортированные по дате
+	Points []*PriceHistoryPoint
+}
+
+// PriceHistoryPoint - данные облигации на конец дня
+type PriceHistoryPoint struct {
+	// Дата
+	Date time.Time
+
+	// Чистая цена, в % от номинала
+	Price *float64
+
+	// Доходность к погашению, % годовых
+	Yield *float64
+
+	// Спред доходности к кривой ОФЗ той же дюрации, б.п.
+	Spread *float64
+}
+
+// NewPriceHistory формирует историю цен облигации
+// Спред рассчитывается относительно доходностей ОФЗ (benchmark, вместе с облигациями) на ту же дату,
+// линейно интерполированных по дюрации
+// Как и кривая доходности, спред строится только по ОФЗ с постоянным купоном (см. isCurveBond)
+func NewPriceHistory(r HistoryRange, records, benchmark []*data.HistoryRecord) *PriceHistory {
+	curves := make(map[time.Time][]*data.HistoryRecord)
+	for _, b := range benchmark {
+		if !isCurveHistoryRecord(b) {
+			continue
+		}
+		curves[b.Time] = append(curves[b.Time], b)
+	}
+	for _, curve := range curves {
+		sort.Slice(curve, func(i, j int) bool {
+			return *curve[i].DurationDays < *curve[j].DurationDays
+		})
+	}
+
+	history := &PriceHistory{
+		Range:  r,
+		Points: make([]*PriceHistoryPoint, 0, len(records)),
+	}
+	for _, record := range records {
+		point := &PriceHistoryPoint{
+			Date:  record.Time,
+			Price: record.Price,
+			Yield: record.YieldToMaturity,
+		}
+
+		if record.YieldToMaturity != nil && record.DurationDays != nil {
+			curve := curves[record.Time]
+			if len(curve) > 0 {
+				spread := round2(100.0 * (*record.YieldToMaturity - interpolateYield(curve, *record.DurationDays)))
+				point.Spread = &spread
+			}
+		}
+
+		history.Points = append(history.Points, point)
+	}
+
+	sort.SliceStable(history.Points, func(i, j int) bool {
+		return history.Points[i].Date.Before(history.Points[j].Date)
+	})
+	return history
+}
+
+// interpolateYield рассчитывает доходность по кривой для заданной дюрации
+// Кривая должна быть отсортирована по дюрации, за ее пределами доходность не экстраполируется
+func interpolateYield(curve []*data.HistoryRecord, duration float64) float64 {
+	if duration <= *curve[0].DurationDays {
+		return *curve[0].YieldToMaturity
+	}
+
+	for i := 1; i < len(curve); i++ {
+		x0, x1 := *curve[i-1].DurationDays, *curve[i].DurationDays
+		if duration > x1 {
+			continue
+		}
+
+		y0, y1 := *curve[i-1].YieldToMaturity, *curve[i].YieldToMaturity
+		if x1 == x0 {
+			return (y0 + y1) / 2
+		}
+		return y0 + (y1-y0)*(duration-x0)/(x1-x0)
+	}
+
+	return *curve[len(curve)-1].YieldToMaturity
+}
@@ -0,0 +1,67 @@
+package recommender
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestParseHistoryRange(t *testing.T) {
+	assert := assertion.New(t)
+
+	for _, r := range HistoryRanges {
+		parsed, err := ParseHistoryRange(string(r))
+		assert.NoError(err)
+		assert.Equal(r, parsed)
+	}
+
+	_, err := ParseHistoryRange("2y")
+	assert.Error(err)
+
+	now := time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)
+	assert.Equal(time.Date(2021, 5, 15, 0, 0, 0, 0, time.UTC), HistoryRange1Month.From(now))
+	assert.Equal(time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), HistoryRange1Year.From(now))
+	assert.True(HistoryRangeAll.From(now).IsZero())
+}
+
+func TestNewPriceHistory(t *testing.T) {
+	assert := assertion.New(t)
+
+	f := func(v float64) *float64 { return &v }
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.AddDate(0, 0, 1)
+
+	records := []*data.HistoryRecord{
+		{Time: t1, Price: f(99), YieldToMaturity: f(9), DurationDays: f(500)},
+		{Time: t0, Price: f(100), YieldToMaturity: f(8), DurationDays: f(500)},
+	}
+	ofz := func(code string) data.Bond {
+		return data.Bond{SecurityID: code, MaturityDate: sql.NullTime{Time: t0.AddDate(5, 0, 0), Valid: true}}
+	}
+	benchmark := []*data.HistoryRecord{
+		{Time: t0, YieldToMaturity: f(7), DurationDays: f(900), Bond: ofz("SU26207RMFS9")},
+		{Time: t0, YieldToMaturity: f(6), DurationDays: f(100), Bond: ofz("SU25083RMFS5")},
+		{Time: t0, YieldToMaturity: f(10), DurationDays: nil, Bond: ofz("SU26212RMFS9")},
+		// Флоатеры и линкеры не используются для расчета спреда
+		{Time: t0, YieldToMaturity: f(12), DurationDays: f(500), Bond: ofz("SU29006RMFS2")},
+		{Time: t0, YieldToMaturity: f(3), DurationDays: f(450), Bond: ofz("SU52002RMFS1")},
+	}
+
+	history := NewPriceHistory(HistoryRange1Year, records, benchmark)
+
+	assert.Equal(HistoryRange1Year, history.Range)
+	assert.Len(history.Points, 2)
+
+	// Точки отсортированы по дате, доходность ОФЗ интерполирована: 6.5% для дюрации 500 дней
+	assert.Equal(t0, history.Points[0].Date)
+	assert.Equal(float64(100), *history.Points[0].Price)
+	assert.Equal(float64(150), *history.Points[0].Spread)
+
+	// Нет данных по ОФЗ на дату - спред не рассчитывается
+	assert.Equal(t1, history.Points[1].Date)
+	assert.Nil(history.Points[1].Spread)
+}
@@ -0,0 +1,242 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// MaxSimulationHorizon - максимальный горизонт моделирования, месяцев
+const MaxSimulationHorizon = 10 * 12
+
+// HorizonRequest - параметры моделирования доходности портфеля на горизонте инвестирования
+type HorizonRequest struct {
+	// Горизонт инвестирования, месяцев
+	Horizon int
+
+	// Ставка реинвестирования выплат, % годовых
+	// Если не задана, то выплаты по каждой позиции реинвестируются в облигации той же коллекции по текущим доходностям
+	ReinvestRate *float64
+
+	// Модель комиссий и налогов, по которой был сформирован портфель
+	// Если не задана, то используется модель сервиса
+	Costs *CostModel
+}
+
+// Validate проверяет корректность параметров
+func (r *HorizonRequest) Validate() error {
+	if r.Horizon <= 0 || r.Horizon > MaxSimulationHorizon {
+		return fmt.Errorf("horizon must be in range 1..%d months", MaxSimulationHorizon)
+	}
+	if r.ReinvestRate != nil && *r.ReinvestRate <= -100 {
+		return fmt.Errorf("reinvestment rate must be greater than -100%%")
+	}
+
+	return nil
+}
+
+// HorizonResult - результат моделирования доходности портфеля на горизонте инвестирования
+type HorizonResult struct {
+	// Дата окончания горизонта
+	Date time.Time
+
+	// Ставка реинвестирования выплат (средняя по позициям с учетом их долей), % годовых
+	ReinvestRate float64
+
+	// Сумма вложений с учетом комиссий, в валюте
+	Invested float64
+
+	// Выплаты по портфелю до окончания горизонта (за вычетом налогов), в валюте
+	Received float64
+
+	// Доход от реинвестирования выплат, в валюте
+	ReinvestmentIncome float64
+
+	// Оценка позиций, не погашенных к окончанию горизонта, в валюте
+	MarketValue float64
+
+	// Стоимость портфеля на дату окончания горизонта (денежные средства и непогашенные позиции), в валюте
+	Value float64
+
+	// Прибыль, в валюте
+	ProfitLoss float64
+
+	// Полная доходность за горизонт, %
+	TotalReturn float64
+
+	// Полная доходность в пересчете на год, % годовых
+	AnnualizedReturn float64
+
+	// Результаты по позициям
+	Positions []*HorizonPosition
+}
+
+// HorizonPosition - результат моделирования по позиции портфеля
+type HorizonPosition struct {
+	// Позиция портфеля
+	Position *SuggestedPortfolioPosition
+
+	// Ставка реинвестирования выплат по позиции, % годовых
+	ReinvestRate float64
+
+	// Выплаты по позиции до окончания горизонта (за вычетом налогов), в валюте
+	Received float64
+
+	// Оценка позиции на дату окончания горизонта, в валюте
+	// Если позиция погашена до окончания горизонта, то 0
+	MarketValue float64
+}
+
+// SimulateHorizon моделирует доходность портфеля при владении им в течение горизонта инвестирования
+// Выплаты по позициям до окончания горизонта реинвестируются под заданную ставку либо в облигации той же коллекции (см. reinvestRates),
+// непогашенные позиции оцениваются по своим выплатам после горизонта при неизменной доходности к погашению
+func (s *service) SimulateHorizon(ctx context.Context, tx *data.TX, portfolio *SuggestResult, request *HorizonRequest) (*HorizonResult, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]float64, len(portfolio.Positions))
+	if request.ReinvestRate != nil {
+		for i := range rates {
+			rates[i] = *request.ReinvestRate
+		}
+	} else {
+		rates, err = s.reinvestRates(ctx, tx, portfolio, request.Horizon)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return simulateHorizon(today(), portfolio, request.Horizon, s.costModel(request.Costs), rates), nil
+}
+
+// reinvestRates возвращает ставки реинвестирования выплат по позициям портфеля, % годовых
+// Выплаты по позиции реинвестируются в облигации первой (по ID) коллекции, в которую входит облигация позиции:
+// ставка равна средней доходности облигаций, которые эта коллекция предлагает для срока, ближайшего к горизонту (см. Suggest).
+// Если облигация не входит ни в одну коллекцию или коллекция не предлагает облигаций, то используется доходность портфеля
+func (s *service) reinvestRates(ctx context.Context, tx *data.TX, portfolio *SuggestResult, horizon int) ([]float64, error) {
+	bondIDs := make([]int, len(portfolio.Positions))
+	for i, p := range portfolio.Positions {
+		bondIDs[i] = p.Bond.ID
+	}
+
+	years := (horizon + 11) / 12
+	if years > len(Durations) {
+		years = len(Durations)
+	}
+	duration := Durations[years-1]
+
+	rates := make([]float64, len(portfolio.Positions))
+	for i := range rates {
+		rates[i] = portfolio.YieldToMaturity
+	}
+
+	assigned := make(map[int]bool)
+	for _, collection := range s.ListCollections() {
+		members, err := tx.Reports.List(0, `
+SELECT DISTINCT bond_id, 0 AS index
+FROM collection_bonds
+WHERE collection_id = ? AND bond_id IN (?)
+`, collection.ID(), bondIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		memberIDs := make(map[int]bool)
+		for _, m := range members {
+			if !assigned[m.Bond.ID] {
+				memberIDs[m.Bond.ID] = true
+			}
+		}
+		if len(memberIDs) == 0 {
+			continue
+		}
+
+		reports, err := s.getBondForSuggestion(tx, collection, duration)
+		if err != nil {
+			return nil, err
+		}
+		if len(reports) == 0 {
+			continue
+		}
+
+		rate := 0.0
+		for _, r := range reports {
+			rate += suggestionYield(r)
+		}
+		rate /= float64(len(reports))
+
+		for i, p := range portfolio.Positions {
+			if memberIDs[p.Bond.ID] {
+				rates[i] = rate
+				assigned[p.Bond.ID] = true
+			}
+		}
+	}
+
+	return rates, nil
+}
+
+// simulateHorizon моделирует доходность портфеля на горизонте horizon месяцев, начиная с даты now
+// Выплаты по i-й позиции наращиваются по ставке reinvestRates[i] со сложным процентом, как и при расчете XIRR
+func simulateHorizon(now time.Time, portfolio *SuggestResult, horizon int, costs *CostModel, reinvestRates []float64) *HorizonResult {
+	if costs == nil {
+		costs = DefaultCostModel()
+	}
+
+	end := now.AddDate(0, horizon, 0)
+	years := func(from, till time.Time) float64 {
+		return till.Sub(from).Hours() / 24.0 / daysInYear
+	}
+
+	result := &HorizonResult{
+		Date:      end,
+		Positions: make([]*HorizonPosition, len(portfolio.Positions)),
+	}
+
+	cash := 0.0
+	for i, p := range portfolio.Positions {
+		position := &HorizonPosition{Position: p, ReinvestRate: reinvestRates[i]}
+		result.ReinvestRate += reinvestRates[i] * p.Weight
+
+		flows := yieldCashFlows(now, p.OpenValue, p.OpenFee, costs.CouponTaxRate(p.Bond), p.Taxes, p.CashFlow)
+		result.Invested -= flows[0].Value
+		for _, flow := range flows[1:] {
+			if !flow.Date.After(end) {
+				position.Received += flow.Value
+				cash += flow.Value * math.Pow(1+position.ReinvestRate/100.0, years(flow.Date, end))
+			} else {
+				position.MarketValue += flow.Value / math.Pow(1+p.YieldToMaturity/100.0, years(end, flow.Date))
+			}
+		}
+
+		result.Received += position.Received
+		result.MarketValue += position.MarketValue
+
+		position.Received = round2(position.Received)
+		position.MarketValue = round2(position.MarketValue)
+		result.Positions[i] = position
+	}
+
+	result.ReinvestmentIncome = cash - result.Received
+	result.Value = cash + result.MarketValue
+	result.ProfitLoss = result.Value - result.Invested
+	if result.Invested > 0 {
+		result.TotalReturn = round2(100.0 * result.ProfitLoss / result.Invested)
+		result.AnnualizedReturn = round2(100.0 * (math.Pow(result.Value/result.Invested, 1/years(now, end)) - 1))
+	}
+
+	result.ReinvestRate = round2(result.ReinvestRate)
+	result.Invested = round2(result.Invested)
+	result.Received = round2(result.Received)
+	result.ReinvestmentIncome = round2(result.ReinvestmentIncome)
+	result.MarketValue = round2(result.MarketValue)
+	result.Value = round2(result.Value)
+	result.ProfitLoss = round2(result.ProfitLoss)
+
+	return result
+}
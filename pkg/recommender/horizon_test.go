@@ -0,0 +1,56 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestSimulateHorizon(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	newPortfolio := func(years int, maturityPayment float64) *SuggestResult {
+		r := newOptimizerReport(1, 1, 1000, 10, float64(years))
+		r.CashFlow = []*CashFlowItem{{Type: Maturity, Date: now.AddDate(years, 0, 0), ValueRub: maturityPayment}}
+		return &SuggestResult{
+			Positions:       []*SuggestedPortfolioPosition{{Report: *r, Quantity: 1, Weight: 1}},
+			Amount:          1000,
+			YieldToMaturity: 10,
+		}
+	}
+
+	// Погашение до окончания горизонта реинвестируется под ставку позиции
+	result := simulateHorizon(now, newPortfolio(1, 1100), 24, nil, []float64{10})
+	assert.Equal(1000.0, result.Invested)
+	assert.Equal(10.0, result.ReinvestRate)
+	assert.Equal(1100.0, result.Received)
+	assert.Equal(0.0, result.MarketValue)
+	assert.InDelta(110.0, result.ReinvestmentIncome, 0.5)
+	assert.InDelta(21.0, result.TotalReturn, 0.1)
+	assert.InDelta(10.0, result.AnnualizedReturn, 0.05)
+
+	// Без дохода от реинвестирования доходность в пересчете на год ниже
+	result = simulateHorizon(now, newPortfolio(1, 1100), 24, nil, []float64{0})
+	assert.Equal(0.0, result.ReinvestmentIncome)
+	assert.Equal(1100.0, result.Value)
+	assert.InDelta(4.88, result.AnnualizedReturn, 0.05)
+
+	// Непогашенная позиция оценивается при неизменной доходности к погашению
+	result = simulateHorizon(now, newPortfolio(3, 1331), 12, nil, []float64{10})
+	assert.Equal(0.0, result.Received)
+	assert.InDelta(1100.0, result.MarketValue, 0.5)
+	assert.InDelta(1100.0, result.Positions[0].MarketValue, 0.5)
+	assert.InDelta(10.0, result.AnnualizedReturn, 0.05)
+}
+
+func TestHorizonRequest_Validate(t *testing.T) {
+	assert := assertion.New(t)
+
+	rate := -100.0
+	assert.NoError((&HorizonRequest{Horizon: 12}).Validate())
+	assert.Error((&HorizonRequest{Horizon: 0}).Validate())
+	assert.Error((&HorizonRequest{Horizon: MaxSimulationHorizon + 1}).Validate())
+	assert.Error((&HorizonRequest{Horizon: 12, ReinvestRate: &rate}).Validate())
+}
@@ -0,0 +1,317 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+const (
+	// MaxLadderHorizon - максимальный срок лесенки, месяцев
+	MaxLadderHorizon = 10 * 12
+
+	// ladderMaxRungCandidates - максимальное количество облигаций-кандидатов на одну ступень
+	ladderMaxRungCandidates = 10
+
+	// ladderMinDaysTillMaturity - минимальный срок до погашения (или оферты) облигации, которая может войти в лесенку, дней
+	ladderMinDaysTillMaturity = 3
+)
+
+// LadderRequest - запрос на построение лесенки облигаций
+// Лесенка делится на ступени длиной Interval месяцев, в каждую ступень подбираются облигации,
+// погашаемые (или предъявляемые к оферте) в ее пределах, так, чтобы суммы погашений по ступеням были примерно равны
+type LadderRequest struct {
+	// Сумма для инвестирования
+	Amount float64
+
+	// Срок лесенки, месяцев
+	Horizon int
+
+	// Интервал между ступенями, месяцев
+	Interval int
+
+	// Коллекция, из которой выбираются облигации
+	// Если не задана, то выбираются облигации без признака высокого риска
+	Collection Collection
+
+	// Модель комиссий и налогов
+	// Если не задана, то используются показатели, рассчитанные в БД
+	Costs *CostModel
+
+	// Ограничения оптимизатора, применяются к каждой ступени отдельно
+	// Если не заданы, то ограничения не применяются (см. также DefaultSuggestConstraints)
+	Constraints *SuggestConstraints
+}
+
+// Validate проверяет корректность запроса
+func (r *LadderRequest) Validate() error {
+	if r.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if r.Interval <= 0 {
+		return fmt.Errorf("rung interval must be positive")
+	}
+	if r.Horizon < r.Interval || r.Horizon > MaxLadderHorizon {
+		return fmt.Errorf("horizon must be in range %d..%d months", r.Interval, MaxLadderHorizon)
+	}
+	if r.Constraints != nil {
+		err := r.Constraints.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LadderResult - результат построения лесенки облигаций
+type LadderResult struct {
+	// Ступени лесенки
+	Rungs []*LadderRung
+
+	// Портфель из позиций всех ступеней
+	Portfolio *SuggestResult
+}
+
+// LadderRung - ступень лесенки облигаций
+type LadderRung struct {
+	// Начало ступени (не включительно)
+	From time.Time
+
+	// Конец ступени (включительно)
+	Till time.Time
+
+	// Позиции, погашаемые (или предъявляемые к оферте) в пределах ступени
+	Positions []*SuggestedPortfolioPosition
+
+	// Сумма вложений, в валюте
+	Amount float64
+
+	// Сумма погашений и амортизаций по позициям, в валюте
+	Principal float64
+}
+
+// BuildLadder выполняет построение лесенки облигаций
+// Если подходящих облигаций не нашлось, то возвращается ошибка ErrNotFound
+func (s *service) BuildLadder(ctx context.Context, tx *data.TX, request *LadderRequest) (*LadderResult, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	now := today()
+
+	entities, err := s.listBondsForLadder(tx, request.Collection, request.Horizon)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, len(entities))
+	for i, entity := range entities {
+		reports[i] = mapReport(entity)
+	}
+
+	rungs := newLadderRungs(now, request)
+	candidates := selectLadderCandidates(now, rungs, reports)
+
+	// Данные по выплатам загружаются только для отобранных облигаций
+	for _, list := range candidates {
+		for _, report := range list {
+			err = s.enrichWithCashFlow(tx, report)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := buildLadder(now, request, rungs, candidates)
+	if result.Portfolio == nil {
+		return nil, ErrNotFound
+	}
+
+	return result, nil
+}
+
+// listBondsForLadder выполняет выборку облигаций для лесенки в БД
+// Выбираются облигации из коллекции collection (или все облигации без признака высокого риска),
+// погашаемые (или предъявляемые к оферте) в течение horizon месяцев
+// Облигации упорядочиваются по убыванию доходности к оферте (или погашению)
+func (s *service) listBondsForLadder(tx *data.TX, collection Collection, horizon int) ([]*data.Report, error) {
+	if collection == nil {
+		// Выборка облигаций по критериям:
+		// - погашение (или оферта) в пределах срока лесенки
+		// - эффективная доходность к погашению (или оферте) в рамках трех сигм
+		// - без признака высокого риска
+		sql := `
+WITH cte AS (
+    SELECT b.id,
+           COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)                 AS ytm,
+           AVG(COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)) OVER ()    AS mean,
+           STDDEV(COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)) OVER () AS stddev
+    FROM reports r
+    INNER JOIN bonds b ON b.id = r.bond_id
+    LEFT JOIN report_metrics m ON m.bond_id = r.bond_id
+    WHERE b.high_risk = FALSE
+      AND COALESCE(m.offer_date, b.maturity_date) <= NOW() + ? * '1 month'::interval
+      AND COALESCE(m.offer_date, b.maturity_date) >= NOW() + ? * '1 day'::interval
+      AND COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate) > 0
+)
+SELECT id AS bond_id, row_number() OVER (ORDER BY ytm DESC) AS index
+FROM cte
+WHERE (ytm <= mean + 3 * stddev)
+`
+		return tx.Reports.List(0, sql, horizon, ladderMinDaysTillMaturity)
+	} else {
+		// Выборка облигаций по критериям:
+		// - облигация входит в коллекцию
+		// - погашение (или оферта) в пределах срока лесенки
+		sql := `
+WITH cte AS (
+    SELECT b.id,
+           COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate) AS ytm
+    FROM reports r
+    INNER JOIN bonds b ON b.id = r.bond_id
+    LEFT JOIN report_metrics m ON m.bond_id = r.bond_id
+    WHERE b.id IN (SELECT cb.bond_id FROM collection_bonds cb WHERE cb.collection_id = ?)
+      AND COALESCE(m.offer_date, b.maturity_date) <= NOW() + ? * '1 month'::interval
+      AND COALESCE(m.offer_date, b.maturity_date) >= NOW() + ? * '1 day'::interval
+      AND COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate) > 0
+)
+SELECT id AS bond_id, row_number() OVER (ORDER BY ytm DESC) AS index
+FROM cte
+`
+		return tx.Reports.List(0, sql, collection.ID(), horizon, ladderMinDaysTillMaturity)
+	}
+}
+
+// newLadderRungs формирует пустые ступени лесенки
+// Последняя ступень может быть короче остальных, если срок лесенки не кратен интервалу
+func newLadderRungs(now time.Time, request *LadderRequest) []*LadderRung {
+	rungs := make([]*LadderRung, 0)
+	for from := 0; from < request.Horizon; from += request.Interval {
+		till := from + request.Interval
+		if till > request.Horizon {
+			till = request.Horizon
+		}
+
+		rungs = append(rungs, &LadderRung{
+			From:      now.AddDate(0, from, 0),
+			Till:      now.AddDate(0, till, 0),
+			Positions: make([]*SuggestedPortfolioPosition, 0),
+		})
+	}
+
+	return rungs
+}
+
+// selectLadderCandidates распределяет облигации по ступеням лесенки по дате оферты (или погашения)
+// Отчеты должны быть упорядочены по убыванию доходности, в каждую ступень отбирается не более ladderMaxRungCandidates облигаций
+func selectLadderCandidates(now time.Time, rungs []*LadderRung, reports []*Report) [][]*Report {
+	minDate := now.AddDate(0, 0, ladderMinDaysTillMaturity)
+	candidates := make([][]*Report, len(rungs))
+	for _, report := range reports {
+		d := horizonDate(report)
+		if d.Before(minDate) {
+			continue
+		}
+
+		for i, rung := range rungs {
+			if d.After(rung.From) && !d.After(rung.Till) {
+				if len(candidates[i]) < ladderMaxRungCandidates {
+					candidates[i] = append(candidates[i], report)
+				}
+				break
+			}
+		}
+	}
+
+	return candidates
+}
+
+// buildLadder подбирает позиции в ступени лесенки
+// Если не удалось подобрать ни одной позиции, то портфель не формируется (nil)
+//
+// Сумма распределяется между ступенями, для которых нашлись облигации, в два прохода:
+// сначала по каждой ступени оценивается стоимость единицы погашаемого номинала при равном распределении суммы,
+// затем сумма распределяется пропорционально этой стоимости, чтобы суммы погашений по ступеням были примерно равны.
+// Неиспользованный остаток ступени переносится в следующую ступень.
+// Лимиты на доли относятся ко всей лесенке: все ступени подбираются одним оптимизатором,
+// поэтому облигации, эмитенты и годы погашения, исчерпавшие лимит в одной ступени, не покупаются в следующих
+func buildLadder(now time.Time, request *LadderRequest, rungs []*LadderRung, candidates [][]*Report) *LadderResult {
+	active := 0
+	for _, list := range candidates {
+		if len(list) > 0 {
+			active++
+		}
+	}
+
+	// Стоимость единицы номинала по ступеням
+	// Ступени оцениваются независимо друг от друга, но с лимитами относительно суммы всей лесенки
+	costs := make([]float64, len(rungs))
+	sumOfCosts := 0.0
+	for i := range rungs {
+		if len(candidates[i]) == 0 {
+			continue
+		}
+
+		budget := request.Amount / float64(active)
+		positions, _ := newPortfolioOptimizer(now, request.Amount, request.Costs, request.Constraints).allocate(candidates[i], budget)
+		amount, principal := ladderPositionsTotals(positions)
+		if principal <= 0 {
+			continue
+		}
+
+		costs[i] = amount / principal
+		sumOfCosts += costs[i]
+	}
+
+	optimizer := newPortfolioOptimizer(now, request.Amount, request.Costs, request.Constraints)
+	result := &LadderResult{Rungs: rungs}
+	allPositions := make([]*SuggestedPortfolioPosition, 0)
+	remainder := 0.0
+	for i, rung := range rungs {
+		if costs[i] == 0 {
+			continue
+		}
+
+		budget := request.Amount*costs[i]/sumOfCosts + remainder
+		rung.Positions, remainder = optimizer.allocate(candidates[i], budget)
+		rung.Amount, rung.Principal = ladderPositionsTotals(rung.Positions)
+		allPositions = append(allPositions, rung.Positions...)
+	}
+
+	// Расчет весов позиций
+	totalAmount := 0.0
+	for _, p := range allPositions {
+		totalAmount += p.OpenValue
+	}
+	for _, p := range allPositions {
+		p.Weight = p.OpenValue / totalAmount
+	}
+
+	if len(allPositions) > 0 {
+		result.Portfolio = newSuggestResult(now, allPositions, request.Costs)
+		result.Portfolio.UnusedAmount = unusedAmount(request.Amount, result.Portfolio)
+		result.Portfolio.LimitsRelaxed = optimizer.relaxed
+	}
+
+	return result
+}
+
+// ladderPositionsTotals возвращает сумму вложений и сумму погашений и амортизаций по позициям
+func ladderPositionsTotals(positions []*SuggestedPortfolioPosition) (float64, float64) {
+	amount, principal := 0.0, 0.0
+	for _, p := range positions {
+		amount += p.OpenValue
+		principal += p.Principal()
+	}
+
+	return amount, principal
+}
+
+// Principal возвращает сумму погашений и амортизаций по позиции, в валюте
+func (p *SuggestedPortfolioPosition) Principal() float64 {
+	return p.MaturityPayment + p.AmortizationPayments
+}
@@ -0,0 +1,92 @@
+package recommender
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestBuildLadder(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	newReport := func(id int, price float64, maturityDate time.Time) *Report {
+		r := newOptimizerReport(id, id, price, 10, 0.5)
+		r.Bond.MaturityDate = sql.NullTime{Time: maturityDate, Valid: true}
+		r.DaysTillMaturity = int(maturityDate.Sub(now).Hours() / 24)
+		r.MaturityPayment = 1000
+		return r
+	}
+	reports := []*Report{
+		newReport(1, 1000, now.AddDate(0, 3, 0)),
+		newReport(2, 1000, now.AddDate(0, 4, 0)),
+		newReport(3, 900, now.AddDate(0, 11, 0)),
+		newReport(4, 950, now.AddDate(0, 0, 1)),
+		newReport(5, 950, now.AddDate(2, 0, 0)),
+	}
+
+	request := &LadderRequest{Amount: 19000, Horizon: 12, Interval: 6, Constraints: &SuggestConstraints{}}
+	assert.NoError(request.Validate())
+
+	rungs := newLadderRungs(now, request)
+	assert.Len(rungs, 2)
+	assert.Equal(now.AddDate(0, 6, 0), rungs[0].Till)
+	assert.Equal(now.AddDate(0, 12, 0), rungs[1].Till)
+
+	// Облигации, погашаемые слишком рано или за пределами лесенки, не отбираются
+	candidates := selectLadderCandidates(now, rungs, reports)
+	assert.Equal([][]*Report{{reports[0], reports[1]}, {reports[2]}}, candidates)
+
+	// Вторая ступень дешевле в пересчете на номинал, поэтому в нее вкладывается меньшая сумма при том же номинале
+	result := buildLadder(now, request, rungs, candidates)
+	assert.Equal(10000.0, result.Rungs[0].Amount)
+	assert.Equal(10000.0, result.Rungs[0].Principal)
+	assert.Equal(9000.0, result.Rungs[1].Amount)
+	assert.Equal(10000.0, result.Rungs[1].Principal)
+	assert.Len(result.Portfolio.Positions, 2)
+	assert.Equal(19000.0, result.Portfolio.Amount)
+}
+
+func TestBuildLadder_SharedLimits(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	newReport := func(id, issuerID int, yield float64, maturityDate time.Time) *Report {
+		r := newOptimizerReport(id, issuerID, 1000, yield, 0.5)
+		r.Bond.MaturityDate = sql.NullTime{Time: maturityDate, Valid: true}
+		r.DaysTillMaturity = int(maturityDate.Sub(now).Hours() / 24)
+		r.MaturityPayment = 1000
+		return r
+	}
+
+	// Облигации первого эмитента самые доходные в каждой ступени, а в первой ступени других облигаций нет
+	candidates := [][]*Report{
+		{newReport(1, 1, 12, now.AddDate(0, 3, 0))},
+		{newReport(2, 1, 12, now.AddDate(0, 9, 0)), newReport(3, 2, 10, now.AddDate(0, 10, 0))},
+	}
+	request := &LadderRequest{Amount: 20000, Horizon: 12, Interval: 6, Constraints: &SuggestConstraints{MaxIssuerWeight: 0.5}}
+	rungs := newLadderRungs(now, request)
+
+	// Лимит на эмитента относится ко всей лесенке: исчерпав его в первой ступени,
+	// эмитент не получает долю во второй, даже если остаток первой ступени переносится в нее
+	result := buildLadder(now, request, rungs, candidates)
+	amounts := positionAmounts(result.Portfolio.Positions)
+	assert.Equal(10000.0, amounts[1]+amounts[2])
+	assert.Equal(10000.0, amounts[3])
+	assert.Equal(20000.0, result.Portfolio.Amount)
+	assert.False(result.Portfolio.LimitsRelaxed)
+}
+
+func TestLadderRequest_Validate(t *testing.T) {
+	assert := assertion.New(t)
+
+	assert.NoError((&LadderRequest{Amount: 1000, Horizon: 36, Interval: 6}).Validate())
+	assert.NoError((&LadderRequest{Amount: 1000, Horizon: 10, Interval: 6}).Validate())
+	assert.Error((&LadderRequest{Amount: 0, Horizon: 36, Interval: 6}).Validate())
+	assert.Error((&LadderRequest{Amount: 1000, Horizon: 36, Interval: 0}).Validate())
+	assert.Error((&LadderRequest{Amount: 1000, Horizon: 3, Interval: 6}).Validate())
+	assert.Error((&LadderRequest{Amount: 1000, Horizon: MaxLadderHorizon + 1, Interval: 6}).Validate())
+	assert.Error((&LadderRequest{Amount: 1000, Horizon: 36, Interval: 6, Constraints: &SuggestConstraints{MaxBondWeight: 2}}).Validate())
+}
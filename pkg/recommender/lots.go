@@ -0,0 +1,16 @@
+package recommender
+
+// lotSize возвращает размер лота облигации, шт.
+// Если рыночные данные неизвестны (например, в бэктесте), то считается, что лот состоит из одной облигации
+func lotSize(r *Report) int {
+	if r.MarketData == nil {
+		return 1
+	}
+
+	return r.MarketData.Lot()
+}
+
+// isTradingActive возвращает true, если торги облигацией не приостановлены и ее можно купить
+func isTradingActive(r *Report) bool {
+	return r.MarketData == nil || r.MarketData.IsTradingActive()
+}
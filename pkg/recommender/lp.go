@@ -0,0 +1,217 @@
+package recommender
+
+import (
+	"math"
+)
+
+const (
+	// lpEpsilon - погрешность сравнения при решении задач линейного программирования
+	lpEpsilon = 1e-9
+
+	// coveringMaxNodes - максимальное количество подзадач, решаемых методом ветвей и границ
+	// Если лимит исчерпан, то возвращается лучшее из найденных решений
+	coveringMaxNodes = 200
+)
+
+// solveCoveringLP решает задачу линейного программирования о покрытии:
+// минимизировать c·x при a·x ≥ b, x ≥ 0
+// Коэффициенты c должны быть неотрицательны, тогда нулевое решение допустимо для двойственной задачи,
+// и задача решается двойственным симплекс-методом без поиска начального базиса
+// Если задача не имеет допустимых решений, то возвращается false
+func solveCoveringLP(a [][]float64, b, c []float64) ([]float64, bool) {
+	m, n := len(a), len(c)
+	width := n + m + 1
+
+	// Ограничения записываются в виде -a·x + s = -b, начальный базис состоит из переменных s
+	tableau := make([][]float64, m)
+	basis := make([]int, m)
+	for i := range a {
+		row := make([]float64, width)
+		for j := 0; j < n; j++ {
+			row[j] = -a[i][j]
+		}
+		row[n+i] = 1
+		row[width-1] = -b[i]
+		tableau[i] = row
+		basis[i] = n + i
+	}
+
+	reducedCosts := make([]float64, width-1)
+	copy(reducedCosts, c)
+
+	for iteration := 0; iteration < 10*(n+m)+100; iteration++ {
+		// Выводимая из базиса строка - строка с наиболее отрицательной правой частью
+		r := -1
+		for i := range tableau {
+			if tableau[i][width-1] < -lpEpsilon && (r < 0 || tableau[i][width-1] < tableau[r][width-1]) {
+				r = i
+			}
+		}
+		if r < 0 {
+			x := make([]float64, n)
+			for i, j := range basis {
+				if j < n {
+					x[j] = tableau[i][width-1]
+				}
+			}
+			return x, true
+		}
+
+		// Вводимый в базис столбец выбирается так, чтобы приведенные стоимости остались неотрицательными
+		q, ratio := -1, math.Inf(1)
+		for j := 0; j < width-1; j++ {
+			if tableau[r][j] < -lpEpsilon {
+				value := reducedCosts[j] / -tableau[r][j]
+				if value < ratio-lpEpsilon {
+					q, ratio = j, value
+				}
+			}
+		}
+		if q < 0 {
+			return nil, false
+		}
+
+		pivot := tableau[r][q]
+		for j := range tableau[r] {
+			tableau[r][j] /= pivot
+		}
+		for i := range tableau {
+			if i == r || tableau[i][q] == 0 {
+				continue
+			}
+			factor := tableau[i][q]
+			for j := range tableau[i] {
+				tableau[i][j] -= factor * tableau[r][j]
+			}
+		}
+		factor := reducedCosts[q]
+		for j := range reducedCosts {
+			reducedCosts[j] -= factor * tableau[r][j]
+		}
+		basis[r] = q
+	}
+
+	return nil, false
+}
+
+// coveringNode - подзадача метода ветвей и границ с ограничениями на значения переменных
+type coveringNode struct {
+	// Нижние границы переменных
+	lower []int
+
+	// Верхние границы переменных, -1 - граница не задана
+	upper []int
+}
+
+// solveCovering решает задачу целочисленного линейного программирования о покрытии:
+// минимизировать c·x при a·x ≥ b, x ≥ 0, x - целые
+// Коэффициенты a и c должны быть неотрицательны, поэтому округление вверх любого допустимого решения также допустимо.
+// Задача решается методом ветвей и границ: в качестве начального решения используется округленное вверх
+// решение линейной релаксации, затем ветвление идет по переменной с дробной частью, ближайшей к 0.5.
+// Если задача не имеет допустимых решений, то возвращается false
+func solveCovering(a [][]float64, b, c []float64) ([]int, bool) {
+	n := len(c)
+	cost := func(x []int) float64 {
+		sum := 0.0
+		for j, v := range x {
+			sum += c[j] * float64(v)
+		}
+		return sum
+	}
+
+	var best []int
+	bestCost := math.Inf(1)
+
+	root := &coveringNode{lower: make([]int, n), upper: make([]int, n)}
+	for j := range root.upper {
+		root.upper[j] = -1
+	}
+
+	stack := []*coveringNode{root}
+	for nodes := 0; len(stack) > 0 && nodes < coveringMaxNodes; nodes++ {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Нижние границы учитываются заменой переменных x = lower + x', верхние - дополнительными ограничениями -x' ≥ lower - upper
+		rows := make([][]float64, 0, len(a)+n)
+		rhs := make([]float64, 0, len(b)+n)
+		for i := range a {
+			value := b[i]
+			for j, l := range node.lower {
+				value -= a[i][j] * float64(l)
+			}
+			rows = append(rows, a[i])
+			rhs = append(rhs, value)
+		}
+		for j, u := range node.upper {
+			if u < 0 {
+				continue
+			}
+			row := make([]float64, n)
+			row[j] = -1
+			rows = append(rows, row)
+			rhs = append(rhs, float64(node.lower[j]-u))
+		}
+
+		relaxed, ok := solveCoveringLP(rows, rhs, c)
+		if !ok {
+			continue
+		}
+
+		x := make([]float64, n)
+		bound := 0.0
+		for j := range x {
+			x[j] = float64(node.lower[j]) + relaxed[j]
+			bound += c[j] * x[j]
+		}
+		if bound >= bestCost-lpEpsilon {
+			continue
+		}
+
+		// Округленное вверх решение подзадачи (с учетом верхних границ) используется как оценка сверху
+		rounded := make([]int, n)
+		branch, fraction := -1, 0.0
+		for j, v := range x {
+			rounded[j] = int(math.Ceil(v - 1e-6))
+			f := v - math.Floor(v+1e-6)
+			if f > 1e-6 && math.Abs(f-0.5) < math.Abs(fraction-0.5) {
+				branch, fraction = j, f
+			}
+		}
+		for j, u := range node.upper {
+			if u >= 0 && rounded[j] > u {
+				rounded[j] = u
+			}
+		}
+		if roundedCost := cost(rounded); roundedCost < bestCost && isCovered(a, b, rounded) {
+			best, bestCost = rounded, roundedCost
+		}
+		if branch < 0 {
+			continue
+		}
+
+		// Ветвление: x ≤ floor(v) и x ≥ ceil(v), ветвь с округлением вверх рассматривается первой
+		down := &coveringNode{lower: append([]int(nil), node.lower...), upper: append([]int(nil), node.upper...)}
+		down.upper[branch] = int(math.Floor(x[branch]))
+		up := &coveringNode{lower: append([]int(nil), node.lower...), upper: append([]int(nil), node.upper...)}
+		up.lower[branch] = int(math.Ceil(x[branch]))
+		stack = append(stack, down, up)
+	}
+
+	return best, best != nil
+}
+
+// isCovered возвращает true, если x удовлетворяет ограничениям a·x ≥ b
+func isCovered(a [][]float64, b []float64, x []int) bool {
+	for i := range a {
+		sum := 0.0
+		for j, v := range x {
+			sum += a[i][j] * float64(v)
+		}
+		if sum < b[i]-1e-6 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,27 @@
+package recommender
+
+import (
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestSolveCovering(t *testing.T) {
+	assert := assertion.New(t)
+
+	// Округление вверх решения линейной релаксации (0, 8/3) дает 15, оптимальное целочисленное решение - 14
+	a := [][]float64{{1, 3}, {5, 5}}
+	b := []float64{8, 4}
+	c := []float64{2, 5}
+
+	x, ok := solveCoveringLP(a, b, c)
+	assert.True(ok)
+	assert.InDeltaSlice([]float64{0, 8.0 / 3}, x, 1e-9)
+
+	lots, ok := solveCovering(a, b, c)
+	assert.True(ok)
+	assert.Equal([]int{2, 2}, lots)
+
+	_, ok = solveCovering([][]float64{{0, 0}}, []float64{1}, c)
+	assert.False(ok)
+}
@@ -0,0 +1,126 @@
+package recommender
+
+import (
+	"math"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// riskMetrics содержит показатели чувствительности цены облигации к изменению процентных ставок
+type riskMetrics struct {
+	// Дюрация Маколея, лет
+	MacaulayDuration float64
+
+	// Модифицированная дюрация, лет
+	ModifiedDuration float64
+
+	// Изменение стоимости облигации при изменении доходности на 1 б.п., в валюте
+	DV01 float64
+
+	// Выпуклость, лет^2
+	Convexity float64
+}
+
+// computeRiskMetrics рассчитывает дюрацию, DV01 и выпуклость облигации
+// Расчет ведется по валовым выплатам (без учета налогов) и доходности, при которой
+// приведенная стоимость выплат равна сумме затрат на покупку
+// Выплаты флоатера после даты пересмотра ставки учитываются как одна выплата на эту дату (см. rateSensitiveFlows)
+// Если показатели рассчитать не удалось, то возвращается false
+func computeRiskMetrics(now time.Time, report *Report) (*riskMetrics, bool) {
+	price := report.OpenValue + report.OpenFee
+	if price <= 0 || len(report.CashFlow) == 0 {
+		return nil, false
+	}
+
+	flows := make([]xirrFlow, 0, len(report.CashFlow)+1)
+	flows = append(flows, xirrFlow{Date: now, Value: -price})
+	for _, item := range report.CashFlow {
+		flows = append(flows, xirrFlow{Date: item.Date, Value: item.ValueRub})
+	}
+
+	rate, err := xirr(flows)
+	if err != nil {
+		return nil, false
+	}
+
+	pv, weightedTime, weightedConvexity := 0.0, 0.0, 0.0
+	for _, f := range rateSensitiveFlows(now, report, flows[1:], rate) {
+		t := f.Date.Sub(now).Hours() / 24.0 / daysInYear
+		if t < 0 {
+			continue
+		}
+
+		v := f.Value / math.Pow(1+rate, t)
+		pv += v
+		weightedTime += t * v
+		weightedConvexity += t * (t + 1) * v
+	}
+
+	if pv <= 0 {
+		return nil, false
+	}
+
+	metrics := &riskMetrics{}
+	metrics.MacaulayDuration = weightedTime / pv
+	metrics.ModifiedDuration = metrics.MacaulayDuration / (1 + rate)
+	metrics.DV01 = metrics.ModifiedDuration * pv * 0.0001
+	metrics.Convexity = weightedConvexity / pv / math.Pow(1+rate, 2)
+
+	return metrics, true
+}
+
+// floatingResetDate возвращает дату, на которую фиксируется ставка первого оценочного купона флоатера, -
+// дату предыдущего купона, но не ранее now
+// Если облигация не является флоатером или все ее купоны объявлены, то возвращается false
+func floatingResetDate(now time.Time, report *Report) (time.Time, bool) {
+	if report.Bond == nil || report.Bond.CouponType != data.FloatingCoupon {
+		return now, false
+	}
+
+	var first *time.Time
+	for _, item := range report.CashFlow {
+		if item.Type == Coupon && item.Estimated && item.Date.After(now) && (first == nil || item.Date.Before(*first)) {
+			date := item.Date
+			first = &date
+		}
+	}
+	if first == nil {
+		return now, false
+	}
+
+	reset := now
+	for _, item := range report.CashFlow {
+		if item.Type == Coupon && !item.Estimated && item.Date.Before(*first) && item.Date.After(reset) {
+			reset = item.Date
+		}
+	}
+
+	return reset, true
+}
+
+// rateSensitiveFlows возвращает выплаты облигации flows в виде, пригодном для оценки чувствительности к процентным ставкам
+// Оценочные купоны флоатера пересматриваются вместе со ставкой, поэтому выплаты после даты пересмотра ставки
+// (см. floatingResetDate) заменяются одной выплатой на эту дату - их стоимостью на эту дату при доходности rate.
+// Выплаты прочих облигаций возвращаются без изменений
+func rateSensitiveFlows(now time.Time, report *Report, flows []xirrFlow, rate float64) []xirrFlow {
+	reset, ok := floatingResetDate(now, report)
+	if !ok {
+		return flows
+	}
+
+	result := make([]xirrFlow, 0, len(flows))
+	tail := 0.0
+	for _, f := range flows {
+		if f.Date.After(reset) {
+			tail += f.Value / math.Pow(1+rate, f.Date.Sub(reset).Hours()/24.0/daysInYear)
+		} else {
+			result = append(result, f)
+		}
+	}
+	if tail != 0 {
+		result = append(result, xirrFlow{Date: reset, Value: tail})
+	}
+
+	return result
+}
@@ -0,0 +1,88 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestComputeRiskMetrics(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	{
+		// Бескупонная облигация - дюрация равна сроку до погашения
+		metrics, ok := computeRiskMetrics(t0, &Report{
+			OpenValue: 900,
+			CashFlow: []*CashFlowItem{
+				{Type: Maturity, Date: t0.AddDate(2, 0, 0), ValueRub: 1000},
+			},
+		})
+		assert.True(ok)
+		assert.InDelta(2.0, metrics.MacaulayDuration, 0.01)
+		assert.Less(metrics.ModifiedDuration, metrics.MacaulayDuration)
+		assert.InDelta(metrics.ModifiedDuration*900*0.0001, metrics.DV01, 0.0001)
+		assert.Greater(metrics.Convexity, 0.0)
+	}
+
+	{
+		// Амортизируемая облигация имеет меньшую дюрацию, чем облигация с погашением в конце срока
+		bullet, ok := computeRiskMetrics(t0, &Report{
+			OpenValue: 1000,
+			CashFlow: []*CashFlowItem{
+				{Type: Coupon, Date: t0.AddDate(1, 0, 0), ValueRub: 100},
+				{Type: Coupon, Date: t0.AddDate(2, 0, 0), ValueRub: 100},
+				{Type: Maturity, Date: t0.AddDate(2, 0, 0), ValueRub: 1000},
+			},
+		})
+		assert.True(ok)
+
+		amortizing, ok := computeRiskMetrics(t0, &Report{
+			OpenValue: 1000,
+			CashFlow: []*CashFlowItem{
+				{Type: Coupon, Date: t0.AddDate(1, 0, 0), ValueRub: 100},
+				{Type: Amortization, Date: t0.AddDate(1, 0, 0), ValueRub: 500},
+				{Type: Coupon, Date: t0.AddDate(2, 0, 0), ValueRub: 50},
+				{Type: Maturity, Date: t0.AddDate(2, 0, 0), ValueRub: 500},
+			},
+		})
+		assert.True(ok)
+		assert.Less(amortizing.MacaulayDuration, bullet.MacaulayDuration)
+		assert.Less(amortizing.DV01, bullet.DV01)
+	}
+
+	{
+		// Оценочные купоны флоатера пересматриваются вместе со ставкой - дюрация равна сроку до пересмотра ставки
+		flows := []*CashFlowItem{
+			{Type: Coupon, Date: t0.AddDate(0, 6, 0), ValueRub: 50},
+			{Type: Coupon, Date: t0.AddDate(1, 0, 0), ValueRub: 50, Estimated: true},
+			{Type: Coupon, Date: t0.AddDate(1, 6, 0), ValueRub: 50, Estimated: true},
+			{Type: Maturity, Date: t0.AddDate(1, 6, 0), ValueRub: 1000},
+		}
+		floating, ok := computeRiskMetrics(t0, &Report{
+			Bond:      &data.Bond{CouponType: data.FloatingCoupon},
+			OpenValue: 1000,
+			CashFlow:  flows,
+		})
+		assert.True(ok)
+		assert.InDelta(0.5, floating.MacaulayDuration, 0.01)
+
+		fixed, ok := computeRiskMetrics(t0, &Report{
+			Bond:      &data.Bond{CouponType: data.FixedCoupon},
+			OpenValue: 1000,
+			CashFlow:  flows,
+		})
+		assert.True(ok)
+		assert.Greater(fixed.MacaulayDuration, 1.4)
+	}
+
+	{
+		// Нет выплат - показатели не определены
+		_, ok := computeRiskMetrics(t0, &Report{OpenValue: 1000})
+		assert.False(ok)
+	}
+}
@@ -0,0 +1,99 @@
+package recommender
+
+import (
+	"math"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// defaultOfferPrice - цена выкупа по оферте (в % от номинала), если она не указана в данных оферты
+const defaultOfferPrice = 100.0
+
+// nearestOffers возвращает ближайшую предстоящую оферту для каждой облигации
+func nearestOffers(offers []*data.Offer) map[int]*data.Offer {
+	result := make(map[int]*data.Offer)
+	for _, offer := range offers {
+		if !offer.Date.Valid {
+			continue
+		}
+
+		existing, exists := result[offer.BondID]
+		if !exists || offer.Date.Time.Before(existing.Date.Time) {
+			result[offer.BondID] = offer
+		}
+	}
+
+	return result
+}
+
+// getOfferPrice возвращает цену выкупа по оферте, в % от номинала
+func getOfferPrice(offer *data.Offer) float64 {
+	if offer.Price != nil && *offer.Price > 0 {
+		return *offer.Price
+	}
+
+	return defaultOfferPrice
+}
+
+// offerReport формирует альтернативный отчет по облигации, в котором облигация
+// предъявляется к выкупу по оферте в указанную дату по указанной цене (в % от номинала)
+// Выплаты после даты оферты отбрасываются, вместо погашения выплачивается непогашенный номинал по цене оферты
+// Комиссии и налоги рассчитываются по модели costs
+func offerReport(now time.Time, report *Report, date time.Time, price float64, costs *CostModel) *Report {
+	r := *report
+	r.ToOffer = nil
+	r.Spread = nil
+	r.CashFlow = make([]*CashFlowItem, 0, len(report.CashFlow))
+	r.CouponPayments = 0
+	r.AmortizationPayments = 0
+	r.MaturityPayment = 0
+
+	for _, item := range report.CashFlow {
+		if item.Date.After(date) || item.Type == Maturity {
+			continue
+		}
+
+		switch item.Type {
+		case Coupon:
+			r.CouponPayments += item.ValueRub
+		case Amortization:
+			r.AmortizationPayments += item.ValueRub
+		}
+
+		c := *item
+		r.CashFlow = append(r.CashFlow, &c)
+	}
+
+	faceValue := math.Max(report.OpenFaceValue-r.AmortizationPayments, 0)
+	r.MaturityPayment = round2(faceValue * price / 100.0)
+	if r.MaturityPayment > 0 {
+		item := &CashFlowItem{Type: Maturity, Date: date, Value: r.MaturityPayment, ValueRub: r.MaturityPayment}
+		if r.FxRate > 0 {
+			item.Value = round2(r.MaturityPayment / r.FxRate)
+		}
+		r.CashFlow = append(r.CashFlow, item)
+	}
+
+	r.Revenue = r.CouponPayments + r.AmortizationPayments + r.MaturityPayment
+	r.DaysTillMaturity = int(math.Round(date.Sub(now).Hours() / 24.0))
+	r.DaysTillOffer = r.DaysTillMaturity
+
+	result := applyCostModel(now, &r, 1, costs)
+	result.YieldToOffer = result.YieldToMaturity
+
+	result.MacaulayDuration, result.ModifiedDuration, result.DV01, result.Convexity = 0, 0, 0, 0
+	if metrics, ok := computeRiskMetrics(now, result); ok {
+		result.MacaulayDuration = metrics.MacaulayDuration
+		result.ModifiedDuration = metrics.ModifiedDuration
+		result.DV01 = metrics.DV01
+		result.Convexity = metrics.Convexity
+	}
+
+	return result
+}
+
+// round2 округляет значение до 2 знаков после запятой
+func round2(v float64) float64 {
+	return math.Round(v*100.0) / 100.0
+}
@@ -0,0 +1,74 @@
+package recommender
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestOfferReport(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	offerDate := t0.AddDate(1, 0, 0)
+
+	report := &Report{
+		OpenPrice:     100,
+		OpenFaceValue: 1000,
+		OpenValue:     1000,
+		CashFlow: []*CashFlowItem{
+			{Type: Coupon, Date: t0.AddDate(0, 6, 0), ValueRub: 50},
+			{Type: Coupon, Date: t0.AddDate(1, 0, 0), ValueRub: 50},
+			{Type: Coupon, Date: t0.AddDate(1, 6, 0), ValueRub: 50},
+			{Type: Coupon, Date: t0.AddDate(2, 0, 0), ValueRub: 50},
+			{Type: Maturity, Date: t0.AddDate(2, 0, 0), ValueRub: 1000},
+		},
+	}
+
+	r := offerReport(t0, report, offerDate, 100, DefaultCostModel())
+
+	// Выплаты после оферты отброшены, номинал выплачивается в дату оферты
+	assert.Len(r.CashFlow, 3)
+	assert.Equal(Maturity, r.CashFlow[2].Type)
+	assert.Equal(offerDate, r.CashFlow[2].Date)
+	assert.Equal(float64(1000), r.MaturityPayment)
+	assert.Equal(float64(100), r.CouponPayments)
+	assert.Equal(0.5, r.OpenFee)
+	assert.Equal(float64(13), r.Taxes)
+	assert.Equal(86.5, r.ProfitLoss)
+	assert.Equal(365, r.DaysTillMaturity)
+	assert.Equal(r.DaysTillMaturity, r.DaysTillOffer)
+	assert.InDelta(8.9, r.YieldToMaturity, 0.1)
+	assert.Equal(r.YieldToMaturity, r.YieldToOffer)
+	assert.InDelta(0.98, r.MacaulayDuration, 0.02)
+
+	// Налоги рассчитываются по модели комиссий и налогов
+	r = offerReport(t0, report, offerDate, 100, &CostModel{Account: IISTypeB})
+	assert.Equal(float64(0), r.OpenFee)
+	assert.Equal(float64(0), r.Taxes)
+	assert.Equal(float64(100), r.ProfitLoss)
+
+	// Исходный отчет не изменился
+	assert.Len(report.CashFlow, 5)
+	assert.Equal(float64(0), report.MaturityPayment)
+}
+
+func TestNearestOffers(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	offers := nearestOffers([]*data.Offer{
+		{BondID: 1, Date: sql.NullTime{Time: t0.AddDate(2, 0, 0), Valid: true}},
+		{BondID: 1, Date: sql.NullTime{Time: t0.AddDate(1, 0, 0), Valid: true}},
+		{BondID: 2, Date: sql.NullTime{}},
+	})
+
+	assert.Len(offers, 1)
+	assert.Equal(t0.AddDate(1, 0, 0), offers[1].Date.Time)
+	assert.Equal(defaultOfferPrice, getOfferPrice(offers[1]))
+}
@@ -0,0 +1,485 @@
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// SuggestConstraints - ограничения оптимизатора, который формирует позиции портфеля
+// Нулевое значение отдельного ограничения означает, что оно не применяется
+type SuggestConstraints struct {
+	// Максимальная доля одной облигации в портфеле (0..1)
+	MaxBondWeight float64
+
+	// Максимальная доля облигаций одного эмитента в портфеле (0..1)
+	MaxIssuerWeight float64
+
+	// Максимальная доля облигаций одного типа (ОФЗ, корпоративные и т.п.) в портфеле (0..1)
+	MaxBondTypeWeight float64
+
+	// Максимальная доля облигаций, погашаемых (или предъявляемых к оферте) в одном календарном году (0..1)
+	MaxMaturityYearWeight float64
+
+	// Минимальное количество позиций в портфеле
+	// Обеспечивается тем, что доля одной облигации ограничивается величиной 1 / MinPositions
+	MinPositions int
+
+	// Целевая дюрация Маколея портфеля, лет
+	TargetDuration float64
+
+	// Допустимое отклонение дюрации портфеля от целевой, лет
+	DurationTolerance float64
+
+	// Максимальная доля неинвестированного остатка (0..1), при превышении которой ослабляются лимиты на доли
+	// По умолчанию (0) лимиты на доли жесткие, а сумма, которую не удалось вложить, остается неинвестированной.
+	// Если значение задано, то лимиты постепенно ослабляются (доля облигации - не выше 1 / MinPositions),
+	// а в результате выставляется признак SuggestResult.LimitsRelaxed
+	MaxCashWeight float64
+}
+
+// DefaultSuggestConstraints возвращает ограничения оптимизатора по умолчанию
+// Они применяются к запросам из командной строки, веб-интерфейса и API, если ограничения не заданы явно
+func DefaultSuggestConstraints() *SuggestConstraints {
+	return &SuggestConstraints{
+		MaxBondWeight:     0.25,
+		MaxIssuerWeight:   0.35,
+		MinPositions:      4,
+		TargetDuration:    0,
+		DurationTolerance: 0.25,
+	}
+}
+
+// Validate проверяет корректность ограничений оптимизатора
+func (c *SuggestConstraints) Validate() error {
+	if c.MaxBondWeight < 0 || c.MaxBondWeight > 1 {
+		return fmt.Errorf("max bond weight must be in range 0..1")
+	}
+	if c.MaxIssuerWeight < 0 || c.MaxIssuerWeight > 1 {
+		return fmt.Errorf("max issuer weight must be in range 0..1")
+	}
+	if c.MaxBondTypeWeight < 0 || c.MaxBondTypeWeight > 1 {
+		return fmt.Errorf("max bond type weight must be in range 0..1")
+	}
+	if c.MaxMaturityYearWeight < 0 || c.MaxMaturityYearWeight > 1 {
+		return fmt.Errorf("max maturity year weight must be in range 0..1")
+	}
+	if c.MinPositions < 0 {
+		return fmt.Errorf("min positions must not be negative")
+	}
+	if c.TargetDuration < 0 {
+		return fmt.Errorf("target duration must not be negative")
+	}
+	if c.DurationTolerance < 0 {
+		return fmt.Errorf("duration tolerance must not be negative")
+	}
+	if c.MaxCashWeight < 0 || c.MaxCashWeight > 1 {
+		return fmt.Errorf("max cash weight must be in range 0..1")
+	}
+
+	return nil
+}
+
+const (
+	// optimizerRelaxationFactor - множитель, с которым ослабляются ограничения на доли
+	optimizerRelaxationFactor = 1.25
+
+	// optimizerLambdaBound - граница множителя Лагранжа при подборе целевой дюрации, % годовых за год дюрации
+	optimizerLambdaBound = 100.0
+
+	// optimizerLambdaIterations - количество итераций бисекции при подборе целевой дюрации
+	optimizerLambdaIterations = 60
+)
+
+// portfolioOptimizer формирует позиции портфеля с максимальной доходностью при заданных ограничениях
+// Лимиты на доли считаются от суммы всего портфеля и учитываются по всем частям портфеля
+type portfolioOptimizer struct {
+	now         time.Time
+	costs       *CostModel
+	constraints *SuggestConstraints
+	totalAmount float64
+	usage       *optimizerUsage
+	relaxed     bool // Лимиты на доли были ослаблены хотя бы для одной части портфеля
+}
+
+// optimizerCandidate - облигация, которая может войти в портфель
+type optimizerCandidate struct {
+	report       *Report
+	price        float64 // Стоимость лота
+	lot          int     // Размер лота, шт.
+	yield        float64
+	duration     float64
+	bondID       int
+	issuerID     int
+	bondType     data.BondType
+	maturityYear int
+}
+
+// optimizerLimits - максимальные доли облигации, эмитента, типа облигаций и года погашения (0..1)
+type optimizerLimits struct {
+	bond         float64
+	issuer       float64
+	bondType     float64
+	maturityYear float64
+	maxBond      float64 // Предел, до которого можно ослабить лимит на долю облигации
+}
+
+// relax ослабляет лимиты, возвращает false, если ослаблять больше нечего
+func (l *optimizerLimits) relax() bool {
+	relaxed := false
+	for _, v := range []struct {
+		value *float64
+		max   float64
+	}{
+		{&l.bond, l.maxBond},
+		{&l.issuer, 1},
+		{&l.bondType, 1},
+		{&l.maturityYear, 1},
+	} {
+		if *v.value < v.max {
+			*v.value = math.Min(v.max, *v.value*optimizerRelaxationFactor)
+			relaxed = true
+		}
+	}
+
+	return relaxed
+}
+
+// optimizerUsage - суммы, вложенные в облигации, эмитентов, типы облигаций и годы погашения, в валюте
+type optimizerUsage struct {
+	bonds         map[int]float64
+	issuers       map[int]float64
+	bondTypes     map[data.BondType]float64
+	maturityYears map[int]float64
+}
+
+func newOptimizerUsage() *optimizerUsage {
+	return &optimizerUsage{
+		bonds:         make(map[int]float64),
+		issuers:       make(map[int]float64),
+		bondTypes:     make(map[data.BondType]float64),
+		maturityYears: make(map[int]float64),
+	}
+}
+
+// add учитывает вложение суммы amount в облигацию кандидата c
+func (u *optimizerUsage) add(c *optimizerCandidate, amount float64) {
+	u.bonds[c.bondID] += amount
+	u.issuers[c.issuerID] += amount
+	u.bondTypes[c.bondType] += amount
+	u.maturityYears[c.maturityYear] += amount
+}
+
+// clone создает копию объекта
+func (u *optimizerUsage) clone() *optimizerUsage {
+	v := newOptimizerUsage()
+	for k, amount := range u.bonds {
+		v.bonds[k] = amount
+	}
+	for k, amount := range u.issuers {
+		v.issuers[k] = amount
+	}
+	for k, amount := range u.bondTypes {
+		v.bondTypes[k] = amount
+	}
+	for k, amount := range u.maturityYears {
+		v.maturityYears[k] = amount
+	}
+
+	return v
+}
+
+// room возвращает сумму, которую еще можно вложить в облигацию кандидата c, не нарушая лимиты
+func (u *optimizerUsage) room(c *optimizerCandidate, limits optimizerLimits, totalAmount float64) float64 {
+	room := limits.bond*totalAmount - u.bonds[c.bondID]
+	room = math.Min(room, limits.issuer*totalAmount-u.issuers[c.issuerID])
+	room = math.Min(room, limits.bondType*totalAmount-u.bondTypes[c.bondType])
+	room = math.Min(room, limits.maturityYear*totalAmount-u.maturityYears[c.maturityYear])
+	return room
+}
+
+// issuerKey возвращает ключ эмитента облигации
+// Если эмитент неизвестен, то облигация считается отдельным эмитентом
+func issuerKey(r *Report) int {
+	switch {
+	case r.Bond.IssuerID != 0:
+		return r.Bond.IssuerID
+	case r.Issuer != nil && r.Issuer.ID != 0:
+		return r.Issuer.ID
+	default:
+		return -r.Bond.ID
+	}
+}
+
+// maturityYear возвращает год погашения (или оферты) облигации
+func maturityYear(now time.Time, r *Report) int {
+	return now.AddDate(0, 0, r.DaysTillMaturity).Year()
+}
+
+// newPortfolioOptimizer создает оптимизатор для портфеля на сумму totalAmount
+// Если ограничения не заданы, то они не применяются
+func newPortfolioOptimizer(now time.Time, totalAmount float64, costs *CostModel, constraints *SuggestConstraints) *portfolioOptimizer {
+	if constraints == nil {
+		constraints = &SuggestConstraints{}
+	}
+
+	return &portfolioOptimizer{
+		now:         now,
+		costs:       costs,
+		constraints: constraints,
+		totalAmount: totalAmount,
+		usage:       newOptimizerUsage(),
+	}
+}
+
+// allocate формирует позиции из подходящих облигаций на сумму не более budget
+// Возвращает позиции и неиспользованный остаток суммы
+// Лимиты на доли не нарушаются: если подходящих облигаций мало, то часть суммы остается неинвестированной
+// (ослабить лимиты можно только явно, см. SuggestConstraints.MaxCashWeight)
+//
+// Задача решается в два этапа: сначала находится оптимальное непрерывное распределение суммы
+// (задача линейного программирования с одним дополнительным ограничением на дюрацию решается подбором
+// множителя Лагранжа), затем объемы округляются вниз до целого числа лотов, а остаток суммы
+// докупается по одному лоту в порядке убывания скорректированной доходности.
+// Облигации, торги которыми приостановлены или лот которых стоит дороже budget, пропускаются
+func (o *portfolioOptimizer) allocate(reports []*Report, budget float64) ([]*SuggestedPortfolioPosition, float64) {
+	candidates := make([]*optimizerCandidate, 0, len(reports))
+	for _, report := range reports {
+		// Если по облигации предвидится оферта, то считаем, что облигация будет предъявлена к выкупу
+		r := report
+		if r.ToOffer != nil {
+			r = r.ToOffer
+		}
+		if r.OpenValue <= 0 || !isTradingActive(r) {
+			continue
+		}
+
+		lot := lotSize(r)
+		price := r.OpenValue * float64(lot)
+		if price > budget {
+			continue
+		}
+
+		candidates = append(candidates, &optimizerCandidate{
+			report:       r,
+			price:        price,
+			lot:          lot,
+			yield:        r.YieldToMaturity,
+			duration:     r.MacaulayDuration,
+			bondID:       r.Bond.ID,
+			issuerID:     issuerKey(r),
+			bondType:     r.Bond.Type,
+			maturityYear: maturityYear(o.now, r),
+		})
+	}
+	if len(candidates) == 0 {
+		return make([]*SuggestedPortfolioPosition, 0), budget
+	}
+
+	limit := func(weight float64) float64 {
+		if weight == 0 {
+			return 1
+		}
+		return weight
+	}
+	limits := optimizerLimits{
+		bond:         limit(o.constraints.MaxBondWeight),
+		issuer:       limit(o.constraints.MaxIssuerWeight),
+		bondType:     limit(o.constraints.MaxBondTypeWeight),
+		maturityYear: limit(o.constraints.MaxMaturityYearWeight),
+		maxBond:      1,
+	}
+	if o.constraints.MinPositions > 0 {
+		limits.maxBond = 1 / float64(o.constraints.MinPositions)
+		limits.bond = math.Min(limits.bond, limits.maxBond)
+	}
+
+	// Лимиты на доли ослабляются, только если задана максимальная доля остатка
+	var quantities []int
+	remainder := budget
+	for {
+		quantities, remainder = o.solve(candidates, budget, limits)
+
+		if o.constraints.MaxCashWeight == 0 || remainder <= o.constraints.MaxCashWeight*budget || !limits.relax() {
+			break
+		}
+		o.relaxed = true
+	}
+
+	positions := make([]*SuggestedPortfolioPosition, 0)
+	for i, c := range candidates {
+		if quantities[i] == 0 {
+			continue
+		}
+
+		o.usage.add(c, c.price*float64(quantities[i]))
+		positions = append(positions, newSuggestedPosition(o.now, c.report, quantities[i]*c.lot, o.costs))
+	}
+
+	// Позиции упорядочиваются по убыванию доходности, как и при выборке облигаций
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].YieldToMaturity > positions[j].YieldToMaturity
+	})
+
+	return positions, remainder
+}
+
+// solve подбирает количество лотов каждого кандидата при заданных лимитах
+// Возвращает количества и неиспользованный остаток суммы
+func (o *portfolioOptimizer) solve(candidates []*optimizerCandidate, budget float64, limits optimizerLimits) ([]int, float64) {
+	var (
+		amounts []float64
+		lambda  float64
+	)
+	if o.constraints.TargetDuration > 0 {
+		amounts, lambda = o.distributeWithTargetDuration(candidates, budget, limits)
+	} else {
+		amounts = o.distribute(candidates, budget, limits, 0)
+	}
+
+	// Округление до целого числа лотов
+	quantities := make([]int, len(candidates))
+	usage := o.usage.clone()
+	remainder := budget
+	for i, c := range candidates {
+		quantities[i] = int(math.Floor(amounts[i]/c.price + 1e-9))
+		remainder -= c.price * float64(quantities[i])
+		usage.add(c, c.price*float64(quantities[i]))
+	}
+
+	// Докупка лотов на остаток суммы
+	order := o.rank(candidates, lambda)
+	for {
+		bought := false
+		for _, i := range order {
+			c := candidates[i]
+			if c.price > remainder || c.price > usage.room(c, limits, o.totalAmount)+1e-9 {
+				continue
+			}
+
+			quantities[i]++
+			remainder -= c.price
+			usage.add(c, c.price)
+			bought = true
+			break
+		}
+
+		if !bought {
+			break
+		}
+	}
+
+	return quantities, remainder
+}
+
+// score возвращает скорректированную доходность облигации
+// Множитель lambda штрафует отклонение дюрации облигации от целевой
+func (o *portfolioOptimizer) score(c *optimizerCandidate, lambda float64) float64 {
+	if o.constraints.TargetDuration <= 0 {
+		return c.yield
+	}
+
+	return c.yield - lambda*(c.duration-o.constraints.TargetDuration)
+}
+
+// rank возвращает индексы кандидатов в порядке убывания скорректированной доходности
+func (o *portfolioOptimizer) rank(candidates []*optimizerCandidate, lambda float64) []int {
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return o.score(candidates[order[i]], lambda) > o.score(candidates[order[j]], lambda)
+	})
+
+	return order
+}
+
+// distribute распределяет сумму между кандидатами в порядке убывания скорректированной доходности с учетом лимитов
+// Для задачи с ограничениями только на сумму и доли такое распределение оптимально
+func (o *portfolioOptimizer) distribute(candidates []*optimizerCandidate, budget float64, limits optimizerLimits, lambda float64) []float64 {
+	amounts := make([]float64, len(candidates))
+	usage := o.usage.clone()
+	remainder := budget
+	for _, i := range o.rank(candidates, lambda) {
+		c := candidates[i]
+		amount := math.Min(remainder, usage.room(c, limits, o.totalAmount))
+		if amount <= 0 {
+			continue
+		}
+
+		amounts[i] = amount
+		usage.add(c, amount)
+		remainder -= amount
+		if remainder <= 0 {
+			break
+		}
+	}
+
+	return amounts
+}
+
+// duration возвращает дюрацию распределения суммы между кандидатами
+func (o *portfolioOptimizer) duration(candidates []*optimizerCandidate, amounts []float64) float64 {
+	total, weighted := 0.0, 0.0
+	for i, c := range candidates {
+		total += amounts[i]
+		weighted += amounts[i] * c.duration
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return weighted / total
+}
+
+// distributeWithTargetDuration распределяет сумму между кандидатами так, чтобы дюрация была близка к целевой
+// Возвращает распределение и множитель Лагранжа, с которым ранжируются кандидаты
+//
+// Дюрация распределения не возрастает с ростом множителя, поэтому он подбирается бисекцией.
+// В точке излома, где распределение меняется скачком, оптимальное решение - смесь распределений по обе стороны
+// от нее, которая дает ровно целевую дюрацию. Если целевая дюрация недостижима, то используется ближайшая к ней
+func (o *portfolioOptimizer) distributeWithTargetDuration(candidates []*optimizerCandidate, budget float64, limits optimizerLimits) ([]float64, float64) {
+	target := o.constraints.TargetDuration
+	distribute := func(lambda float64) ([]float64, float64) {
+		amounts := o.distribute(candidates, budget, limits, lambda)
+		return amounts, o.duration(candidates, amounts)
+	}
+
+	// Если без штрафа дюрация уже в пределах допуска, то доходность не приносится в жертву
+	amounts, duration := distribute(0)
+	if math.Abs(duration-target) <= o.constraints.DurationTolerance {
+		return amounts, 0
+	}
+
+	lo, hi := -optimizerLambdaBound, optimizerLambdaBound
+	for i := 0; i < optimizerLambdaIterations; i++ {
+		mid := (lo + hi) / 2
+		if _, d := distribute(mid); d > target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	amountsLo, dLo := distribute(lo)
+	amountsHi, dHi := distribute(hi)
+	if dLo <= target {
+		return amountsLo, lo
+	}
+	if dHi >= target || math.Abs(dLo-dHi) < 1e-9 {
+		return amountsHi, hi
+	}
+
+	// dHi < target < dLo: оба распределения вкладывают одну и ту же сумму, поэтому дюрация смеси линейна по ее доле
+	theta := (target - dHi) / (dLo - dHi)
+	mixed := make([]float64, len(candidates))
+	for i := range mixed {
+		mixed[i] = theta*amountsLo[i] + (1-theta)*amountsHi[i]
+	}
+
+	return mixed, (lo + hi) / 2
+}
@@ -0,0 +1,314 @@
+package recommender
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func newOptimizerReport(id, issuerID int, price, yield, duration float64) *Report {
+	return &Report{
+		Bond:             &data.Bond{ID: id, IssuerID: issuerID},
+		OpenValue:        price,
+		YieldToMaturity:  yield,
+		MacaulayDuration: duration,
+	}
+}
+
+func positionAmounts(positions []*SuggestedPortfolioPosition) map[int]float64 {
+	amounts := make(map[int]float64)
+	for _, p := range positions {
+		amounts[p.Bond.ID] += p.OpenValue
+	}
+
+	return amounts
+}
+
+func TestPortfolioOptimizer_Limits(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newOptimizerReport(1, 1, 1000, 12, 1),
+		newOptimizerReport(2, 1, 1000, 11, 1),
+		newOptimizerReport(3, 2, 1000, 10, 1),
+		newOptimizerReport(4, 3, 1000, 9, 1),
+		newOptimizerReport(5, 4, 1000, 8, 1),
+	}
+
+	o := newPortfolioOptimizer(time.Now(), 100000, nil, DefaultSuggestConstraints())
+	positions, remainder := o.allocate(reports, 100000)
+	assert.Equal(float64(0), remainder)
+	assert.False(o.relaxed)
+
+	// Не более 25% на облигацию и 35% на эмитента: у эмитента #1 остается 10% на вторую облигацию
+	amounts := positionAmounts(positions)
+	assert.Equal(map[int]float64{1: 25000, 2: 10000, 3: 25000, 4: 25000, 5: 15000}, amounts)
+	assert.Equal(1, positions[0].Bond.ID)
+}
+
+func TestPortfolioOptimizer_Unconstrained(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newOptimizerReport(1, 1, 980, 12, 1),
+		newOptimizerReport(2, 2, 1000, 11, 1),
+	}
+
+	// Без ограничений вся сумма вкладывается в облигацию с наибольшей доходностью
+	o := newPortfolioOptimizer(time.Now(), 10000, nil, &SuggestConstraints{})
+	positions, remainder := o.allocate(reports, 10000)
+	assert.Len(positions, 1)
+	assert.Equal(10, positions[0].Quantity)
+	assert.InDelta(200, remainder, 1e-9)
+}
+
+func TestPortfolioOptimizer_LotSize(t *testing.T) {
+	assert := assertion.New(t)
+
+	lot := func(r *Report, size int, status string) *Report {
+		r.MarketData = &data.MarketData{LotSize: &size, TradingStatus: &status}
+		return r
+	}
+	reports := []*Report{
+		lot(newOptimizerReport(1, 1, 1000, 14, 1), 1, "S"),
+		lot(newOptimizerReport(2, 2, 980, 12, 1), 20, data.TradingStatusActive),
+		lot(newOptimizerReport(3, 3, 1000, 11, 1), 3, data.TradingStatusActive),
+	}
+
+	// Торги первой облигацией приостановлены, лот второй дороже всей суммы,
+	// поэтому сумма вкладывается в третью облигацию целыми лотами
+	o := newPortfolioOptimizer(time.Now(), 10000, nil, &SuggestConstraints{})
+	positions, remainder := o.allocate(reports, 10000)
+	assert.Len(positions, 1)
+	assert.Equal(3, positions[0].Bond.ID)
+	assert.Equal(9, positions[0].Quantity)
+	assert.InDelta(1000, remainder, 1e-9)
+}
+
+func TestPortfolioOptimizer_DefaultLimitsAreHard(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newOptimizerReport(1, 1, 1000, 12, 1),
+		newOptimizerReport(2, 2, 1000, 11, 1),
+	}
+
+	// Двух облигаций недостаточно, чтобы вложить сумму с лимитом 25% на облигацию,
+	// но лимиты не ослабляются: остаток суммы не инвестируется
+	o := newPortfolioOptimizer(time.Now(), 10000, nil, DefaultSuggestConstraints())
+	positions, remainder := o.allocate(reports, 10000)
+	assert.Equal(map[int]float64{1: 2000, 2: 2000}, positionAmounts(positions))
+	assert.Equal(6000.0, remainder)
+	assert.False(o.relaxed)
+
+	// Без ограничений вся сумма вкладывается в облигацию с наибольшей доходностью
+	o = newPortfolioOptimizer(time.Now(), 10000, nil, nil)
+	positions, remainder = o.allocate(reports, 10000)
+	assert.Equal(map[int]float64{1: 10000}, positionAmounts(positions))
+	assert.Equal(0.0, remainder)
+}
+
+func TestPortfolioOptimizer_CashRelaxation(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newOptimizerReport(1, 1, 1000, 12, 1),
+		newOptimizerReport(2, 2, 1000, 11, 1),
+	}
+
+	// Если задана максимальная доля остатка, то лимиты ослабляются, пока остаток не станет допустимым
+	o := newPortfolioOptimizer(time.Now(), 10000, nil, &SuggestConstraints{MaxBondWeight: 0.25, MaxCashWeight: 0.02})
+	positions, remainder := o.allocate(reports, 10000)
+	assert.Len(positions, 2)
+	assert.LessOrEqual(remainder, 200.0)
+	assert.Greater(positions[0].Quantity, positions[1].Quantity)
+	assert.True(o.relaxed)
+
+	// Лимит на долю облигации, который следует из минимального количества позиций, не ослабляется
+	o = newPortfolioOptimizer(time.Now(), 10000, nil, &SuggestConstraints{MinPositions: 4, MaxCashWeight: 0.02})
+	positions, remainder = o.allocate(reports, 10000)
+	assert.Equal(map[int]float64{1: 2000, 2: 2000}, positionAmounts(positions))
+	assert.Equal(6000.0, remainder)
+}
+
+func TestGeneratePositionsForSuggestion_LimitsRelaxed(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newOptimizerReport(1, 1, 1000, 12, 1),
+		newOptimizerReport(2, 2, 1000, 11, 1),
+	}
+	selectBonds := func(collection Collection, duration Duration) ([]*Report, error) {
+		return reports, nil
+	}
+
+	request := &SuggestRequest{Amount: 10000, Constraints: DefaultSuggestConstraints()}
+	positions, relaxed, err := generatePositionsForSuggestion(time.Now(), request, selectBonds)
+	assert.NoError(err)
+	assert.Len(positions, 2)
+	assert.False(relaxed)
+
+	request.Constraints.MinPositions = 0
+	request.Constraints.MaxCashWeight = 0.02
+	_, relaxed, err = generatePositionsForSuggestion(time.Now(), request, selectBonds)
+	assert.NoError(err)
+	assert.True(relaxed)
+}
+
+func TestPortfolioOptimizer_TargetDuration(t *testing.T) {
+	assert := assertion.New(t)
+
+	reports := []*Report{
+		newOptimizerReport(1, 1, 1000, 12, 4),
+		newOptimizerReport(2, 2, 1000, 11, 3.5),
+		newOptimizerReport(3, 3, 1000, 9, 1),
+		newOptimizerReport(4, 4, 1000, 8, 0.5),
+	}
+
+	constraints := &SuggestConstraints{MaxBondWeight: 0.5, TargetDuration: 2, DurationTolerance: 0.1}
+	o := newPortfolioOptimizer(time.Now(), 100000, nil, constraints)
+	positions, remainder := o.allocate(reports, 100000)
+	assert.Equal(float64(0), remainder)
+
+	total, duration := 0.0, 0.0
+	for _, p := range positions {
+		total += p.OpenValue
+		duration += p.OpenValue * p.MacaulayDuration
+	}
+	duration /= total
+	assert.LessOrEqual(math.Abs(duration-2), 0.1)
+
+	// Без целевой дюрации выбираются наиболее доходные длинные облигации
+	o = newPortfolioOptimizer(time.Now(), 100000, nil, &SuggestConstraints{MaxBondWeight: 0.5})
+	positions, _ = o.allocate(reports, 100000)
+	assert.Equal(map[int]float64{1: 50000, 2: 50000}, positionAmounts(positions))
+}
+
+func TestSuggestConstraints_Validate(t *testing.T) {
+	assert := assertion.New(t)
+
+	assert.NoError(DefaultSuggestConstraints().Validate())
+	assert.NoError((&SuggestConstraints{}).Validate())
+	assert.Error((&SuggestConstraints{MaxBondWeight: 1.5}).Validate())
+	assert.Error((&SuggestConstraints{MaxIssuerWeight: -0.1}).Validate())
+	assert.Error((&SuggestConstraints{MaxBondTypeWeight: 1.1}).Validate())
+	assert.Error((&SuggestConstraints{MaxMaturityYearWeight: -1}).Validate())
+	assert.Error((&SuggestConstraints{MinPositions: -1}).Validate())
+	assert.Error((&SuggestConstraints{TargetDuration: -1}).Validate())
+	assert.Error((&SuggestConstraints{MaxCashWeight: 2}).Validate())
+}
+
+func TestPortfolioOptimizer_GroupLimits(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	newReport := func(id int, bondType data.BondType, yield float64, days int) *Report {
+		r := newOptimizerReport(id, id, 1000, yield, 1)
+		r.Bond.Type = bondType
+		r.Bond.FaceUnit = "RUB"
+		r.DaysTillMaturity = days
+		return r
+	}
+	reports := []*Report{
+		newReport(1, data.OFZBond, 12, 100),
+		newReport(2, data.OFZBond, 11, 100),
+		newReport(3, data.CorporateBond, 10, 800),
+		newReport(4, data.CorporateBond, 9, 800),
+		newReport(5, data.MunicipalBond, 8, 1200),
+	}
+	reports[2].Bond.FaceUnit = "CNY"
+
+	// Не более 40% облигаций одного типа
+	o := newPortfolioOptimizer(now, 100000, nil, &SuggestConstraints{MaxBondWeight: 0.5, MaxBondTypeWeight: 0.4})
+	positions, remainder := o.allocate(reports, 100000)
+	assert.Equal(float64(0), remainder)
+	assert.Equal(map[int]float64{1: 40000, 3: 40000, 5: 20000}, positionAmounts(positions))
+
+	issuers, bondTypes, maturityYears, currencies := concentrations(now, positions, 100000)
+	assert.Len(issuers, 3)
+	assert.Equal([]*SuggestConcentration{
+		{Name: string(data.OFZBond), Amount: 40000, Weight: 0.4},
+		{Name: string(data.CorporateBond), Amount: 40000, Weight: 0.4},
+		{Name: string(data.MunicipalBond), Amount: 20000, Weight: 0.2},
+	}, bondTypes)
+	assert.Equal([]*SuggestConcentration{
+		{Name: "2026", Amount: 40000, Weight: 0.4},
+		{Name: "2028", Amount: 40000, Weight: 0.4},
+		{Name: "2029", Amount: 20000, Weight: 0.2},
+	}, maturityYears)
+	assert.Equal([]*SuggestConcentration{
+		{Name: "RUB", Amount: 60000, Weight: 0.6},
+		{Name: "CNY", Amount: 40000, Weight: 0.4},
+	}, currencies)
+
+	// Не более 30% облигаций с погашением в одном году
+	o = newPortfolioOptimizer(now, 100000, nil, &SuggestConstraints{MaxMaturityYearWeight: 0.3})
+	positions, remainder = o.allocate(reports, 100000)
+	assert.Equal(float64(10000), remainder)
+	assert.Equal(map[int]float64{1: 30000, 3: 30000, 5: 30000}, positionAmounts(positions))
+}
+
+func TestPortfolioOptimizer_GroupLimitsForceCash(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	newReport := func(id, issuerID int, bondType data.BondType, yield float64, days int) *Report {
+		r := newOptimizerReport(id, issuerID, 1000, yield, 1)
+		r.Bond.Type = bondType
+		r.DaysTillMaturity = days
+		return r
+	}
+
+	// Лимиты на доли эмитента, типа облигаций и года погашения жесткие:
+	// если облигаций для диверсификации не хватает, то остаток суммы не инвестируется
+	testCases := []struct {
+		name        string
+		reports     []*Report
+		constraints *SuggestConstraints
+		amounts     map[int]float64
+	}{
+		{
+			name: "issuer",
+			reports: []*Report{
+				newReport(1, 1, data.CorporateBond, 12, 100),
+				newReport(2, 1, data.CorporateBond, 11, 800),
+			},
+			constraints: &SuggestConstraints{MaxIssuerWeight: 0.3},
+			amounts:     map[int]float64{1: 30000},
+		},
+		{
+			name: "bond type",
+			reports: []*Report{
+				newReport(1, 1, data.CorporateBond, 12, 100),
+				newReport(2, 2, data.CorporateBond, 11, 800),
+			},
+			constraints: &SuggestConstraints{MaxBondTypeWeight: 0.4},
+			amounts:     map[int]float64{1: 40000},
+		},
+		{
+			name: "maturity year",
+			reports: []*Report{
+				newReport(1, 1, data.CorporateBond, 12, 100),
+				newReport(2, 2, data.OFZBond, 11, 100),
+			},
+			constraints: &SuggestConstraints{MaxMaturityYearWeight: 0.2},
+			amounts:     map[int]float64{1: 20000},
+		},
+	}
+
+	for _, tc := range testCases {
+		o := newPortfolioOptimizer(now, 100000, nil, tc.constraints)
+		positions, remainder := o.allocate(tc.reports, 100000)
+		assert.Equal(tc.amounts, positionAmounts(positions), tc.name)
+		invested := 0.0
+		for _, amount := range tc.amounts {
+			invested += amount
+		}
+		assert.Equal(100000-invested, remainder, tc.name)
+		assert.False(o.relaxed, tc.name)
+	}
+}
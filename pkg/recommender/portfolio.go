@@ -0,0 +1,258 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// PortfolioValuation - оценка портфеля пользователя по текущим рыночным данным
+type PortfolioValuation struct {
+	// Портфель
+	Portfolio *data.Portfolio
+
+	// Открытые позиции
+	Holdings []*PortfolioHolding
+
+	// Сумма вложений в открытые позиции (с учетом НКД и комиссий, за вычетом полученных амортизаций), в валюте
+	Cost float64
+
+	// Текущая рыночная стоимость открытых позиций (с учетом НКД), в валюте
+	MarketValue float64
+
+	// Нереализованная прибыль по открытым позициям, в валюте
+	UnrealizedProfitLoss float64
+
+	// Реализованная прибыль (продажи, погашения и полученные купоны), в валюте
+	RealizedProfitLoss float64
+
+	// Нереализованная прибыль, в % по отношению к сумме вложений
+	RelativeProfitLoss float64
+
+	// Эффективная доходность к погашению (средневзвешенная по рыночной стоимости позиций), % годовых
+	YieldToMaturity float64
+
+	// Модифицированная дюрация (средневзвешенная по рыночной стоимости позиций), лет
+	ModifiedDuration float64
+
+	// Изменение стоимости портфеля при изменении доходности на 1 б.п., в валюте
+	DV01 float64
+}
+
+// PortfolioHolding - открытая позиция в портфеле пользователя
+type PortfolioHolding struct {
+	// Облигация
+	Bond *data.Bond
+
+	// Текущий отчет по облигации (nil, если облигация уже не торгуется)
+	Report *Report
+
+	// Размер позиции
+	Quantity int
+
+	// Сумма вложений в позицию (по средней цене покупки, с учетом НКД и комиссий, за вычетом полученных амортизаций), в валюте
+	Cost float64
+
+	// Текущая рыночная стоимость позиции (с учетом НКД), в валюте
+	MarketValue float64
+
+	// Нереализованная прибыль, в валюте
+	UnrealizedProfitLoss float64
+
+	// Реализованная прибыль (продажи, погашение и полученные купоны), в валюте
+	RealizedProfitLoss float64
+
+	// Доля в составе портфеля (0..1)
+	Weight float64
+}
+
+// ValuatePortfolio выполняет оценку портфеля пользователя по текущим рыночным данным
+// Выплаты по облигациям, полученные с даты покупки до текущего момента, учитываются в прибыли и себестоимости позиций
+func (s *service) ValuatePortfolio(ctx context.Context, tx *data.TX, portfolio *data.Portfolio) (*PortfolioValuation, error) {
+	payments := make(map[int][]*data.Payment)
+	for _, trade := range portfolio.Trades {
+		if _, exists := payments[trade.BondID]; exists {
+			continue
+		}
+
+		list, err := tx.Payments.List(data.PaymentListQuery{BondID: trade.BondID})
+		if err != nil {
+			return nil, err
+		}
+		payments[trade.BondID] = list
+	}
+
+	holdings, err := computeHoldings(today(), portfolio.Trades, payments)
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := &PortfolioValuation{
+		Portfolio: portfolio,
+		Holdings:  make([]*PortfolioHolding, 0, len(holdings)),
+	}
+
+	for _, h := range holdings {
+		valuation.RealizedProfitLoss += h.RealizedProfitLoss
+		if h.Quantity == 0 {
+			continue
+		}
+
+		report, err := s.GetReport(ctx, tx, h.Bond.ID)
+		if err != nil {
+			if err != ErrNotFound {
+				return nil, err
+			}
+			report = nil
+		}
+
+		valuateHolding(h, report)
+
+		valuation.Holdings = append(valuation.Holdings, h)
+		valuation.Cost += h.Cost
+		valuation.MarketValue += h.MarketValue
+		valuation.UnrealizedProfitLoss += h.UnrealizedProfitLoss
+	}
+
+	for _, h := range valuation.Holdings {
+		if valuation.MarketValue > 0 {
+			h.Weight = h.MarketValue / valuation.MarketValue
+		}
+
+		if h.Report != nil {
+			valuation.YieldToMaturity += h.Report.YieldToMaturity * h.Weight
+			valuation.ModifiedDuration += h.Report.ModifiedDuration * h.Weight
+			valuation.DV01 += h.Report.DV01 * float64(h.Quantity)
+		}
+	}
+
+	valuation.RealizedProfitLoss = round2(valuation.RealizedProfitLoss)
+	if valuation.Cost > 0 {
+		valuation.RelativeProfitLoss = round2(100.0 * valuation.UnrealizedProfitLoss / valuation.Cost)
+	}
+	valuation.YieldToMaturity = round2(valuation.YieldToMaturity)
+
+	return valuation, nil
+}
+
+// valuateHolding оценивает позицию по отчету report
+// Если отчета нет (облигация не торгуется, но еще не погашена), то позиция оценивается по стоимости покупки
+func valuateHolding(h *PortfolioHolding, report *Report) {
+	h.Report = report
+	h.MarketValue = h.Cost
+	if report != nil {
+		// Рыночная стоимость - это стоимость продажи по текущей цене с НКД, комиссия в ней не учитывается
+		h.MarketValue = round2(float64(h.Quantity) * report.OpenValue)
+	}
+	h.UnrealizedProfitLoss = round2(h.MarketValue - h.Cost)
+}
+
+// ValidateTrades проверяет согласованность сделок портфеля:
+// количество облигаций в сделке не может быть нулевым, а продажа не может превышать размер позиции на дату сделки
+func ValidateTrades(trades []*data.Trade) error {
+	_, err := computeHoldings(time.Time{}, trades, nil)
+	return err
+}
+
+// computeHoldings рассчитывает позиции по списку сделок методом средней цены
+// Выплаты по облигациям (payments, по ID облигации) до момента now учитываются так:
+// купоны увеличивают реализованную прибыль, амортизации уменьшают себестоимость позиции,
+// а погашение закрывает позицию по сумме погашения
+// Выплата причитается по облигациям, купленным до даты выплаты; сделки в дату выплаты учитываются после нее
+// Возвращаются все облигации, по которым были сделки, в т.ч. с закрытыми позициями
+// Если сделки несогласованы (см. ValidateTrades), то возвращается ошибка
+func computeHoldings(now time.Time, trades []*data.Trade, payments map[int][]*data.Payment) ([]*PortfolioHolding, error) {
+	sorted := make([]*data.Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	holdings := make([]*PortfolioHolding, 0)
+	index := make(map[int]*PortfolioHolding)
+	pending := make(map[int][]*data.Payment)
+
+	// receivePayments учитывает выплаты по позиции h до даты date (не включая ее)
+	receivePayments := func(h *PortfolioHolding, bondID int, date time.Time) {
+		for len(pending[bondID]) > 0 && pending[bondID][0].Date.Before(date) {
+			payment := pending[bondID][0]
+			pending[bondID] = pending[bondID][1:]
+			if h.Quantity == 0 || payment.ValueRub <= 0 {
+				continue
+			}
+
+			value := float64(h.Quantity) * payment.ValueRub
+			switch payment.Type {
+			case data.CouponPayment:
+				h.RealizedProfitLoss += value
+			case data.AmortizationPayment:
+				h.Cost -= value
+			case data.MaturityPayment:
+				h.RealizedProfitLoss += value - h.Cost
+				h.Cost = 0
+				h.Quantity = 0
+			}
+		}
+	}
+
+	for _, trade := range sorted {
+		h, exists := index[trade.BondID]
+		if !exists {
+			bond := trade.Bond
+			h = &PortfolioHolding{Bond: &bond}
+			index[trade.BondID] = h
+			holdings = append(holdings, h)
+
+			for _, payment := range payments[trade.BondID] {
+				if !payment.Date.Before(trade.Date) && !payment.Date.After(now) {
+					pending[trade.BondID] = append(pending[trade.BondID], payment)
+				}
+			}
+			// Погашение закрывает позицию, поэтому в ту же дату оно учитывается после купона и амортизации
+			list := pending[trade.BondID]
+			sort.SliceStable(list, func(i, j int) bool {
+				if !list[i].Date.Equal(list[j].Date) {
+					return list[i].Date.Before(list[j].Date)
+				}
+				return list[i].Type != data.MaturityPayment && list[j].Type == data.MaturityPayment
+			})
+		}
+
+		receivePayments(h, trade.BondID, trade.Date)
+
+		if trade.Quantity == 0 {
+			return nil, fmt.Errorf("%w: quantity of %s trade on %s is zero",
+				ErrInvalidTrade, trade.Bond.ISIN, trade.Date.Format("2006-01-02"))
+		}
+
+		unitValue := trade.Price*trade.FaceValue/100.0 + trade.AccruedInterest
+		if trade.Quantity > 0 {
+			h.Cost += float64(trade.Quantity)*unitValue + trade.Fee
+			h.Quantity += trade.Quantity
+			continue
+		}
+
+		// При продаже себестоимость списывается по средней цене
+		quantity := -trade.Quantity
+		if quantity > h.Quantity {
+			return nil, fmt.Errorf("%w: cannot sell %d bonds of %s on %s, the position is %d bonds",
+				ErrInvalidTrade, quantity, trade.Bond.ISIN, trade.Date.Format("2006-01-02"), h.Quantity)
+		}
+
+		cost := h.Cost * float64(quantity) / float64(h.Quantity)
+		h.RealizedProfitLoss += float64(quantity)*unitValue - trade.Fee - cost
+		h.Cost -= cost
+		h.Quantity -= quantity
+	}
+
+	for _, h := range holdings {
+		receivePayments(h, h.Bond.ID, now.AddDate(0, 0, 1))
+		h.Cost = round2(h.Cost)
+		h.RealizedProfitLoss = round2(h.RealizedProfitLoss)
+	}
+
+	return holdings, nil
+}
@@ -0,0 +1,124 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestComputeHoldings(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*data.Trade{
+		{BondID: 1, Bond: data.Bond{ID: 1}, Date: t0.AddDate(0, 1, 0), Quantity: -5, Price: 110, FaceValue: 1000, Fee: 1},
+		{BondID: 1, Bond: data.Bond{ID: 1}, Date: t0, Quantity: 10, Price: 100, FaceValue: 1000, AccruedInterest: 10, Fee: 5},
+		{BondID: 2, Bond: data.Bond{ID: 2}, Date: t0, Quantity: 1, Price: 95, FaceValue: 1000, Fee: 0.5},
+		{BondID: 2, Bond: data.Bond{ID: 2}, Date: t0.AddDate(0, 2, 0), Quantity: -1, Price: 97, FaceValue: 1000},
+	}
+
+	holdings, err := computeHoldings(t0.AddDate(1, 0, 0), trades, nil)
+	assert.NoError(err)
+	assert.Len(holdings, 2)
+
+	// Продажа половины позиции списывает половину себестоимости
+	assert.Equal(1, holdings[0].Bond.ID)
+	assert.Equal(5, holdings[0].Quantity)
+	assert.Equal(5052.5, holdings[0].Cost)
+	assert.Equal(5500.0-1-5052.5, holdings[0].RealizedProfitLoss)
+
+	// Закрытая позиция
+	assert.Equal(2, holdings[1].Bond.ID)
+	assert.Equal(0, holdings[1].Quantity)
+	assert.Equal(float64(0), holdings[1].Cost)
+	assert.Equal(19.5, holdings[1].RealizedProfitLoss)
+}
+
+func TestComputeHoldings_Payments(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*data.Trade{
+		{BondID: 1, Bond: data.Bond{ID: 1}, Date: t0, Quantity: 10, Price: 100, FaceValue: 1000},
+		{BondID: 2, Bond: data.Bond{ID: 2}, Date: t0, Quantity: 2, Price: 100, FaceValue: 1000},
+	}
+	payments := map[int][]*data.Payment{
+		1: {
+			// Выплата до покупки не учитывается
+			{BondID: 1, Type: data.CouponPayment, Date: t0.AddDate(0, -1, 0), ValueRub: 30},
+			{BondID: 1, Type: data.CouponPayment, Date: t0.AddDate(0, 3, 0), ValueRub: 25},
+			{BondID: 1, Type: data.AmortizationPayment, Date: t0.AddDate(0, 3, 0), ValueRub: 500},
+			// Будущая выплата не учитывается
+			{BondID: 1, Type: data.CouponPayment, Date: t0.AddDate(0, 9, 0), ValueRub: 12.5},
+		},
+		2: {
+			{BondID: 2, Type: data.CouponPayment, Date: t0.AddDate(0, 6, 0), ValueRub: 40},
+			{BondID: 2, Type: data.MaturityPayment, Date: t0.AddDate(0, 6, 0), ValueRub: 1000},
+		},
+	}
+
+	holdings, err := computeHoldings(t0.AddDate(0, 7, 0), trades, payments)
+	assert.NoError(err)
+	assert.Len(holdings, 2)
+
+	// Амортизация возвращает часть вложений и не приводит к убытку
+	assert.Equal(10, holdings[0].Quantity)
+	assert.Equal(5000.0, holdings[0].Cost)
+	assert.Equal(250.0, holdings[0].RealizedProfitLoss)
+
+	// Погашенная позиция закрывается по сумме погашения
+	assert.Equal(0, holdings[1].Quantity)
+	assert.Equal(0.0, holdings[1].Cost)
+	assert.Equal(80.0, holdings[1].RealizedProfitLoss)
+}
+
+func TestValidateTrades(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	buy := &data.Trade{ID: 1, BondID: 1, Bond: data.Bond{ID: 1}, Date: t0, Quantity: 10, Price: 100, FaceValue: 1000}
+
+	assert.NoError(ValidateTrades([]*data.Trade{
+		buy,
+		{ID: 2, BondID: 1, Bond: data.Bond{ID: 1}, Date: t0.AddDate(0, 1, 0), Quantity: -10, Price: 100, FaceValue: 1000},
+	}))
+
+	// Продажа больше позиции
+	assert.Error(ValidateTrades([]*data.Trade{
+		buy,
+		{ID: 2, BondID: 1, Bond: data.Bond{ID: 1}, Date: t0.AddDate(0, 1, 0), Quantity: -11, Price: 100, FaceValue: 1000},
+	}))
+
+	// Продажа до покупки
+	assert.Error(ValidateTrades([]*data.Trade{
+		buy,
+		{ID: 2, BondID: 1, Bond: data.Bond{ID: 1}, Date: t0.AddDate(0, -1, 0), Quantity: -1, Price: 100, FaceValue: 1000},
+	}))
+
+	// Нулевое количество
+	assert.Error(ValidateTrades([]*data.Trade{
+		{ID: 3, BondID: 1, Bond: data.Bond{ID: 1}, Date: t0, Price: 100, FaceValue: 1000},
+	}))
+}
+
+func TestValuateHolding(t *testing.T) {
+	assert := assertion.New(t)
+
+	h := &PortfolioHolding{Bond: &data.Bond{ID: 1}, Quantity: 10, Cost: 10055}
+	report := &Report{OpenValue: 1020, OpenFee: 0.51}
+
+	// Комиссия за покупку не уменьшает рыночную стоимость позиции
+	valuateHolding(h, report)
+	assert.Same(report, h.Report)
+	assert.Equal(10200.0, h.MarketValue)
+	assert.Equal(145.0, h.UnrealizedProfitLoss)
+
+	// Без отчета позиция оценивается по стоимости покупки
+	valuateHolding(h, nil)
+	assert.Nil(h.Report)
+	assert.Equal(10055.0, h.MarketValue)
+	assert.Equal(0.0, h.UnrealizedProfitLoss)
+}
@@ -0,0 +1,196 @@
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// DefaultInflation - прогноз инфляции по умолчанию для индексации номинала линкеров, % годовых
+const DefaultInflation = 4.0
+
+// ProjectionAssumptions - допущения, по которым оцениваются еще не объявленные выплаты по облигациям
+type ProjectionAssumptions struct {
+	// Прогнозная ставка купона флоатеров, % годовых
+	// Если не задана, то используется ставка последнего известного купона
+	FloatingRate *float64
+
+	// Прогноз инфляции для индексации номинала линкеров, % годовых
+	Inflation float64
+}
+
+// Validate проверяет корректность допущений
+func (a *ProjectionAssumptions) Validate() error {
+	if a.FloatingRate != nil && *a.FloatingRate < 0 {
+		return fmt.Errorf("floating rate must not be negative")
+	}
+	if a.Inflation <= -100 {
+		return fmt.Errorf("inflation must be greater than -100%%")
+	}
+
+	return nil
+}
+
+// DefaultProjectionAssumptions возвращает допущения по умолчанию
+func DefaultProjectionAssumptions() *ProjectionAssumptions {
+	return &ProjectionAssumptions{Inflation: DefaultInflation}
+}
+
+// WithProjectionAssumptions задает допущения, по которым оцениваются еще не объявленные выплаты по облигациям
+// По умолчанию используется DefaultProjectionAssumptions
+func WithProjectionAssumptions(assumptions *ProjectionAssumptions) Option {
+	return func(s *service) error {
+		err := assumptions.Validate()
+		if err != nil {
+			return err
+		}
+
+		s.projections = assumptions
+		return nil
+	}
+}
+
+// rebuildProjections пересчитывает оценки еще не объявленных выплат по флоатерам и линкерам
+// Оценки используются в таблице текущих выплат вместо нулевых значений, поэтому пересчет выполняется до ее обновления
+// Еще не объявленные купоны облигаций с фиксированным купоном не оцениваются: их ставка не следует из известных выплат
+func (s *service) rebuildProjections(tx *data.TX) error {
+	bonds, err := tx.Bonds.ListTradedByCouponType(data.FloatingCoupon, data.IndexedCoupon)
+	if err != nil {
+		return err
+	}
+
+	// Ставка последнего известного купона определяется по выплатам за последний год
+	now := today()
+	since := now.AddDate(-1, 0, 0)
+
+	payments, err := tx.Payments.List(data.PaymentListQuery{Since: &since})
+	if err != nil {
+		return err
+	}
+
+	paymentsPerBond := make(map[int][]*data.Payment)
+	for _, payment := range payments {
+		paymentsPerBond[payment.BondID] = append(paymentsPerBond[payment.BondID], payment)
+	}
+
+	items := make([]*data.PaymentProjection, 0)
+	for _, bond := range bonds {
+		if len(paymentsPerBond[bond.ID]) == 0 {
+			continue
+		}
+
+		faceValue := bond.InitialFaceValue
+		marketData, err := tx.MarketData.Get(bond.ID)
+		if err != nil && err != data.ErrNotFound {
+			return err
+		}
+		if err == nil && marketData.FaceValue != nil {
+			faceValue = *marketData.FaceValue
+		}
+
+		items = append(items, projectPayments(now, bond, faceValue, paymentsPerBond[bond.ID], s.projections)...)
+	}
+
+	return tx.PaymentProjections.Rebuild(items)
+}
+
+// projectPayments оценивает еще не объявленные будущие выплаты по облигации
+// payments - выплаты по облигации по возрастанию даты, faceValue - текущий непогашенный номинал
+//
+// Купон оценивается как номинал * ставка * длительность купонного периода / 365, где ставка - собственная ставка купона,
+// если она объявлена, прогнозная ставка флоатеров или ставка последнего известного купона.
+// Номинал линкеров индексируется по прогнозу инфляции, также оценивается и их погашение.
+// Еще не объявленные амортизации не оцениваются
+func projectPayments(now time.Time, bond *data.Bond, faceValue float64, payments []*data.Payment, assumptions *ProjectionAssumptions) []*data.PaymentProjection {
+	if assumptions == nil {
+		assumptions = DefaultProjectionAssumptions()
+	}
+
+	// Номинал на дату выплаты с учетом будущих амортизаций и индексации
+	faceValueAt := func(date time.Time) float64 {
+		value := faceValue
+		for _, payment := range payments {
+			if payment.Type == data.AmortizationPayment && payment.Date.After(now) && payment.Date.Before(date) {
+				value -= payment.Value
+			}
+		}
+
+		if bond.CouponType == data.IndexedCoupon {
+			years := date.Sub(now).Hours() / 24.0 / daysInYear
+			value *= math.Pow(1+assumptions.Inflation/100.0, years)
+		}
+
+		return math.Max(value, 0)
+	}
+
+	items := make([]*data.PaymentProjection, 0)
+	lastRate := 0.0
+	var lastCouponDate *time.Time
+	for _, payment := range payments {
+		switch payment.Type {
+		case data.CouponPayment:
+			rate := payment.ValuePercent
+			if rate <= 0 {
+				rate = lastRate
+				if bond.CouponType == data.FloatingCoupon && assumptions.FloatingRate != nil {
+					rate = *assumptions.FloatingRate
+				}
+			}
+
+			if payment.Value <= 0 && payment.Date.After(now) {
+				days := couponPeriodDays(bond, payment, lastCouponDate)
+				if rate > 0 && days > 0 {
+					value := round2(faceValueAt(payment.Date) * rate / 100.0 * days / 365.0)
+					if value > 0 {
+						items = append(items, &data.PaymentProjection{PaymentID: payment.ID, Value: value})
+					}
+				}
+			}
+
+			if payment.Value > 0 && payment.ValuePercent > 0 {
+				lastRate = payment.ValuePercent
+			}
+
+			date := payment.Date
+			lastCouponDate = &date
+
+		case data.MaturityPayment:
+			if payment.Value <= 0 && payment.Date.After(now) {
+				value := round2(faceValueAt(payment.Date))
+				if value > 0 {
+					items = append(items, &data.PaymentProjection{PaymentID: payment.ID, Value: value})
+				}
+			}
+		}
+	}
+
+	return items
+}
+
+// couponPeriodDays возвращает длительность купонного периода, дней
+// Если дата начала купонного периода неизвестна, то она определяется по дате предыдущего купона или по частоте купонов
+func couponPeriodDays(bond *data.Bond, payment *data.Payment, lastCouponDate *time.Time) float64 {
+	switch {
+	case payment.CouponStartDate.Valid:
+		return payment.Date.Sub(payment.CouponStartDate.Time).Hours() / 24.0
+	case lastCouponDate != nil:
+		return payment.Date.Sub(*lastCouponDate).Hours() / 24.0
+	case bond.CouponFrequency > 0:
+		return math.Round(365.0 / float64(bond.CouponFrequency))
+	default:
+		return 0
+	}
+}
+
+// HasEstimatedCashFlow возвращает true, если среди выплат по облигации есть расчетные оценки
+func (r *Report) HasEstimatedCashFlow() bool {
+	for _, item := range r.CashFlow {
+		if item.Estimated {
+			return true
+		}
+	}
+
+	return false
+}
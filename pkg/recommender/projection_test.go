@@ -0,0 +1,85 @@
+package recommender
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestProjectPayments_Floating(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bond := &data.Bond{CouponType: data.FloatingCoupon, CouponFrequency: 4}
+
+	payments := []*data.Payment{
+		{ID: 1, Type: data.CouponPayment, Date: t0.AddDate(0, -1, 0), Value: 20, ValuePercent: 8},
+		{ID: 2, Type: data.CouponPayment, Date: t0.AddDate(0, 2, 0), Value: 0},
+		{ID: 3, Type: data.CouponPayment, Date: t0.AddDate(0, 5, 0), Value: 0},
+		{ID: 4, Type: data.MaturityPayment, Date: t0.AddDate(0, 5, 0), Value: 1000},
+	}
+
+	// Купоны оцениваются по ставке последнего известного купона, погашение уже объявлено
+	items := projectPayments(t0, bond, 1000, payments, DefaultProjectionAssumptions())
+	assert.Len(items, 2)
+	assert.Equal(2, items[0].PaymentID)
+	assert.Equal(round2(1000*0.08*90/365.0), items[0].Value)
+	assert.Equal(3, items[1].PaymentID)
+	assert.Equal(round2(1000*0.08*92/365.0), items[1].Value)
+
+	// Прогнозная ставка флоатеров заменяет ставку последнего известного купона
+	rate := 10.0
+	items = projectPayments(t0, bond, 1000, payments, &ProjectionAssumptions{FloatingRate: &rate})
+	assert.Len(items, 2)
+	assert.Equal(round2(1000*0.1*90/365.0), items[0].Value)
+}
+
+func TestProjectPayments_Indexed(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bond := &data.Bond{CouponType: data.IndexedCoupon, CouponFrequency: 2}
+
+	couponDate := t0.AddDate(1, 0, 0)
+	payments := []*data.Payment{
+		{ID: 1, Type: data.CouponPayment, Date: t0.AddDate(0, 6, 0), Value: 12.5, ValuePercent: 2.5},
+		{
+			ID:              2,
+			Type:            data.CouponPayment,
+			Date:            couponDate,
+			CouponStartDate: sql.NullTime{Time: t0.AddDate(0, 6, 0), Valid: true},
+		},
+		{ID: 3, Type: data.MaturityPayment, Date: couponDate},
+	}
+
+	items := projectPayments(t0, bond, 1000, payments, &ProjectionAssumptions{Inflation: 4})
+	assert.Len(items, 2)
+
+	// Номинал линкера индексируется по прогнозу инфляции
+	faceValue := 1000 * math.Pow(1.04, couponDate.Sub(t0).Hours()/24.0/daysInYear)
+	days := couponDate.Sub(t0.AddDate(0, 6, 0)).Hours() / 24.0
+	assert.Equal(2, items[0].PaymentID)
+	assert.Equal(round2(faceValue*0.025*days/365.0), items[0].Value)
+	assert.Equal(3, items[1].PaymentID)
+	assert.Equal(round2(faceValue), items[1].Value)
+}
+
+func TestProjectPayments_NoKnownRate(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bond := &data.Bond{CouponType: data.FixedCoupon, CouponFrequency: 2}
+
+	payments := []*data.Payment{
+		{ID: 1, Type: data.CouponPayment, Date: t0.AddDate(0, 6, 0)},
+	}
+
+	// Без известной ставки купон не оценивается
+	items := projectPayments(t0, bond, 1000, payments, DefaultProjectionAssumptions())
+	assert.Len(items, 0)
+}
@@ -0,0 +1,99 @@
+package recommender
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// creditRatingScale - национальная шкала кредитных рейтингов по убыванию надежности
+var creditRatingScale = []string{
+	"AAA",
+	"AA+", "AA", "AA-",
+	"A+", "A", "A-",
+	"BBB+", "BBB", "BBB-",
+	"BB+", "BB", "BB-",
+	"B+", "B", "B-",
+	"CCC", "CC", "C",
+	"RD", "D",
+}
+
+// IssuerRating содержит кредитный рейтинг эмитента по национальной шкале
+type IssuerRating struct {
+	// ИНН эмитента
+	INN string `json:"inn" yaml:"inn"`
+
+	// Рейтинг, например "AA-"
+	// Допускается запись рейтинговых агентств: "AA-(RU)", "ruAA-", "AA-.ru", "AA-|ru|"
+	Rating string `json:"rating" yaml:"rating"`
+}
+
+// issuerRatingFile - структура файла с кредитными рейтингами эмитентов
+type issuerRatingFile struct {
+	Ratings []*IssuerRating `json:"ratings" yaml:"ratings"`
+}
+
+// LoadIssuerRatings загружает кредитные рейтинги эмитентов из файла
+// Поддерживаются файлы в форматах YAML (.yaml, .yml) и JSON (.json) вида {"ratings": [{"inn": ..., "rating": ...}]}
+// Неизвестные поля считаются ошибкой
+func LoadIssuerRatings(path string) ([]*IssuerRating, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file issuerRatingFile
+	err = decodeConfigFile(path, content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, rating := range file.Ratings {
+		err = rating.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return file.Ratings, nil
+}
+
+// Validate проверяет корректность рейтинга эмитента
+func (r *IssuerRating) Validate() error {
+	if !innRegexp.MatchString(r.INN) {
+		return fmt.Errorf("\"%s\" is not a valid issuer INN", r.INN)
+	}
+	if _, ok := normalizeCreditRating(r.Rating); !ok {
+		return fmt.Errorf("issuer \"%s\": \"%s\" is not a valid credit rating", r.INN, r.Rating)
+	}
+
+	return nil
+}
+
+// normalizeCreditRating приводит запись рейтинга к виду национальной шкалы ("ruAA-" -> "AA-")
+func normalizeCreditRating(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RU")
+	for _, suffix := range []string{"(RU)", ".RU", "|RU|"} {
+		s = strings.TrimSuffix(s, suffix)
+	}
+
+	for _, rating := range creditRatingScale {
+		if s == rating {
+			return s, true
+		}
+	}
+
+	return "", false
+}
+
+// creditRatingsAtLeast возвращает рейтинги национальной шкалы, не ниже заданного
+func creditRatingsAtLeast(rating string) []string {
+	for i, r := range creditRatingScale {
+		if r == rating {
+			return creditRatingScale[:i+1]
+		}
+	}
+
+	return nil
+}
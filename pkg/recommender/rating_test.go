@@ -0,0 +1,62 @@
+package recommender
+
+import (
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCreditRating(t *testing.T) {
+	assert := assertion.New(t)
+
+	for input, expected := range map[string]string{
+		"AA-":     "AA-",
+		"AA-(RU)": "AA-",
+		"ruAA-":   "AA-",
+		"AA-.ru":  "AA-",
+		"AA-|ru|": "AA-",
+		" bbb+ ":  "BBB+",
+		"ruD":     "D",
+	} {
+		rating, ok := normalizeCreditRating(input)
+		assert.True(ok, input)
+		assert.Equal(expected, rating, input)
+	}
+
+	for _, input := range []string{"", "AAAA", "Baa1", "A++"} {
+		_, ok := normalizeCreditRating(input)
+		assert.False(ok, input)
+	}
+}
+
+func TestLoadIssuerRatings(t *testing.T) {
+	assert := assertion.New(t)
+
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "ratings.yaml", "ratings:\n  - inn: \"7707083893\"\n    rating: AAA(RU)\n")
+
+	ratings, err := LoadIssuerRatings(path)
+	if assert.NoError(err) && assert.Len(ratings, 1) {
+		assert.Equal("7707083893", ratings[0].INN)
+		assert.Equal("AAA(RU)", ratings[0].Rating)
+	}
+
+	path = writeTestFile(t, dir, "invalid.json", `{"ratings": [{"inn": "7707083893", "rating": "AAAA"}]}`)
+	_, err = LoadIssuerRatings(path)
+	assert.Error(err)
+}
+
+func TestWithIssuerRatings(t *testing.T) {
+	assert := assertion.New(t)
+
+	_, err := New(WithIssuerRatings(
+		&IssuerRating{INN: "7707083893", Rating: "AAA"},
+		&IssuerRating{INN: "7707083893", Rating: "AA"},
+	))
+	assert.Error(err)
+
+	s, err := New(WithIssuerRatings(&IssuerRating{INN: "7707083893", Rating: "ruAA+"}))
+	if assert.NoError(err) {
+		assert.Equal(map[string]string{"7707083893": "AA+"}, s.(*service).issuerRatings)
+	}
+}
@@ -3,6 +3,7 @@ package recommender
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/data"
@@ -11,6 +12,12 @@ import (
 // ErrNotFound возвращается, если запрошенный объект не найден
 var ErrNotFound = errors.New("not found")
 
+// ErrUnsupportedRanking возвращается, если коллекция не поддерживает запрошенный порядок облигаций
+var ErrUnsupportedRanking = errors.New("unsupported ranking")
+
+// ErrInvalidTrade возвращается, если сделка несовместима с портфелем (например, продажа больше позиции)
+var ErrInvalidTrade = errors.New("invalid trade")
+
 // Service предоставляет доступ к модулю рекомендаций
 type Service interface {
 	// ListCollections возвращает список коллекций рекомендаций
@@ -27,6 +34,44 @@ type Service interface {
 	// Suggest выполняет расчет предложений по инвестированию
 	Suggest(ctx context.Context, tx *data.TX, request *SuggestRequest) (*SuggestResult, error)
 
+	// ValuatePortfolio выполняет оценку портфеля пользователя по текущим рыночным данным
+	ValuatePortfolio(ctx context.Context, tx *data.TX, portfolio *data.Portfolio) (*PortfolioValuation, error)
+
+	// GetCalendar формирует календарь предстоящих выплат по набору позиций
+	// Если по облигации нет отчета, то возвращается ошибка ErrNotFound
+	GetCalendar(ctx context.Context, tx *data.TX, positions []CalendarPosition) (*Calendar, error)
+
+	// Backtest выполняет бэктест стратегии инвестирования на исторических данных
+	// Портфель формируется по тем же правилам, что и в Suggest, на каждую дату из заданного периода,
+	// после чего моделируется владение им до погашения с учетом выплат и реинвестирования
+	Backtest(ctx context.Context, tx *data.TX, request *BacktestRequest) (*BacktestResult, error)
+
+	// GetYieldCurve возвращает последнюю построенную кривую доходности ОФЗ и положение текущих ОФЗ относительно нее
+	// Если кривая еще не построена, то возвращается ошибка ErrNotFound
+	GetYieldCurve(ctx context.Context, tx *data.TX) (*YieldCurve, error)
+
+	// Screen выполняет отбор облигаций по условиям скринера
+	// Если условия некорректны, то возвращается ошибка валидации
+	Screen(ctx context.Context, tx *data.TX, query *ScreenerQuery) (*ScreenerResult, error)
+
+	// BuildLadder выполняет построение лесенки облигаций
+	// Если подходящих облигаций не нашлось, то возвращается ошибка ErrNotFound
+	BuildLadder(ctx context.Context, tx *data.TX, request *LadderRequest) (*LadderResult, error)
+
+	// MatchCashFlows подбирает портфель минимальной стоимости, выплаты по которому покрывают заданные обязательства (см. matchCashFlows)
+	// Если подходящих облигаций не нашлось, то возвращается ошибка ErrNotFound
+	MatchCashFlows(ctx context.Context, tx *data.TX, request *CashFlowMatchRequest) (*CashFlowMatchResult, error)
+
+	// SimulateHorizon моделирует доходность предложенного портфеля при владении им в течение горизонта инвестирования
+	// Если ставка реинвестирования не задана, то выплаты реинвестируются в облигации тех же коллекций
+	SimulateHorizon(ctx context.Context, tx *data.TX, portfolio *SuggestResult, request *HorizonRequest) (*HorizonResult, error)
+
+	// RunScenarios выполняет переоценку предложенного портфеля по сценариям
+	RunScenarios(ctx context.Context, tx *data.TX, portfolio *SuggestResult, scenarios []*Scenario) ([]*ScenarioResult, error)
+
+	// RunPortfolioScenarios выполняет переоценку открытых позиций портфеля пользователя по сценариям
+	RunPortfolioScenarios(ctx context.Context, tx *data.TX, portfolio *data.Portfolio, scenarios []*Scenario) ([]*ScenarioResult, error)
+
 	// Rebuild выполняет обновление данных рекомендаций
 	Rebuild(ctx context.Context, tx *data.TX) error
 }
@@ -62,8 +107,34 @@ type Collection interface {
 	// Name возвращает название коллекции
 	Name() string
 
-	// ListBonds возвращает список облигаций из коллекции
-	ListBonds(ctx context.Context, tx *data.TX, limit int, duration Duration) ([]*Report, error)
+	// Rankings возвращает список поддерживаемых порядков облигаций, первый из них используется по умолчанию
+	Rankings() []Ranking
+
+	// ListBonds возвращает список облигаций из коллекции в заданном порядке
+	// Если коллекция не поддерживает порядок ranking, то возвращается ошибка ErrUnsupportedRanking
+	ListBonds(ctx context.Context, tx *data.TX, limit int, duration Duration, ranking Ranking) ([]*Report, error)
+}
+
+// Ranking задает порядок облигаций в коллекции
+type Ranking string
+
+const (
+	// RankByYield - по убыванию доходности к погашению
+	RankByYield Ranking = "yield"
+
+	// RankBySpread - по убыванию спреда к кривой ОФЗ
+	RankBySpread Ranking = "spread"
+)
+
+// SupportsRanking проверяет, поддерживает ли коллекция порядок облигаций ranking
+func SupportsRanking(collection Collection, ranking Ranking) bool {
+	for _, r := range collection.Rankings() {
+		if r == ranking {
+			return true
+		}
+	}
+
+	return false
 }
 
 // Report содержит данные отчета по облигации
@@ -80,9 +151,13 @@ type Report struct {
 	// Дней до погашения
 	DaysTillMaturity int
 
-	// Валюта торгов
+	// Валюта, в которой выражены суммы отчета
+	// Суммы по облигациям в иностранной валюте пересчитываются в рубли по текущему курсу
 	Currency string
 
+	// Курс валюты номинала к рублю (1 для рублевых облигаций)
+	FxRate float64
+
 	// Чистая цена открытия, в %
 	OpenPrice float64
 
@@ -120,8 +195,45 @@ type Report struct {
 	RelativeProfitLoss float64
 
 	// Приведенная доходность, % годовых
+	// Рассчитывается линейно, без учета распределения выплат во времени
 	InterestRate float64
 
+	// Эффективная доходность к погашению (XIRR) с учетом дат всех выплат, % годовых
+	YieldToMaturity float64
+
+	// Дюрация Маколея, лет
+	MacaulayDuration float64
+
+	// Модифицированная дюрация, лет
+	ModifiedDuration float64
+
+	// Изменение стоимости при изменении доходности на 1 б.п., в валюте
+	DV01 float64
+
+	// Выпуклость, лет^2
+	Convexity float64
+
+	// Дата ближайшей оферты (nil, если оферт не предвидится)
+	OfferDate *time.Time
+
+	// Цена выкупа по оферте, в % от номинала
+	OfferPrice float64
+
+	// Дней до оферты (если оферт не предвидится, то совпадает с DaysTillMaturity)
+	DaysTillOffer int
+
+	// Эффективная доходность к оферте (XIRR), % годовых
+	// Если оферт не предвидится, то совпадает с YieldToMaturity
+	YieldToOffer float64
+
+	// Спред доходности к погашению (без учета налогов и комиссий) к доходности по цене, рассчитанной по кривой ОФЗ, б.п.
+	// Рассчитывается только для облигаций с номиналом в рублях; если кривая не построена, то nil
+	Spread *float64
+
+	// Альтернативный отчет, в котором облигация предъявляется к выкупу по ближайшей оферте
+	// Если оферт не предвидится, то nil
+	ToOffer *Report
+
 	// Таблица выплат
 	CashFlow []*CashFlowItem
 }
@@ -148,8 +260,14 @@ type CashFlowItem struct {
 	// Дата выплаты
 	Date time.Time
 
+	// Сумма выплаты, в валюте номинала
+	Value float64
+
 	// Сумма выплаты, в рублях
 	ValueRub float64
+
+	// Признак расчетной оценки: размер выплаты еще не объявлен эмитентом
+	Estimated bool
 }
 
 // SuggestRequest - запрос на формирование предложений по инвестированию
@@ -162,6 +280,14 @@ type SuggestRequest struct {
 
 	// Ограничения по составу портфеля
 	Parts []*SuggestRequestPart
+
+	// Модель комиссий и налогов
+	// Если не задана, то используется модель сервиса (см. WithCostModel)
+	Costs *CostModel
+
+	// Ограничения оптимизатора
+	// Если не заданы, то ограничения не применяются (см. также DefaultSuggestConstraints)
+	Constraints *SuggestConstraints
 }
 
 // SuggestRequestPart - ограничения по составу портфеля для запроса SuggestRequest
@@ -192,6 +318,55 @@ type SuggestResult struct {
 
 	// Приведенная доходность, % годовых
 	InterestRate float64
+
+	// Эффективная доходность к погашению (XIRR), % годовых
+	YieldToMaturity float64
+
+	// Дюрация Маколея портфеля (средневзвешенная по стоимости позиций), лет
+	MacaulayDuration float64
+
+	// Модифицированная дюрация портфеля (средневзвешенная по стоимости позиций), лет
+	ModifiedDuration float64
+
+	// Изменение стоимости портфеля при изменении доходности на 1 б.п., в валюте
+	DV01 float64
+
+	// Выпуклость портфеля (средневзвешенная по стоимости позиций), лет^2
+	Convexity float64
+
+	// Налоговый вычет на сумму инвестирования (только для ИИС типа А), в валюте
+	TaxDeduction float64
+
+	// Неиспользованный остаток суммы инвестирования, в валюте
+	// Облигации покупаются целыми лотами, поэтому часть суммы может остаться неинвестированной
+	UnusedAmount float64
+
+	// Лимиты на доли были ослаблены, чтобы неинвестированный остаток не превышал SuggestConstraints.MaxCashWeight
+	LimitsRelaxed bool
+
+	// Доли эмитентов в портфеле, по убыванию доли
+	IssuerConcentrations []*SuggestConcentration
+
+	// Доли типов облигаций в портфеле, по убыванию доли
+	BondTypeConcentrations []*SuggestConcentration
+
+	// Доли облигаций по годам погашения (или оферты), по возрастанию года
+	MaturityYearConcentrations []*SuggestConcentration
+
+	// Валютная структура портфеля (доли валют номинала облигаций), по убыванию доли
+	CurrencyConcentrations []*SuggestConcentration
+}
+
+// SuggestConcentration - доля группы позиций (эмитента, типа облигаций, года погашения, валюты) в портфеле
+type SuggestConcentration struct {
+	// Название группы: название эмитента, тип облигаций (data.BondType), год погашения или валюта номинала
+	Name string
+
+	// Сумма вложений, в валюте
+	Amount float64
+
+	// Доля в составе портфеля (0..1)
+	Weight float64
 }
 
 // SuggestedPortfolioPosition - позиция в предложенном портфеле
@@ -205,7 +380,87 @@ type SuggestedPortfolioPosition struct {
 	Weight float64
 }
 
+// Option конфигурирует объект Service
+type Option func(s *service) error
+
+// WithCollections добавляет пользовательские коллекции рекомендаций
+// Если описание коллекции некорректно или ее ID совпадает с ID другой коллекции, то возвращается ошибка
+func WithCollections(definitions ...*CollectionDefinition) Option {
+	return func(s *service) error {
+		for _, definition := range definitions {
+			if _, exists := s.collections[definition.ID]; exists {
+				return fmt.Errorf("collection \"%s\" already exists", definition.ID)
+			}
+
+			coll, err := definition.compile()
+			if err != nil {
+				return err
+			}
+
+			s.collections[coll.id] = coll
+		}
+
+		return nil
+	}
+}
+
+// WithIssuerRatings задает кредитные рейтинги эмитентов, которые записываются в БД при каждом пересчете
+// Если рейтинг некорректен или для одного ИНН задано несколько рейтингов, то возвращается ошибка
+func WithIssuerRatings(ratings ...*IssuerRating) Option {
+	return func(s *service) error {
+		s.issuerRatings = make(map[string]string, len(ratings))
+		for _, rating := range ratings {
+			err := rating.Validate()
+			if err != nil {
+				return err
+			}
+			if _, exists := s.issuerRatings[rating.INN]; exists {
+				return fmt.Errorf("issuer \"%s\" has more than one rating", rating.INN)
+			}
+
+			s.issuerRatings[rating.INN], _ = normalizeCreditRating(rating.Rating)
+		}
+
+		return nil
+	}
+}
+
+// WithCostModel задает модель комиссий и налогов, по которой рассчитываются отчеты, коллекции и скринер,
+// а также запросы, в которых модель не задана
+// По умолчанию используется DefaultCostModel
+func WithCostModel(model *CostModel) Option {
+	return func(s *service) error {
+		if model == nil {
+			return fmt.Errorf("cost model must not be nil")
+		}
+
+		s.costs = model
+		return nil
+	}
+}
+
 // New создает новый объект Service
-func New() (Service, error) {
-	return &service{}, nil
+func New(options ...Option) (Service, error) {
+	s := &service{
+		collections: make(map[string]*internalCollection),
+		projections: DefaultProjectionAssumptions(),
+		costs:       DefaultCostModel(),
+	}
+	for id, coll := range collections {
+		c := *coll
+		s.collections[id] = &c
+	}
+
+	for _, fn := range options {
+		err := fn(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, coll := range s.collections {
+		coll.costs = s.costs
+	}
+
+	return s, nil
 }
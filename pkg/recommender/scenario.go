@@ -0,0 +1,249 @@
+package recommender
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// scenarioPivotYears - срок, относительно которого изменяется наклон кривой доходности, лет
+const scenarioPivotYears = 2.0
+
+// Scenario - сценарий мгновенного изменения доходностей облигаций
+// Изменение доходности облигации складывается из параллельного сдвига, изменения наклона кривой и изменения спредов
+type Scenario struct {
+	// Название сценария
+	Name string
+
+	// Параллельный сдвиг доходностей, б.п.
+	Shift float64
+
+	// Изменение наклона кривой, б.п. на год дюрации
+	// Доходность облигации с дюрацией Маколея D изменяется на Twist * (D - 2 года):
+	// положительное значение увеличивает наклон кривой (steepener), отрицательное - уменьшает (flattener)
+	Twist float64
+
+	// Изменение кредитного спреда негосударственных облигаций (кроме ОФЗ, субфедеральных и муниципальных), б.п.
+	CreditSpread float64
+
+	// Изменение спреда облигаций отдельных эмитентов (ID эмитента -> б.п.)
+	IssuerSpreads map[int]float64
+}
+
+// YieldShift возвращает изменение доходности облигации по сценарию, б.п.
+func (s *Scenario) YieldShift(r *Report) float64 {
+	return s.Shift + s.Twist*(r.MacaulayDuration-scenarioPivotYears) + s.spreadShift(r)
+}
+
+// spreadShift возвращает часть изменения доходности облигации по сценарию, которая приходится на изменение спредов, б.п.
+func (s *Scenario) spreadShift(r *Report) float64 {
+	shift := 0.0
+
+	switch r.Bond.Type {
+	case data.OFZBond, data.SubfederalBond, data.MunicipalBond:
+		// Изменение кредитного спреда не затрагивает государственные и муниципальные облигации
+	default:
+		shift += s.CreditSpread
+	}
+
+	if s.IssuerSpreads != nil {
+		shift += s.IssuerSpreads[issuerKey(r)]
+	}
+
+	return shift
+}
+
+// StandardScenarios возвращает набор сценариев, по которым оценивается риск каждого портфеля
+func StandardScenarios() []*Scenario {
+	return []*Scenario{
+		{Name: "-100 б.п.", Shift: -100},
+		{Name: "+100 б.п.", Shift: 100},
+		{Name: "+200 б.п.", Shift: 200},
+		{Name: "Рост наклона", Twist: 25},
+		{Name: "Снижение наклона", Twist: -25},
+		{Name: "Спреды +100 б.п.", CreditSpread: 100},
+	}
+}
+
+// ScenarioResult - результат переоценки портфеля по сценарию
+type ScenarioResult struct {
+	// Сценарий
+	Scenario *Scenario
+
+	// Текущая стоимость позиций (без учета комиссий), в валюте
+	Value float64
+
+	// Изменение стоимости позиций, в валюте
+	ProfitLoss float64
+
+	// Изменение стоимости позиций, в % по отношению к текущей стоимости
+	RelativeProfitLoss float64
+
+	// Результаты по позициям
+	Positions []*ScenarioPosition
+}
+
+// ScenarioPosition - результат переоценки позиции по сценарию
+type ScenarioPosition struct {
+	// Облигация
+	Bond *data.Bond
+
+	// Размер позиции
+	Quantity int
+
+	// Изменение доходности облигации, б.п.
+	YieldShift float64
+
+	// Текущая стоимость позиции (без учета комиссий), в валюте
+	Value float64
+
+	// Изменение стоимости позиции, в валюте
+	ProfitLoss float64
+
+	// Изменение стоимости позиции, в % по отношению к текущей стоимости
+	RelativeProfitLoss float64
+}
+
+// scenarioHolding - позиция, которая переоценивается по сценариям
+type scenarioHolding struct {
+	// Отчет по одной облигации
+	report *Report
+
+	// Размер позиции
+	quantity int
+}
+
+// RunScenarios выполняет переоценку предложенного портфеля по сценариям
+// Если по позиции не загружены данные по выплатам (например, портфель получен не из Suggest), то они дозагружаются
+func (s *service) RunScenarios(ctx context.Context, tx *data.TX, portfolio *SuggestResult, scenarios []*Scenario) ([]*ScenarioResult, error) {
+	holdings := make([]*scenarioHolding, len(portfolio.Positions))
+	for i, p := range portfolio.Positions {
+		// Отчет по позиции уже пересчитан на всю позицию, поэтому он переоценивается как одна облигация
+		report := p.Report
+		if len(report.CashFlow) == 0 {
+			r, err := s.GetReport(ctx, tx, p.Bond.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			report.CashFlow = scaleReport(r, float64(p.Quantity)).CashFlow
+		}
+		holdings[i] = &scenarioHolding{report: &report, quantity: 1}
+	}
+
+	results := runScenarios(today(), holdings, scenarios)
+	for _, result := range results {
+		for i, p := range result.Positions {
+			p.Quantity = portfolio.Positions[i].Quantity
+		}
+	}
+
+	return results, nil
+}
+
+// RunPortfolioScenarios выполняет переоценку открытых позиций портфеля пользователя по сценариям
+// Позиции оцениваются по текущим рыночным данным (см. ValuatePortfolio), позиции по облигациям, которые уже не торгуются, не переоцениваются
+func (s *service) RunPortfolioScenarios(ctx context.Context, tx *data.TX, portfolio *data.Portfolio, scenarios []*Scenario) ([]*ScenarioResult, error) {
+	valuation, err := s.ValuatePortfolio(ctx, tx, portfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := make([]*scenarioHolding, 0, len(valuation.Holdings))
+	for _, h := range valuation.Holdings {
+		if h.Report == nil {
+			continue
+		}
+
+		// Если по облигации предвидится оферта, то считаем, что облигация будет предъявлена к выкупу
+		report := h.Report
+		if report.ToOffer != nil {
+			report = report.ToOffer
+		}
+
+		holdings = append(holdings, &scenarioHolding{report: report, quantity: h.Quantity})
+	}
+
+	return runScenarios(today(), holdings, scenarios), nil
+}
+
+// runScenarios выполняет переоценку позиций по сценариям на дату now
+//
+// Текущая стоимость облигации (без учета комиссий) и ее будущие выплаты определяют доходность облигации,
+// по сценарию доходность изменяется, и выплаты дисконтируются по новой доходности
+func runScenarios(now time.Time, holdings []*scenarioHolding, scenarios []*Scenario) []*ScenarioResult {
+	results := make([]*ScenarioResult, len(scenarios))
+	for i, scenario := range scenarios {
+		result := &ScenarioResult{
+			Scenario:  scenario,
+			Positions: make([]*ScenarioPosition, len(holdings)),
+		}
+
+		for j, h := range holdings {
+			shift := scenario.YieldShift(h.report)
+			price, newPrice := repriceReport(now, h.report, shift, scenario.spreadShift(h.report))
+
+			p := &ScenarioPosition{
+				Bond:       h.report.Bond,
+				Quantity:   h.quantity,
+				YieldShift: shift,
+				Value:      round2(price * float64(h.quantity)),
+				ProfitLoss: round2((newPrice - price) * float64(h.quantity)),
+			}
+			if p.Value > 0 {
+				p.RelativeProfitLoss = round2(100.0 * p.ProfitLoss / p.Value)
+			}
+
+			result.Positions[j] = p
+			result.Value += p.Value
+			result.ProfitLoss += p.ProfitLoss
+		}
+
+		result.Value = round2(result.Value)
+		result.ProfitLoss = round2(result.ProfitLoss)
+		if result.Value > 0 {
+			result.RelativeProfitLoss = round2(100.0 * result.ProfitLoss / result.Value)
+		}
+
+		results[i] = result
+	}
+
+	return results
+}
+
+// repriceReport возвращает текущую стоимость облигации (без учета комиссий) и ее стоимость
+// при изменении доходности на shift б.п., из которых spreadShift б.п. приходится на изменение спредов
+// Оценочные купоны флоатера пересматриваются вместе со ставкой, поэтому его выплаты после даты пересмотра ставки
+// оцениваются на эту дату с учетом только изменения спредов (см. rateSensitiveFlows)
+// Если доходность облигации рассчитать не удалось, то стоимость не изменяется
+func repriceReport(now time.Time, r *Report, shift, spreadShift float64) (float64, float64) {
+	price := r.OpenValue
+
+	flows := []xirrFlow{{Date: now, Value: -price}}
+	for _, item := range r.CashFlow {
+		if item.Date.After(now) {
+			flows = append(flows, xirrFlow{Date: item.Date, Value: item.ValueRub})
+		}
+	}
+
+	rate, err := xirr(flows)
+	if err != nil {
+		return price, price
+	}
+
+	newRate := rate + shift/10000.0
+	tailRate := rate + spreadShift/10000.0
+	if newRate <= -1 || tailRate <= -1 {
+		return price, price
+	}
+
+	newPrice := 0.0
+	for _, flow := range rateSensitiveFlows(now, r, flows[1:], tailRate) {
+		years := flow.Date.Sub(now).Hours() / 24.0 / daysInYear
+		newPrice += flow.Value / math.Pow(1+newRate, years)
+	}
+
+	return price, newPrice
+}
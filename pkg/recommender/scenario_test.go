@@ -0,0 +1,85 @@
+package recommender
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestScenario_YieldShift(t *testing.T) {
+	assert := assertion.New(t)
+
+	ofz := newOptimizerReport(1, 1, 1000, 10, 5)
+	ofz.Bond.Type = data.OFZBond
+	corporate := newOptimizerReport(2, 2, 1000, 12, 1)
+	corporate.Bond.Type = data.CorporateBond
+
+	s := &Scenario{Shift: 100, Twist: 10, CreditSpread: 50, IssuerSpreads: map[int]float64{2: 200}}
+	assert.Equal(130.0, s.YieldShift(ofz))
+	assert.Equal(340.0, s.YieldShift(corporate))
+}
+
+func TestRunScenarios(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	short := newOptimizerReport(1, 1, 1000, 10, 1)
+	short.CashFlow = []*CashFlowItem{{Type: Maturity, Date: now.AddDate(1, 0, 0), ValueRub: 1100}}
+	long := newOptimizerReport(2, 2, 1000, 10, 3)
+	long.CashFlow = []*CashFlowItem{
+		{Type: Coupon, Date: now.AddDate(0, 0, -10), ValueRub: 100},
+		{Type: Maturity, Date: now.AddDate(3, 0, 0), ValueRub: 1331},
+	}
+	holdings := []*scenarioHolding{{report: short, quantity: 2}, {report: long, quantity: 1}}
+
+	results := runScenarios(now, holdings, []*Scenario{{Name: "+200", Shift: 200}, {Name: "0"}})
+	assert.Len(results, 2)
+
+	// Стоимость длинной облигации снижается сильнее
+	r := results[0]
+	assert.Equal(3000.0, r.Value)
+	assert.InDelta(-2*1000*(1-1.1/1.12), r.Positions[0].ProfitLoss, 0.5)
+	assert.InDelta(-1000*(1-math.Pow(1.1/1.12, 3)), r.Positions[1].ProfitLoss, 0.5)
+	assert.Less(r.Positions[1].RelativeProfitLoss, r.Positions[0].RelativeProfitLoss)
+	assert.Equal(r.Positions[0].ProfitLoss+r.Positions[1].ProfitLoss, r.ProfitLoss)
+
+	// Без изменения доходностей стоимость не изменяется
+	assert.InDelta(0.0, results[1].ProfitLoss, 0.01)
+}
+
+func TestRunScenarios_Floating(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	newReport := func(couponType data.CouponType) *Report {
+		r := newOptimizerReport(1, 1, 1000, 10, 0.5)
+		r.Bond.Type = data.CorporateBond
+		r.Bond.CouponType = couponType
+		r.CashFlow = []*CashFlowItem{
+			{Type: Coupon, Date: now.AddDate(0, 6, 0), ValueRub: 50},
+			{Type: Coupon, Date: now.AddDate(1, 0, 0), ValueRub: 50, Estimated: true},
+			{Type: Coupon, Date: now.AddDate(3, 0, 0), ValueRub: 50, Estimated: true},
+			{Type: Maturity, Date: now.AddDate(3, 0, 0), ValueRub: 1000},
+		}
+		return r
+	}
+	floating := newReport(data.FloatingCoupon)
+	fixed := newReport(data.FixedCoupon)
+	holdings := []*scenarioHolding{{report: floating, quantity: 1}, {report: fixed, quantity: 1}}
+
+	results := runScenarios(now, holdings, []*Scenario{{Shift: 200}, {CreditSpread: 200}})
+
+	// Рост ставок влияет на флоатер только до даты пересмотра ставки через полгода
+	r := results[0]
+	assert.InDelta(-1000*(1-math.Pow(1.0/1.02, 0.5)), r.Positions[0].ProfitLoss, 2)
+	assert.Less(r.Positions[1].ProfitLoss, 5*r.Positions[0].ProfitLoss)
+
+	// Рост спредов влияет на флоатер в полной мере, т.к. спред к ставке в купоне зафиксирован
+	r = results[1]
+	assert.Less(r.Positions[0].ProfitLoss, 5*results[0].Positions[0].ProfitLoss)
+	assert.InDelta(r.Positions[1].ProfitLoss, r.Positions[0].ProfitLoss, 1)
+}
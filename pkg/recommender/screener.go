@@ -0,0 +1,326 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// ScreenerSort задает поле, по которому сортируются результаты скринера
+type ScreenerSort string
+
+const (
+	// SortByYield - по доходности к погашению
+	SortByYield ScreenerSort = "yield"
+
+	// SortBySpread - по спреду к кривой ОФЗ
+	SortBySpread ScreenerSort = "spread"
+
+	// SortByPrice - по чистой цене
+	SortByPrice ScreenerSort = "price"
+
+	// SortByMaturity - по дате погашения
+	SortByMaturity ScreenerSort = "maturity"
+
+	// SortByOffer - по дате ближайшей оферты
+	SortByOffer ScreenerSort = "offer"
+
+	// SortByDuration - по дюрации
+	SortByDuration ScreenerSort = "duration"
+
+	// SortByName - по названию облигации
+	SortByName ScreenerSort = "name"
+)
+
+// ScreenerSorts содержит список всех возможных значений ScreenerSort
+var ScreenerSorts = []ScreenerSort{SortByYield, SortBySpread, SortByPrice, SortByMaturity, SortByOffer, SortByDuration, SortByName}
+
+// screenerSortSQL содержит SQL-выражения для полей сортировки
+// В запрос попадают только выражения из этого списка
+var screenerSortSQL = map[ScreenerSort]string{
+	SortByYield:    "COALESCE(report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate)",
+	SortBySpread:   "report_metrics.spread",
+	SortByPrice:    "reports.open_price",
+	SortByMaturity: "bonds.maturity_date",
+	SortByOffer:    "report_metrics.offer_date",
+	SortByDuration: "report_metrics.macaulay_duration",
+	SortByName:     "bonds.short_name",
+}
+
+const (
+	// DefaultScreenerLimit - количество облигаций на странице скринера по умолчанию
+	DefaultScreenerLimit = 25
+
+	// MaxScreenerLimit - максимальное количество облигаций на странице скринера
+	MaxScreenerLimit = 100
+)
+
+// ScreenerQuery содержит условия отбора облигаций в скринере
+// Все условия необязательны и объединяются по "И", незаданное условие не ограничивает выборку
+// В выборку попадают только торгующиеся облигации
+type ScreenerQuery struct {
+	// Типы облигаций
+	Types []data.BondType
+
+	// ID эмитента
+	IssuerID int
+
+	// Часть названия эмитента (без учета регистра)
+	Issuer string
+
+	// Уровни листинга
+	ListingLevels []int
+
+	// Признак "только для квалифицированных инвесторов"
+	QualifiedOnly *bool
+
+	// Признак "высокий риск"
+	HighRisk *bool
+
+	// Валюты номинала
+	Currencies []string
+
+	// Количество купонов в год
+	CouponFrequencies []int
+
+	// Диапазон дат погашения (включительно)
+	MaturityFrom, MaturityTo *time.Time
+
+	// Диапазон дат ближайшей оферты (включительно)
+	// Облигации без оферт не удовлетворяют этому условию
+	OfferFrom, OfferTo *time.Time
+
+	// Диапазон доходности к погашению, % годовых
+	MinYield, MaxYield *float64
+
+	// Диапазон спреда к кривой ОФЗ, б.п.
+	// Облигации без спреда не удовлетворяют этому условию
+	MinSpread, MaxSpread *float64
+
+	// Диапазон чистой цены, % от номинала
+	MinPrice, MaxPrice *float64
+
+	// Наличие амортизационных выплат до погашения
+	HasAmortization *bool
+
+	// Поле для сортировки (по умолчанию - доходность к погашению)
+	Sort ScreenerSort
+
+	// Сортировка по возрастанию (по умолчанию - по убыванию)
+	Ascending bool
+
+	// Количество пропускаемых облигаций
+	Skip int
+
+	// Количество облигаций на странице (по умолчанию - DefaultScreenerLimit)
+	Limit int
+}
+
+// ScreenerResult содержит результат отбора облигаций в скринере
+type ScreenerResult struct {
+	// Облигации на текущей странице
+	Reports []*Report
+
+	// Общее количество облигаций, удовлетворяющих условиям
+	TotalCount int
+}
+
+// Validate проверяет корректность условий скринера
+func (q *ScreenerQuery) Validate() error {
+	for _, t := range q.Types {
+		valid := false
+		for _, v := range validBondTypes {
+			valid = valid || t == v
+		}
+		if !valid {
+			return fmt.Errorf("\"%s\" is not a valid bond type", t)
+		}
+	}
+	if q.IssuerID < 0 {
+		return fmt.Errorf("issuer id must not be negative")
+	}
+	for _, level := range q.ListingLevels {
+		if level < 1 || level > 3 {
+			return fmt.Errorf("listing level must be in range 1..3")
+		}
+	}
+	for _, currency := range q.Currencies {
+		if !currencyRegexp.MatchString(currency) {
+			return fmt.Errorf("\"%s\" is not a valid currency code", currency)
+		}
+	}
+	for _, frequency := range q.CouponFrequencies {
+		if frequency < 0 {
+			return fmt.Errorf("coupon frequency must not be negative")
+		}
+	}
+	if q.MaturityFrom != nil && q.MaturityTo != nil && q.MaturityFrom.After(*q.MaturityTo) {
+		return fmt.Errorf("maturity window is empty")
+	}
+	if q.OfferFrom != nil && q.OfferTo != nil && q.OfferFrom.After(*q.OfferTo) {
+		return fmt.Errorf("offer window is empty")
+	}
+	if q.MinYield != nil && q.MaxYield != nil && *q.MinYield > *q.MaxYield {
+		return fmt.Errorf("min yield must not exceed max yield")
+	}
+	if q.MinSpread != nil && q.MaxSpread != nil && *q.MinSpread > *q.MaxSpread {
+		return fmt.Errorf("min spread must not exceed max spread")
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return fmt.Errorf("min price must not exceed max price")
+	}
+	if _, exists := screenerSortSQL[q.Sort]; q.Sort != "" && !exists {
+		return fmt.Errorf("\"%s\" is not a valid sort key", q.Sort)
+	}
+	if q.Skip < 0 {
+		return fmt.Errorf("skip must not be negative")
+	}
+	if q.Limit < 0 || q.Limit > MaxScreenerLimit {
+		return fmt.Errorf("limit must be in range 0..%d", MaxScreenerLimit)
+	}
+
+	return nil
+}
+
+// compile формирует подзапрос для отбора облигаций по условиям скринера
+// Значения передаются только через параметры запроса, а сортировка выбирается из фиксированного списка выражений
+func (q *ScreenerQuery) compile() (string, []interface{}) {
+	conditions := []string{"bonds.is_traded"}
+	args := make([]interface{}, 0)
+
+	addCondition := func(condition string, value interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, value)
+	}
+
+	if len(q.Types) > 0 {
+		types := make([]string, len(q.Types))
+		for i, t := range q.Types {
+			types[i] = string(t)
+		}
+		addCondition("bonds.type IN ?", types)
+	}
+	if q.IssuerID > 0 {
+		addCondition("bonds.issuer_id = ?", q.IssuerID)
+	}
+	if issuer := strings.TrimSpace(q.Issuer); issuer != "" {
+		addCondition("issuers.name ILIKE ?", "%"+escapeLikePattern(issuer)+"%")
+	}
+	if len(q.ListingLevels) > 0 {
+		addCondition("bonds.listing_level IN ?", q.ListingLevels)
+	}
+	if q.QualifiedOnly != nil {
+		addCondition("bonds.qualified_only = ?", *q.QualifiedOnly)
+	}
+	if q.HighRisk != nil {
+		addCondition("bonds.high_risk = ?", *q.HighRisk)
+	}
+	if len(q.Currencies) > 0 {
+		addCondition("bonds.face_unit IN ?", q.Currencies)
+	}
+	if len(q.CouponFrequencies) > 0 {
+		addCondition("bonds.coupon_freq IN ?", q.CouponFrequencies)
+	}
+	if q.MaturityFrom != nil {
+		addCondition("bonds.maturity_date >= ?", *q.MaturityFrom)
+	}
+	if q.MaturityTo != nil {
+		addCondition("bonds.maturity_date <= ?", *q.MaturityTo)
+	}
+	if q.OfferFrom != nil {
+		addCondition("report_metrics.offer_date >= ?", *q.OfferFrom)
+	}
+	if q.OfferTo != nil {
+		addCondition("report_metrics.offer_date <= ?", *q.OfferTo)
+	}
+	if q.MinYield != nil {
+		addCondition(screenerSortSQL[SortByYield]+" >= ?", *q.MinYield)
+	}
+	if q.MaxYield != nil {
+		addCondition(screenerSortSQL[SortByYield]+" <= ?", *q.MaxYield)
+	}
+	if q.MinSpread != nil {
+		addCondition("report_metrics.spread >= ?", *q.MinSpread)
+	}
+	if q.MaxSpread != nil {
+		addCondition("report_metrics.spread <= ?", *q.MaxSpread)
+	}
+	if q.MinPrice != nil {
+		addCondition("reports.open_price >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		addCondition("reports.open_price <= ?", *q.MaxPrice)
+	}
+	if q.HasAmortization != nil {
+		if *q.HasAmortization {
+			conditions = append(conditions, "reports.amortization_payments > 0")
+		} else {
+			conditions = append(conditions, "reports.amortization_payments = 0")
+		}
+	}
+
+	sort := q.Sort
+	if sort == "" {
+		sort = SortByYield
+	}
+	direction := "DESC"
+	if q.Ascending {
+		direction = "ASC"
+	}
+
+	// Облигации без значения поля сортировки (например, без оферты) всегда идут в конце
+	text := `
+SELECT reports.bond_id,
+       ROW_NUMBER() OVER (ORDER BY ` + screenerSortSQL[sort] + ` ` + direction + ` NULLS LAST, reports.bond_id ASC) AS index
+FROM reports
+INNER JOIN bonds ON bonds.id = reports.bond_id
+INNER JOIN issuers ON issuers.id = bonds.issuer_id
+LEFT JOIN report_metrics ON report_metrics.bond_id = reports.bond_id
+WHERE ` + strings.Join(conditions, "\n  AND ") + `
+`
+	return text, args
+}
+
+// escapeLikePattern экранирует спецсимволы шаблона LIKE
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Screen выполняет отбор облигаций по условиям скринера
+func (s *service) Screen(ctx context.Context, tx *data.TX, query *ScreenerQuery) (*ScreenerResult, error) {
+	err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	filterSQL, args := query.compile()
+
+	totalCount, err := tx.Reports.Count(filterSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = DefaultScreenerLimit
+	}
+
+	pageSQL := filterSQL + "ORDER BY index ASC\nLIMIT ? OFFSET ?\n"
+	entities, err := tx.Reports.List(0, pageSQL, append(args, limit, query.Skip)...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScreenerResult{
+		Reports:    make([]*Report, len(entities)),
+		TotalCount: totalCount,
+	}
+	for i, entity := range entities {
+		result.Reports[i] = applyCostModel(today(), mapReport(entity), 1, s.costs)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package recommender
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+func TestScreenerQuery_Validate(t *testing.T) {
+	assert := assertion.New(t)
+
+	minYield, maxYield := 12.0, 8.0
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(-1, 0, 0)
+
+	assert.NoError((&ScreenerQuery{}).Validate())
+	assert.NoError((&ScreenerQuery{Types: []data.BondType{data.OFZBond}, Sort: SortBySpread, Limit: MaxScreenerLimit}).Validate())
+	assert.Error((&ScreenerQuery{Types: []data.BondType{"stock"}}).Validate())
+	assert.Error((&ScreenerQuery{ListingLevels: []int{4}}).Validate())
+	assert.Error((&ScreenerQuery{Currencies: []string{"rub"}}).Validate())
+	assert.Error((&ScreenerQuery{MinYield: &minYield, MaxYield: &maxYield}).Validate())
+	assert.Error((&ScreenerQuery{MaturityFrom: &from, MaturityTo: &to}).Validate())
+	assert.Error((&ScreenerQuery{Sort: "id; DROP TABLE bonds"}).Validate())
+	assert.Error((&ScreenerQuery{Skip: -1}).Validate())
+	assert.Error((&ScreenerQuery{Limit: MaxScreenerLimit + 1}).Validate())
+}
+
+func TestScreenerQuery_Compile(t *testing.T) {
+	assert := assertion.New(t)
+
+	qualifiedOnly, hasAmortization := false, true
+	minSpread := 100.0
+	query := &ScreenerQuery{
+		Types:             []data.BondType{data.CorporateBond},
+		Issuer:            "100%_",
+		QualifiedOnly:     &qualifiedOnly,
+		CouponFrequencies: []int{4, 12},
+		MinSpread:         &minSpread,
+		HasAmortization:   &hasAmortization,
+		Sort:              SortByMaturity,
+		Ascending:         true,
+	}
+
+	text, args := query.compile()
+	assert.Equal([]interface{}{[]string{"corporate_bond"}, `%100\%\_%`, false, []int{4, 12}, 100.0}, args)
+	assert.Equal(len(args), strings.Count(text, "?"))
+	assert.Contains(text, "reports.amortization_payments > 0")
+	assert.Contains(text, "ORDER BY bonds.maturity_date ASC NULLS LAST")
+
+	// По умолчанию облигации сортируются по убыванию доходности
+	text, args = (&ScreenerQuery{}).compile()
+	assert.Empty(args)
+	assert.Contains(text, "WHERE bonds.is_traded\n")
+	assert.Contains(text, "ORDER BY COALESCE(report_metrics.yield_to_maturity, report_metrics.interest_rate, reports.interest_rate) DESC NULLS LAST")
+}
@@ -4,19 +4,25 @@ import (
 	"context"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/data"
 )
 
 type service struct {
+	collections   map[string]*internalCollection
+	projections   *ProjectionAssumptions
+	issuerRatings map[string]string
+	costs         *CostModel
 }
 
 // ListCollections возвращает список коллекций рекомендаций
 func (s *service) ListCollections() []Collection {
-	array := make([]Collection, len(collections))
+	array := make([]Collection, len(s.collections))
 	i := 0
-	for _, coll := range collections {
+	for _, coll := range s.collections {
 		array[i] = coll
 		i++
 	}
@@ -31,7 +37,7 @@ func (s *service) ListCollections() []Collection {
 // GetCollection возвращает коллекцию рекомендаций по ее ID
 // Если коллекция не найдена, то возвращается ошибка ErrNotFound
 func (s *service) GetCollection(id string) (Collection, error) {
-	coll, exists := collections[id]
+	coll, exists := s.collections[id]
 	if !exists {
 		return nil, ErrNotFound
 	}
@@ -60,34 +66,79 @@ func (s *service) GetReport(ctx context.Context, tx *data.TX, id int) (*Report,
 	return report, nil
 }
 
+// costModel возвращает модель комиссий и налогов из запроса, а если она не задана - модель сервиса
+func (s *service) costModel(model *CostModel) *CostModel {
+	if model != nil {
+		return model
+	}
+
+	return s.costs
+}
+
 // enrichWithCashFlow дозагружает в отчет данные по выплатам
+// и пересчитывает его по модели комиссий и налогов сервиса
 func (s *service) enrichWithCashFlow(tx *data.TX, report *Report) error {
 	payments, err := tx.CashFlow.List(report.Bond.ID)
 	if err != nil {
 		return err
 	}
 
-	report.CashFlow = make([]*CashFlowItem, len(payments))
+	now := today()
+	report.CashFlow = mapCashFlow(payments)
+	if report.OfferDate != nil {
+		report.ToOffer = offerReport(now, report, *report.OfferDate, report.OfferPrice, s.costs)
+	}
+
+	*report = *applyCostModel(now, report, 1, s.costs)
+	return nil
+}
+
+// mapCashFlow преобразует выплаты из БД в элементы таблицы выплат
+func mapCashFlow(payments []*data.CashFlowItem) []*CashFlowItem {
+	items := make([]*CashFlowItem, len(payments))
 	for i, payment := range payments {
-		report.CashFlow[i] = &CashFlowItem{
-			Type:     CashFlowItemType(payment.Type),
-			Date:     payment.Date,
-			ValueRub: payment.ValueRub,
+		items[i] = &CashFlowItem{
+			Type:      CashFlowItemType(payment.Type),
+			Date:      payment.Date,
+			Value:     payment.Value,
+			ValueRub:  payment.ValueRub,
+			Estimated: payment.Estimated,
 		}
 	}
 
-	return nil
+	return items
 }
 
 // Suggest выполняет расчет предложений по инвестированию
 func (s *service) Suggest(ctx context.Context, tx *data.TX, request *SuggestRequest) (*SuggestResult, error) {
+	now := today()
+
+	r := *request
+	r.Costs = s.costModel(request.Costs)
+
 	// Формирование позиций
-	positions, err := s.generatePositionsForSuggestion(tx, request)
+	positions, relaxed, err := generatePositionsForSuggestion(now, &r, func(collection Collection, duration Duration) ([]*Report, error) {
+		return s.getBondForSuggestion(tx, collection, duration)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Формирование портфеля
+	result := newSuggestResult(now, positions, r.Costs)
+	result.UnusedAmount = unusedAmount(request.Amount, result)
+	result.LimitsRelaxed = relaxed
+	return result, nil
+}
+
+// unusedAmount возвращает неиспользованный остаток суммы amount, на которую сформирован портфель
+func unusedAmount(amount float64, result *SuggestResult) float64 {
+	return math.Max(round2(amount-result.Amount), 0)
+}
+
+// newSuggestResult рассчитывает показатели портфеля, сформированного на момент now
+// Если модель комиссий и налогов задана, то НДФЛ по позициям пересчитывается по совокупному доходу портфеля
+func newSuggestResult(now time.Time, positions []*SuggestedPortfolioPosition, costs *CostModel) *SuggestResult {
 	result := &SuggestResult{
 		Positions:          positions,
 		Amount:             0, // Рассчитывается отдельно
@@ -95,26 +146,147 @@ func (s *service) Suggest(ctx context.Context, tx *data.TX, request *SuggestRequ
 		ProfitLoss:         0, // Рассчитывается отдельно
 		RelativeProfitLoss: 0, // Рассчитывается отдельно
 		InterestRate:       0, // Рассчитывается отдельно
+		YieldToMaturity:    0, // Рассчитывается отдельно
+		MacaulayDuration:   0, // Рассчитывается отдельно
+		ModifiedDuration:   0, // Рассчитывается отдельно
+		DV01:               0, // Рассчитывается отдельно
+		Convexity:          0, // Рассчитывается отдельно
+		TaxDeduction:       0, // Рассчитывается отдельно
+
+		IssuerConcentrations:       nil, // Рассчитывается отдельно
+		BondTypeConcentrations:     nil, // Рассчитывается отдельно
+		MaturityYearConcentrations: nil, // Рассчитывается отдельно
+		CurrencyConcentrations:     nil, // Рассчитывается отдельно
 	}
 
+	if costs != nil {
+		applyPortfolioCostModel(now, positions, costs)
+	} else {
+		costs = DefaultCostModel()
+	}
+
+	flows := make([]xirrFlow, 0)
 	for _, p := range positions {
 		result.Amount += p.OpenValue
 		if result.DurationDays < p.DaysTillMaturity {
 			result.DurationDays = p.DaysTillMaturity
 		}
 		result.ProfitLoss += p.ProfitLoss
+		result.MacaulayDuration += p.MacaulayDuration * p.OpenValue
+		result.ModifiedDuration += p.ModifiedDuration * p.OpenValue
+		result.DV01 += p.DV01
+		result.Convexity += p.Convexity * p.OpenValue
+		flows = append(flows, yieldCashFlows(now, p.OpenValue, p.OpenFee, costs.CouponTaxRate(p.Bond), p.Taxes, p.CashFlow)...)
 	}
 
 	result.RelativeProfitLoss = 100.0 * result.ProfitLoss / result.Amount
-	result.InterestRate = result.RelativeProfitLoss / (float64(result.DurationDays) / 356.25)
+	result.MacaulayDuration /= result.Amount
+	result.ModifiedDuration /= result.Amount
+	result.Convexity /= result.Amount
+	result.InterestRate = result.RelativeProfitLoss / (float64(result.DurationDays) / daysInYear)
+	result.TaxDeduction = costs.TaxDeduction(result.Amount)
+
+	// Эффективная доходность портфеля рассчитывается по объединенному ряду платежей всех позиций
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].Date.Before(flows[j].Date)
+	})
+	if rate, err := xirr(flows); err == nil {
+		result.YieldToMaturity = math.Round(rate*100.0*100.0) / 100.0
+	}
 
-	return result, nil
+	result.IssuerConcentrations, result.BondTypeConcentrations, result.MaturityYearConcentrations, result.CurrencyConcentrations =
+		concentrations(now, positions, result.Amount)
+
+	return result
 }
 
+// concentrations рассчитывает доли эмитентов, типов облигаций, годов погашения и валют номинала в портфеле на сумму amount
+func concentrations(now time.Time, positions []*SuggestedPortfolioPosition, amount float64) (issuers, bondTypes, maturityYears, currencies []*SuggestConcentration) {
+	issuerMap := make(map[int]*SuggestConcentration)
+	bondTypeMap := make(map[data.BondType]*SuggestConcentration)
+	maturityYearMap := make(map[int]*SuggestConcentration)
+	currencyMap := make(map[string]*SuggestConcentration)
+	for _, p := range positions {
+		key := issuerKey(&p.Report)
+		c, exists := issuerMap[key]
+		if !exists {
+			c = &SuggestConcentration{Name: p.Bond.ShortName}
+			if p.Issuer != nil {
+				c.Name = p.Issuer.Name
+			}
+			issuerMap[key] = c
+			issuers = append(issuers, c)
+		}
+		c.Amount += p.OpenValue
+
+		c, exists = bondTypeMap[p.Bond.Type]
+		if !exists {
+			c = &SuggestConcentration{Name: string(p.Bond.Type)}
+			bondTypeMap[p.Bond.Type] = c
+			bondTypes = append(bondTypes, c)
+		}
+		c.Amount += p.OpenValue
+
+		year := maturityYear(now, &p.Report)
+		c, exists = maturityYearMap[year]
+		if !exists {
+			c = &SuggestConcentration{Name: strconv.Itoa(year)}
+			maturityYearMap[year] = c
+			maturityYears = append(maturityYears, c)
+		}
+		c.Amount += p.OpenValue
+
+		c, exists = currencyMap[p.Bond.FaceUnit]
+		if !exists {
+			c = &SuggestConcentration{Name: p.Bond.FaceUnit}
+			currencyMap[p.Bond.FaceUnit] = c
+			currencies = append(currencies, c)
+		}
+		c.Amount += p.OpenValue
+	}
+
+	for _, list := range [][]*SuggestConcentration{issuers, bondTypes, maturityYears, currencies} {
+		for _, c := range list {
+			if amount > 0 {
+				c.Weight = c.Amount / amount
+			}
+		}
+	}
+
+	byAmount := func(list []*SuggestConcentration) {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Amount > list[j].Amount
+		})
+	}
+	byAmount(issuers)
+	byAmount(bondTypes)
+	byAmount(currencies)
+	sort.SliceStable(maturityYears, func(i, j int) bool {
+		return maturityYears[i].Name < maturityYears[j].Name
+	})
+
+	return issuers, bondTypes, maturityYears, currencies
+}
+
+// bondSelector выполняет выборку облигаций для формирования предложений по инвестированию
+// Если коллекция не задана, то выборка выполняется по всем облигациям
+// Отчеты должны содержать данные по выплатам и быть отсортированы по убыванию доходности
+type bondSelector func(collection Collection, duration Duration) ([]*Report, error)
+
 // generatePositionsForSuggestion выполняет генерацию позиций по запросу
-func (s *service) generatePositionsForSuggestion(tx *data.TX, request *SuggestRequest) ([]*SuggestedPortfolioPosition, error) {
+// Позиции подбираются оптимизатором с учетом ограничений request.Constraints
+// Также возвращает признак того, что лимиты на доли были ослаблены (см. SuggestConstraints.MaxCashWeight)
+func generatePositionsForSuggestion(now time.Time, request *SuggestRequest, selectBonds bondSelector) ([]*SuggestedPortfolioPosition, bool, error) {
 	var positions []*SuggestedPortfolioPosition
 
+	if request.Constraints != nil {
+		err := request.Constraints.Validate()
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	optimizer := newPortfolioOptimizer(now, request.Amount, request.Costs, request.Constraints)
+
 	// Расчет позиций согласно ограничениям
 	if request.Parts != nil && len(request.Parts) > 0 {
 		// Нормализация весов и сортировка групп по убыванию веса
@@ -135,21 +307,23 @@ func (s *service) generatePositionsForSuggestion(tx *data.TX, request *SuggestRe
 		unusedAmount := float64(0)
 		for _, part := range request.Parts {
 			maxAmount := math.Floor(request.Amount*part.Weight) + unusedAmount
-			ps, remainingAmount, err := s.generatePositionsForSuggestionPart(tx, part.Collection, request.MaxDuration, maxAmount)
+			reports, err := selectBonds(part.Collection, request.MaxDuration)
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 
+			ps, remainingAmount := optimizer.allocate(reports, maxAmount)
 			positions = append(positions, ps...)
 			unusedAmount = remainingAmount
 		}
 
 	} else {
-		var err error
-		positions, _, err = s.generatePositionsForSuggestionPart(tx, nil, request.MaxDuration, request.Amount)
+		reports, err := selectBonds(nil, request.MaxDuration)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
+
+		positions, _ = optimizer.allocate(reports, request.Amount)
 	}
 
 	// Расчет весов позиций
@@ -161,103 +335,102 @@ func (s *service) generatePositionsForSuggestion(tx *data.TX, request *SuggestRe
 		p.Weight = p.OpenValue / totalAmount
 	}
 
-	return positions, nil
+	return positions, optimizer.relaxed, nil
 
 }
 
-// generatePositionsForSuggestionPart выполняет генерацию позиций по запросу для отдельно взятой коллекции
-func (s *service) generatePositionsForSuggestionPart(
-	tx *data.TX,
-	collection Collection,
-	duration Duration,
-	maxAmount float64) ([]*SuggestedPortfolioPosition, float64, error) {
+// newSuggestedPosition формирует позицию из quantity облигаций по отчету report
+func newSuggestedPosition(now time.Time, report *Report, quantity int, costs *CostModel) *SuggestedPortfolioPosition {
+	r := scaleReport(report, float64(quantity))
 
-	// Выбираем подходящие облигации
-	reports, err := s.getBondForSuggestion(tx, collection, duration)
-	if err != nil {
-		return nil, 0, err
+	// Комиссии и налоги пересчитываются на всю позицию, т.к. они зависят от суммы сделки
+	if costs != nil {
+		r = applyCostModel(now, r, quantity, costs)
 	}
 
-	// До тех пор, пока у нас имеется неиспользованный объем инвестиций,
-	// перебираем все подходящие облигации, рассчитывая объем позиции
-	positions := make([]*SuggestedPortfolioPosition, 0)
-	for _, report := range reports {
-		// Рассчитываем объем позиции
-		quantity := int(math.Floor(maxAmount / report.OpenValue))
-		if quantity <= 0 {
-			continue
-		}
-		quantityF := float64(quantity)
-
-		// Корректируем объем инвестиций
-		maxAmount -= report.OpenValue * float64(quantity)
+	return &SuggestedPortfolioPosition{
+		Report:   *r,
+		Quantity: quantity,
+		Weight:   0, // Веса рассчитываются позже, т.к. они считаются по всему портфелю
+	}
+}
 
-		// Формируем позицию
-		r := mapReport(report)
-		err = s.enrichWithCashFlow(tx, r)
-		if err != nil {
-			return nil, 0, err
-		}
+// scaleReport пересчитывает суммы в отчете по одной облигации на позицию из quantity облигаций
+// Исходный отчет не изменяется
+func scaleReport(report *Report, quantity float64) *Report {
+	r := *report
+	r.OpenFee *= quantity
+	r.OpenValue *= quantity
+	r.CouponPayments *= quantity
+	r.AmortizationPayments *= quantity
+	r.MaturityPayment *= quantity
+	r.Taxes *= quantity
+	r.Revenue *= quantity
+	r.ProfitLoss *= quantity
+	r.DV01 *= quantity
+	r.CashFlow = make([]*CashFlowItem, len(report.CashFlow))
+	for i, c := range report.CashFlow {
+		item := *c
+		item.Value *= quantity
+		item.ValueRub *= quantity
+		r.CashFlow[i] = &item
+	}
 
-		r.OpenFee *= quantityF
-		r.OpenValue *= quantityF
-		r.CouponPayments *= quantityF
-		r.AmortizationPayments *= quantityF
-		r.MaturityPayment *= quantityF
-		r.Taxes *= quantityF
-		r.Revenue *= quantityF
-		r.ProfitLoss *= quantityF
-		for _, c := range r.CashFlow {
-			c.ValueRub *= quantityF
-		}
+	return &r
+}
 
-		position := &SuggestedPortfolioPosition{
-			Report:   *r,
-			Quantity: quantity,
-			Weight:   0, // Веса рассчитываются позже, т.к. они считаются по всему портфелю
-		}
-		positions = append(positions, position)
+// getBondForSuggestion выполняет выборку облигаций по запросу
+// Отчеты загружаются вместе с данными по выплатам
+func (s *service) getBondForSuggestion(tx *data.TX, collection Collection, duration Duration) ([]*Report, error) {
+	entities, err := s.listBondsForSuggestion(tx, collection, duration)
+	if err != nil {
+		return nil, err
+	}
 
-		if maxAmount <= 0 {
-			break
+	reports := make([]*Report, len(entities))
+	for i, entity := range entities {
+		reports[i] = mapReport(entity)
+		err = s.enrichWithCashFlow(tx, reports[i])
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return positions, maxAmount, nil
+	return reports, nil
 }
 
-// getBondForSuggestion выполняет выборку облигаций по запросу
-func (s *service) getBondForSuggestion(tx *data.TX, collection Collection, duration Duration) ([]*data.Report, error) {
+// listBondsForSuggestion выполняет выборку облигаций по запросу в БД
+func (s *service) listBondsForSuggestion(tx *data.TX, collection Collection, duration Duration) ([]*data.Report, error) {
 	if collection == nil {
 		// Выборка облигаций по критериям:
-		// - погашение в пределах срока инвестирования
-		// - доходность в рамках трех сигм
+		// - погашение (или оферта) в пределах срока инвестирования
+		// - эффективная доходность к погашению (или оферте) в рамках трех сигм
 		// - не более 10 облигаций
 		sql := `
 WITH cte AS (
     SELECT b.id,
-           r.interest_rate,
-           AVG(r.interest_rate) OVER ()    AS mean,
-           STDDEV(r.interest_rate) OVER () AS stddev
+           COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)                 AS ytm,
+           AVG(COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)) OVER ()    AS mean,
+           STDDEV(COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)) OVER () AS stddev
     FROM reports r
     INNER JOIN bonds b ON b.id = r.bond_id
+    LEFT JOIN report_metrics m ON m.bond_id = r.bond_id
     WHERE b.high_risk = FALSE
-      AND b.maturity_date <= NOW() + ? * '1y'::interval
-      AND b.maturity_date >= NOW() + (0.5 * ? * '1y'::interval)
-      AND r.interest_rate > 0
-    ORDER BY r.interest_rate DESC
+      AND COALESCE(m.offer_date, b.maturity_date) <= NOW() + ? * '1y'::interval
+      AND COALESCE(m.offer_date, b.maturity_date) >= NOW() + (0.5 * ? * '1y'::interval)
+      AND COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate) > 0
+    ORDER BY ytm DESC
 )
 SELECT id AS bond_id, row_number() OVER () AS index
 FROM cte
-WHERE (interest_rate <= mean + 3 * stddev)
+WHERE (ytm <= mean + 3 * stddev)
 `
 		d := getAge(duration)
 		return tx.Reports.List(10, sql, d, d)
 	} else {
 		// Выборка облигаций по критериям:
-		// - погашение в пределах срока инвестирования
-		// - погашение в пределах срока инвестирования
-		// - доходность в рамках: [max - 1, max]
+		// - погашение (или оферта) в пределах срока инвестирования
+		// - эффективная доходность к погашению (или оферте) в рамках: [max - 1, max]
 		// - не более 10 облигаций
 
 		sql := `
@@ -269,20 +442,20 @@ WITH cte_bonds AS (
 ),
      cte AS (
          SELECT b.id,
-                r.interest_rate,
-                MAX(r.interest_rate) OVER ()                     AS max_interest_rate,
-                (MAX(r.interest_rate) OVER () - r.interest_rate) AS delta_interest_rate
+                COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)              AS ytm,
+                MAX(COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate)) OVER () AS max_ytm
          FROM reports r
          INNER JOIN bonds b ON b.id = r.bond_id
          INNER JOIN cte_bonds ON cte_bonds.id = r.bond_id
-         WHERE b.maturity_date <= NOW() + ? * '1y'::interval
-           AND b.maturity_date >= NOW() + (0.5 * ? * '1y'::interval)
-           AND r.interest_rate > 0
-         ORDER BY r.interest_rate DESC
+         LEFT JOIN report_metrics m ON m.bond_id = r.bond_id
+         WHERE COALESCE(m.offer_date, b.maturity_date) <= NOW() + ? * '1y'::interval
+           AND COALESCE(m.offer_date, b.maturity_date) >= NOW() + (0.5 * ? * '1y'::interval)
+           AND COALESCE(m.yield_to_offer, m.yield_to_maturity, m.interest_rate, r.interest_rate) > 0
+         ORDER BY ytm DESC
      )
 SELECT id AS bond_id, row_number() OVER () AS index
 FROM cte
-WHERE (max_interest_rate - interest_rate) <= 1
+WHERE (max_ytm - ytm) <= 1
 `
 		d := getAge(duration)
 		return tx.Reports.List(10, sql, collection.ID(), d, d)
@@ -291,8 +464,14 @@ WHERE (max_interest_rate - interest_rate) <= 1
 
 // Rebuild выполняет обновление данных рекомендаций
 func (s *service) Rebuild(ctx context.Context, tx *data.TX) error {
+	// Обновляем оценки еще не объявленных выплат (купоны флоатеров и линкеров)
+	err := s.rebuildProjections(tx)
+	if err != nil {
+		return err
+	}
+
 	// Обновляем данные текущих выплат по облигациям
-	err := tx.CashFlow.Rebuild()
+	err = tx.CashFlow.Rebuild()
 	if err != nil {
 		return err
 	}
@@ -303,8 +482,22 @@ func (s *service) Rebuild(ctx context.Context, tx *data.TX) error {
 		return err
 	}
 
+	// Обновляем показатели, которые рассчитываются вне БД (включая кривую доходности ОФЗ и спреды к ней)
+	err = s.rebuildMetrics(tx)
+	if err != nil {
+		return err
+	}
+
+	// Обновляем кредитные рейтинги эмитентов, по которым отбираются облигации в пользовательские коллекции
+	if s.issuerRatings != nil {
+		err = tx.Issuers.SetRatings(s.issuerRatings)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Обновляем данные коллекций
-	for _, coll := range collections {
+	for _, coll := range s.collections {
 		err := coll.Rebuild(ctx, tx)
 		if err != nil {
 			return err
@@ -323,6 +516,7 @@ func mapReport(entity *data.Report) *Report {
 		MarketData:           &entity.MarketData,
 		DaysTillMaturity:     entity.DaysTillMaturity,
 		Currency:             entity.Currency,
+		FxRate:               entity.FxRate,
 		OpenPrice:            entity.OpenPrice,
 		OpenAccruedInterest:  entity.OpenAccruedInterest,
 		OpenFaceValue:        entity.OpenFaceValue,
@@ -336,7 +530,23 @@ func mapReport(entity *data.Report) *Report {
 		ProfitLoss:           entity.ProfitLoss,
 		RelativeProfitLoss:   entity.RelativeProfitLoss,
 		InterestRate:         entity.InterestRate,
+		YieldToMaturity:      entity.YieldToMaturity,
+		MacaulayDuration:     entity.MacaulayDuration,
+		ModifiedDuration:     entity.ModifiedDuration,
+		DV01:                 entity.DV01,
+		Convexity:            entity.Convexity,
+		OfferDate:            nil, // Заполняется ниже
+		OfferPrice:           entity.OfferPrice,
+		DaysTillOffer:        entity.DaysTillOffer,
+		YieldToOffer:         entity.YieldToOffer,
+		Spread:               entity.Spread,
+		ToOffer:              nil, // Заполняется при загрузке данных по выплатам
 		CashFlow:             emptyCashFlowArray,
 	}
+	if entity.OfferDate.Valid {
+		offerDate := entity.OfferDate.Time
+		report.OfferDate = &offerDate
+	}
+
 	return &report
 }
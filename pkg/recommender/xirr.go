@@ -0,0 +1,105 @@
+package recommender
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// errXIRRNotConverged возвращается, если не удалось найти доходность для ряда платежей
+var errXIRRNotConverged = errors.New("xirr didn't converge")
+
+// daysInYear - количество дней в году, используемое для приведения доходности к годовой
+const daysInYear = 365.25
+
+// xirrFlow - платеж в ряду платежей для расчета XIRR
+// Отрицательные значения соответствуют вложениям, положительные - выплатам
+type xirrFlow struct {
+	Date  time.Time
+	Value float64
+}
+
+// xirr рассчитывает эффективную годовую доходность ряда платежей (в долях, не в процентах)
+// Отсчет времени ведется от даты первого платежа в ряду
+func xirr(flows []xirrFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, errXIRRNotConverged
+	}
+
+	hasPositive, hasNegative := false, false
+	for _, f := range flows {
+		if f.Value > 0 {
+			hasPositive = true
+		}
+		if f.Value < 0 {
+			hasNegative = true
+		}
+	}
+	if !hasPositive || !hasNegative {
+		return 0, errXIRRNotConverged
+	}
+
+	t0 := flows[0].Date
+	years := make([]float64, len(flows))
+	for i, f := range flows {
+		years[i] = f.Date.Sub(t0).Hours() / 24.0 / daysInYear
+	}
+
+	npv := func(rate float64) float64 {
+		sum := 0.0
+		for i, f := range flows {
+			sum += f.Value / math.Pow(1+rate, years[i])
+		}
+		return sum
+	}
+
+	dnpv := func(rate float64) float64 {
+		sum := 0.0
+		for i, f := range flows {
+			sum -= years[i] * f.Value / math.Pow(1+rate, years[i]+1)
+		}
+		return sum
+	}
+
+	// Сначала пробуем метод Ньютона, он сходится за несколько итераций для типичных облигаций
+	rate := 0.1
+	for i := 0; i < 100; i++ {
+		d := dnpv(rate)
+		if d == 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+			break
+		}
+
+		next := rate - npv(rate)/d
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+
+		if math.Abs(next-rate) < 1e-10 {
+			return next, nil
+		}
+		rate = next
+	}
+
+	// Если метод Ньютона не сошелся, то используем метод бисекции
+	lo, hi := -0.9999, 10.0
+	fLo, fHi := npv(lo), npv(hi)
+	if math.IsNaN(fLo) || math.IsNaN(fHi) || fLo*fHi > 0 {
+		return 0, errXIRRNotConverged
+	}
+
+	for i := 0; i < 300; i++ {
+		mid := (lo + hi) / 2
+		fMid := npv(mid)
+		if math.Abs(fMid) < 1e-9 || (hi-lo)/2 < 1e-12 {
+			return mid, nil
+		}
+
+		if fLo*fMid < 0 {
+			hi = mid
+		} else {
+			lo, fLo = mid, fMid
+		}
+	}
+
+	return (lo + hi) / 2, nil
+}
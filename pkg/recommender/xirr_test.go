@@ -0,0 +1,55 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestXIRR(t *testing.T) {
+	assert := assertion.New(t)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	{
+		// Вложение 1000, через год получено 1100 - доходность 10%
+		rate, err := xirr([]xirrFlow{
+			{Date: t0, Value: -1000},
+			{Date: t0.AddDate(0, 0, 365), Value: 1100},
+		})
+		assert.Nil(err)
+		assert.InDelta(0.1, rate, 0.001)
+	}
+
+	{
+		// Купонная облигация по номиналу: 2 купона по 50 в год, погашение через 2 года
+		rate, err := xirr([]xirrFlow{
+			{Date: t0, Value: -1000},
+			{Date: t0.AddDate(0, 6, 0), Value: 50},
+			{Date: t0.AddDate(1, 0, 0), Value: 50},
+			{Date: t0.AddDate(1, 6, 0), Value: 50},
+			{Date: t0.AddDate(2, 0, 0), Value: 1050},
+		})
+		assert.Nil(err)
+		assert.InDelta(0.1025, rate, 0.001)
+	}
+
+	{
+		// Убыточная позиция
+		rate, err := xirr([]xirrFlow{
+			{Date: t0, Value: -1000},
+			{Date: t0.AddDate(0, 0, 365), Value: 900},
+		})
+		assert.Nil(err)
+		assert.InDelta(-0.1, rate, 0.001)
+	}
+
+	{
+		// Нет выплат - доходность не определена
+		_, err := xirr([]xirrFlow{
+			{Date: t0, Value: -1000},
+		})
+		assert.Equal(errXIRRNotConverged, err)
+	}
+}
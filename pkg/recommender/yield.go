@@ -0,0 +1,153 @@
+package recommender
+
+import (
+	"math"
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+)
+
+// couponTaxRate - ставка НДФЛ, удерживаемого при выплате купонов
+const couponTaxRate = 0.13
+
+// today возвращает текущую дату (UTC, без учета времени)
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// yieldCashFlows формирует ряд платежей по позиции для расчета доходности
+// Налог с купонов (по ставке taxRate) удерживается в дату выплаты купона, остаток налога - в дату последней выплаты
+func yieldCashFlows(now time.Time, openValue, openFee, taxRate, taxes float64, cashFlow []*CashFlowItem) []xirrFlow {
+	flows := make([]xirrFlow, 0, len(cashFlow)+1)
+	flows = append(flows, xirrFlow{Date: now, Value: -(openValue + openFee)})
+
+	remainingTaxes := taxes
+	for _, item := range cashFlow {
+		value := item.ValueRub
+		if item.Type == Coupon && remainingTaxes > 0 {
+			tax := math.Min(item.ValueRub*taxRate, remainingTaxes)
+			value -= tax
+			remainingTaxes -= tax
+		}
+
+		flows = append(flows, xirrFlow{Date: item.Date, Value: value})
+	}
+
+	if remainingTaxes > 0 && len(flows) > 1 {
+		flows[len(flows)-1].Value -= remainingTaxes
+	}
+
+	return flows
+}
+
+// computeYieldToMaturity рассчитывает эффективную доходность к погашению (XIRR), % годовых
+// Налог с купонов удерживается по ставке couponRate (см. yieldCashFlows)
+// Если доходность рассчитать не удалось, то возвращается false
+func computeYieldToMaturity(now time.Time, report *Report, couponRate float64) (float64, bool) {
+	flows := yieldCashFlows(now, report.OpenValue, report.OpenFee, couponRate, report.Taxes, report.CashFlow)
+	rate, err := xirr(flows)
+	if err != nil {
+		return 0, false
+	}
+
+	return math.Round(rate*100.0*100.0) / 100.0, true
+}
+
+// rebuildMetrics выполняет перерасчет показателей по облигациям, которые вычисляются вне БД
+func (s *service) rebuildMetrics(tx *data.TX) error {
+	entities, err := tx.Reports.List(0, "SELECT bond_id, bond_id AS index FROM reports")
+	if err != nil {
+		return err
+	}
+
+	payments, err := tx.CashFlow.ListAll()
+	if err != nil {
+		return err
+	}
+
+	upcomingOffers, err := tx.Offers.ListUpcoming()
+	if err != nil {
+		return err
+	}
+	offers := nearestOffers(upcomingOffers)
+
+	paymentsPerBond := make(map[int][]*data.CashFlowItem)
+	for _, payment := range payments {
+		paymentsPerBond[payment.BondID] = append(paymentsPerBond[payment.BondID], payment)
+	}
+
+	now := today()
+	reports := make([]*Report, 0, len(entities))
+	items := make([]*data.ReportMetrics, 0, len(entities))
+	for _, entity := range entities {
+		// Показатели рассчитываются по модели комиссий и налогов сервиса, а не по комиссии и налогу из БД
+		report := mapReport(entity)
+		report.CashFlow = mapCashFlow(paymentsPerBond[entity.Bond.ID])
+		report = applyCostModel(now, report, 1, s.costs)
+
+		item := &data.ReportMetrics{BondID: entity.Bond.ID, InterestRate: &report.InterestRate}
+		report.YieldToMaturity, report.MacaulayDuration = 0, 0
+		if ytm, ok := computeYieldToMaturity(now, report, s.costs.CouponTaxRate(report.Bond)); ok {
+			item.YieldToMaturity = &ytm
+			report.YieldToMaturity = ytm
+		}
+		if metrics, ok := computeRiskMetrics(now, report); ok {
+			item.MacaulayDuration = &metrics.MacaulayDuration
+			item.ModifiedDuration = &metrics.ModifiedDuration
+			item.DV01 = &metrics.DV01
+			item.Convexity = &metrics.Convexity
+			report.MacaulayDuration = metrics.MacaulayDuration
+		}
+
+		// Оферта учитывается, только если она состоится раньше погашения
+		if offer, exists := offers[entity.Bond.ID]; exists &&
+			(!entity.Bond.MaturityDate.Valid || offer.Date.Time.Before(entity.Bond.MaturityDate.Time)) {
+			price := getOfferPrice(offer)
+			r := offerReport(now, report, offer.Date.Time, price, s.costs)
+
+			item.OfferDate = offer.Date
+			item.OfferPrice = &price
+			item.YieldToOffer = &r.YieldToMaturity
+		}
+
+		reports = append(reports, report)
+		items = append(items, item)
+	}
+
+	// Кривая ОФЗ строится по ценам и выплатам ОФЗ из только что рассчитанных отчетов,
+	// после чего по ней рассчитываются спреды всех облигаций
+	curve, ok, err := s.rebuildYieldCurve(tx, now, reports)
+	if err != nil {
+		return err
+	}
+	if ok {
+		for i, report := range reports {
+			items[i].Spread = computeSpread(now, curve, report)
+		}
+	}
+
+	return tx.ReportMetrics.Rebuild(items)
+}
+
+// computeSpread рассчитывает спред доходности к погашению облигации к кривой ОФЗ на дату now, б.п.
+// Спред - это разница между доходностью облигации по рыночной цене и доходностью по цене, рассчитанной
+// дисконтированием ее выплат по кривой; обе доходности рассчитываются без учета налогов и комиссий, как и сама кривая
+// Если выплаты или дюрация неизвестны либо номинал облигации не в рублях, то возвращается nil
+func computeSpread(now time.Time, curve NSSParams, report *Report) *float64 {
+	if report.Bond.FaceUnit != "RUB" {
+		return nil
+	}
+
+	point, ok := newCurvePoint(now, report)
+	if !ok {
+		return nil
+	}
+	curveYield, ok := point.curveYield(curve)
+	if !ok {
+		return nil
+	}
+
+	spread := round2(100.0 * (point.Yield - curveYield))
+	return &spread
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// GetBond обрабатывает запросы "GET /api/v1/bonds/:id"
+// Параметр id - ID, ISIN или код облигации
+// Модель комиссий и налогов задается query-параметрами (как и для страницы "GET /bonds/:id")
+func (ctrl *Controller) GetBond(c *gin.Context) {
+	id := c.Param("id")
+
+	params, err := pages.NewCostModelParams(c)
+	if err != nil {
+		panic(err)
+	}
+
+	costs, err := params.ToCostModel()
+	if err != nil {
+		panic(err)
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	report, err := u.GetReport(id)
+	if err != nil {
+		panic(notFound(err, "bond \"%s\" doesn't exist", id))
+	}
+
+	if costs != nil {
+		report = recommender.ApplyCostModel(report, costs)
+	}
+
+	c.JSON(http.StatusOK, NewReportModel(report))
+}
+
+// GetBondHistory обрабатывает запросы "GET /api/v1/bonds/:id/history?range=1y"
+// Если параметр range не задан, то возвращается история за последний год
+func (ctrl *Controller) GetBondHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	r := recommender.HistoryRange1Year
+	if s := c.Query("range"); s != "" {
+		var err error
+		r, err = recommender.ParseHistoryRange(s)
+		if err != nil {
+			panic(pages.NewError(400, "invalid value for \"range\" parameter"))
+		}
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	history, err := u.GetPriceHistory(id, r)
+	if err != nil {
+		panic(notFound(err, "bond \"%s\" doesn't exist", id))
+	}
+
+	c.JSON(http.StatusOK, NewPriceHistoryModel(history))
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// ListCollections обрабатывает запросы "GET /api/v1/collections"
+func (ctrl *Controller) ListCollections(c *gin.Context) {
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	collections := u.ListCollections()
+	response := make([]*CollectionModel, len(collections))
+	for i, collection := range collections {
+		response[i] = NewCollectionModel(collection)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCollection обрабатывает запросы "GET /api/v1/collections/:id?duration=5y&rank=spread"
+// Если параметр duration не задан, то используется срок 5 лет
+// Если параметр rank не задан, то используется порядок по умолчанию для коллекции
+func (ctrl *Controller) GetCollection(c *gin.Context) {
+	id := c.Param("id")
+
+	duration := recommender.Duration5Year
+	if s := c.Query("duration"); s != "" {
+		duration = recommender.Duration(s)
+		if !isValidDuration(duration) {
+			panic(pages.NewError(400, "invalid value for \"duration\" parameter"))
+		}
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	collection, err := u.GetCollection(id)
+	if err != nil {
+		panic(notFound(err, "collection \"%s\" doesn't exist", id))
+	}
+
+	ranking := collection.Rankings()[0]
+	if s := c.Query("rank"); s != "" {
+		ranking = recommender.Ranking(s)
+		if !recommender.SupportsRanking(collection, ranking) {
+			panic(pages.NewError(400, "invalid value for \"rank\" parameter"))
+		}
+	}
+
+	reports, err := u.ListCollectionBonds(collection.ID(), duration, ranking)
+	if err != nil {
+		panic(err)
+	}
+
+	response := CollectionBondsResponse{
+		CollectionModel: NewCollectionModel(collection),
+		Duration:        duration,
+		Ranking:         ranking,
+		Bonds:           make([]*ReportModel, len(reports)),
+	}
+	for i, report := range reports {
+		response.Bonds[i] = NewReportModel(report)
+	}
+
+	c.JSON(http.StatusOK, &response)
+}
+
+// CollectionBondsResponse - ответ на запрос "GET /api/v1/collections/:id"
+type CollectionBondsResponse struct {
+	*CollectionModel
+	Duration recommender.Duration `json:"duration"`
+	Ranking  recommender.Ranking  `json:"rank"`
+	Bonds    []*ReportModel       `json:"bonds"`
+}
+
+// isValidDuration проверяет, что срок входит в список recommender.Durations
+func isValidDuration(duration recommender.Duration) bool {
+	for _, d := range recommender.Durations {
+		if d == duration {
+			return true
+		}
+	}
+	return false
+}
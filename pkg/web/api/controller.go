@@ -0,0 +1,82 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// Controller - контроллер JSON API
+type Controller struct {
+	app    app.App
+	logger *log.Logger
+}
+
+// New создает новый Controller
+func New(app app.App, logger *log.Logger) *Controller {
+	return &Controller{app: app, logger: logger}
+}
+
+// ErrorResponse - ответ API в случае ошибки
+type ErrorResponse struct {
+	Error ErrorModel `json:"error"`
+}
+
+// ErrorModel - описание ошибки
+type ErrorModel struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// ErrorMiddleware отвечает за обработку ошибок
+// Ошибки преобразуются в JSON ответ типа ErrorResponse
+func (ctrl *Controller) ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			var model ErrorModel
+			switch e := err.(type) {
+			case pages.Error:
+				model = ErrorModel{Status: e.StatusCode, Message: e.Message}
+			case error:
+				if e == recommender.ErrNotFound || e == data.ErrNotFound {
+					model = ErrorModel{Status: http.StatusNotFound, Message: "Not Found"}
+					break
+				}
+				ctrl.logger.Printf("error while handling \"%s %s\": %s", c.Request.Method, c.Request.RequestURI, err)
+				model = ErrorModel{Status: http.StatusInternalServerError, Message: "Internal Server Error"}
+			default:
+				ctrl.logger.Printf("error while handling \"%s %s\": %s", c.Request.Method, c.Request.RequestURI, err)
+				model = ErrorModel{Status: http.StatusInternalServerError, Message: "Internal Server Error"}
+			}
+
+			c.AbortWithStatusJSON(model.Status, &ErrorResponse{Error: model})
+		}()
+
+		c.Next()
+	}
+}
+
+// NoRoute обрабатывает запросы к несуществующим методам API
+func (ctrl *Controller) NoRoute(c *gin.Context) {
+	panic(pages.NewError(http.StatusNotFound, "no such method: %s %s", c.Request.Method, c.Request.URL.Path))
+}
+
+// notFound преобразует ошибку ErrNotFound в ошибку с кодом 404 и заданным сообщением
+// Прочие ошибки возвращаются как есть
+func notFound(err error, message string, a ...interface{}) error {
+	if err == recommender.ErrNotFound || err == data.ErrNotFound {
+		return pages.NewError(http.StatusNotFound, message, a...)
+	}
+	return err
+}
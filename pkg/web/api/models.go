@@ -0,0 +1,488 @@
+package api
+
+import (
+	"time"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// BondModel - облигация
+type BondModel struct {
+	ID               int          `json:"id"`
+	SecurityID       string       `json:"security_id"`
+	ISIN             string       `json:"isin"`
+	ShortName        string       `json:"short_name"`
+	FullName         string       `json:"full_name"`
+	Type             string       `json:"type"`
+	IsTraded         bool         `json:"is_traded"`
+	QualifiedOnly    bool         `json:"qualified_only"`
+	IsHighRisk       bool         `json:"high_risk"`
+	InitialFaceValue float64      `json:"initial_face_value"`
+	FaceUnit         string       `json:"face_unit"`
+	IssueDate        *time.Time   `json:"issue_date"`
+	MaturityDate     *time.Time   `json:"maturity_date"`
+	ListingLevel     int          `json:"listing_level"`
+	CouponFrequency  int          `json:"coupon_frequency"`
+	CouponType       string       `json:"coupon_type"`
+	Issuer           *IssuerModel `json:"issuer,omitempty"`
+}
+
+// NewBondModel создает объекты типа BondModel
+// Если эмитент не загружен, то поле Issuer не заполняется
+func NewBondModel(bond *data.Bond) *BondModel {
+	model := &BondModel{
+		ID:               bond.ID,
+		SecurityID:       bond.SecurityID,
+		ISIN:             bond.ISIN,
+		ShortName:        bond.ShortName,
+		FullName:         bond.FullName,
+		Type:             string(bond.Type),
+		CouponType:       string(bond.CouponType),
+		IsTraded:         bond.IsTraded,
+		QualifiedOnly:    bond.QualifiedOnly,
+		IsHighRisk:       bond.IsHighRisk,
+		InitialFaceValue: bond.InitialFaceValue,
+		FaceUnit:         bond.FaceUnit,
+		ListingLevel:     bond.ListingLevel,
+		CouponFrequency:  bond.CouponFrequency,
+	}
+
+	if bond.IssueDate.Valid {
+		model.IssueDate = &bond.IssueDate.Time
+	}
+	if bond.MaturityDate.Valid {
+		model.MaturityDate = &bond.MaturityDate.Time
+	}
+	if bond.Issuer.ID != 0 {
+		model.Issuer = NewIssuerModel(&bond.Issuer)
+	}
+
+	return model
+}
+
+// IssuerModel - эмитент
+type IssuerModel struct {
+	ID   int     `json:"id"`
+	Name string  `json:"name"`
+	INN  *string `json:"inn"`
+	OKPO *string `json:"okpo"`
+}
+
+// NewIssuerModel создает объекты типа IssuerModel
+func NewIssuerModel(issuer *data.Issuer) *IssuerModel {
+	return &IssuerModel{
+		ID:   issuer.ID,
+		Name: issuer.Name,
+		INN:  issuer.INN,
+		OKPO: issuer.OKPO,
+	}
+}
+
+// MarketDataModel - рыночные данные
+type MarketDataModel struct {
+	Time            time.Time `json:"time"`
+	FaceValue       *float64  `json:"face_value"`
+	Currency        *string   `json:"currency"`
+	Last            *float64  `json:"last"`
+	LastChange      *float64  `json:"last_change"`
+	ClosePrice      *float64  `json:"close_price"`
+	LegalClosePrice *float64  `json:"legal_close_price"`
+	AccruedInterest *float64  `json:"accrued_interest"`
+	LotSize         *int      `json:"lot_size"`
+	TradingStatus   *string   `json:"trading_status"`
+}
+
+// NewMarketDataModel создает объекты типа MarketDataModel
+func NewMarketDataModel(marketData *data.MarketData) *MarketDataModel {
+	return &MarketDataModel{
+		Time:            marketData.Time,
+		FaceValue:       marketData.FaceValue,
+		Currency:        marketData.Currency,
+		Last:            marketData.Last,
+		LastChange:      marketData.LastChange,
+		ClosePrice:      marketData.ClosePrice,
+		LegalClosePrice: marketData.LegalClosePrice,
+		AccruedInterest: marketData.AccruedInterest,
+		LotSize:         marketData.LotSize,
+		TradingStatus:   marketData.TradingStatus,
+	}
+}
+
+// ReportModel - отчет по облигации
+type ReportModel struct {
+	Bond                 *BondModel           `json:"bond"`
+	MarketData           *MarketDataModel     `json:"market_data,omitempty"`
+	DaysTillMaturity     int                  `json:"days_till_maturity"`
+	Currency             string               `json:"currency"`
+	FxRate               float64              `json:"fx_rate"`
+	OpenPrice            float64              `json:"open_price"`
+	OpenAccruedInterest  float64              `json:"open_accrued_interest"`
+	OpenFaceValue        float64              `json:"open_face_value"`
+	OpenFee              float64              `json:"open_fee"`
+	OpenValue            float64              `json:"open_value"`
+	CouponPayments       float64              `json:"coupon_payments"`
+	AmortizationPayments float64              `json:"amortization_payments"`
+	MaturityPayment      float64              `json:"maturity_payment"`
+	Taxes                float64              `json:"taxes"`
+	Revenue              float64              `json:"revenue"`
+	ProfitLoss           float64              `json:"profit_loss"`
+	RelativeProfitLoss   float64              `json:"relative_profit_loss"`
+	InterestRate         float64              `json:"interest_rate"`
+	YieldToMaturity      float64              `json:"yield_to_maturity"`
+	MacaulayDuration     float64              `json:"macaulay_duration"`
+	ModifiedDuration     float64              `json:"modified_duration"`
+	DV01                 float64              `json:"dv01"`
+	Convexity            float64              `json:"convexity"`
+	OfferDate            *time.Time           `json:"offer_date"`
+	OfferPrice           float64              `json:"offer_price"`
+	DaysTillOffer        int                  `json:"days_till_offer"`
+	YieldToOffer         float64              `json:"yield_to_offer"`
+	Spread               *float64             `json:"spread"`
+	ToOffer              *ReportModel         `json:"to_offer,omitempty"`
+	CashFlow             []*CashFlowItemModel `json:"cash_flow"`
+}
+
+// NewReportModel создает объекты типа ReportModel
+func NewReportModel(report *recommender.Report) *ReportModel {
+	model := &ReportModel{
+		Bond:                 NewBondModel(report.Bond),
+		DaysTillMaturity:     report.DaysTillMaturity,
+		Currency:             report.Currency,
+		FxRate:               report.FxRate,
+		OpenPrice:            report.OpenPrice,
+		OpenAccruedInterest:  report.OpenAccruedInterest,
+		OpenFaceValue:        report.OpenFaceValue,
+		OpenFee:              report.OpenFee,
+		OpenValue:            report.OpenValue,
+		CouponPayments:       report.CouponPayments,
+		AmortizationPayments: report.AmortizationPayments,
+		MaturityPayment:      report.MaturityPayment,
+		Taxes:                report.Taxes,
+		Revenue:              report.Revenue,
+		ProfitLoss:           report.ProfitLoss,
+		RelativeProfitLoss:   report.RelativeProfitLoss,
+		InterestRate:         report.InterestRate,
+		YieldToMaturity:      report.YieldToMaturity,
+		MacaulayDuration:     report.MacaulayDuration,
+		ModifiedDuration:     report.ModifiedDuration,
+		DV01:                 report.DV01,
+		Convexity:            report.Convexity,
+		OfferDate:            report.OfferDate,
+		OfferPrice:           report.OfferPrice,
+		DaysTillOffer:        report.DaysTillOffer,
+		YieldToOffer:         report.YieldToOffer,
+		Spread:               report.Spread,
+		CashFlow:             make([]*CashFlowItemModel, len(report.CashFlow)),
+	}
+
+	if report.Issuer != nil {
+		model.Bond.Issuer = NewIssuerModel(report.Issuer)
+	}
+	if report.MarketData != nil {
+		model.MarketData = NewMarketDataModel(report.MarketData)
+	}
+	if report.ToOffer != nil {
+		model.ToOffer = NewReportModel(report.ToOffer)
+	}
+	for i, item := range report.CashFlow {
+		model.CashFlow[i] = &CashFlowItemModel{
+			Type:      cashFlowItemTypes[item.Type],
+			Date:      item.Date,
+			Value:     item.Value,
+			ValueRub:  item.ValueRub,
+			Estimated: item.Estimated,
+		}
+	}
+
+	return model
+}
+
+// CashFlowItemModel - выплата по облигации
+type CashFlowItemModel struct {
+	Type      string    `json:"type"`
+	Date      time.Time `json:"date"`
+	Value     float64   `json:"value"`
+	ValueRub  float64   `json:"value_rub"`
+	Estimated bool      `json:"estimated"`
+}
+
+var cashFlowItemTypes = map[recommender.CashFlowItemType]string{
+	recommender.Coupon:       "coupon",
+	recommender.Amortization: "amortization",
+	recommender.Maturity:     "maturity",
+}
+
+// CollectionModel - коллекция облигаций
+type CollectionModel struct {
+	ID       string                `json:"id"`
+	Name     string                `json:"name"`
+	Rankings []recommender.Ranking `json:"rankings"`
+}
+
+// NewCollectionModel создает объекты типа CollectionModel
+func NewCollectionModel(collection recommender.Collection) *CollectionModel {
+	return &CollectionModel{
+		ID:       collection.ID(),
+		Name:     collection.Name(),
+		Rankings: collection.Rankings(),
+	}
+}
+
+// SuggestResultModel - результат расчета предложений по инвестированию
+type SuggestResultModel struct {
+	Positions          []*SuggestedPositionModel `json:"positions"`
+	Amount             float64                   `json:"amount"`
+	DurationDays       int                       `json:"duration_days"`
+	ProfitLoss         float64                   `json:"profit_loss"`
+	RelativeProfitLoss float64                   `json:"relative_profit_loss"`
+	InterestRate       float64                   `json:"interest_rate"`
+	YieldToMaturity    float64                   `json:"yield_to_maturity"`
+	MacaulayDuration   float64                   `json:"macaulay_duration"`
+	ModifiedDuration   float64                   `json:"modified_duration"`
+	DV01               float64                   `json:"dv01"`
+	Convexity          float64                   `json:"convexity"`
+	TaxDeduction       float64                   `json:"tax_deduction"`
+	UnusedAmount       float64                   `json:"unused_amount"`
+	LimitsRelaxed      bool                      `json:"limits_relaxed"`
+
+	IssuerConcentrations       []*SuggestConcentrationModel `json:"issuer_concentrations"`
+	BondTypeConcentrations     []*SuggestConcentrationModel `json:"bond_type_concentrations"`
+	MaturityYearConcentrations []*SuggestConcentrationModel `json:"maturity_year_concentrations"`
+	CurrencyConcentrations     []*SuggestConcentrationModel `json:"currency_concentrations"`
+}
+
+// SuggestConcentrationModel - доля эмитента, типа облигаций, года погашения или валюты в предложенном портфеле
+type SuggestConcentrationModel struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+	Weight float64 `json:"weight"`
+}
+
+// SuggestedPositionModel - позиция в предложенном портфеле
+// Суммы выплат и затрат указаны на всю позицию, цены - на одну облигацию
+type SuggestedPositionModel struct {
+	*ReportModel
+	Quantity int     `json:"quantity"`
+	Weight   float64 `json:"weight"`
+}
+
+// NewSuggestResultModel создает объекты типа SuggestResultModel
+func NewSuggestResultModel(result *recommender.SuggestResult) *SuggestResultModel {
+	model := &SuggestResultModel{
+		Positions:          make([]*SuggestedPositionModel, len(result.Positions)),
+		Amount:             result.Amount,
+		DurationDays:       result.DurationDays,
+		ProfitLoss:         result.ProfitLoss,
+		RelativeProfitLoss: result.RelativeProfitLoss,
+		InterestRate:       result.InterestRate,
+		YieldToMaturity:    result.YieldToMaturity,
+		MacaulayDuration:   result.MacaulayDuration,
+		ModifiedDuration:   result.ModifiedDuration,
+		DV01:               result.DV01,
+		Convexity:          result.Convexity,
+		TaxDeduction:       result.TaxDeduction,
+		UnusedAmount:       result.UnusedAmount,
+		LimitsRelaxed:      result.LimitsRelaxed,
+
+		IssuerConcentrations:       newSuggestConcentrationModels(result.IssuerConcentrations),
+		BondTypeConcentrations:     newSuggestConcentrationModels(result.BondTypeConcentrations),
+		MaturityYearConcentrations: newSuggestConcentrationModels(result.MaturityYearConcentrations),
+		CurrencyConcentrations:     newSuggestConcentrationModels(result.CurrencyConcentrations),
+	}
+
+	for i, p := range result.Positions {
+		model.Positions[i] = &SuggestedPositionModel{
+			ReportModel: NewReportModel(&p.Report),
+			Quantity:    p.Quantity,
+			Weight:      p.Weight,
+		}
+	}
+
+	return model
+}
+
+func newSuggestConcentrationModels(concentrations []*recommender.SuggestConcentration) []*SuggestConcentrationModel {
+	models := make([]*SuggestConcentrationModel, len(concentrations))
+	for i, c := range concentrations {
+		models[i] = &SuggestConcentrationModel{
+			Name:   c.Name,
+			Amount: c.Amount,
+			Weight: c.Weight,
+		}
+	}
+
+	return models
+}
+
+// PriceHistoryModel - история цен и доходностей облигации
+type PriceHistoryModel struct {
+	Range  string                    `json:"range"`
+	Points []*PriceHistoryPointModel `json:"points"`
+}
+
+// PriceHistoryPointModel - данные облигации на конец дня
+type PriceHistoryPointModel struct {
+	Date   time.Time `json:"date"`
+	Price  *float64  `json:"price"`
+	Yield  *float64  `json:"yield"`
+	Spread *float64  `json:"spread"`
+}
+
+// NewPriceHistoryModel создает объекты типа PriceHistoryModel
+func NewPriceHistoryModel(history *recommender.PriceHistory) *PriceHistoryModel {
+	model := &PriceHistoryModel{
+		Range:  string(history.Range),
+		Points: make([]*PriceHistoryPointModel, len(history.Points)),
+	}
+
+	for i, p := range history.Points {
+		model.Points[i] = &PriceHistoryPointModel{
+			Date:   p.Date,
+			Price:  p.Price,
+			Yield:  p.Yield,
+			Spread: p.Spread,
+		}
+	}
+
+	return model
+}
+
+// PortfolioModel - портфель пользователя
+type PortfolioModel struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created"`
+	UpdatedAt time.Time `json:"updated"`
+}
+
+// NewPortfolioModel создает объекты типа PortfolioModel
+func NewPortfolioModel(portfolio *data.Portfolio) *PortfolioModel {
+	return &PortfolioModel{
+		ID:        portfolio.ID,
+		Name:      portfolio.Name,
+		CreatedAt: portfolio.CreatedAt,
+		UpdatedAt: portfolio.UpdatedAt,
+	}
+}
+
+// TradeModel - сделка в портфеле пользователя
+// Положительное количество означает покупку, отрицательное - продажу
+type TradeModel struct {
+	ID              int        `json:"id"`
+	Bond            *BondModel `json:"bond"`
+	Date            time.Time  `json:"date"`
+	Quantity        int        `json:"quantity"`
+	Price           float64    `json:"price"`
+	FaceValue       float64    `json:"face_value"`
+	AccruedInterest float64    `json:"accrued_interest"`
+	Fee             float64    `json:"fee"`
+}
+
+// NewTradeModel создает объекты типа TradeModel
+func NewTradeModel(trade *data.Trade) *TradeModel {
+	return &TradeModel{
+		ID:              trade.ID,
+		Bond:            NewBondModel(&trade.Bond),
+		Date:            trade.Date,
+		Quantity:        trade.Quantity,
+		Price:           trade.Price,
+		FaceValue:       trade.FaceValue,
+		AccruedInterest: trade.AccruedInterest,
+		Fee:             trade.Fee,
+	}
+}
+
+// PortfolioValuationModel - оценка портфеля пользователя по текущим рыночным данным
+type PortfolioValuationModel struct {
+	Portfolio            *PortfolioModel          `json:"portfolio"`
+	Holdings             []*PortfolioHoldingModel `json:"holdings"`
+	Trades               []*TradeModel            `json:"trades"`
+	Cost                 float64                  `json:"cost"`
+	MarketValue          float64                  `json:"market_value"`
+	UnrealizedProfitLoss float64                  `json:"unrealized_profit_loss"`
+	RealizedProfitLoss   float64                  `json:"realized_profit_loss"`
+	RelativeProfitLoss   float64                  `json:"relative_profit_loss"`
+	YieldToMaturity      float64                  `json:"yield_to_maturity"`
+	ModifiedDuration     float64                  `json:"modified_duration"`
+	DV01                 float64                  `json:"dv01"`
+}
+
+// PortfolioHoldingModel - открытая позиция в портфеле пользователя
+type PortfolioHoldingModel struct {
+	Bond                 *BondModel   `json:"bond"`
+	Report               *ReportModel `json:"report,omitempty"`
+	Quantity             int          `json:"quantity"`
+	Cost                 float64      `json:"cost"`
+	MarketValue          float64      `json:"market_value"`
+	UnrealizedProfitLoss float64      `json:"unrealized_profit_loss"`
+	RealizedProfitLoss   float64      `json:"realized_profit_loss"`
+	Weight               float64      `json:"weight"`
+}
+
+// NewPortfolioValuationModel создает объекты типа PortfolioValuationModel
+func NewPortfolioValuationModel(valuation *recommender.PortfolioValuation) *PortfolioValuationModel {
+	model := &PortfolioValuationModel{
+		Portfolio:            NewPortfolioModel(valuation.Portfolio),
+		Holdings:             make([]*PortfolioHoldingModel, len(valuation.Holdings)),
+		Trades:               make([]*TradeModel, len(valuation.Portfolio.Trades)),
+		Cost:                 valuation.Cost,
+		MarketValue:          valuation.MarketValue,
+		UnrealizedProfitLoss: valuation.UnrealizedProfitLoss,
+		RealizedProfitLoss:   valuation.RealizedProfitLoss,
+		RelativeProfitLoss:   valuation.RelativeProfitLoss,
+		YieldToMaturity:      valuation.YieldToMaturity,
+		ModifiedDuration:     valuation.ModifiedDuration,
+		DV01:                 valuation.DV01,
+	}
+
+	for i, h := range valuation.Holdings {
+		model.Holdings[i] = &PortfolioHoldingModel{
+			Bond:                 NewBondModel(h.Bond),
+			Quantity:             h.Quantity,
+			Cost:                 h.Cost,
+			MarketValue:          h.MarketValue,
+			UnrealizedProfitLoss: h.UnrealizedProfitLoss,
+			RealizedProfitLoss:   h.RealizedProfitLoss,
+			Weight:               h.Weight,
+		}
+		if h.Report != nil {
+			model.Holdings[i].Report = NewReportModel(h.Report)
+		}
+	}
+	for i, trade := range valuation.Portfolio.Trades {
+		model.Trades[i] = NewTradeModel(trade)
+	}
+
+	return model
+}
+
+// CalendarModel - календарь предстоящих выплат
+type CalendarModel struct {
+	Events []*CalendarEventModel `json:"events"`
+}
+
+// CalendarEventModel - событие в календаре выплат
+type CalendarEventModel struct {
+	Date     time.Time  `json:"date"`
+	Type     string     `json:"type"`
+	Bond     *BondModel `json:"bond"`
+	Quantity int        `json:"quantity"`
+	ValueRub float64    `json:"value_rub"`
+}
+
+// NewCalendarModel создает объекты типа CalendarModel
+func NewCalendarModel(calendar *recommender.Calendar) *CalendarModel {
+	model := &CalendarModel{Events: make([]*CalendarEventModel, len(calendar.Events))}
+	for i, e := range calendar.Events {
+		model.Events[i] = &CalendarEventModel{
+			Date:     e.Date,
+			Type:     string(e.Type),
+			Bond:     NewBondModel(e.Bond),
+			Quantity: e.Quantity,
+			ValueRub: e.ValueRub,
+		}
+	}
+
+	return model
+}
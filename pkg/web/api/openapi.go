@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPISpec содержит спецификацию API в формате OpenAPI 3 (JSON)
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// OpenAPI обрабатывает запросы "GET /api/v1/openapi.json"
+func (ctrl *Controller) OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", OpenAPISpec)
+}
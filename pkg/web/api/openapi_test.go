@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/api"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+func TestOpenAPISpec_MatchesModels(t *testing.T) {
+	assert := assertion.New(t)
+
+	var spec struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	err := json.Unmarshal(api.OpenAPISpec, &spec)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(strings.HasPrefix(spec.OpenAPI, "3."))
+
+	models := map[string]interface{}{
+		"Error":                  api.ErrorResponse{},
+		"ErrorDetails":           api.ErrorModel{},
+		"Issuer":                 api.IssuerModel{},
+		"Bond":                   api.BondModel{},
+		"MarketData":             api.MarketDataModel{},
+		"CashFlowItem":           api.CashFlowItemModel{},
+		"Report":                 api.ReportModel{},
+		"Collection":             api.CollectionModel{},
+		"CollectionBonds":        api.CollectionBondsResponse{},
+		"SearchResult":           api.SearchResponse{},
+		"ScreenerResult":         api.ScreenerResponse{},
+		"CostModel":              pages.CostModelParams{},
+		"SuggestRequest":         pages.SuggestPortfolioRequest{},
+		"SuggestRequestPart":     pages.SuggestPortfolioRequestPart{},
+		"SuggestConstraints":     pages.SuggestConstraintsParams{},
+		"SuggestResult":          api.SuggestResultModel{},
+		"SuggestedPosition":      api.SuggestedPositionModel{},
+		"SuggestConcentration":   api.SuggestConcentrationModel{},
+		"PriceHistory":           api.PriceHistoryModel{},
+		"PriceHistoryPoint":      api.PriceHistoryPointModel{},
+		"Portfolio":              api.PortfolioModel{},
+		"CreatePortfolioRequest": api.CreatePortfolioRequest{},
+		"Trade":                  api.TradeModel{},
+		"AddTradeRequest":        api.AddTradeRequest{},
+		"PortfolioValuation":     api.PortfolioValuationModel{},
+		"PortfolioHolding":       api.PortfolioHoldingModel{},
+		"Calendar":               api.CalendarModel{},
+		"CalendarEvent":          api.CalendarEventModel{},
+	}
+
+	for name, model := range models {
+		schema, exists := spec.Components.Schemas[name]
+		if !assert.True(exists, "schema %s is missing", name) {
+			continue
+		}
+
+		expected := jsonFields(reflect.TypeOf(model))
+		actual := make([]string, 0, len(schema.Properties))
+		for property := range schema.Properties {
+			actual = append(actual, property)
+		}
+		sort.Strings(actual)
+
+		assert.Equal(expected, actual, "schema %s", name)
+	}
+}
+
+// jsonFields возвращает отсортированный список JSON полей структуры
+func jsonFields(t reflect.Type) []string {
+	fields := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			fields = append(fields, jsonFields(ft)...)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// CreatePortfolioRequest - тело запроса "POST /api/v1/portfolios"
+type CreatePortfolioRequest struct {
+	Name string `json:"name"`
+}
+
+// AddTradeRequest - тело запроса "POST /api/v1/portfolios/:id/trades"
+type AddTradeRequest struct {
+	// ID, ISIN или код облигации
+	Bond string `json:"bond"`
+
+	// Количество облигаций (отрицательное - для продажи)
+	Quantity int `json:"quantity"`
+
+	// Чистая цена, в % от номинала (если не задана, то используется текущая цена, а для сделки прошлой датой - цена закрытия за эту дату)
+	Price *float64 `json:"price,omitempty"`
+
+	// Комиссия за сделку, в валюте (если не задана, то рассчитывается по тарифу по умолчанию)
+	Fee *float64 `json:"fee,omitempty"`
+
+	// Дата сделки в формате YYYY-MM-DD (если не задана, то используется текущая дата)
+	Date string `json:"date,omitempty"`
+}
+
+// ListPortfolios обрабатывает запросы "GET /api/v1/portfolios"
+func (ctrl *Controller) ListPortfolios(c *gin.Context) {
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	portfolios, err := u.ListPortfolios()
+	if err != nil {
+		panic(err)
+	}
+
+	response := make([]*PortfolioModel, len(portfolios))
+	for i, portfolio := range portfolios {
+		response[i] = NewPortfolioModel(portfolio)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreatePortfolio обрабатывает запросы "POST /api/v1/portfolios"
+func (ctrl *Controller) CreatePortfolio(c *gin.Context) {
+	var req CreatePortfolioRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		panic(pages.NewError(400, "malformed request body"))
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		panic(pages.NewError(400, "invalid value for \"name\" field"))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	portfolio, err := u.CreatePortfolio(name)
+	if err != nil {
+		panic(err)
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.JSON(http.StatusCreated, NewPortfolioModel(portfolio))
+}
+
+// GetPortfolio обрабатывает запросы "GET /api/v1/portfolios/:id"
+// Портфель оценивается по текущим рыночным данным
+func (ctrl *Controller) GetPortfolio(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	valuation, err := u.GetPortfolio(portfolioID)
+	if err != nil {
+		panic(notFound(err, "portfolio #%d doesn't exist", portfolioID))
+	}
+
+	c.JSON(http.StatusOK, NewPortfolioValuationModel(valuation))
+}
+
+// DeletePortfolio обрабатывает запросы "DELETE /api/v1/portfolios/:id"
+func (ctrl *Controller) DeletePortfolio(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	err = u.DeletePortfolio(portfolioID)
+	if err != nil {
+		panic(notFound(err, "portfolio #%d doesn't exist", portfolioID))
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddTrade обрабатывает запросы "POST /api/v1/portfolios/:id/trades"
+func (ctrl *Controller) AddTrade(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	var req AddTradeRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		panic(pages.NewError(400, "malformed request body"))
+	}
+
+	args := app.AddTradeArgs{Bond: strings.TrimSpace(req.Bond), Quantity: req.Quantity, Price: req.Price, Fee: req.Fee}
+	if args.Bond == "" {
+		panic(pages.NewError(400, "invalid value for \"bond\" field"))
+	}
+	if args.Quantity == 0 {
+		panic(pages.NewError(400, "invalid value for \"quantity\" field"))
+	}
+	if args.Price != nil && *args.Price <= 0 {
+		panic(pages.NewError(400, "invalid value for \"price\" field"))
+	}
+	if args.Fee != nil && *args.Fee < 0 {
+		panic(pages.NewError(400, "invalid value for \"fee\" field"))
+	}
+	if req.Date != "" {
+		args.Date, err = time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			panic(pages.NewError(400, "invalid value for \"date\" field"))
+		}
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	trade, err := u.AddTrade(portfolioID, args)
+	if err != nil {
+		if errors.Is(err, recommender.ErrInvalidTrade) {
+			panic(pages.NewError(400, "%s", err))
+		}
+		panic(notFound(err, "bond \"%s\" or portfolio #%d doesn't exist", args.Bond, portfolioID))
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.JSON(http.StatusCreated, NewTradeModel(trade))
+}
+
+// DeleteTrade обрабатывает запросы "DELETE /api/v1/portfolios/:id/trades/:trade_id"
+func (ctrl *Controller) DeleteTrade(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	tradeID, err := strconv.Atoi(c.Param("trade_id"))
+	if err != nil {
+		panic(pages.NewError(404, "trade \"%s\" doesn't exist", c.Param("trade_id")))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	err = u.DeleteTrade(portfolioID, tradeID)
+	if err != nil {
+		if errors.Is(err, recommender.ErrInvalidTrade) {
+			panic(pages.NewError(400, "%s", err))
+		}
+		panic(notFound(err, "trade #%d doesn't exist", tradeID))
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetPortfolioCalendar обрабатывает запросы "GET /api/v1/portfolios/:id/calendar"
+func (ctrl *Controller) GetPortfolioCalendar(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	_, calendar, err := u.GetPortfolioCalendar(portfolioID)
+	if err != nil {
+		panic(notFound(err, "portfolio #%d doesn't exist", portfolioID))
+	}
+
+	c.JSON(http.StatusOK, NewCalendarModel(calendar))
+}
+
+// GetCalendar обрабатывает запросы "GET /api/v1/calendar?bonds=BOND:QUANTITY,..."
+func (ctrl *Controller) GetCalendar(c *gin.Context) {
+	positions, err := app.ParseCalendarPositions(strings.Split(c.Query("bonds"), ","))
+	if err != nil || len(positions) == 0 {
+		panic(pages.NewError(400, "invalid value for \"bonds\" parameter"))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	calendar, err := u.GetCalendar(positions)
+	if err != nil {
+		panic(notFound(err, "bond doesn't exist"))
+	}
+
+	c.JSON(http.StatusOK, NewCalendarModel(calendar))
+}
+
+// parsePortfolioID разбирает ID портфеля из параметров маршрута
+func parsePortfolioID(c *gin.Context) int {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		panic(pages.NewError(404, "portfolio \"%s\" doesn't exist", c.Param("id")))
+	}
+
+	return id
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// Screener обрабатывает запросы "GET /api/v1/screener"
+// Параметры запроса описаны в pages.ScreenerParams
+func (ctrl *Controller) Screener(c *gin.Context) {
+	params, err := pages.NewScreenerParams(c)
+	if err != nil {
+		panic(err)
+	}
+
+	query, err := params.ToQuery()
+	if err != nil {
+		panic(err)
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	result, err := u.Screen(query)
+	if err != nil {
+		panic(err)
+	}
+
+	response := ScreenerResponse{
+		Bonds:      make([]*ReportModel, len(result.Reports)),
+		TotalCount: result.TotalCount,
+	}
+	for i, report := range result.Reports {
+		response.Bonds[i] = NewReportModel(report)
+	}
+
+	c.JSON(http.StatusOK, &response)
+}
+
+// ScreenerResponse - ответ на запрос "GET /api/v1/screener"
+type ScreenerResponse struct {
+	Bonds      []*ReportModel `json:"bonds"`
+	TotalCount int            `json:"total_count"`
+}
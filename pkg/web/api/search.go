@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/search"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// Search обрабатывает запросы "GET /api/v1/search"
+func (ctrl *Controller) Search(c *gin.Context) {
+	var query SearchQuery
+	err := c.BindQuery(&query)
+	if err != nil {
+		panic(pages.NewError(400, "malformed query"))
+	}
+
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		panic(pages.NewError(400, "missing \"q\" parameter"))
+	}
+	if query.Skip < 0 {
+		panic(pages.NewError(400, "invalid value for \"skip\" parameter"))
+	}
+	if query.Limit < 0 || query.Limit > MaxSearchLimit {
+		panic(pages.NewError(400, "invalid value for \"limit\" parameter"))
+	}
+	if query.Limit == 0 {
+		query.Limit = search.DefaultLimit
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	result, err := u.Search(search.Request{Text: query.Text, Skip: query.Skip, Limit: query.Limit})
+	if err != nil {
+		panic(err)
+	}
+
+	response := SearchResponse{
+		Bonds:      make([]*BondModel, len(result.Bonds)),
+		TotalCount: result.TotalCount,
+	}
+	for i, bond := range result.Bonds {
+		response.Bonds[i] = NewBondModel(bond)
+	}
+
+	c.JSON(http.StatusOK, &response)
+}
+
+// MaxSearchLimit - максимальное количество облигаций в ответе на поисковый запрос
+const MaxSearchLimit = 100
+
+// SearchQuery - параметры запроса "GET /api/v1/search"
+type SearchQuery struct {
+	Text  string `form:"q"`
+	Skip  int    `form:"skip"`
+	Limit int    `form:"limit"`
+}
+
+// SearchResponse - ответ на запрос "GET /api/v1/search"
+type SearchResponse struct {
+	Bonds      []*BondModel `json:"bonds"`
+	TotalCount int          `json:"total_count"`
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
+)
+
+// Suggest обрабатывает запросы "POST /api/v1/suggest"
+// Тело запроса совпадает с параметром json страницы "GET /suggest"
+func (ctrl *Controller) Suggest(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		panic(pages.NewError(400, "unable to read request body"))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	req, err := pages.ParseSuggestPortfolioRequest(string(body), u)
+	if err != nil {
+		panic(err)
+	}
+	if req == nil {
+		panic(pages.NewError(400, "missing request body"))
+	}
+
+	suggestRequest, err := req.ToSuggestRequest()
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := u.Suggest(suggestRequest)
+	if err != nil {
+		panic(err)
+	}
+
+	c.JSON(http.StatusOK, NewSuggestResultModel(result))
+}
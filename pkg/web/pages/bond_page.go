@@ -15,7 +15,12 @@ import (
 func (ctrl *Controller) BondPage(c *gin.Context) {
 	id := c.Param("id")
 
-	model, err := NewBondPageModel(ctrl.app, c, id)
+	costs, err := NewCostModelParams(c)
+	if err != nil {
+		panic(err)
+	}
+
+	model, err := NewBondPageModel(ctrl.app, c, id, costs)
 	if err != nil {
 		if err == recommender.ErrNotFound || err == data.ErrNotFound {
 			ctrl.renderHTML(c, http.StatusNotFound, "pages/bond_not_found", id)
@@ -36,7 +41,12 @@ type BondPageModel struct {
 }
 
 // NewBondPageModel создает новые объекты типа BondPageModel
-func NewBondPageModel(app app.App, context context.Context, id string) (*BondPageModel, error) {
+func NewBondPageModel(app app.App, context context.Context, id string, costs *CostModelParams) (*BondPageModel, error) {
+	costModel, err := costs.ToCostModel()
+	if err != nil {
+		return nil, err
+	}
+
 	u, err := app.NewUnitOfWork(context)
 	if err != nil {
 		return nil, err
@@ -48,6 +58,10 @@ func NewBondPageModel(app app.App, context context.Context, id string) (*BondPag
 		return nil, err
 	}
 
+	if costModel != nil {
+		report = recommender.ApplyCostModel(report, costModel)
+	}
+
 	model := BondPageModel{
 		Bond:   report.Bond,
 		Issuer: report.Issuer,
@@ -0,0 +1,75 @@
+package pages
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/ical"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// CalendarICS обрабатывает запросы "GET /calendar.ics?bonds=BOND:QUANTITY,..."
+func (ctrl *Controller) CalendarICS(c *gin.Context) {
+	positions, err := app.ParseCalendarPositions(strings.Split(c.Query("bonds"), ","))
+	if err != nil || len(positions) == 0 {
+		panic(NewError(400, "invalid value for \"bonds\" parameter"))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	calendar, err := u.GetCalendar(positions)
+	if err != nil {
+		if err == data.ErrNotFound || err == recommender.ErrNotFound {
+			panic(NewError(404, "bond doesn't exist"))
+		}
+		panic(err)
+	}
+
+	ctrl.renderICS(c, "Выплаты по облигациям", calendar)
+}
+
+// PortfolioCalendarICS обрабатывает запросы "GET /portfolios/:id/calendar.ics"
+func (ctrl *Controller) PortfolioCalendarICS(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		panic(NewError(404, "portfolio \"%s\" doesn't exist", c.Param("id")))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	portfolio, calendar, err := u.GetPortfolioCalendar(id)
+	if err != nil {
+		if err == data.ErrNotFound {
+			panic(NewError(404, "portfolio #%d doesn't exist", id))
+		}
+		panic(err)
+	}
+
+	ctrl.renderICS(c, fmt.Sprintf("Выплаты: %s", portfolio.Name), calendar)
+}
+
+func (ctrl *Controller) renderICS(c *gin.Context, name string, calendar *recommender.Calendar) {
+	var buffer bytes.Buffer
+	err := ical.Write(&buffer, name, calendar)
+	if err != nil {
+		panic(err)
+	}
+
+	c.Header("Content-Disposition", "inline; filename=\"calendar.ics\"")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buffer.Bytes())
+}
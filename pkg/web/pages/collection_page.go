@@ -11,15 +11,19 @@ import (
 	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
 )
 
-// CollectionPage обрабатывает запросы "GET /collections/:id"
+// CollectionPage обрабатывает запросы "GET /collections/:id?rank=spread"
+// Если параметр rank не задан, то используется порядок по умолчанию для коллекции
 func (ctrl *Controller) CollectionPage(c *gin.Context) {
 	id := c.Param("id")
-	model, err := NewCollectionPageModel(ctrl.app, c, id)
+	model, err := NewCollectionPageModel(ctrl.app, c, id, recommender.Ranking(c.Query("rank")))
 	if err != nil {
 		if err == recommender.ErrNotFound {
 			ctrl.renderHTML(c, http.StatusNotFound, "pages/collection_not_found", id)
 			return
 		}
+		if err == recommender.ErrUnsupportedRanking {
+			panic(NewError(http.StatusBadRequest, "invalid value for \"rank\" parameter"))
+		}
 
 		panic(err)
 	}
@@ -31,11 +35,14 @@ func (ctrl *Controller) CollectionPage(c *gin.Context) {
 type CollectionPageModel struct {
 	ID               string
 	Name             string
+	Ranking          recommender.Ranking
+	Rankings         []recommender.Ranking
 	ItemsPerDuration map[recommender.Duration][]CollectionPageItemModel
 }
 
 // NewCollectionPageModel создает новые объекты типа CollectionPageModel
-func NewCollectionPageModel(app app.App, context context.Context, id string) (*CollectionPageModel, error) {
+// Если порядок ranking пустой, то используется порядок по умолчанию для коллекции
+func NewCollectionPageModel(app app.App, context context.Context, id string, ranking recommender.Ranking) (*CollectionPageModel, error) {
 	u, err := app.NewUnitOfWork(context)
 	if err != nil {
 		return nil, err
@@ -50,7 +57,11 @@ func NewCollectionPageModel(app app.App, context context.Context, id string) (*C
 			return nil, err
 		}
 
-		reports, err := u.ListCollectionBonds(collection.ID(), duration)
+		if ranking == "" {
+			ranking = collection.Rankings()[0]
+		}
+
+		reports, err := u.ListCollectionBonds(collection.ID(), duration, ranking)
 		if err != nil {
 			return nil, err
 		}
@@ -66,6 +77,8 @@ func NewCollectionPageModel(app app.App, context context.Context, id string) (*C
 
 		model.ID = collection.ID()
 		model.Name = collection.Name()
+		model.Ranking = ranking
+		model.Rankings = collection.Rankings()
 		model.ItemsPerDuration[duration] = array
 	}
 
@@ -0,0 +1,75 @@
+package pages
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// CostModelParams - параметры модели комиссий и налогов
+// Передаются как query-параметры либо как часть JSON запроса
+type CostModelParams struct {
+	BrokerFee           string  `json:"broker_fee,omitempty" form:"broker_fee"`
+	MinBrokerFee        float64 `json:"min_broker_fee,omitempty" form:"min_broker_fee"`
+	ExchangeFee         float64 `json:"exchange_fee,omitempty" form:"exchange_fee"`
+	Account             string  `json:"account,omitempty" form:"account"`
+	LongTermExemption   bool    `json:"long_term_exemption,omitempty" form:"long_term_exemption"`
+	GovCouponsTaxExempt bool    `json:"gov_coupons_tax_exempt,omitempty" form:"gov_coupons_tax_exempt"`
+}
+
+// NewCostModelParams создает объект CostModelParams из query-параметров
+// Если ни один из параметров не задан, то возвращается nil
+func NewCostModelParams(c *gin.Context) (*CostModelParams, error) {
+	var params CostModelParams
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, NewError(400, "malformed cost model parameters")
+	}
+
+	if params == (CostModelParams{}) {
+		return nil, nil
+	}
+
+	return &params, nil
+}
+
+// ToCostModel создает recommender.CostModel из CostModelParams
+// Незаданные параметры принимают значения по умолчанию
+func (p *CostModelParams) ToCostModel() (*recommender.CostModel, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	model := recommender.DefaultCostModel()
+
+	if p.BrokerFee != "" {
+		schedule, err := recommender.ParseBrokerFeeSchedule(p.BrokerFee)
+		if err != nil {
+			return nil, NewError(400, "invalid value for \"broker_fee\" parameter")
+		}
+		model.BrokerFee = schedule
+	}
+
+	if p.MinBrokerFee < 0 {
+		return nil, NewError(400, "invalid value for \"min_broker_fee\" parameter")
+	}
+	model.MinBrokerFee = p.MinBrokerFee
+
+	if p.ExchangeFee < 0 {
+		return nil, NewError(400, "invalid value for \"exchange_fee\" parameter")
+	}
+	model.ExchangeFeeRate = p.ExchangeFee / 100.0
+
+	if p.Account != "" {
+		account, err := recommender.ParseAccountType(p.Account)
+		if err != nil {
+			return nil, NewError(400, "invalid value for \"account\" parameter")
+		}
+		model.Account = account
+	}
+
+	model.LongTermExemption = p.LongTermExemption
+	model.GovCouponsTaxExempt = p.GovCouponsTaxExempt
+
+	return model, nil
+}
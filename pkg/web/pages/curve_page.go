@@ -0,0 +1,96 @@
+package pages
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// CurvePage обрабатывает запросы "GET /curve"
+func (ctrl *Controller) CurvePage(c *gin.Context) {
+	model, err := NewCurvePageModel(ctrl.app, c)
+	if err != nil {
+		if err == recommender.ErrNotFound {
+			ctrl.renderHTML(c, http.StatusOK, "pages/curve_not_found", nil)
+			return
+		}
+
+		panic(err)
+	}
+
+	ctrl.renderHTML(c, http.StatusOK, "pages/curve", model)
+}
+
+// CurvePageModel - модель для страницы "pages/curve.html"
+type CurvePageModel struct {
+	Curve *recommender.YieldCurve
+
+	// ОФЗ, отсортированные по убыванию отклонения от кривой (сначала самые дешевые)
+	Points []*recommender.YieldCurvePoint
+
+	// Данные для графиков
+	Chart *CurveChartModel
+}
+
+// CurveChartModel - данные для графиков кривой доходности
+type CurveChartModel struct {
+	Line   []*CurveChartPointModel `json:"line"`
+	Points []*CurveChartPointModel `json:"points"`
+}
+
+// CurveChartPointModel - точка на графике кривой доходности
+type CurveChartPointModel struct {
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Label     string  `json:"label,omitempty"`
+	ISIN      string  `json:"isin,omitempty"`
+	Deviation float64 `json:"deviation"`
+}
+
+// NewCurvePageModel создает новые объекты типа CurvePageModel
+func NewCurvePageModel(app app.App, context context.Context) (*CurvePageModel, error) {
+	u, err := app.NewUnitOfWork(context)
+	if err != nil {
+		return nil, err
+	}
+	defer u.Close()
+
+	curve, err := u.GetYieldCurve()
+	if err != nil {
+		return nil, err
+	}
+
+	model := &CurvePageModel{
+		Curve:  curve,
+		Points: make([]*recommender.YieldCurvePoint, len(curve.Points)),
+		Chart: &CurveChartModel{
+			Line:   make([]*CurveChartPointModel, len(curve.Line)),
+			Points: make([]*CurveChartPointModel, len(curve.Points)),
+		},
+	}
+
+	for i, p := range curve.Line {
+		model.Chart.Line[i] = &CurveChartPointModel{X: p.Duration, Y: p.Yield}
+	}
+	for i, p := range curve.Points {
+		model.Chart.Points[i] = &CurveChartPointModel{
+			X:         p.Duration,
+			Y:         p.Yield,
+			Label:     p.Report.Bond.ShortName,
+			ISIN:      p.Report.Bond.ISIN,
+			Deviation: p.Deviation,
+		}
+	}
+
+	copy(model.Points, curve.Points)
+	sort.SliceStable(model.Points, func(i, j int) bool {
+		return model.Points[i].Deviation > model.Points[j].Deviation
+	})
+
+	return model, nil
+}
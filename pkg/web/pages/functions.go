@@ -26,15 +26,19 @@ func DefineFunctions(googleAnalyticsID string) template.FuncMap {
 	fns["formatPercent"] = formatPercent
 	fns["formatPercentNoScale"] = formatPercentNoScale
 	fns["formatMoney"] = formatMoney
+	fns["formatDecimal"] = formatDecimal
 	fns["formatDuration"] = formatDuration
 	fns["formatDaysTillMaturity"] = formatDaysTillMaturity
 	fns["formatCashFlowItemType"] = formatCashFlowItemType
+	fns["formatCalendarEventType"] = formatCalendarEventType
 	fns["getFullOpenValue"] = getFullOpenValue
 	fns["getFullRevenue"] = getFullRevenue
 	fns["formatBool"] = formatBool
 	fns["formatBondType"] = formatBondType
+	fns["formatCouponType"] = formatCouponType
 	fns["formatPercentWithSign"] = formatPercentWithSign
 	fns["formatMoneyWithSign"] = formatMoneyWithSign
+	fns["formatSpread"] = formatSpread
 	fns["json"] = formatJSON
 
 	fns["googleAnalyticsID"] = func() (string, error) {
@@ -120,6 +124,21 @@ func formatMoney(currency string, v interface{}) (template.HTML, error) {
 	return template.HTML(str), nil
 }
 
+func formatDecimal(v interface{}) (template.HTML, error) {
+	str := ""
+	switch t := v.(type) {
+	case float64:
+		str = fmt.Sprintf("%0.2f", t)
+	case *float64:
+		if t != nil {
+			str = fmt.Sprintf("%0.2f", *t)
+		}
+	}
+
+	str = template.HTMLEscapeString(str)
+	return template.HTML(str), nil
+}
+
 func formatDuration(v interface{}) (template.HTML, error) {
 	str := ""
 	switch t := v.(type) {
@@ -189,6 +208,27 @@ func formatCashFlowItemType(v interface{}) (template.HTML, error) {
 	return template.HTML(str), nil
 }
 
+func formatCalendarEventType(v interface{}) (template.HTML, error) {
+	str := ""
+
+	switch t := v.(type) {
+	case recommender.CalendarEventType:
+		switch t {
+		case recommender.CouponEvent:
+			str = "Купон"
+		case recommender.AmortizationEvent:
+			str = "Амортизация"
+		case recommender.MaturityEvent:
+			str = "Погашение"
+		case recommender.OfferEvent:
+			str = "Оферта"
+		}
+	}
+
+	str = template.HTMLEscapeString(str)
+	return template.HTML(str), nil
+}
+
 func getFullOpenValue(v interface{}) (interface{}, error) {
 	switch t := v.(type) {
 	case *recommender.Report:
@@ -242,6 +282,28 @@ func formatBondType(v interface{}) (interface{}, error) {
 		default:
 			return string(t), nil
 		}
+	case string:
+		return formatBondType(data.BondType(t))
+	}
+
+	return v, nil
+}
+
+func formatCouponType(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case data.CouponType:
+		switch t {
+		case data.FixedCoupon:
+			return "Фиксированный", nil
+		case data.FloatingCoupon:
+			return "Переменный (флоатер)", nil
+		case data.IndexedCoupon:
+			return "Индексируемый номинал (линкер)", nil
+		default:
+			return string(t), nil
+		}
+	case string:
+		return formatCouponType(data.CouponType(t))
 	}
 
 	return v, nil
@@ -271,6 +333,21 @@ func formatPercentWithSign(v interface{}) (template.HTML, error) {
 	return template.HTML(str), nil
 }
 
+func formatSpread(v interface{}) (template.HTML, error) {
+	str := ""
+	switch t := v.(type) {
+	case float64:
+		str = fmt.Sprintf("%0.0f\u00a0б.п.", t)
+	case *float64:
+		if t != nil {
+			str = fmt.Sprintf("%0.0f\u00a0б.п.", *t)
+		}
+	}
+
+	str = template.HTMLEscapeString(str)
+	return template.HTML(str), nil
+}
+
 func formatMoneyWithSign(currency string, v interface{}) (template.HTML, error) {
 	str := ""
 	switch t := v.(type) {
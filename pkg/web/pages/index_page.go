@@ -63,7 +63,7 @@ type CollectionModel struct {
 // NewCollectionModel создает объекты типа CollectionModel
 func NewCollectionModel(u app.UnitOfWork, collection recommender.Collection) (*CollectionModel, error) {
 	duration := recommender.Duration5Year
-	reports, err := u.ListCollectionBonds(collection.ID(), duration)
+	reports, err := u.ListCollectionBonds(collection.ID(), duration, collection.Rankings()[0])
 	if err != nil {
 		return nil, err
 	}
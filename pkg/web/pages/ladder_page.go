@@ -0,0 +1,115 @@
+package pages
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// LadderPage обрабатывает запросы "GET /ladder"
+// Параметры запроса: amount - сумма, horizon - срок лесенки, interval - интервал между ступенями (в месяцах),
+// collection - ID коллекции (необязательный)
+// Если сумма не задана, то выводится только форма
+func (ctrl *Controller) LadderPage(c *gin.Context) {
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	model := &LadderPageModel{
+		Collections: u.ListCollections(),
+		Amount:      c.DefaultQuery("amount", "100000"),
+		Horizon:     c.DefaultQuery("horizon", "36"),
+		Interval:    c.DefaultQuery("interval", "6"),
+		Collection:  c.Query("collection"),
+	}
+	if _, exists := c.GetQuery("amount"); !exists {
+		ctrl.renderHTML(c, http.StatusOK, "pages/ladder", model)
+		return
+	}
+
+	request, err := model.ToLadderRequest(u.GetCollection)
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := u.BuildLadder(request)
+	if err != nil {
+		if err == recommender.ErrNotFound {
+			model.NotFound = true
+			ctrl.renderHTML(c, http.StatusOK, "pages/ladder", model)
+			return
+		}
+
+		panic(err)
+	}
+
+	model.Ladder = result
+	model.CashFlow = NewSuggestViewCashFlow(result.Portfolio)
+	ctrl.renderHTML(c, http.StatusOK, "pages/ladder", model)
+}
+
+// LadderPageModel - модель для страницы "pages/ladder.html"
+type LadderPageModel struct {
+	Collections []recommender.Collection
+
+	// Параметры лесенки, как они заданы в форме
+	Amount     string
+	Horizon    string
+	Interval   string
+	Collection string
+
+	// Результат построения лесенки (nil, если лесенка не строилась)
+	Ladder   *recommender.LadderResult
+	CashFlow []*SuggestViewCashFlowPageModel
+
+	// Признак того, что подходящих облигаций не нашлось
+	NotFound bool
+}
+
+// ToLadderRequest создает recommender.LadderRequest из параметров формы
+func (m *LadderPageModel) ToLadderRequest(getCollection func(id string) (recommender.Collection, error)) (*recommender.LadderRequest, error) {
+	amount, err := parseScreenerFloat("amount", m.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if amount == nil {
+		return nil, NewError(400, "missing \"amount\" parameter")
+	}
+
+	horizon, err := parseScreenerInt("horizon", m.Horizon)
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := parseScreenerInt("interval", m.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &recommender.LadderRequest{
+		Amount:      *amount,
+		Horizon:     horizon,
+		Interval:    interval,
+		Constraints: recommender.DefaultSuggestConstraints(),
+	}
+	if m.Collection != "" {
+		request.Collection, err = getCollection(m.Collection)
+		if err != nil {
+			if err == recommender.ErrNotFound {
+				return nil, NewError(400, "collection \"%s\" doesn't exist", m.Collection)
+			}
+			return nil, err
+		}
+	}
+
+	err = request.Validate()
+	if err != nil {
+		return nil, NewError(400, "%s", err.Error())
+	}
+
+	return request, nil
+}
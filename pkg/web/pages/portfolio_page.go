@@ -0,0 +1,286 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// PortfolioListPage обрабатывает запросы "GET /portfolios"
+func (ctrl *Controller) PortfolioListPage(c *gin.Context) {
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	portfolios, err := u.ListPortfolios()
+	if err != nil {
+		panic(err)
+	}
+
+	model := &PortfolioListPageModel{Portfolios: portfolios}
+	ctrl.renderHTML(c, http.StatusOK, "pages/portfolios", model)
+}
+
+// CreatePortfolio обрабатывает запросы "POST /portfolios"
+func (ctrl *Controller) CreatePortfolio(c *gin.Context) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		panic(NewError(400, "invalid value for \"name\" parameter"))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	portfolio, err := u.CreatePortfolio(name)
+	if err != nil {
+		panic(err)
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/portfolios/%d", portfolio.ID))
+}
+
+// PortfolioPage обрабатывает запросы "GET /portfolios/:id"
+func (ctrl *Controller) PortfolioPage(c *gin.Context) {
+	id := c.Param("id")
+	portfolioID, err := strconv.Atoi(id)
+	if err != nil {
+		ctrl.renderHTML(c, http.StatusNotFound, "pages/portfolio_not_found", id)
+		return
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	valuation, err := u.GetPortfolio(portfolioID)
+	if err != nil {
+		if err == data.ErrNotFound {
+			ctrl.renderHTML(c, http.StatusNotFound, "pages/portfolio_not_found", id)
+			return
+		}
+
+		panic(err)
+	}
+
+	_, calendar, err := u.GetPortfolioCalendar(portfolioID)
+	if err != nil {
+		panic(err)
+	}
+
+	model := &PortfolioPageModel{
+		Valuation: valuation,
+		Calendar:  calendar,
+		Today:     time.Now().Format("2006-01-02"),
+	}
+	ctrl.renderHTML(c, http.StatusOK, "pages/portfolio", model)
+}
+
+// DeletePortfolio обрабатывает запросы "POST /portfolios/:id/delete"
+func (ctrl *Controller) DeletePortfolio(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	err = u.DeletePortfolio(portfolioID)
+	if err != nil {
+		if err == data.ErrNotFound {
+			panic(NewError(404, "portfolio #%d doesn't exist", portfolioID))
+		}
+		panic(err)
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Redirect(http.StatusSeeOther, "/portfolios")
+}
+
+// AddTrade обрабатывает запросы "POST /portfolios/:id/trades"
+func (ctrl *Controller) AddTrade(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	args := app.AddTradeArgs{Bond: strings.TrimSpace(c.PostForm("bond"))}
+	if args.Bond == "" {
+		panic(NewError(400, "invalid value for \"bond\" parameter"))
+	}
+
+	quantity, err := strconv.Atoi(c.PostForm("quantity"))
+	if err != nil || quantity == 0 {
+		panic(NewError(400, "invalid value for \"quantity\" parameter"))
+	}
+	args.Quantity = quantity
+
+	if s := c.PostForm("price"); s != "" {
+		price, err := strconv.ParseFloat(s, 64)
+		if err != nil || price <= 0 {
+			panic(NewError(400, "invalid value for \"price\" parameter"))
+		}
+		args.Price = &price
+	}
+
+	if s := c.PostForm("fee"); s != "" {
+		fee, err := strconv.ParseFloat(s, 64)
+		if err != nil || fee < 0 {
+			panic(NewError(400, "invalid value for \"fee\" parameter"))
+		}
+		args.Fee = &fee
+	}
+
+	if s := c.PostForm("date"); s != "" {
+		args.Date, err = time.Parse("2006-01-02", s)
+		if err != nil {
+			panic(NewError(400, "invalid value for \"date\" parameter"))
+		}
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	_, err = u.AddTrade(portfolioID, args)
+	if err != nil {
+		if errors.Is(err, recommender.ErrInvalidTrade) {
+			panic(NewError(400, "%s", err))
+		}
+		if err == data.ErrNotFound || err == recommender.ErrNotFound {
+			panic(NewError(404, "bond \"%s\" or portfolio #%d doesn't exist", args.Bond, portfolioID))
+		}
+		panic(err)
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/portfolios/%d", portfolioID))
+}
+
+// DeleteTrade обрабатывает запросы "POST /portfolios/:id/trades/:trade_id/delete"
+func (ctrl *Controller) DeleteTrade(c *gin.Context) {
+	portfolioID := parsePortfolioID(c)
+
+	tradeID, err := strconv.Atoi(c.Param("trade_id"))
+	if err != nil {
+		panic(NewError(404, "trade \"%s\" doesn't exist", c.Param("trade_id")))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	err = u.DeleteTrade(portfolioID, tradeID)
+	if err != nil {
+		if errors.Is(err, recommender.ErrInvalidTrade) {
+			panic(NewError(400, "%s", err))
+		}
+		if err == data.ErrNotFound {
+			panic(NewError(404, "trade #%d doesn't exist", tradeID))
+		}
+		panic(err)
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/portfolios/%d", portfolioID))
+}
+
+// SaveSuggestion обрабатывает запросы "POST /suggest/save"
+func (ctrl *Controller) SaveSuggestion(c *gin.Context) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		panic(NewError(400, "invalid value for \"name\" parameter"))
+	}
+
+	u, err := ctrl.app.NewUnitOfWork(c)
+	if err != nil {
+		panic(err)
+	}
+	defer u.Close()
+
+	req, err := ParseSuggestPortfolioRequest(c.PostForm("json"), u)
+	if err != nil {
+		panic(err)
+	}
+	if req == nil {
+		panic(NewError(400, "missing \"json\" parameter"))
+	}
+
+	suggestRequest, err := req.ToSuggestRequest()
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := u.Suggest(suggestRequest)
+	if err != nil {
+		panic(err)
+	}
+
+	portfolio, err := u.SaveSuggestion(name, result)
+	if err != nil {
+		panic(err)
+	}
+
+	err = u.Commit()
+	if err != nil {
+		panic(err)
+	}
+
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/portfolios/%d", portfolio.ID))
+}
+
+// parsePortfolioID разбирает ID портфеля из параметров маршрута
+func parsePortfolioID(c *gin.Context) int {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		panic(NewError(404, "portfolio \"%s\" doesn't exist", c.Param("id")))
+	}
+
+	return id
+}
+
+// PortfolioListPageModel - модель для страницы "pages/portfolios.html"
+type PortfolioListPageModel struct {
+	Portfolios []*data.Portfolio
+}
+
+// PortfolioPageModel - модель для страницы "pages/portfolio.html"
+type PortfolioPageModel struct {
+	Valuation *recommender.PortfolioValuation
+	Calendar  *recommender.Calendar
+	Today     string
+}
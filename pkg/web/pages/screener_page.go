@@ -0,0 +1,188 @@
+package pages
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// ScreenerPage обрабатывает запросы "GET /screener"
+// Параметры запроса описаны в ScreenerParams
+func (ctrl *Controller) ScreenerPage(c *gin.Context) {
+	params, err := NewScreenerParams(c)
+	if err != nil {
+		panic(err)
+	}
+
+	query, err := params.ToQuery()
+	if err != nil {
+		panic(err)
+	}
+
+	model, err := NewScreenerPageModel(ctrl.app, c, query, c.Request.URL.Query())
+	if err != nil {
+		panic(err)
+	}
+
+	ctrl.renderHTML(c, http.StatusOK, "pages/screener", model)
+}
+
+// screenerBondTypes содержит типы облигаций, которые предлагаются в форме скринера
+var screenerBondTypes = []data.BondType{
+	data.OFZBond,
+	data.SubfederalBond,
+	data.MunicipalBond,
+	data.CorporateBond,
+	data.ExchangeBond,
+	data.CBBond,
+	data.IFIBond,
+	data.EuroBond,
+}
+
+// ScreenerPageModel - модель для страницы "pages/screener.html"
+type ScreenerPageModel struct {
+	// Текущие параметры скринера
+	Values url.Values
+
+	// Значения для формы
+	BondTypes         []data.BondType
+	ListingLevels     []string
+	Currencies        []string
+	CouponFrequencies []string
+
+	Reports    []*recommender.Report
+	Sort       recommender.ScreenerSort
+	Ascending  bool
+	Skip       int
+	Limit      int
+	TotalCount int
+}
+
+// NewScreenerPageModel создает новые объекты типа ScreenerPageModel
+func NewScreenerPageModel(app app.App, context context.Context, query *recommender.ScreenerQuery, values url.Values) (*ScreenerPageModel, error) {
+	u, err := app.NewUnitOfWork(context)
+	if err != nil {
+		return nil, err
+	}
+	defer u.Close()
+
+	result, err := u.Screen(query)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &ScreenerPageModel{
+		Values:            values,
+		BondTypes:         screenerBondTypes,
+		ListingLevels:     []string{"1", "2", "3"},
+		Currencies:        []string{"RUB", "USD", "EUR", "CNY"},
+		CouponFrequencies: []string{"1", "2", "4", "12"},
+		Reports:           result.Reports,
+		Sort:              query.Sort,
+		Ascending:         query.Ascending,
+		Skip:              query.Skip,
+		Limit:             query.Limit,
+		TotalCount:        result.TotalCount,
+	}
+	if model.Sort == "" {
+		model.Sort = recommender.SortByYield
+	}
+	if model.Limit == 0 {
+		model.Limit = recommender.DefaultScreenerLimit
+	}
+
+	return model, nil
+}
+
+// Value возвращает значение параметра скринера
+func (m *ScreenerPageModel) Value(name string) string {
+	return m.Values.Get(name)
+}
+
+// Has проверяет, выбрано ли значение value в параметре скринера name
+func (m *ScreenerPageModel) Has(name, value string) bool {
+	for _, v := range m.Values[name] {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SortURL возвращает ссылку на первую страницу результатов, отсортированных по полю sort
+// Повторный выбор того же поля меняет направление сортировки
+func (m *ScreenerPageModel) SortURL(sort string) string {
+	values := m.copyValues()
+	values.Set("sort", sort)
+	values.Del("skip")
+	if recommender.ScreenerSort(sort) == m.Sort && !m.Ascending {
+		values.Set("order", "asc")
+	} else {
+		values.Set("order", "desc")
+	}
+
+	return "/screener?" + values.Encode()
+}
+
+// PageURL возвращает ссылку на страницу результатов, начинающуюся с облигации skip
+func (m *ScreenerPageModel) PageURL(skip int) string {
+	values := m.copyValues()
+	values.Set("skip", strconv.Itoa(skip))
+
+	return "/screener?" + values.Encode()
+}
+
+// First возвращает номер первой облигации на странице (начиная с 1)
+func (m *ScreenerPageModel) First() int {
+	if len(m.Reports) == 0 {
+		return 0
+	}
+
+	return m.Skip + 1
+}
+
+// Last возвращает номер последней облигации на странице
+func (m *ScreenerPageModel) Last() int {
+	return m.Skip + len(m.Reports)
+}
+
+// HasPrevPage проверяет, есть ли предыдущая страница результатов
+func (m *ScreenerPageModel) HasPrevPage() bool {
+	return m.Skip > 0
+}
+
+// PrevPageSkip возвращает смещение предыдущей страницы результатов
+func (m *ScreenerPageModel) PrevPageSkip() int {
+	if m.Skip < m.Limit {
+		return 0
+	}
+
+	return m.Skip - m.Limit
+}
+
+// HasNextPage проверяет, есть ли следующая страница результатов
+func (m *ScreenerPageModel) HasNextPage() bool {
+	return m.Skip+len(m.Reports) < m.TotalCount
+}
+
+// NextPageSkip возвращает смещение следующей страницы результатов
+func (m *ScreenerPageModel) NextPageSkip() int {
+	return m.Skip + m.Limit
+}
+
+func (m *ScreenerPageModel) copyValues() url.Values {
+	values := make(url.Values)
+	for k, v := range m.Values {
+		values[k] = append([]string{}, v...)
+	}
+
+	return values
+}
@@ -0,0 +1,246 @@
+package pages
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// ScreenerParams - параметры скринера облигаций
+// Передаются как query-параметры, списочные параметры можно повторять (например, "?type=ofz_bond&type=municipal_bond")
+// Пустые значения игнорируются, чтобы форма скринера могла отправлять незаполненные поля
+type ScreenerParams struct {
+	Types             []string `form:"type"`
+	IssuerID          string   `form:"issuer_id"`
+	Issuer            string   `form:"issuer"`
+	ListingLevels     []string `form:"listing_level"`
+	QualifiedOnly     string   `form:"qualified_only"`
+	HighRisk          string   `form:"high_risk"`
+	Currencies        []string `form:"currency"`
+	CouponFrequencies []string `form:"coupon_frequency"`
+	MaturityFrom      string   `form:"maturity_from"`
+	MaturityTo        string   `form:"maturity_to"`
+	OfferFrom         string   `form:"offer_from"`
+	OfferTo           string   `form:"offer_to"`
+	MinYield          string   `form:"min_yield"`
+	MaxYield          string   `form:"max_yield"`
+	MinSpread         string   `form:"min_spread"`
+	MaxSpread         string   `form:"max_spread"`
+	MinPrice          string   `form:"min_price"`
+	MaxPrice          string   `form:"max_price"`
+	Amortization      string   `form:"amortization"`
+	Sort              string   `form:"sort"`
+	Order             string   `form:"order"`
+	Skip              string   `form:"skip"`
+	Limit             string   `form:"limit"`
+}
+
+// NewScreenerParams создает объект ScreenerParams из query-параметров
+func NewScreenerParams(c *gin.Context) (*ScreenerParams, error) {
+	var params ScreenerParams
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, NewError(400, "malformed screener parameters")
+	}
+
+	return &params, nil
+}
+
+// ToQuery создает recommender.ScreenerQuery из ScreenerParams
+func (p *ScreenerParams) ToQuery() (*recommender.ScreenerQuery, error) {
+	var (
+		query recommender.ScreenerQuery
+		err   error
+	)
+
+	for _, s := range nonEmpty(p.Types) {
+		query.Types = append(query.Types, data.BondType(s))
+	}
+	if query.IssuerID, err = parseScreenerInt("issuer_id", p.IssuerID); err != nil {
+		return nil, err
+	}
+	query.Issuer = strings.TrimSpace(p.Issuer)
+	if query.ListingLevels, err = parseScreenerInts("listing_level", p.ListingLevels); err != nil {
+		return nil, err
+	}
+	if query.QualifiedOnly, err = parseScreenerBool("qualified_only", p.QualifiedOnly); err != nil {
+		return nil, err
+	}
+	if query.HighRisk, err = parseScreenerBool("high_risk", p.HighRisk); err != nil {
+		return nil, err
+	}
+	for _, s := range nonEmpty(p.Currencies) {
+		query.Currencies = append(query.Currencies, strings.ToUpper(s))
+	}
+	if query.CouponFrequencies, err = parseScreenerInts("coupon_frequency", p.CouponFrequencies); err != nil {
+		return nil, err
+	}
+	if query.MaturityFrom, err = parseScreenerDate("maturity_from", p.MaturityFrom); err != nil {
+		return nil, err
+	}
+	if query.MaturityTo, err = parseScreenerDate("maturity_to", p.MaturityTo); err != nil {
+		return nil, err
+	}
+	if query.OfferFrom, err = parseScreenerDate("offer_from", p.OfferFrom); err != nil {
+		return nil, err
+	}
+	if query.OfferTo, err = parseScreenerDate("offer_to", p.OfferTo); err != nil {
+		return nil, err
+	}
+	if query.MinYield, err = parseScreenerFloat("min_yield", p.MinYield); err != nil {
+		return nil, err
+	}
+	if query.MaxYield, err = parseScreenerFloat("max_yield", p.MaxYield); err != nil {
+		return nil, err
+	}
+	if query.MinSpread, err = parseScreenerFloat("min_spread", p.MinSpread); err != nil {
+		return nil, err
+	}
+	if query.MaxSpread, err = parseScreenerFloat("max_spread", p.MaxSpread); err != nil {
+		return nil, err
+	}
+	if query.MinPrice, err = parseScreenerFloat("min_price", p.MinPrice); err != nil {
+		return nil, err
+	}
+	if query.MaxPrice, err = parseScreenerFloat("max_price", p.MaxPrice); err != nil {
+		return nil, err
+	}
+	if query.HasAmortization, err = parseScreenerBool("amortization", p.Amortization); err != nil {
+		return nil, err
+	}
+
+	query.Sort = recommender.ScreenerSort(p.Sort)
+	switch p.Order {
+	case "", "desc":
+		query.Ascending = false
+	case "asc":
+		query.Ascending = true
+	default:
+		return nil, NewError(400, "invalid value for \"order\" parameter")
+	}
+
+	if query.Skip, err = parseScreenerInt("skip", p.Skip); err != nil {
+		return nil, err
+	}
+	if query.Limit, err = parseScreenerInt("limit", p.Limit); err != nil {
+		return nil, err
+	}
+
+	err = query.Validate()
+	if err != nil {
+		return nil, NewError(400, "%s", err.Error())
+	}
+
+	return &query, nil
+}
+
+// Values возвращает параметры скринера в виде query-параметров
+// Пустые значения не включаются
+func (p *ScreenerParams) Values() url.Values {
+	values := url.Values{}
+	v := reflect.ValueOf(p).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("form")
+		switch f := v.Field(i).Interface().(type) {
+		case string:
+			if s := strings.TrimSpace(f); s != "" {
+				values.Set(name, s)
+			}
+		case []string:
+			for _, s := range nonEmpty(f) {
+				values.Add(name, s)
+			}
+		}
+	}
+
+	return values
+}
+
+// nonEmpty возвращает непустые значения из списка
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, s := range values {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// parseScreenerInt разбирает целочисленный параметр скринера (пустое значение - 0)
+func parseScreenerInt(name, s string) (int, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, NewError(400, "invalid value for \"%s\" parameter", name)
+	}
+
+	return v, nil
+}
+
+// parseScreenerInts разбирает списочный целочисленный параметр скринера
+func parseScreenerInts(name string, values []string) ([]int, error) {
+	var result []int
+	for _, s := range nonEmpty(values) {
+		v, err := parseScreenerInt(name, s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+// parseScreenerFloat разбирает необязательный числовой параметр скринера
+func parseScreenerFloat(name, s string) (*float64, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return nil, NewError(400, "invalid value for \"%s\" parameter", name)
+	}
+
+	return &v, nil
+}
+
+// parseScreenerBool разбирает необязательный логический параметр скринера
+func parseScreenerBool(name, s string) (*bool, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, NewError(400, "invalid value for \"%s\" parameter", name)
+	}
+
+	return &v, nil
+}
+
+// parseScreenerDate разбирает необязательный параметр-дату скринера в формате "YYYY-MM-DD"
+func parseScreenerDate(name, s string) (*time.Time, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+
+	v, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, NewError(400, "invalid value for \"%s\" parameter", name)
+	}
+
+	return &v, nil
+}
@@ -0,0 +1,60 @@
+package pages
+
+import (
+	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
+)
+
+// SuggestConstraintsParams - параметры оптимизатора портфеля
+// Передаются как часть JSON запроса, доли задаются в процентах
+type SuggestConstraintsParams struct {
+	MaxBondShare         *float64 `json:"max_bond_share,omitempty"`
+	MaxIssuerShare       *float64 `json:"max_issuer_share,omitempty"`
+	MaxBondTypeShare     *float64 `json:"max_bond_type_share,omitempty"`
+	MaxMaturityYearShare *float64 `json:"max_maturity_year_share,omitempty"`
+	MinPositions         *int     `json:"min_positions,omitempty"`
+	TargetDuration       *float64 `json:"target_duration,omitempty"`
+	DurationTolerance    *float64 `json:"duration_tolerance,omitempty"`
+	MaxCashShare         *float64 `json:"max_cash_share,omitempty"`
+}
+
+// ToSuggestConstraints создает recommender.SuggestConstraints из SuggestConstraintsParams
+// Незаданные параметры (или все параметры, если p == nil) принимают значения по умолчанию,
+// нулевое значение отключает ограничение
+func (p *SuggestConstraintsParams) ToSuggestConstraints() (*recommender.SuggestConstraints, error) {
+	constraints := recommender.DefaultSuggestConstraints()
+	if p == nil {
+		return constraints, nil
+	}
+
+	if p.MaxBondShare != nil {
+		constraints.MaxBondWeight = *p.MaxBondShare / 100.0
+	}
+	if p.MaxIssuerShare != nil {
+		constraints.MaxIssuerWeight = *p.MaxIssuerShare / 100.0
+	}
+	if p.MaxBondTypeShare != nil {
+		constraints.MaxBondTypeWeight = *p.MaxBondTypeShare / 100.0
+	}
+	if p.MaxMaturityYearShare != nil {
+		constraints.MaxMaturityYearWeight = *p.MaxMaturityYearShare / 100.0
+	}
+	if p.MinPositions != nil {
+		constraints.MinPositions = *p.MinPositions
+	}
+	if p.TargetDuration != nil {
+		constraints.TargetDuration = *p.TargetDuration
+	}
+	if p.DurationTolerance != nil {
+		constraints.DurationTolerance = *p.DurationTolerance
+	}
+	if p.MaxCashShare != nil {
+		constraints.MaxCashWeight = *p.MaxCashShare / 100.0
+	}
+
+	err := constraints.Validate()
+	if err != nil {
+		return nil, NewError(400, "invalid value for \"constraints\" parameter: %s", err.Error())
+	}
+
+	return constraints, nil
+}
@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/data"
 	"github.com/kapitanov/moex-bond-recommender/pkg/recommender"
 )
 
@@ -42,7 +42,12 @@ func (ctrl *Controller) SuggestPage(c *gin.Context) {
 		return
 	}
 
-	portfolio, err := u.Suggest(req.toSuggestRequest())
+	suggestRequest, err := req.ToSuggestRequest()
+	if err != nil {
+		panic(err)
+	}
+
+	portfolio, err := u.Suggest(suggestRequest)
 	if err != nil {
 		panic(err)
 	}
@@ -52,35 +57,29 @@ func (ctrl *Controller) SuggestPage(c *gin.Context) {
 		Request:          req,
 		Portfolio:        portfolio,
 		ShareUrl:         fmt.Sprintf("/suggests?json=%s", req),
+		RequestJSON:      req.JSON(),
+		CashFlow:         NewSuggestViewCashFlow(portfolio),
+		Horizons:         make([]*recommender.HorizonResult, 0, len(recommender.Durations)),
 	}
-	cashFlowDict := make(map[time.Time]*SuggestViewCashFlowPageModel)
-	for _, p := range portfolio.Positions {
-		for _, c := range p.CashFlow {
-			item, exists := cashFlowDict[c.Date]
-			if !exists {
-				item = &SuggestViewCashFlowPageModel{Date: c.Date}
-				cashFlowDict[c.Date] = item
-			}
-			item.Amount += c.ValueRub
-			switch c.Type {
-			case recommender.Coupon:
-				item.HasCoupon = true
-			case recommender.Amortization:
-				item.HasAmortization = true
-			case recommender.Maturity:
-				item.HasMaturity = true
-			}
-		}
+
+	scenarios, err := u.RunScenarios(portfolio, recommender.StandardScenarios())
+	if err != nil {
+		panic(err)
 	}
-	extModel.CashFlow = make([]*SuggestViewCashFlowPageModel, len(cashFlowDict))
-	i := 0
-	for _, item := range cashFlowDict {
-		extModel.CashFlow[i] = item
-		i++
+	extModel.Scenarios = NewSuggestViewScenarios(scenarios)
+
+	// Доходность на горизонте рассчитывается для каждого срока от 1 года до максимального
+	for i := range recommender.Durations {
+		horizon, err := u.SimulateHorizon(portfolio, &recommender.HorizonRequest{
+			Horizon: 12 * (i + 1),
+			Costs:   suggestRequest.Costs,
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		extModel.Horizons = append(extModel.Horizons, horizon)
 	}
-	sort.Slice(extModel.CashFlow, func(i, j int) bool {
-		return extModel.CashFlow[i].Date.Before(extModel.CashFlow[j].Date)
-	})
 
 	ctrl.renderHTML(c, http.StatusOK, "pages/suggest_view", extModel)
 }
@@ -91,6 +90,8 @@ type SuggestPortfolioRequest struct {
 	MaxDuration    recommender.Duration           `json:"-"`
 	MaxDurationRaw int                            `json:"max_duration"`
 	Parts          []*SuggestPortfolioRequestPart `json:"parts"`
+	Costs          *CostModelParams               `json:"costs,omitempty"`
+	Constraints    *SuggestConstraintsParams      `json:"constraints,omitempty"`
 }
 
 // SuggestPortfolioRequestPart - элемент параметра запроса GET /api/suggest-portfolio
@@ -103,8 +104,14 @@ type SuggestPortfolioRequestPart struct {
 
 // NewSuggestPortfolioRequest создает объект SuggestPortfolioRequest из строки
 func NewSuggestPortfolioRequest(c *gin.Context, u app.UnitOfWork) (*SuggestPortfolioRequest, error) {
-	raw, exists := c.GetQuery("json")
-	if !exists || raw == "" {
+	raw, _ := c.GetQuery("json")
+	return ParseSuggestPortfolioRequest(raw, u)
+}
+
+// ParseSuggestPortfolioRequest создает объект SuggestPortfolioRequest из JSON строки
+// Если строка пустая, то возвращается nil
+func ParseSuggestPortfolioRequest(raw string, u app.UnitOfWork) (*SuggestPortfolioRequest, error) {
+	if raw == "" {
 		return nil, nil
 	}
 
@@ -139,7 +146,7 @@ func NewSuggestPortfolioRequest(c *gin.Context, u app.UnitOfWork) (*SuggestPortf
 			collection, err := u.GetCollection(part.CollectionID)
 			if err != nil {
 				if err == recommender.ErrNotFound {
-					return nil, NewError(400, "collection \"%s\" doesn't exist", part.Collection)
+					return nil, NewError(400, "collection \"%s\" doesn't exist", part.CollectionID)
 				}
 				return nil, err
 			}
@@ -161,6 +168,13 @@ func NewSuggestPortfolioRequest(c *gin.Context, u app.UnitOfWork) (*SuggestPortf
 		request.Parts = nil
 	}
 
+	if _, err := request.Costs.ToCostModel(); err != nil {
+		return nil, err
+	}
+	if _, err := request.Constraints.ToSuggestConstraints(); err != nil {
+		return nil, err
+	}
+
 	return &request, nil
 }
 
@@ -175,12 +189,33 @@ func (r *SuggestPortfolioRequest) String() string {
 	return str
 }
 
-// toSuggestRequest создает recommender.SuggestRequest из SuggestPortfolioRequest
-func (r *SuggestPortfolioRequest) toSuggestRequest() *recommender.SuggestRequest {
+// JSON преобразует значение в JSON строку (без экранирования для URL)
+func (r *SuggestPortfolioRequest) JSON() string {
+	bytes, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	return string(bytes)
+}
+
+// ToSuggestRequest создает recommender.SuggestRequest из SuggestPortfolioRequest
+func (r *SuggestPortfolioRequest) ToSuggestRequest() (*recommender.SuggestRequest, error) {
+	costs, err := r.Costs.ToCostModel()
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, err := r.Constraints.ToSuggestConstraints()
+	if err != nil {
+		return nil, err
+	}
+
 	req := recommender.SuggestRequest{
 		Amount:      r.Amount,
 		MaxDuration: r.MaxDuration,
 		Parts:       nil,
+		Costs:       costs,
+		Constraints: constraints,
 	}
 
 	if r.Parts != nil && len(r.Parts) > 0 {
@@ -193,7 +228,7 @@ func (r *SuggestPortfolioRequest) toSuggestRequest() *recommender.SuggestRequest
 		}
 	}
 
-	return &req
+	return &req, nil
 }
 
 // SuggestPageModel - модель для страницы "pages/suggest.html"
@@ -210,10 +245,13 @@ type SuggestPageCollectionModel struct {
 // SuggestViewPageModel - модель для страницы "pages/suggest_view.html"
 type SuggestViewPageModel struct {
 	SuggestPageModel
-	Request   *SuggestPortfolioRequest
-	Portfolio *recommender.SuggestResult
-	ShareUrl  string
-	CashFlow  []*SuggestViewCashFlowPageModel
+	Request     *SuggestPortfolioRequest
+	Portfolio   *recommender.SuggestResult
+	ShareUrl    string
+	RequestJSON string
+	CashFlow    []*SuggestViewCashFlowPageModel
+	Horizons    []*recommender.HorizonResult
+	Scenarios   *SuggestViewScenariosPageModel
 }
 
 // SuggestViewCashFlowPageModel - модель выплаты для страницы "pages/suggest_view.html"
@@ -224,3 +262,57 @@ type SuggestViewCashFlowPageModel struct {
 	HasAmortization bool
 	HasMaturity     bool
 }
+
+// NewSuggestViewCashFlow формирует модели выплат по портфелю для шаблона "pages/suggest_cash_flow_partial.html"
+func NewSuggestViewCashFlow(portfolio *recommender.SuggestResult) []*SuggestViewCashFlowPageModel {
+	days := recommender.NewSuggestionCalendar(portfolio).Days()
+	cashFlow := make([]*SuggestViewCashFlowPageModel, len(days))
+	for i, day := range days {
+		cashFlow[i] = &SuggestViewCashFlowPageModel{
+			Date:            day.Date,
+			Amount:          day.Amount,
+			HasCoupon:       day.HasCoupon,
+			HasAmortization: day.HasAmortization,
+			HasMaturity:     day.HasMaturity,
+		}
+	}
+
+	return cashFlow
+}
+
+// SuggestViewScenariosPageModel - модель переоценки портфеля по сценариям для страницы "pages/suggest_view.html"
+type SuggestViewScenariosPageModel struct {
+	Results   []*recommender.ScenarioResult
+	Positions []*SuggestViewScenarioPositionPageModel
+}
+
+// SuggestViewScenarioPositionPageModel - модель переоценки позиции по сценариям для страницы "pages/suggest_view.html"
+type SuggestViewScenarioPositionPageModel struct {
+	Bond       *data.Bond
+	ProfitLoss []float64
+}
+
+// NewSuggestViewScenarios формирует таблицу переоценки позиций по сценариям (позиции - строки, сценарии - столбцы)
+func NewSuggestViewScenarios(results []*recommender.ScenarioResult) *SuggestViewScenariosPageModel {
+	model := &SuggestViewScenariosPageModel{
+		Results:   results,
+		Positions: make([]*SuggestViewScenarioPositionPageModel, 0),
+	}
+	if len(results) == 0 {
+		return model
+	}
+
+	for i, p := range results[0].Positions {
+		position := &SuggestViewScenarioPositionPageModel{
+			Bond:       p.Bond,
+			ProfitLoss: make([]float64, len(results)),
+		}
+		for j, r := range results {
+			position.ProfitLoss[j] = r.Positions[i].ProfitLoss
+		}
+
+		model.Positions = append(model.Positions, position)
+	}
+
+	return model
+}
@@ -4,6 +4,7 @@ import (
 	"mime"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,12 +17,52 @@ func (s *service) ConfigureEndpoints() {
 	routes.GET("/search", s.pagesController.SearchPage)
 	routes.GET("/bonds/:id", s.pagesController.BondPage)
 	routes.GET("/collections/:id", s.pagesController.CollectionPage)
+	routes.GET("/screener", s.pagesController.ScreenerPage)
+	routes.GET("/curve", s.pagesController.CurvePage)
 	routes.GET("/suggest", s.pagesController.SuggestPage)
+	routes.POST("/suggest/save", s.pagesController.SaveSuggestion)
+	routes.GET("/ladder", s.pagesController.LadderPage)
+	routes.GET("/portfolios", s.pagesController.PortfolioListPage)
+	routes.POST("/portfolios", s.pagesController.CreatePortfolio)
+	routes.GET("/portfolios/:id", s.pagesController.PortfolioPage)
+	routes.POST("/portfolios/:id/delete", s.pagesController.DeletePortfolio)
+	routes.POST("/portfolios/:id/trades", s.pagesController.AddTrade)
+	routes.POST("/portfolios/:id/trades/:trade_id/delete", s.pagesController.DeleteTrade)
+	routes.GET("/portfolios/:id/calendar.ics", s.pagesController.PortfolioCalendarICS)
+	routes.GET("/calendar.ics", s.pagesController.CalendarICS)
 
-	s.router.NoRoute(s.serveStaticFiles)
+	v1 := s.router.Group("/api/v1", s.apiController.ErrorMiddleware())
+	v1.GET("/search", s.apiController.Search)
+	v1.GET("/bonds/:id", s.apiController.GetBond)
+	v1.GET("/bonds/:id/history", s.apiController.GetBondHistory)
+	v1.GET("/collections", s.apiController.ListCollections)
+	v1.GET("/collections/:id", s.apiController.GetCollection)
+	v1.GET("/screener", s.apiController.Screener)
+	v1.POST("/suggest", s.apiController.Suggest)
+	v1.GET("/portfolios", s.apiController.ListPortfolios)
+	v1.POST("/portfolios", s.apiController.CreatePortfolio)
+	v1.GET("/portfolios/:id", s.apiController.GetPortfolio)
+	v1.DELETE("/portfolios/:id", s.apiController.DeletePortfolio)
+	v1.POST("/portfolios/:id/trades", s.apiController.AddTrade)
+	v1.DELETE("/portfolios/:id/trades/:trade_id", s.apiController.DeleteTrade)
+	v1.GET("/portfolios/:id/calendar", s.apiController.GetPortfolioCalendar)
+	v1.GET("/calendar", s.apiController.GetCalendar)
+	v1.GET("/openapi.json", s.apiController.OpenAPI)
+
+	s.router.NoRoute(s.apiController.ErrorMiddleware(), s.handleNoRoute)
 	_ = mime.AddExtensionType(".js", "application/javascript")
 }
 
+// handleNoRoute обрабатывает запросы, для которых не найден маршрут
+func (s *service) handleNoRoute(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		s.apiController.NoRoute(c)
+		return
+	}
+
+	s.serveStaticFiles(c)
+}
+
 // serveStaticFiles отвечает за раздачу статики
 func (s *service) serveStaticFiles(c *gin.Context) {
 	dir, file := path.Split(c.Request.RequestURI)
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/api"
+)
+
+func TestConfigureEndpoints_MatchesOpenAPISpec(t *testing.T) {
+	assert := assertion.New(t)
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	err := json.Unmarshal(api.OpenAPISpec, &spec)
+	if !assert.NoError(err) {
+		return
+	}
+
+	expected := make([]string, 0)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			expected = append(expected, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(expected)
+
+	s, err := New()
+	if !assert.NoError(err) {
+		return
+	}
+
+	const prefix = "/api/v1"
+	actual := make([]string, 0)
+	for _, route := range s.(*service).router.Routes() {
+		if !strings.HasPrefix(route.Path, prefix+"/") {
+			continue
+		}
+
+		segments := strings.Split(strings.TrimPrefix(route.Path, prefix), "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+		actual = append(actual, route.Method+" "+strings.Join(segments, "/"))
+	}
+	sort.Strings(actual)
+
+	assert.Equal(expected, actual)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/kapitanov/moex-bond-recommender/pkg/app"
+	"github.com/kapitanov/moex-bond-recommender/pkg/web/api"
 	"github.com/kapitanov/moex-bond-recommender/pkg/web/pages"
 )
 
@@ -57,6 +58,7 @@ func New(options ...Option) (Service, error) {
 		Delims:       goview.Delims{Left: "{{", Right: "}}"},
 	})
 	s.pagesController = pages.New(s.app, s.googleAnalyticsID, s.debugMode, s.logger)
+	s.apiController = api.New(s.app, s.logger)
 	s.ConfigureEndpoints()
 	return s, nil
 }
@@ -111,6 +113,7 @@ type service struct {
 	done              *sync.WaitGroup
 	app               app.App
 	pagesController   *pages.Controller
+	apiController     *api.Controller
 	server            *http.Server
 	googleAnalyticsID string
 	debugMode         bool
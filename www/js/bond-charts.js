@@ -0,0 +1,96 @@
+'use strict';
+
+(function (window) {
+
+	function createLineChart(el, label, color) {
+		var config = {
+			type: 'line',
+			data: {
+				labels: [],
+				datasets: [
+					{
+						label: label,
+						data: [],
+						backgroundColor: color,
+						borderColor: color,
+						borderWidth: 2,
+						pointRadius: 0,
+						spanGaps: true
+					}
+				]
+			},
+			options: {
+				interaction: {
+					mode: 'index',
+					intersect: false
+				},
+				plugins: {
+					legend: {
+						display: false
+					}
+				}
+			}
+		};
+
+		return new Chart(document.getElementById(el), config);
+	}
+
+	function updateChart(chart, labels, values) {
+		chart.data.labels = labels;
+		chart.data.datasets[0].data = values;
+		chart.update();
+	}
+
+	window.createBondHistoryCharts = function (bondId, elements) {
+		var priceChart = createLineChart(elements.price, 'Цена', 'rgb(54, 162, 235)');
+		var yieldChart = createLineChart(elements.yield, 'Доходность', 'rgb(75, 192, 192)');
+		var spreadChart = createLineChart(elements.spread, 'Спред', 'rgb(255, 159, 64)');
+
+		var buttons = document.getElementById(elements.buttons).querySelectorAll('button[data-range]');
+		var container = document.getElementById(elements.container);
+		var empty = document.getElementById(elements.empty);
+
+		function load(range) {
+			for (var i = 0; i < buttons.length; i++) {
+				buttons[i].classList.toggle('active', buttons[i].dataset.range === range);
+			}
+
+			fetch('/api/v1/bonds/' + encodeURIComponent(bondId) + '/history?range=' + encodeURIComponent(range))
+				.then(function (response) {
+					if (!response.ok) {
+						throw new Error('HTTP ' + response.status);
+					}
+					return response.json();
+				})
+				.then(function (history) {
+					var labels = [];
+					var prices = [];
+					var yields = [];
+					var spreads = [];
+					for (var i = 0; i < history.points.length; i++) {
+						var point = history.points[i];
+						labels.push(new Date(point.date).toLocaleDateString());
+						prices.push(point.price);
+						yields.push(point.yield);
+						spreads.push(point.spread);
+					}
+
+					container.classList.toggle('d-none', labels.length === 0);
+					empty.classList.toggle('d-none', labels.length !== 0);
+
+					updateChart(priceChart, labels, prices);
+					updateChart(yieldChart, labels, yields);
+					updateChart(spreadChart, labels, spreads);
+				})
+				.catch(console.error);
+		}
+
+		for (var i = 0; i < buttons.length; i++) {
+			buttons[i].addEventListener('click', function (e) {
+				load(e.currentTarget.dataset.range);
+			});
+		}
+
+		load('1y');
+	};
+})(window);
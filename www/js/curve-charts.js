@@ -0,0 +1,97 @@
+'use strict';
+
+(function (window) {
+
+	var positiveColor = 'rgb(75, 192, 192)';
+	var negativeColor = 'rgb(255, 99, 132)';
+
+	function openBond(points, elements) {
+		if (elements.length > 0) {
+			var point = points[elements[0].index];
+			if (!!point && !!point.isin) {
+				window.location = '/bonds/' + encodeURIComponent(point.isin);
+			}
+		}
+	}
+
+	window.createYieldCurveCharts = function (data, elements) {
+		new Chart(document.getElementById(elements.curve), {
+			type: 'scatter',
+			data: {
+				datasets: [
+					{
+						label: 'Кривая',
+						data: data.line,
+						showLine: true,
+						borderColor: 'rgb(54, 162, 235)',
+						backgroundColor: 'rgb(54, 162, 235)',
+						borderWidth: 2,
+						pointRadius: 0
+					},
+					{
+						label: 'ОФЗ',
+						data: data.points,
+						borderColor: 'rgb(255, 159, 64)',
+						backgroundColor: 'rgb(255, 159, 64)',
+						pointRadius: 4
+					}
+				]
+			},
+			options: {
+				onClick: function (e, active) {
+					openBond(data.points, active.filter(function (el) {
+						return el.datasetIndex === 1;
+					}));
+				},
+				plugins: {
+					tooltip: {
+						callbacks: {
+							label: function (context) {
+								var point = context.raw;
+								if (!point.label) {
+									return point.x.toFixed(2) + ' лет: ' + point.y.toFixed(2) + '%';
+								}
+								return point.label + ': ' + point.y.toFixed(2) + '% (' +
+									(point.deviation > 0 ? '+' : '') + point.deviation.toFixed(1) + ' б.п.)';
+							}
+						}
+					}
+				}
+			}
+		});
+
+		var labels = [];
+		var deviations = [];
+		var colors = [];
+		for (var i = 0; i < data.points.length; i++) {
+			labels.push(data.points[i].label);
+			deviations.push(data.points[i].deviation);
+			colors.push(data.points[i].deviation > 0 ? positiveColor : negativeColor);
+		}
+
+		new Chart(document.getElementById(elements.deviation), {
+			type: 'bar',
+			data: {
+				labels: labels,
+				datasets: [
+					{
+						label: 'Отклонение',
+						data: deviations,
+						backgroundColor: colors
+					}
+				]
+			},
+			options: {
+				onClick: function (e, active) {
+					openBond(data.points, active);
+				},
+				plugins: {
+					legend: {
+						display: false
+					}
+				}
+			}
+		});
+	};
+
+})(window);
@@ -117,6 +117,85 @@
 				</div>
 			</div>
 
+			<div class="row mt-3">
+				<div class="col-12 col-md-5">
+					<label for="inputAccount" class="col-form-label">Тип счета</label>
+				</div>
+				<div class="col-12 col-md-7">
+					<select id="inputAccount" class="form-select" :disabled="busy" v-model="account">
+						<option v-for="a in accounts" :value="a.value">{{ a.name }}</option>
+					</select>
+				</div>
+			</div>
+
+			<div class="row mt-3">
+				<div class="col-12 col-md-5">
+					<label for="inputBrokerFee" class="col-form-label">Комиссия брокера</label>
+				</div>
+				<div class="col-12 col-md-7">
+					<div class="input-group">
+						<input type="number" id="inputBrokerFee" class="form-control" v-model.number="brokerFee" :disabled="busy" autocomplete="off" min="0" step="0.001">
+						<span class="input-group-text">%</span>
+					</div>
+				</div>
+			</div>
+
+			<div class="row mt-4">
+				<div class="col-12">
+					<div class="form-check">
+						<input class="form-check-input d-block" type="checkbox" id="checkConstraints" v-model="enableConstraints" :disabled="busy">
+						<label class="form-check-label" for="checkConstraints">Ограничения диверсификации</label>
+					</div>
+				</div>
+			</div>
+
+			<div class="mt-2" v-if="enableConstraints">
+				<div class="row mt-2">
+					<div class="col-12 col-md-5">
+						<label for="inputMaxBondShare" class="col-form-label">Максимальная доля облигации</label>
+					</div>
+					<div class="col-12 col-md-7">
+						<div class="input-group">
+							<input type="number" id="inputMaxBondShare" class="form-control" v-model.number="maxBondShare" :disabled="busy" autocomplete="off" min="0" max="100" step="1" placeholder="Без ограничений">
+							<span class="input-group-text">%</span>
+						</div>
+					</div>
+				</div>
+				<div class="row mt-2">
+					<div class="col-12 col-md-5">
+						<label for="inputMaxIssuerShare" class="col-form-label">Максимальная доля эмитента</label>
+					</div>
+					<div class="col-12 col-md-7">
+						<div class="input-group">
+							<input type="number" id="inputMaxIssuerShare" class="form-control" v-model.number="maxIssuerShare" :disabled="busy" autocomplete="off" min="0" max="100" step="1" placeholder="Без ограничений">
+							<span class="input-group-text">%</span>
+						</div>
+					</div>
+				</div>
+				<div class="row mt-2">
+					<div class="col-12 col-md-5">
+						<label for="inputMaxBondTypeShare" class="col-form-label">Максимальная доля типа облигаций</label>
+					</div>
+					<div class="col-12 col-md-7">
+						<div class="input-group">
+							<input type="number" id="inputMaxBondTypeShare" class="form-control" v-model.number="maxBondTypeShare" :disabled="busy" autocomplete="off" min="0" max="100" step="1" placeholder="Без ограничений">
+							<span class="input-group-text">%</span>
+						</div>
+					</div>
+				</div>
+				<div class="row mt-2">
+					<div class="col-12 col-md-5">
+						<label for="inputMaxMaturityYearShare" class="col-form-label">Максимальная доля года погашения</label>
+					</div>
+					<div class="col-12 col-md-7">
+						<div class="input-group">
+							<input type="number" id="inputMaxMaturityYearShare" class="form-control" v-model.number="maxMaturityYearShare" :disabled="busy" autocomplete="off" min="0" max="100" step="1" placeholder="Без ограничений">
+							<span class="input-group-text">%</span>
+						</div>
+					</div>
+				</div>
+			</div>
+
 			<div class="row mt-4">
 				<div class="col-12">
 					<div class="form-check">
@@ -160,6 +239,18 @@
 						{value: 5, name: 'До 5 лет'},
 					],
 					duration: 1,
+					accounts: [
+						{value: 'brokerage', name: 'Брокерский счет'},
+						{value: 'iis_a', name: 'ИИС типа А (вычет на взносы)'},
+						{value: 'iis_b', name: 'ИИС типа Б (вычет на доход)'},
+					],
+					account: 'brokerage',
+					brokerFee: 0.05,
+					enableConstraints: false,
+					maxBondShare: 25,
+					maxIssuerShare: 35,
+					maxBondTypeShare: '',
+					maxMaturityYearShare: '',
 					enableStructure: false,
 					items: [],
 					busy: false
@@ -181,6 +272,25 @@
 						max_duration: this.duration
 					};
 
+					if (this.account !== 'brokerage' || this.brokerFee !== 0.05) {
+						request.costs = {
+							account: this.account,
+							broker_fee: String(this.brokerFee)
+						};
+					}
+
+					if (this.enableConstraints) {
+						var share = function (v) {
+							return v === '' ? 0 : v;
+						};
+						request.constraints = {
+							max_bond_share: share(this.maxBondShare),
+							max_issuer_share: share(this.maxIssuerShare),
+							max_bond_type_share: share(this.maxBondTypeShare),
+							max_maturity_year_share: share(this.maxMaturityYearShare)
+						};
+					}
+
 					if (this.enableStructure) {
 						var dict = {};
 